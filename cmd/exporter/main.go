@@ -0,0 +1,124 @@
+// Command exporter dumps the subscription tables into a directory, one file
+// per table, or restores them from such a directory. The export is taken
+// from a single consistent snapshot; the restore runs in one transaction.
+//
+//	exporter -mode export -format ndjson -dir ./backup
+//	exporter -mode restore -format ndjson -dir ./backup -truncate
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+)
+
+const (
+	modeExport  = "export"
+	modeRestore = "restore"
+)
+
+func main() {
+	mode := flag.String("mode", modeExport, "export or restore")
+	format := flag.String("format", string(postgresql.DumpNDJSON), "ndjson or csv")
+	dir := flag.String("dir", "./backup", "directory holding one file per table")
+	truncate := flag.Bool("truncate", false, "on restore, empty the tables and anything referencing them first")
+	flag.Parse()
+
+	cfg := config.MustLoad()
+
+	log := logger.New(cfg.Env)
+	log.Info("starting exporter", slog.String("env", cfg.Env), slog.String("mode", *mode), slog.String("format", *format))
+
+	dumpFormat := postgresql.DumpFormat(*format)
+	if dumpFormat != postgresql.DumpNDJSON && dumpFormat != postgresql.DumpCSV {
+		log.Error("unknown format", slog.String("format", *format))
+		os.Exit(2)
+	}
+
+	// Dumps are bounded by postgresql.stream_timeout, not by the statement
+	// timeout of the application.
+	pgCfg := cfg.PostgreSQL
+	pgCfg.StatementTimeout = 0
+
+	db, err := postgresql.New(pgCfg)
+	if err != nil {
+		log.Error("failed to connect to database", slog.Any("error", err))
+		os.Exit(1)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Warn("failed to close postgresql connection", slog.Any("error", err))
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch *mode {
+	case modeExport:
+		err = export(ctx, db, dumpFormat, *dir, log)
+	case modeRestore:
+		err = restore(ctx, db, dumpFormat, *dir, *truncate, log)
+	default:
+		log.Error("unknown mode", slog.String("mode", *mode))
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Error("exporter failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	log.Info("exporter finished", slog.String("dir", *dir))
+}
+
+func export(ctx context.Context, db *postgresql.Storage, format postgresql.DumpFormat, dir string, log *slog.Logger) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	return db.ExportTables(ctx, postgresql.DumpTables, format, func(table string) (io.WriteCloser, error) {
+		path := dumpPath(dir, table, format)
+		log.Info("exporting table", slog.String("table", table), slog.String("file", path))
+		return os.Create(path)
+	})
+}
+
+// restore loads the tables that have a file in dir; the others are left
+// alone.
+func restore(ctx context.Context, db *postgresql.Storage, format postgresql.DumpFormat, dir string, truncate bool, log *slog.Logger) error {
+	tables := make([]string, 0, len(postgresql.DumpTables))
+	for _, table := range postgresql.DumpTables {
+		_, err := os.Stat(dumpPath(dir, table, format))
+		if errors.Is(err, os.ErrNotExist) {
+			log.Info("no dump for table, skipping", slog.String("table", table))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	}
+	if len(tables) == 0 {
+		return errors.New("no dump files found in " + dir)
+	}
+
+	return db.RestoreTables(ctx, tables, format, truncate, func(table string) (io.ReadCloser, error) {
+		path := dumpPath(dir, table, format)
+		log.Info("restoring table", slog.String("table", table), slog.String("file", path))
+		return os.Open(path)
+	})
+}
+
+func dumpPath(dir, table string, format postgresql.DumpFormat) string {
+	return filepath.Join(dir, table+"."+string(format))
+}
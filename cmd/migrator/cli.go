@@ -0,0 +1,350 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/migrate"
+)
+
+const (
+	defaultMigrationsPath = "./migrations"
+	defaultSeedsPath      = "./seeds"
+
+	upCommand        = "up"
+	downCommand      = "down"
+	statusCommand    = "status"
+	versionCommand   = "version"
+	createCommand    = "create"
+	baselineCommand  = "baseline"
+	forceCommand     = "force"
+	seedCommand      = "seed"
+	validateCommand  = "validate"
+	genConfigCommand = "gen-config"
+)
+
+type commandSpec struct {
+	name    string
+	usage   string
+	summary string
+}
+
+var commands = []commandSpec{
+	{upCommand, "up [flags]", "apply pending migrations; the default command"},
+	{downCommand, "down [flags]", "revert applied migrations, the latest one unless told otherwise"},
+	{statusCommand, "status [flags]", "list migrations with their state"},
+	{versionCommand, "version [flags]", "print the latest applied migration of each target"},
+	{createCommand, "create [flags] <name>", "write an empty up and down migration with the next number"},
+	{baselineCommand, "baseline [flags]", "mark pending migrations as applied without running them"},
+	{forceCommand, "force [flags] <version>", "mark one migration as applied and clear its dirty flag"},
+	{seedCommand, "seed [flags]", "load fixture scripts into the database"},
+	{validateCommand, "validate [flags]", "check the config and print it with secrets redacted"},
+	{genConfigCommand, "gen-config [flags]", "write a commented example config with every default"},
+}
+
+// errUsage is returned once the problem and the usage have been printed.
+var errUsage = errors.New("invalid usage")
+
+// globalFlags may be given before the command as well as after it.
+type globalFlags struct {
+	path    string
+	config  string
+	timeout time.Duration
+}
+
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.path, "path", g.path, "migrations directory, instead of migrations.path or MIGRATIONS_PATH")
+	fs.StringVar(&g.config, "config", g.config, "config file, instead of CONFIG_PATH")
+	fs.DurationVar(&g.timeout, "timeout", g.timeout, "default timeout per migration or seed file; a -- timeout: directive in the file takes precedence")
+}
+
+// targetRunner does the work of a command against one target and returns how
+// many migrations, or seed files, it applied or reverted.
+type targetRunner func(db *sql.DB, target config.MigrationTarget, settings config.MigrationsConfig, log *slog.Logger) (int, error)
+
+// command is a parsed command line. run is set for the commands that change
+// the database and so run under the migration lock; args holds the
+// positional arguments of create and force; generate writes the example
+// config for gen-config; target names the one target seed loads into.
+type command struct {
+	name     string
+	global   globalFlags
+	run      targetRunner
+	args     []string
+	generate func() error
+	target   string
+}
+
+// parseCommandLine reads "[flags] [command] [command flags] [args]"; no
+// command means up.
+func parseCommandLine(args []string) (command, error) {
+	var cmd command
+
+	fs := flag.NewFlagSet("migrator", flag.ContinueOnError)
+	cmd.global.register(fs)
+	fs.Usage = func() {
+		printUsage(fs.Output(), fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return command{}, err
+	}
+
+	args = fs.Args()
+	if len(args) == 0 {
+		args = []string{upCommand}
+	}
+	cmd.name = args[0]
+
+	spec, ok := findCommand(cmd.name)
+	if !ok {
+		return command{}, usageError(fs, "unknown command %q", cmd.name)
+	}
+
+	sub := flag.NewFlagSet(spec.name, flag.ContinueOnError)
+	cmd.global.register(sub)
+	sub.Usage = func() {
+		printCommandUsage(sub.Output(), spec, sub)
+	}
+
+	var err error
+	switch cmd.name {
+	case upCommand:
+		cmd.run, err = parseUp(sub, args[1:])
+	case downCommand:
+		cmd.run, err = parseDown(sub, args[1:])
+	case baselineCommand:
+		cmd.run, err = parseBaseline(sub, args[1:])
+	case seedCommand:
+		cmd.run, cmd.target, err = parseSeed(sub, args[1:])
+	case forceCommand:
+		cmd.args, err = parsePositional(sub, args[1:], "version")
+		if err == nil {
+			version := cmd.args[0]
+			cmd.run = func(db *sql.DB, target config.MigrationTarget, _ config.MigrationsConfig, log *slog.Logger) (int, error) {
+				return migrate.Force(db, target.Path, version, log)
+			}
+		}
+	case createCommand:
+		cmd.args, err = parsePositional(sub, args[1:], "name")
+	case genConfigCommand:
+		cmd.generate, err = parseGenConfig(sub, args[1:])
+	default:
+		_, err = parsePositional(sub, args[1:])
+	}
+	if err != nil {
+		return command{}, err
+	}
+
+	if cmd.global.timeout < 0 {
+		return command{}, usageError(sub, "invalid --timeout %s", cmd.global.timeout)
+	}
+
+	return cmd, nil
+}
+
+func parseUp(fs *flag.FlagSet, args []string) (targetRunner, error) {
+	to := fs.String("to", "", "apply migrations up to and including this version")
+	steps := fs.Int("steps", 0, "apply at most this many migrations per target")
+	allowOutOfOrder := fs.Bool("allow-out-of-order", false, "apply pending migrations numbered below the latest applied one")
+	if _, err := parsePositional(fs, args); err != nil {
+		return nil, err
+	}
+
+	if *steps < 0 {
+		return nil, usageError(fs, "invalid --steps %d", *steps)
+	}
+
+	n, err := parseTargetVersion(*to)
+	if err != nil {
+		return nil, usageError(fs, "%v", err)
+	}
+
+	return func(db *sql.DB, target config.MigrationTarget, settings config.MigrationsConfig, log *slog.Logger) (int, error) {
+		opts := migrate.Options{To: n, Steps: *steps, Timeout: settings.StatementTimeout, AllowOutOfOrder: *allowOutOfOrder}
+		return migrate.Up(db, target.Path, opts, log)
+	}, nil
+}
+
+func parseDown(fs *flag.FlagSet, args []string) (targetRunner, error) {
+	to := fs.String("to", "", "revert the migrations numbered above this version")
+	steps := fs.Int("steps", 0, "revert at most this many migrations per target (default 1)")
+	all := fs.Bool("all", false, "revert every applied migration")
+	if _, err := parsePositional(fs, args); err != nil {
+		return nil, err
+	}
+
+	if *steps < 0 {
+		return nil, usageError(fs, "invalid --steps %d", *steps)
+	}
+
+	n, err := parseTargetVersion(*to)
+	if err != nil {
+		return nil, usageError(fs, "%v", err)
+	}
+
+	bounds := 0
+	for _, set := range []bool{n > 0, *steps > 0, *all} {
+		if set {
+			bounds++
+		}
+	}
+	if bounds > 1 {
+		return nil, usageError(fs, "--to, --steps and --all cannot be combined")
+	}
+
+	// Reverting drops data, so without a bound only the latest migration goes.
+	if bounds == 0 {
+		*steps = 1
+	}
+
+	return func(db *sql.DB, target config.MigrationTarget, settings config.MigrationsConfig, log *slog.Logger) (int, error) {
+		opts := migrate.Options{To: n, Steps: *steps, Timeout: settings.StatementTimeout}
+		return migrate.Down(db, target.Path, opts, log)
+	}, nil
+}
+
+func parseBaseline(fs *flag.FlagSet, args []string) (targetRunner, error) {
+	to := fs.String("to", "", "mark migrations up to and including this version; all of them by default")
+	if _, err := parsePositional(fs, args); err != nil {
+		return nil, err
+	}
+
+	n, err := parseTargetVersion(*to)
+	if err != nil {
+		return nil, usageError(fs, "%v", err)
+	}
+
+	return func(db *sql.DB, target config.MigrationTarget, _ config.MigrationsConfig, log *slog.Logger) (int, error) {
+		return migrate.Baseline(db, target.Path, n, log)
+	}, nil
+}
+
+func parseSeed(fs *flag.FlagSet, args []string) (targetRunner, string, error) {
+	dir := os.Getenv("SEEDS_PATH")
+	if dir == "" {
+		dir = defaultSeedsPath
+	}
+
+	fs.StringVar(&dir, "dir", dir, "directory with the fixture scripts, instead of SEEDS_PATH")
+	target := fs.String("target", "", "migrations target to load the fixtures into, instead of the first one")
+	if _, err := parsePositional(fs, args); err != nil {
+		return nil, "", err
+	}
+
+	return func(db *sql.DB, _ config.MigrationTarget, settings config.MigrationsConfig, log *slog.Logger) (int, error) {
+		return migrate.Seed(db, dir, settings.StatementTimeout, log)
+	}, *target, nil
+}
+
+func parseGenConfig(fs *flag.FlagSet, args []string) (func() error, error) {
+	out := fs.String("out", "", "file to write instead of standard output")
+	force := fs.Bool("force", false, "overwrite the --out file if it exists")
+	if _, err := parsePositional(fs, args); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		if *out == "" {
+			return config.WriteExample(os.Stdout)
+		}
+
+		flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if *force {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+		f, err := os.OpenFile(*out, flags, 0o644)
+		if err != nil {
+			return err
+		}
+
+		if err := config.WriteExample(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}, nil
+}
+
+// parsePositional parses the flags in args and expects exactly the
+// positional arguments named after them.
+func parsePositional(fs *flag.FlagSet, args []string, names ...string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != len(names) {
+		if len(names) == 0 {
+			return nil, usageError(fs, "unexpected argument %q", fs.Arg(0))
+		}
+		return nil, usageError(fs, "expected <%s>", names[0])
+	}
+
+	return fs.Args(), nil
+}
+
+// parseTargetVersion reads the value of --to, the numeric prefix of a
+// migration, so "0005" and "5" are the same. Empty means no bound.
+func parseTargetVersion(to string) (int, error) {
+	if to == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(to)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid --to %q", to)
+	}
+
+	return n, nil
+}
+
+func findCommand(name string) (commandSpec, bool) {
+	for _, spec := range commands {
+		if spec.name == name {
+			return spec, true
+		}
+	}
+	return commandSpec{}, false
+}
+
+// usageError prints the problem followed by the usage, the way the flag
+// package reports a bad flag.
+func usageError(fs *flag.FlagSet, format string, args ...any) error {
+	fmt.Fprintf(fs.Output(), format+"\n", args...)
+	fs.Usage()
+	return errUsage
+}
+
+func printUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: migrator [flags] [command] [command flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, spec := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", spec.name, spec.summary)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "migrator <command> --help" for the flags of a command.`)
+}
+
+func printCommandUsage(w io.Writer, spec commandSpec, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: migrator %s\n\n", spec.usage)
+	fmt.Fprintf(w, "%s.\n\n", strings.ToUpper(spec.summary[:1])+spec.summary[1:])
+	fmt.Fprintln(w, "Flags:")
+	fs.PrintDefaults()
+}
@@ -2,190 +2,171 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
-	"sort"
-	"strings"
 	"time"
 
+	"github.com/Kulibyka/effective-mobile/internal/buildinfo"
 	"github.com/Kulibyka/effective-mobile/internal/config"
 	"github.com/Kulibyka/effective-mobile/internal/logger"
-	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
-)
-
-const (
-	migrationsTable           = "schema_migrations"
-	defaultMigrationsPath     = "./migrations"
-	migrationStatementTimeout = 30 * time.Second
+	"github.com/Kulibyka/effective-mobile/internal/migrate"
+	"github.com/Kulibyka/effective-mobile/internal/vault"
 )
 
 func main() {
-	cfg := config.MustLoad()
-
-	log := logger.New(cfg.Env)
-	log.Info("starting migrator", slog.String("env", cfg.Env))
+	os.Exit(run(os.Args[1:]))
+}
 
-	storage, err := postgresql.New(cfg.PostgreSQL)
+// run carries out the command line and returns the exit code, so the
+// deferred cleanup happens before the process exits.
+func run(args []string) int {
+	cmd, err := parseCommandLine(args)
 	if err != nil {
-		log.Error("failed to connect to database", slog.Any("error", err))
-		os.Exit(1)
-	}
-	defer func() {
-		if err := storage.Close(); err != nil {
-			log.Warn("failed to close database connection", slog.Any("error", err))
+		// The flag set has already printed the problem and the usage.
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
 		}
-	}()
-
-	migrationsPath := os.Getenv("MIGRATIONS_PATH")
-	if migrationsPath == "" {
-		migrationsPath = defaultMigrationsPath
+		return 2
 	}
 
-	if err := runMigrations(storage.GetDB(), migrationsPath, log); err != nil {
-		log.Error("migration failed", slog.Any("error", err))
-		os.Exit(1)
+	// gen-config writes a new config rather than reading one.
+	if cmd.name == genConfigCommand {
+		if err := cmd.generate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
 	}
 
-	log.Info("migrations applied successfully")
-}
-
-func runMigrations(db *sql.DB, migrationsPath string, log *slog.Logger) error {
-	info, err := os.Stat(migrationsPath)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("migrations directory does not exist: %s", migrationsPath)
+	// create only touches the migrations directory, so it needs neither the
+	// config nor a database.
+	if cmd.name == createCommand {
+		path := cmd.global.path
+		if path == "" {
+			path = migrationsPath()
 		}
 
-		return fmt.Errorf("failed to access migrations directory: %w", err)
-	}
-
-	if !info.IsDir() {
-		return fmt.Errorf("migrations path is not a directory: %s", migrationsPath)
+		paths, err := migrate.Create(path, cmd.args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		return 0
 	}
 
-	ctx := context.Background()
+	cfg := loadConfig(cmd.global)
 
-	if err := ensureMigrationsTable(ctx, db); err != nil {
-		return err
+	if cmd.name == validateCommand {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
 	}
 
-	entries, err := os.ReadDir(migrationsPath)
-	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %w", err)
-	}
+	log := logger.New(cfg.Env, logger.WithExport(cfg.LogExport), logger.WithFile(cfg.LogFile))
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
 
-	files := make([]string, 0, len(entries))
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+		if err := logger.Shutdown(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err)
 		}
+	}()
+	log.Info("starting migrator", slog.String("env", cfg.Env), slog.String("command", cmd.name), slog.Any("build", buildinfo.Get()))
 
-		name := entry.Name()
-		if strings.HasSuffix(name, ".up.sql") {
-			files = append(files, filepath.Join(migrationsPath, name))
+	// The migrator finishes well within a lease, so the credentials are read
+	// once and left to expire.
+	if cfg.Vault.Enabled {
+		creds, err := vault.NewProvider(context.Background(), vault.New(cfg.Vault), cfg.Vault.RetryBackoff, log)
+		if err != nil {
+			log.Error("failed to get database credentials from vault", slog.Any("error", err))
+			return 1
 		}
+		cfg.PostgreSQL.User, cfg.PostgreSQL.Password = creds.Credentials()
 	}
 
-	sort.Strings(files)
+	targets := migrationTargets(cfg)
+	if cmd.name == seedCommand {
+		targets, err = seedTarget(targets, cmd.target)
+		if err != nil {
+			log.Error("failed to pick seed target", slog.Any("error", err))
+			return 1
+		}
+	}
 
-	applied, err := loadAppliedMigrations(ctx, db)
+	targets, cleanup, err := fetchTargets(targets, cfg.Migrations.SourceTimeout, log)
 	if err != nil {
-		return err
+		log.Error("failed to fetch migrations", slog.Any("error", err))
+		return 1
 	}
+	defer cleanup()
 
-	for _, file := range files {
-		version := strings.TrimSuffix(filepath.Base(file), ".up.sql")
-		if _, ok := applied[version]; ok {
-			log.Info("migration already applied", slog.String("version", version))
-			continue
+	switch cmd.name {
+	case statusCommand:
+		if err := printStatus(os.Stdout, targets, log); err != nil {
+			return 1
 		}
-
-		contents, err := os.ReadFile(file)
-		if err != nil {
-			return fmt.Errorf("failed to read migration %s: %w", file, err)
+		return 0
+	case versionCommand:
+		if err := printVersions(os.Stdout, targets, log); err != nil {
+			return 1
 		}
+		return 0
+	}
 
-		log.Info("applying migration", slog.String("version", version), slog.String("file", file))
+	results := make([]targetResult, 0, len(targets))
 
-		if err := execMigration(ctx, db, string(contents)); err != nil {
-			return fmt.Errorf("failed to apply migration %s: %w", file, err)
+	failed := false
+	for _, target := range targets {
+		if failed {
+			results = append(results, targetResult{name: target.Name, status: targetSkipped})
+			continue
 		}
 
-		if err := markMigrationApplied(ctx, db, version); err != nil {
-			return err
-		}
+		result := migrateTarget(target, cfg.Migrations, cmd.run, log)
+		results = append(results, result)
+		failed = result.err != nil
 	}
 
-	return nil
-}
-
-func ensureMigrationsTable(ctx context.Context, db *sql.DB) error {
-	execCtx, cancel := context.WithTimeout(ctx, migrationStatementTimeout)
-	defer cancel()
+	reportTargets(results, log)
+	printRunSummary(os.Stdout, results)
 
-	const query = `CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (
-        version TEXT PRIMARY KEY,
-        applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-)`
-
-	if _, err := db.ExecContext(execCtx, query); err != nil {
-		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	if failed {
+		return 1
 	}
 
-	return nil
+	log.Info("migrator finished successfully")
+	return 0
 }
 
-func loadAppliedMigrations(ctx context.Context, db *sql.DB) (map[string]struct{}, error) {
-	queryCtx, cancel := context.WithTimeout(ctx, migrationStatementTimeout)
-	defer cancel()
-
-	rows, err := db.QueryContext(queryCtx, "SELECT version FROM "+migrationsTable)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+// loadConfig reads the config named by --config, or by CONFIG_PATH, and
+// applies the command line overrides on top of it.
+func loadConfig(global globalFlags) *config.Config {
+	var cfg *config.Config
+	if global.config != "" {
+		cfg = config.MustLoadFile(global.config)
+	} else {
+		cfg = config.MustLoad()
 	}
-	defer rows.Close()
 
-	applied := make(map[string]struct{})
-	for rows.Next() {
-		var version string
-		if err := rows.Scan(&version); err != nil {
-			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+	if global.path != "" {
+		if len(cfg.Migrations.Targets) > 0 {
+			fmt.Fprintln(os.Stderr, "--path cannot be used with migrations.targets, each target sets its own path")
+			os.Exit(2)
 		}
-
-		applied[version] = struct{}{}
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate applied migrations: %w", err)
-	}
-
-	return applied, nil
-}
-
-func markMigrationApplied(ctx context.Context, db *sql.DB, version string) error {
-	execCtx, cancel := context.WithTimeout(ctx, migrationStatementTimeout)
-	defer cancel()
-
-	const query = "INSERT INTO " + migrationsTable + " (version) VALUES ($1)"
-
-	if _, err := db.ExecContext(execCtx, query, version); err != nil {
-		return fmt.Errorf("failed to mark migration %s as applied: %w", version, err)
+		cfg.Migrations.Path = global.path
 	}
 
-	return nil
-}
-
-func execMigration(ctx context.Context, db *sql.DB, statement string) error {
-	execCtx, cancel := context.WithTimeout(ctx, migrationStatementTimeout)
-	defer cancel()
-
-	if _, err := db.ExecContext(execCtx, statement); err != nil {
-		return err
+	if global.timeout > 0 {
+		cfg.Migrations.StatementTimeout = global.timeout
 	}
 
-	return nil
+	return cfg
 }
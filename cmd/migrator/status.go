@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/migrate"
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+)
+
+// printStatus writes, for every target, each migration with its state. A
+// failing target is logged and the next one still reported.
+func printStatus(w io.Writer, targets []config.MigrationTarget, log *slog.Logger) error {
+	var failed error
+	for _, target := range targets {
+		statuses, err := targetStatus(target)
+		if err != nil {
+			log.Error("failed to read migration status", slog.String("target", target.Name), slog.Any("error", err))
+			failed = err
+			continue
+		}
+
+		writeStatus(w, target, statuses)
+	}
+
+	return failed
+}
+
+func targetStatus(target config.MigrationTarget) ([]migrate.MigrationStatus, error) {
+	storage, err := postgresql.New(target.PostgreSQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		_ = storage.Close()
+	}()
+
+	return migrate.Status(storage.GetDB(), target.Path)
+}
+
+// printVersions writes the latest applied migration of every target.
+func printVersions(w io.Writer, targets []config.MigrationTarget, log *slog.Logger) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer func() {
+		_ = tw.Flush()
+	}()
+
+	fmt.Fprintln(tw, "TARGET\tVERSION")
+
+	var failed error
+	for _, target := range targets {
+		version, dirty, err := targetVersion(target)
+		if err != nil {
+			log.Error("failed to read migration version", slog.String("target", target.Name), slog.Any("error", err))
+			failed = err
+			continue
+		}
+
+		switch {
+		case version == "":
+			version = "-"
+		case dirty:
+			version += " (dirty)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", target.Name, version)
+	}
+
+	return failed
+}
+
+func targetVersion(target config.MigrationTarget) (string, bool, error) {
+	storage, err := postgresql.New(target.PostgreSQL)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		_ = storage.Close()
+	}()
+
+	return migrate.Version(storage.GetDB())
+}
+
+func writeStatus(w io.Writer, target config.MigrationTarget, statuses []migrate.MigrationStatus) {
+	counts := make(map[string]int)
+
+	fmt.Fprintf(w, "target %s (%s)\n", target.Name, target.Path)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", status.Version, status.State, appliedAt)
+		counts[status.State]++
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintf(w, "%d applied, %d dirty, %d pending, %d changed, %d missing on disk\n\n",
+		counts[migrate.StateApplied], counts[migrate.StateDirty], counts[migrate.StatePending], counts[migrate.StateChanged], counts[migrate.StateMissingOnDisk])
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/migrate"
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+)
+
+const (
+	defaultTargetName = "default"
+
+	targetApplied = "applied"
+	targetFailed  = "failed"
+	targetSkipped = "skipped"
+)
+
+type targetResult struct {
+	name    string
+	status  string
+	applied int
+	err     error
+	// recorded holds the schema_migrations rows written by this run.
+	recorded []migrate.Record
+}
+
+func migrationTargets(cfg *config.Config) []config.MigrationTarget {
+	if len(cfg.Migrations.Targets) > 0 {
+		return cfg.Migrations.Targets
+	}
+
+	return []config.MigrationTarget{{
+		Name:       defaultTargetName,
+		Path:       cfg.Migrations.Path,
+		PostgreSQL: cfg.PostgreSQL,
+	}}
+}
+
+// seedTarget picks the only target seed loads fixtures into: the named one
+// or, without a name, the first, which holds the application schema.
+func seedTarget(targets []config.MigrationTarget, name string) ([]config.MigrationTarget, error) {
+	if name == "" {
+		return targets[:1], nil
+	}
+
+	for _, target := range targets {
+		if target.Name == name {
+			return []config.MigrationTarget{target}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown migrations target %q", name)
+}
+
+// fetchTargets downloads the migrations of the targets whose path is a URL
+// and points them at the local copy, which cleanup removes.
+func fetchTargets(targets []config.MigrationTarget, timeout time.Duration, log *slog.Logger) ([]config.MigrationTarget, func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cleanups []func()
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+
+	fetched := make([]config.MigrationTarget, len(targets))
+	for i, target := range targets {
+		fetched[i] = target
+		if !migrate.IsRemote(target.Path) {
+			continue
+		}
+
+		dir, remove, err := migrate.Fetch(ctx, target.Path)
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("target %s: %w", target.Name, err)
+		}
+		cleanups = append(cleanups, remove)
+
+		log.Info("fetched migrations", slog.String("target", target.Name), slog.String("dir", dir))
+		fetched[i].Path = dir
+	}
+
+	return fetched, cleanup, nil
+}
+
+// migrationsPath is where create writes, before any config is loaded.
+func migrationsPath() string {
+	if path := os.Getenv("MIGRATIONS_PATH"); path != "" {
+		return path
+	}
+	return defaultMigrationsPath
+}
+
+func migrateTarget(target config.MigrationTarget, settings config.MigrationsConfig, run targetRunner, log *slog.Logger) targetResult {
+	log = log.With(slog.String("target", target.Name))
+	log.Info("migrating target", slog.String("path", target.Path))
+
+	result := targetResult{name: target.Name}
+
+	// Migrations carry their own timeouts; the session statement_timeout of
+	// the application would cut long index builds short.
+	pgCfg := target.PostgreSQL
+	pgCfg.StatementTimeout = 0
+
+	storage, err := postgresql.New(pgCfg)
+	if err != nil {
+		log.Error("failed to connect to database", slog.Any("error", err))
+		result.status = targetFailed
+		result.err = err
+		return result
+	}
+	defer func() {
+		if err := storage.Close(); err != nil {
+			log.Warn("failed to close database connection", slog.Any("error", err))
+		}
+	}()
+
+	lock, err := migrate.Lock(storage, settings.LockTimeout, log)
+	if err != nil {
+		log.Error("failed to acquire migration lock", slog.Any("error", err))
+		result.status = targetFailed
+		result.err = err
+		return result
+	}
+	defer func() {
+		if err := lock.Release(context.Background()); err != nil {
+			log.Warn("failed to release migration lock", slog.Any("error", err))
+		}
+	}()
+
+	db := storage.GetDB()
+
+	var started time.Time
+	if err := db.QueryRowContext(context.Background(), "SELECT NOW()").Scan(&started); err != nil {
+		log.Warn("failed to read database time, no run summary", slog.Any("error", err))
+	}
+
+	result.applied, result.err = run(db, target, settings, log)
+
+	if !started.IsZero() {
+		recorded, err := migrate.RecordedSince(db, started)
+		if err != nil {
+			log.Warn("failed to load run summary", slog.Any("error", err))
+		}
+		result.recorded = recorded
+	}
+
+	if result.err != nil {
+		log.Error("migration failed", slog.Any("error", result.err))
+		result.status = targetFailed
+		return result
+	}
+
+	result.status = targetApplied
+	return result
+}
+
+// printRunSummary writes one table row per migration recorded by this run.
+func printRunSummary(w io.Writer, results []targetResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := false
+	for _, result := range results {
+		for _, r := range result.recorded {
+			if !header {
+				fmt.Fprintln(tw, "TARGET\tVERSION\tDURATION\tCHECKSUM\tAPPLIED BY")
+				header = true
+			}
+
+			duration, sum, by := "-", "-", "-"
+			if r.DurationMs != nil {
+				duration = (time.Duration(*r.DurationMs) * time.Millisecond).String()
+			}
+			if r.Checksum != nil && len(*r.Checksum) >= 12 {
+				sum = (*r.Checksum)[:12]
+			}
+			if r.AppliedBy != nil {
+				by = *r.AppliedBy
+				if r.AppliedHost != nil {
+					by += "@" + *r.AppliedHost
+				}
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.name, r.Version, duration, sum, by)
+		}
+	}
+	_ = tw.Flush()
+}
+
+func reportTargets(results []targetResult, log *slog.Logger) {
+	for _, result := range results {
+		attrs := []any{
+			slog.String("target", result.name),
+			slog.String("status", result.status),
+			slog.Int("applied", result.applied),
+		}
+		if result.err != nil {
+			attrs = append(attrs, slog.Any("error", result.err))
+		}
+
+		log.Info("migration target finished", attrs...)
+	}
+}
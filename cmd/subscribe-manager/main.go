@@ -2,30 +2,79 @@ package main
 
 import (
 	"context"
-	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/Kulibyka/effective-mobile/internal/app"
+	"github.com/Kulibyka/effective-mobile/internal/buildinfo"
 	"github.com/Kulibyka/effective-mobile/internal/config"
-	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
-	"github.com/Kulibyka/effective-mobile/internal/http/handlers/subscriptions"
-	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
 	"github.com/Kulibyka/effective-mobile/internal/logger"
-	service "github.com/Kulibyka/effective-mobile/internal/services/subscriptions"
+	"github.com/Kulibyka/effective-mobile/internal/migrate"
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
 	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+	"github.com/Kulibyka/effective-mobile/internal/vault"
 )
 
 func main() {
-	cfg := config.MustLoad()
+	validate := flag.Bool("validate", false, "load and validate the config, print it with secrets redacted and exit")
+	genConfig := flag.Bool("gen-config", false, "print a commented example config with every default and exit")
+	flag.Parse()
+
+	if *genConfig {
+		if err := config.WriteExample(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configPath := config.Path()
+
+	if *validate {
+		os.Exit(validateConfig(configPath))
+	}
+
+	cfg := config.MustLoadFile(configPath)
+
+	level := new(slog.LevelVar)
+	log := setupLogger(cfg, level)
+	defer shutdownLogger()
 
-	log := setupLogger(cfg.Env)
-	log.Info("starting app", slog.String("env", cfg.Env))
+	runtimeCfg := runtimeconfig.New(cfg.Env, cfg.Runtime, level, func() (*config.Config, error) {
+		return config.Load(configPath)
+	}, log)
+
+	log.Info("starting app", slog.String("env", cfg.Env), slog.Any("build", buildinfo.Get()))
 	log.Debug("debug messages are enabled")
 
-	db, err := postgresql.New(cfg.PostgreSQL)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var (
+		dbOpts      []postgresql.Option
+		credentials *vault.Provider
+	)
+	if cfg.Vault.Enabled {
+		var err error
+		credentials, err = vault.NewProvider(ctx, vault.New(cfg.Vault), cfg.Vault.RetryBackoff, log)
+		if err != nil {
+			panic(err)
+		}
+		dbOpts = append(dbOpts, postgresql.WithCredentials(credentials.Credentials))
+	}
+
+	if cfg.Migrations.AutoMigrate {
+		if err := runMigrations(cfg, log, dbOpts...); err != nil {
+			panic(err)
+		}
+	}
+
+	db, err := postgresql.New(cfg.PostgreSQL, dbOpts...)
 	if err != nil {
 		panic(err)
 	}
@@ -35,82 +84,103 @@ func main() {
 		}
 	}()
 
-	repo := &storageWrapper{Storage: db}
-	subscriptionsService := service.New(repo, log)
-	handler := subscriptions.New(subscriptionsService, log)
+	if credentials != nil {
+		credentials.OnRotate(db.ResetConnections)
+		go credentials.Run(ctx)
+	}
 
-	mux := http.NewServeMux()
-	handler.Register(mux)
+	runtimeCfg.Watch(ctx, configPath)
 
-	mux.HandleFunc("/swagger", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/swagger" {
-			http.NotFound(w, r)
-			return
+	application, err := app.New(cfg, runtimeCfg, db, log)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := application.Close(); err != nil {
+			log.Warn("failed to close event publisher", slog.Any("error", err))
 		}
+	}()
 
-		http.Redirect(w, r, "/swagger/", http.StatusMovedPermanently)
-	})
-	mux.Handle("/swagger/", http.StripPrefix("/swagger/", http.FileServer(http.Dir("docs/swagger"))))
-
-	server := &http.Server{
-		Addr:         cfg.HTTPServer.Address,
-		Handler:      mux,
-		ReadTimeout:  cfg.HTTPServer.Timeout,
-		WriteTimeout: cfg.HTTPServer.Timeout,
-		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+	if err := application.Run(ctx); err != nil {
+		log.Error("http server error", slog.Any("error", err))
 	}
+}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
-
-	go func() {
-		<-ctx.Done()
+// runMigrations applies the pending migrations over a connection of its own
+// without the application statement_timeout, holding the migrator lock so
+// replicas starting together take turns.
+func runMigrations(cfg *config.Config, log *slog.Logger, opts ...postgresql.Option) error {
+	pgCfg := cfg.PostgreSQL
+	pgCfg.StatementTimeout = 0
 
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.Timeout)
-		defer cancel()
+	storage, err := postgresql.New(pgCfg, opts...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := storage.Close(); err != nil {
+			log.Warn("failed to close migration connection", slog.Any("error", err))
+		}
+	}()
 
-		log.Info("shutting down http server")
-		if err := server.Shutdown(shutdownCtx); err != nil {
-			log.Error("failed to shutdown http server", slog.Any("error", err))
+	lock, err := migrate.Lock(storage, cfg.Migrations.LockTimeout, log)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if err := lock.Release(context.Background()); err != nil {
+			log.Warn("failed to release migration lock", slog.Any("error", err))
 		}
 	}()
 
-	log.Info("starting http server", slog.String("address", cfg.HTTPServer.Address))
+	fetchCtx, cancel := context.WithTimeout(context.Background(), cfg.Migrations.SourceTimeout)
+	defer cancel()
 
-	if err := server.ListenAndServe(); err != nil {
-		if !errors.Is(err, http.ErrServerClosed) {
-			log.Error("http server error", slog.Any("error", err))
-		}
+	dir, cleanup, err := migrate.Fetch(fetchCtx, cfg.Migrations.Path)
+	if err != nil {
+		return fmt.Errorf("failed to fetch migrations: %w", err)
 	}
-}
+	defer cleanup()
 
-func setupLogger(env string) *slog.Logger {
-	log := logger.New(env)
-	log.Debug("logger configured", slog.String("mode", env))
+	applied, err := migrate.Up(storage.GetDB(), dir, migrate.Options{Timeout: cfg.Migrations.StatementTimeout}, log)
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	log.Info("migrations applied", slog.Int("count", applied))
 
-	return log
+	return nil
 }
 
-type storageWrapper struct {
-	*postgresql.Storage
-}
+// validateConfig prints the effective config, with the environment applied,
+// or the problems with it, for deploy pipelines to check before rolling out.
+func validateConfig(path string) int {
+	cfg, err := config.Load(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
 
-func (s *storageWrapper) CreateSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, error) {
-	return s.Storage.CreateSubscription(ctx, input)
-}
+	if err := config.Print(os.Stdout, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
 
-func (s *storageWrapper) GetSubscription(ctx context.Context, id uuid.UUID) (domain.Subscription, error) {
-	return s.Storage.GetSubscription(ctx, id)
+	return 0
 }
 
-func (s *storageWrapper) UpdateSubscription(ctx context.Context, id uuid.UUID, input domain.UpdateInput) (domain.Subscription, error) {
-	return s.Storage.UpdateSubscription(ctx, id, input)
-}
+func setupLogger(cfg *config.Config, level *slog.LevelVar) *slog.Logger {
+	log := logger.NewWithLevel(cfg.Env, level, logger.WithExport(cfg.LogExport), logger.WithFile(cfg.LogFile))
+	log.Debug("logger configured", slog.String("mode", cfg.Env), slog.String("export", cfg.LogExport.Exporter))
 
-func (s *storageWrapper) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
-	return s.Storage.DeleteSubscription(ctx, id)
+	return log
 }
 
-func (s *storageWrapper) ListSubscriptions(ctx context.Context, filter domain.ListFilter) ([]domain.Subscription, error) {
-	return s.Storage.ListSubscriptions(ctx, filter)
+// shutdownLogger sends the logs still queued for export.
+func shutdownLogger() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := logger.Shutdown(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
 }
@@ -0,0 +1,87 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+var ErrForbidden = errors.New("outside of the caller's allowed scope")
+
+// Scope restricts which subscriptions a caller may see or change. Empty sets
+// mean no restriction on that dimension. Admin grants the admin API and Role
+// selects the response fields masked for the caller.
+type Scope struct {
+	Name     string
+	UserIDs  []uuid.UUID
+	Services []string
+	Admin    bool
+	Role     string
+}
+
+type ctxKey struct{}
+
+func NewContext(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, ctxKey{}, scope)
+}
+
+func FromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(ctxKey{}).(Scope)
+	return scope, ok
+}
+
+func (s Scope) Restricted() bool {
+	return len(s.UserIDs) > 0 || len(s.Services) > 0
+}
+
+func (s Scope) Allows(userID uuid.UUID, serviceName string) bool {
+	if !s.AllowsUser(userID) {
+		return false
+	}
+
+	if len(s.Services) > 0 && !slices.Contains(s.Services, serviceName) {
+		return false
+	}
+
+	return true
+}
+
+// AllowsUser is Allows for the user dimension alone.
+func (s Scope) AllowsUser(userID uuid.UUID) bool {
+	return len(s.UserIDs) == 0 || slices.Contains(s.UserIDs, userID)
+}
+
+// IsAdmin reports whether ctx carries a scope granting the admin API.
+func IsAdmin(ctx context.Context) bool {
+	scope, ok := FromContext(ctx)
+	return ok && scope.Admin
+}
+
+// Apply narrows filter to the scope. It reports false when the filter asks
+// for data outside the scope, in which case nothing should be returned.
+func (s Scope) Apply(filter domain.ListFilter) (domain.ListFilter, bool) {
+	if len(s.UserIDs) > 0 {
+		if !filter.UserID.IsNil() {
+			if !slices.Contains(s.UserIDs, filter.UserID) {
+				return filter, false
+			}
+		} else {
+			filter.UserIDs = s.UserIDs
+		}
+	}
+
+	if len(s.Services) > 0 {
+		if filter.ServiceName != nil {
+			if !slices.Contains(s.Services, *filter.ServiceName) {
+				return filter, false
+			}
+		} else {
+			filter.ServiceNames = s.Services
+		}
+	}
+
+	return filter, true
+}
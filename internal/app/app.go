@@ -0,0 +1,545 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Kulibyka/effective-mobile/internal/audit"
+	"github.com/Kulibyka/effective-mobile/internal/buildinfo"
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/events"
+	"github.com/Kulibyka/effective-mobile/internal/health"
+	"github.com/Kulibyka/effective-mobile/internal/http/apikey"
+	"github.com/Kulibyka/effective-mobile/internal/http/bodylog"
+	"github.com/Kulibyka/effective-mobile/internal/http/cors"
+	"github.com/Kulibyka/effective-mobile/internal/http/handlers/admin"
+	"github.com/Kulibyka/effective-mobile/internal/http/handlers/budgets"
+	"github.com/Kulibyka/effective-mobile/internal/http/handlers/categories"
+	"github.com/Kulibyka/effective-mobile/internal/http/handlers/eventstream"
+	healthhttp "github.com/Kulibyka/effective-mobile/internal/http/handlers/health"
+	"github.com/Kulibyka/effective-mobile/internal/http/handlers/public"
+	"github.com/Kulibyka/effective-mobile/internal/http/handlers/subscriptions"
+	"github.com/Kulibyka/effective-mobile/internal/http/handlers/suggestions"
+	"github.com/Kulibyka/effective-mobile/internal/http/handlers/telegramlinks"
+	"github.com/Kulibyka/effective-mobile/internal/http/handlers/version"
+	"github.com/Kulibyka/effective-mobile/internal/http/masking"
+	"github.com/Kulibyka/effective-mobile/internal/http/ratelimit"
+	"github.com/Kulibyka/effective-mobile/internal/http/requestlog"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+	"github.com/Kulibyka/effective-mobile/internal/http/slo"
+	"github.com/Kulibyka/effective-mobile/internal/jobs/budgetalerts"
+	"github.com/Kulibyka/effective-mobile/internal/jobs/dbhealth"
+	"github.com/Kulibyka/effective-mobile/internal/jobs/expiration"
+	"github.com/Kulibyka/effective-mobile/internal/jobs/prices"
+	"github.com/Kulibyka/effective-mobile/internal/jobs/reporting"
+	"github.com/Kulibyka/effective-mobile/internal/jobs/retention"
+	"github.com/Kulibyka/effective-mobile/internal/jobs/rollup"
+	"github.com/Kulibyka/effective-mobile/internal/metrics"
+	"github.com/Kulibyka/effective-mobile/internal/notifications"
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
+	budgetsvc "github.com/Kulibyka/effective-mobile/internal/services/budgets"
+	categorysvc "github.com/Kulibyka/effective-mobile/internal/services/categories"
+	"github.com/Kulibyka/effective-mobile/internal/services/pricenotices"
+	"github.com/Kulibyka/effective-mobile/internal/services/reports"
+	"github.com/Kulibyka/effective-mobile/internal/services/stats"
+	service "github.com/Kulibyka/effective-mobile/internal/services/subscriptions"
+	suggestionsvc "github.com/Kulibyka/effective-mobile/internal/services/suggestions"
+	telegramlinksvc "github.com/Kulibyka/effective-mobile/internal/services/telegramlinks"
+	"github.com/Kulibyka/effective-mobile/internal/storage/cache"
+	"github.com/Kulibyka/effective-mobile/internal/storage/mongodb"
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+	"github.com/Kulibyka/effective-mobile/internal/storage/slowlog"
+	"github.com/Kulibyka/effective-mobile/internal/telegram"
+)
+
+// App is the fully wired application: the HTTP handler with its middleware
+// chain and the background workers enabled in the config.
+type App struct {
+	cfg       *config.Config
+	runtime   *runtimeconfig.Store
+	handler   http.Handler
+	metrics   http.Handler
+	workers   []func(ctx context.Context)
+	publisher events.Publisher
+	feed      *events.ChangeFeed
+	mongo     *mongodb.Storage
+	redis     *redis.Client
+	tracker   *errortracker.Tracker
+	audit     *audit.Logger
+	log       *slog.Logger
+}
+
+// New wires the application. runtime supplies the settings that can be
+// reloaded while it runs.
+func New(cfg *config.Config, runtime *runtimeconfig.Store, db *postgresql.Storage, log *slog.Logger) (*App, error) {
+	publisher, err := events.NewPublisher(cfg.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &App{cfg: cfg, runtime: runtime, publisher: publisher, log: log}
+	if err := a.wire(db); err != nil {
+		_ = a.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *App) wire(db *postgresql.Storage) error {
+	cfg, log := a.cfg, a.log
+
+	checker := health.New()
+	checker.Ping("postgresql", "database", db.Ping)
+
+	publisher := a.publisher
+	if cfg.Events.Publisher != "" && cfg.Events.Publisher != events.PublisherNone {
+		publisher = observedPublisher{Publisher: publisher, probe: checker.Observe(cfg.Events.Publisher, "message_broker")}
+		publisher = events.NewAsyncPublisher(publisher, cfg.Events.QueueSize, cfg.HTTPServer.ShutdownTimeout, log)
+		a.publisher = publisher
+	}
+
+	bus := events.NewBus(log)
+	bus.Subscribe("publisher", events.PublishTo(publisher))
+
+	// Event stream clients follow the local bus, or with the change feed the
+	// events of every instance.
+	streamBus := bus
+	if cfg.Events.ChangeFeed.Enabled {
+		a.feed = events.NewChangeFeed(db, cfg.Events.ChangeFeed, log)
+		bus.Subscribe("change_feed", events.PublishTo(a.feed))
+		a.workers = append(a.workers, a.feed.Run)
+		streamBus = a.feed.Bus()
+	}
+
+	queryGuard, err := service.NewQueryGuard(cfg.QueryGuard)
+	if err != nil {
+		return err
+	}
+
+	a.audit, err = audit.New(cfg.Audit)
+	if err != nil {
+		return err
+	}
+
+	var (
+		repo        service.Repository
+		priceRepo   pricenotices.Repository
+		reportsFrom reports.SubscriptionSource
+
+		suggestionsRepo suggestionsvc.Repository = db
+		categoriesRepo  categorysvc.Repository   = db
+		statsRepo       stats.Repository         = db
+	)
+
+	switch cfg.Storage.Backend {
+	case "postgresql":
+		repo, priceRepo, reportsFrom = &storageWrapper{Storage: db}, db, db
+	case "mongodb":
+		a.mongo, err = mongodb.New(context.Background(), cfg.MongoDB)
+		if err != nil {
+			return err
+		}
+
+		checker.Ping("mongodb", "database", a.mongo.Ping)
+
+		mongoRepo := &mongoRepository{Storage: a.mongo, categories: db}
+		repo, priceRepo, reportsFrom = mongoRepo, a.mongo, mongoRepo
+		suggestionsRepo, statsRepo = &mongoSuggestions{Storage: db, subscriptions: a.mongo}, a.mongo
+	default:
+		return fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+
+	if cfg.Storage.SlowQueryThreshold > 0 {
+		repo = slowlog.New(repo, cfg.Storage.SlowQueryThreshold, log)
+	}
+
+	if cfg.Cache.Enabled {
+		a.redis, err = cache.NewClient(context.Background(), cfg.Cache)
+		if err != nil {
+			return err
+		}
+
+		checker.Ping("redis", "cache", func(ctx context.Context) error {
+			return a.redis.Ping(ctx).Err()
+		})
+
+		repo = cache.New(repo, a.redis, cfg.Cache, log)
+		suggestionsRepo = cache.NewSuggestions(suggestionsRepo, a.redis, cfg.Cache, log)
+		categoriesRepo = cache.NewCategories(categoriesRepo, a.redis, cfg.Cache, log)
+	}
+
+	subscriptionsService := service.New(repo, bus, queryGuard, a.audit, log)
+	handler := subscriptions.New(subscriptionsService, log)
+
+	if cfg.Jobs.Expiration.Enabled {
+		a.workers = append(a.workers, singleton(db, "expiration", cfg.Jobs.LockRetry, log, expiration.New(subscriptionsService, cfg.Jobs.Expiration.Interval, log).Run))
+	}
+
+	// The rollup table lives in PostgreSQL and only covers subscriptions
+	// stored there.
+	if cfg.Jobs.Rollup.Enabled && a.mongo == nil {
+		a.workers = append(a.workers, singleton(db, "rollup", cfg.Jobs.LockRetry, log, rollup.New(db, cfg.Jobs.Rollup.Interval, cfg.Jobs.Rollup.HorizonMonths, log).Run))
+	}
+
+	var linksHandler *telegramlinks.Handler
+	if cfg.Notifications.Telegram.Enabled && cfg.Notifications.Telegram.Commands {
+		// Telegram answers concurrent getUpdates pollers with 409, so only
+		// one instance runs the bot.
+		bot := telegram.NewBot(telegram.NewClient(cfg.Notifications.Telegram), subscriptionsService, db, log)
+		a.workers = append(a.workers, singleton(db, "telegram_bot", cfg.Jobs.LockRetry, log, bot.Run))
+		linksHandler = telegramlinks.New(telegramlinksvc.New(db, cfg.Notifications.Telegram.LinkTokenTTL, a.audit, log), log)
+	}
+
+	var dbMonitor *dbhealth.Monitor
+	if cfg.Jobs.DBHealth.Enabled {
+		dbMonitor = dbhealth.New(db, cfg.Jobs.DBHealth, log)
+		a.workers = append(a.workers, dbMonitor.Run)
+	}
+
+	var retentionJob *retention.Job
+	if cfg.Jobs.Retention.Enabled {
+		retentionJob = retention.New(subscriptionsService, cfg.Jobs.Retention, log)
+		a.workers = append(a.workers, singleton(db, "retention", cfg.Jobs.LockRetry, log, retentionJob.Run))
+	}
+
+	suggestionsService := suggestionsvc.New(suggestionsRepo, bus, a.audit, log)
+	suggestionsHandler := suggestions.New(suggestionsService, log)
+
+	categoriesHandler := categories.New(categorysvc.New(categoriesRepo, a.audit, log), log)
+
+	statsService := stats.New(statsRepo, cfg.PublicStats.MinCohortSize, log)
+	publicHandler := public.New(statsService, log)
+
+	var emailSender notifications.Sender
+	if cfg.Notifications.Email.Enabled {
+		emailSender, err = notifications.NewEmailSender(cfg.Notifications.Email, log)
+		if err != nil {
+			return err
+		}
+		emailSender = observedSender{Sender: emailSender, probe: checker.Observe("email", "notifications")}
+	}
+
+	templates, err := notifications.NewTemplates()
+	if err != nil {
+		return err
+	}
+	senders := &userSenders{cfg: cfg, db: db, checker: checker, built: make(map[string]notifications.Sender)}
+
+	if cfg.Jobs.Prices.Enabled {
+		notifier := notifications.New(senders.sender(cfg.Jobs.Prices.Channels, log), templates, log)
+		noticesService := pricenotices.New(priceRepo, notifier, cfg.Jobs.Prices.NoticeLead, log)
+
+		a.workers = append(a.workers, singleton(db, "prices", cfg.Jobs.LockRetry, log, prices.New(noticesService, subscriptionsService, cfg.Jobs.Prices.Interval, log).Run))
+	}
+
+	var budgetNotifier budgetsvc.Notifier
+	if cfg.Jobs.Budgets.Enabled {
+		budgetNotifier = notifications.New(senders.sender(cfg.Jobs.Budgets.Channels, log), templates, log)
+	}
+	budgetsService := budgetsvc.New(db, subscriptionsService, budgetNotifier, a.audit, log)
+	if cfg.Jobs.Budgets.Enabled {
+		a.workers = append(a.workers, singleton(db, "budget_alerts", cfg.Jobs.LockRetry, log, budgetalerts.New(budgetsService, cfg.Jobs.Budgets.Interval, log).Run))
+	}
+
+	reportsService := reports.New(db, reportsFrom, reports.NewDelivery(cfg.Reports, emailSender), cfg.Reports.BatchSize, cfg.Reports.Lease, a.audit, log)
+	if cfg.Reports.Enabled {
+		a.workers = append(a.workers, reporting.New(reportsService, cfg.Reports.Interval, log).Run)
+	}
+
+	sloTracker := slo.NewTracker(cfg.SLO)
+	adminHandler := admin.New(sloTracker, subscriptionsService, reportsService, a.runtime, log)
+
+	registry := metrics.NewRegistry()
+	sloTracker.RegisterMetrics(registry)
+	response.RegisterMetrics(registry)
+	buildinfo.RegisterMetrics(registry)
+	if retentionJob != nil {
+		retentionJob.RegisterMetrics(registry)
+	}
+	if dbMonitor != nil {
+		dbMonitor.RegisterMetrics(registry)
+	}
+
+	mux := http.NewServeMux()
+	handler.Register(mux)
+	eventstream.New(streamBus, log).Register(mux)
+	suggestionsHandler.Register(mux)
+	categoriesHandler.Register(mux)
+	budgets.New(budgetsService, log).Register(mux)
+	publicHandler.Register(mux)
+	adminHandler.Register(mux)
+	if linksHandler != nil {
+		linksHandler.Register(mux)
+	}
+	version.New(log).Register(mux)
+	healthhttp.New(checker, log).Register(mux)
+	// With a metrics address /metrics is only served there, see Run.
+	a.metrics = registry.Handler()
+	if cfg.HTTPServer.MetricsAddress == "" {
+		mux.HandleFunc("/metrics", apikey.RequireAdmin(a.metrics.ServeHTTP))
+	}
+
+	mux.HandleFunc("/swagger", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/swagger" {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.Redirect(w, r, "/swagger/", http.StatusMovedPermanently)
+	})
+	mux.Handle("/swagger/", http.StripPrefix("/swagger/", http.FileServer(http.Dir("docs/swagger"))))
+
+	maskingPolicy := masking.NewPolicy(cfg.Masking)
+
+	apiKeys, err := apikey.New(cfg.APIKeys, log)
+	if err != nil {
+		return err
+	}
+
+	a.tracker, err = errortracker.New(cfg.ErrorTracking, cfg.Env, log)
+	if err != nil {
+		return err
+	}
+
+	limiter := ratelimit.New(log)
+	bodyLogger := bodylog.New(log)
+	requestLogger := requestlog.New(mux, log, "/metrics", "/health/details")
+
+	a.handler = a.runtime.Middleware(requestLogger.Middleware(a.tracker.Middleware(bodyLogger.Middleware(cors.Middleware(limiter.Middleware(
+		sloTracker.Middleware(apiKeys.Middleware(maskingPolicy.Middleware(mux), "/health/details", "/version", "/swagger", "/swagger/", "/api/v1/public/"), eventstream.StreamPath),
+	))))))
+
+	return nil
+}
+
+// Handler returns the root HTTP handler, middleware included.
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// MetricsHandler returns the handler of /metrics, without authentication.
+func (a *App) MetricsHandler() http.Handler {
+	return a.metrics
+}
+
+// StartWorkers launches the enabled background jobs; they stop when ctx is
+// cancelled.
+func (a *App) StartWorkers(ctx context.Context) {
+	for _, run := range a.workers {
+		go run(ctx)
+	}
+}
+
+// Run starts the workers and serves HTTP until ctx is cancelled.
+func (a *App) Run(ctx context.Context) error {
+	tlsCfg := a.cfg.HTTPServer.TLS
+
+	server := &http.Server{
+		Addr:              a.cfg.HTTPServer.Address,
+		Handler:           a.handler,
+		ReadTimeout:       a.cfg.HTTPServer.ReadTimeout,
+		ReadHeaderTimeout: a.cfg.HTTPServer.ReadHeaderTimeout,
+		WriteTimeout:      a.cfg.HTTPServer.WriteTimeout,
+		IdleTimeout:       a.cfg.HTTPServer.IdleTimeout,
+	}
+	if tlsCfg.Enabled() {
+		var err error
+		if server.TLSConfig, err = serverTLSConfig(tlsCfg); err != nil {
+			return err
+		}
+	}
+
+	var redirect *http.Server
+	if tlsCfg.RedirectAddress != "" {
+		redirect = &http.Server{
+			Addr:              tlsCfg.RedirectAddress,
+			Handler:           redirectToHTTPS(a.cfg.HTTPServer.Address),
+			ReadTimeout:       a.cfg.HTTPServer.ReadTimeout,
+			ReadHeaderTimeout: a.cfg.HTTPServer.ReadHeaderTimeout,
+			WriteTimeout:      a.cfg.HTTPServer.WriteTimeout,
+			IdleTimeout:       a.cfg.HTTPServer.IdleTimeout,
+		}
+	}
+
+	var metricsServer *http.Server
+	if a.cfg.HTTPServer.MetricsAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", a.metrics)
+		metricsServer = &http.Server{
+			Addr:              a.cfg.HTTPServer.MetricsAddress,
+			Handler:           mux,
+			ReadTimeout:       a.cfg.HTTPServer.ReadTimeout,
+			ReadHeaderTimeout: a.cfg.HTTPServer.ReadHeaderTimeout,
+			WriteTimeout:      a.cfg.HTTPServer.WriteTimeout,
+			IdleTimeout:       a.cfg.HTTPServer.IdleTimeout,
+		}
+	}
+
+	a.StartWorkers(ctx)
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.HTTPServer.ShutdownTimeout)
+		defer cancel()
+
+		a.log.Info("shutting down http server")
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			a.log.Error("failed to shutdown http server", slog.Any("error", err))
+		}
+		if redirect != nil {
+			if err := redirect.Shutdown(shutdownCtx); err != nil {
+				a.log.Error("failed to shutdown https redirect server", slog.Any("error", err))
+			}
+		}
+		if metricsServer != nil {
+			if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+				a.log.Error("failed to shutdown metrics server", slog.Any("error", err))
+			}
+		}
+	}()
+
+	if redirect != nil {
+		go func() {
+			a.log.Info("starting https redirect server", slog.String("address", redirect.Addr))
+			if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.log.Error("https redirect server error", slog.Any("error", err))
+			}
+		}()
+	}
+
+	if metricsServer != nil {
+		go func() {
+			a.log.Info("starting metrics server", slog.String("address", metricsServer.Addr))
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.log.Error("metrics server error", slog.Any("error", err))
+			}
+		}()
+	}
+
+	a.log.Info("starting http server",
+		slog.String("address", a.cfg.HTTPServer.Address),
+		slog.Bool("tls", tlsCfg.Enabled()),
+		slog.Bool("client_certificates", tlsCfg.ClientCAFile != ""),
+	)
+
+	var err error
+	if tlsCfg.Enabled() {
+		err = server.ListenAndServeTLS(tlsCfg.CertFile, tlsCfg.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// ListenAndServe returns as soon as Shutdown starts; in-flight requests
+	// are done only once Shutdown returns.
+	<-shutdownDone
+
+	return nil
+}
+
+func (a *App) Close() error {
+	err := a.publisher.Close()
+
+	if a.mongo != nil {
+		err = errors.Join(err, a.mongo.Close())
+	}
+
+	if a.redis != nil {
+		err = errors.Join(err, a.redis.Close())
+	}
+
+	err = errors.Join(err, a.audit.Close())
+
+	if a.tracker != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), a.cfg.ErrorTracking.Timeout)
+		defer cancel()
+		err = errors.Join(err, a.tracker.Close(ctx))
+	}
+
+	return err
+}
+
+// userSenders builds the user-addressed notification channels on first use
+// and shares them between the jobs notifying users, so each channel has one
+// health probe.
+type userSenders struct {
+	cfg     *config.Config
+	db      *postgresql.Storage
+	checker *health.Checker
+	built   map[string]notifications.Sender
+}
+
+// sender reaches users through every enabled one of channels, falling back
+// to the log when none is available.
+func (u *userSenders) sender(channels []string, log *slog.Logger) notifications.Sender {
+	var senders []notifications.Sender
+
+	for _, channel := range channels {
+		if sender := u.channel(channel, log); sender != nil {
+			senders = append(senders, sender)
+		}
+	}
+
+	if len(senders) == 0 {
+		return notifications.NewLogSender(log)
+	}
+
+	return notifications.NewMultiSender(senders...)
+}
+
+// channel returns the sender of the named channel, or nil when it is
+// disabled or unknown.
+func (u *userSenders) channel(name string, log *slog.Logger) notifications.Sender {
+	if sender, ok := u.built[name]; ok {
+		return sender
+	}
+
+	var sender notifications.Sender
+	switch name {
+	case "telegram":
+		if u.cfg.Notifications.Telegram.Enabled {
+			retry := u.cfg.Notifications.Telegram.Retry
+			sender = observedSender{
+				Sender: notifications.NewRetrySender(notifications.NewTelegramSender(telegram.NewClient(u.cfg.Notifications.Telegram), u.db), retry.Attempts, retry.Backoff),
+				probe:  u.checker.Observe("telegram", "notifications"),
+			}
+		}
+	case "slack":
+		if u.cfg.Notifications.Slack.Enabled {
+			retry := u.cfg.Notifications.Slack.Retry
+			sender = observedSender{
+				Sender: notifications.NewRetrySender(notifications.NewSlackSender(u.cfg.Notifications.Slack), retry.Attempts, retry.Backoff),
+				probe:  u.checker.Observe("slack", "notifications"),
+			}
+		}
+	default:
+		log.Warn("unknown notification channel", slog.String("channel", name))
+	}
+
+	u.built[name] = sender
+	return sender
+}
+
+// storageWrapper adapts WithinTx to service.Repository; every other
+// method is promoted from the embedded storage.
+type storageWrapper struct {
+	*postgresql.Storage
+}
+
+func (s *storageWrapper) WithinTx(ctx context.Context, fn func(repo service.Repository) error) error {
+	return s.Storage.WithinTx(ctx, func(tx *postgresql.Storage) error {
+		return fn(&storageWrapper{Storage: tx})
+	})
+}
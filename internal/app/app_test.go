@@ -0,0 +1,237 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Kulibyka/effective-mobile/internal/app"
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+)
+
+// configPath is the config the application is wired from; the database comes
+// from TEST_POSTGRES_DSN.
+const configPath = "../../config/local.yaml"
+
+// newServer wires the whole application against the migrated database named
+// by TEST_POSTGRES_DSN and serves App.Handler, so requests go through the real
+// mux and middleware chain. Without the variable the test is skipped.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return serve(t, newApp(t).Handler())
+}
+
+func newApp(t *testing.T) *app.App {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	t.Setenv("POSTGRES_DSN", dsn)
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	runtimeCfg := runtimeconfig.New(cfg.Env, cfg.Runtime, new(slog.LevelVar), func() (*config.Config, error) {
+		return config.Load(configPath)
+	}, log)
+
+	db, err := postgresql.New(cfg.PostgreSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	application, err := app.New(cfg, runtimeCfg, db, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = application.Close() })
+
+	return application
+}
+
+func serve(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	server := newServer(t)
+	userID := uuid.New().String()
+
+	var created struct {
+		ID      string `json:"id"`
+		Version int    `json:"version"`
+	}
+	body := map[string]any{
+		"service_name": "Smoke Plus",
+		"price":        400,
+		"user_id":      userID,
+		"start_date":   "01-2025",
+		"end_date":     "03-2025",
+	}
+	do(t, server, http.MethodPost, "/api/v1/subscriptions", body, http.StatusCreated, &created)
+	path := "/api/v1/subscriptions/" + created.ID
+
+	var fetched struct {
+		ServiceName string `json:"service_name"`
+		UserID      string `json:"user_id"`
+	}
+	do(t, server, http.MethodGet, path, nil, http.StatusOK, &fetched)
+	if fetched.ServiceName != "Smoke Plus" || fetched.UserID != userID {
+		t.Fatalf("get: unexpected subscription %+v", fetched)
+	}
+
+	var listed []json.RawMessage
+	do(t, server, http.MethodGet, "/api/v1/subscriptions?user_id="+userID, nil, http.StatusOK, &listed)
+	if len(listed) != 1 {
+		t.Fatalf("list: expected 1 subscription, got %d", len(listed))
+	}
+
+	body["price"] = 500
+	body["version"] = created.Version
+	do(t, server, http.MethodPut, path, body, http.StatusOK, nil)
+
+	var summary struct {
+		Total int `json:"total"`
+	}
+	do(t, server, http.MethodGet, "/api/v1/subscriptions/summary?start_date=01-2025&end_date=12-2025&user_id="+userID, nil, http.StatusOK, &summary)
+	if summary.Total != 1500 {
+		t.Fatalf("summary: expected total 1500, got %d", summary.Total)
+	}
+
+	var history []json.RawMessage
+	do(t, server, http.MethodGet, path+"/history", nil, http.StatusOK, &history)
+	if len(history) < 2 {
+		t.Fatalf("history: expected at least 2 entries, got %d", len(history))
+	}
+
+	do(t, server, http.MethodDelete, path, nil, http.StatusNoContent, nil)
+	do(t, server, http.MethodGet, path, nil, http.StatusNotFound, nil)
+}
+
+func TestInvalidInput(t *testing.T) {
+	server := newServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+	}{
+		{
+			name:   "invalid id",
+			method: http.MethodGet,
+			path:   "/api/v1/subscriptions/not-a-uuid",
+		},
+		{
+			name:   "invalid start date",
+			method: http.MethodPost,
+			path:   "/api/v1/subscriptions",
+			body:   map[string]any{"service_name": "Smoke Plus", "price": 400, "user_id": uuid.New().String(), "start_date": "2025-01"},
+		},
+		{
+			name:   "invalid period",
+			method: http.MethodGet,
+			path:   "/api/v1/subscriptions/summary?start_date=13-2025&end_date=12-2025",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			do(t, server, tt.method, tt.path, tt.body, http.StatusBadRequest, nil)
+		})
+	}
+}
+
+// TestMetricsExposed relies on the config setting http_server.metrics_address,
+// which keeps /metrics off the API listener.
+func TestMetricsExposed(t *testing.T) {
+	application := newApp(t)
+	server := serve(t, application.Handler())
+	metricsServer := serve(t, application.MetricsHandler())
+
+	do(t, server, http.MethodGet, "/api/v1/subscriptions/summary?start_date=01-2025&end_date=01-2025&user_id="+uuid.New().String(), nil, http.StatusOK, nil)
+	do(t, server, http.MethodGet, "/metrics", nil, http.StatusNotFound, nil)
+
+	resp, err := metricsServer.Client().Get(metricsServer.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	if !strings.Contains(string(data), "http_request_duration_seconds") {
+		t.Fatal("request duration histogram is missing")
+	}
+}
+
+// do sends body as JSON, checks the status and decodes the response into out
+// when it is not nil.
+func do(t *testing.T, server *httptest.Server, method, path string, body any, want int, out any) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(t.Context(), method, server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != want {
+		t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, want, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/events"
+	"github.com/Kulibyka/effective-mobile/internal/health"
+	"github.com/Kulibyka/effective-mobile/internal/notifications"
+)
+
+// observedPublisher reports the outcome of every publish to the health
+// checker: the brokers are connected to lazily and have nothing to ping.
+type observedPublisher struct {
+	events.Publisher
+	probe *health.Probe
+}
+
+func (p observedPublisher) Publish(ctx context.Context, event events.Event) error {
+	start := time.Now()
+	err := p.Publisher.Publish(ctx, event)
+	p.probe.Observe(start, err)
+	return err
+}
+
+// observedSender does the same for a notification provider. Messages for
+// users the provider has no address for never reach it and are not counted.
+type observedSender struct {
+	notifications.Sender
+	probe *health.Probe
+}
+
+func (s observedSender) Send(ctx context.Context, msg notifications.Message) error {
+	start := time.Now()
+	err := s.Sender.Send(ctx, msg)
+	if !errors.Is(err, notifications.ErrNoRecipient) {
+		s.probe.Observe(start, err)
+	}
+	return err
+}
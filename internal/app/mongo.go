@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/Kulibyka/effective-mobile/internal/domain/category"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	service "github.com/Kulibyka/effective-mobile/internal/services/subscriptions"
+	"github.com/Kulibyka/effective-mobile/internal/storage/mongodb"
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+)
+
+// mongoRepository keeps subscriptions in MongoDB while categories stay in
+// PostgreSQL, so a category filter is resolved into service names before it
+// reaches the document store.
+type mongoRepository struct {
+	*mongodb.Storage
+	categories *postgresql.Storage
+}
+
+func (r *mongoRepository) ListSubscriptions(ctx context.Context, filter domain.ListFilter) (domain.Page, error) {
+	filter, ok, err := r.resolveCategory(ctx, filter)
+	if err != nil {
+		return domain.Page{}, err
+	}
+	if !ok {
+		var page domain.Page
+		if filter.WithTotal {
+			page.Total = new(int64)
+		}
+		return page, nil
+	}
+
+	return r.Storage.ListSubscriptions(ctx, filter)
+}
+
+func (r *mongoRepository) ListSubscriptionsFunc(ctx context.Context, filter domain.ListFilter, fn func(domain.Subscription) error) error {
+	filter, ok, err := r.resolveCategory(ctx, filter)
+	if err != nil || !ok {
+		return err
+	}
+
+	return r.Storage.ListSubscriptionsFunc(ctx, filter, fn)
+}
+
+func (r *mongoRepository) ServiceNames(ctx context.Context, filter domain.ListFilter) ([]string, error) {
+	filter, ok, err := r.resolveCategory(ctx, filter)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return r.Storage.ServiceNames(ctx, filter)
+}
+
+func (r *mongoRepository) UserSpend(ctx context.Context, month domain.MonthKey, filter domain.ListFilter) ([]domain.UserSpend, error) {
+	filter, ok, err := r.resolveCategory(ctx, filter)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return r.Storage.UserSpend(ctx, month, filter)
+}
+
+func (r *mongoRepository) EstimateSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error) {
+	filter, ok, err := r.resolveCategory(ctx, filter)
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	return r.Storage.EstimateSubscriptions(ctx, filter)
+}
+
+func (r *mongoRepository) ServiceCategories(ctx context.Context) (map[string]category.Category, error) {
+	return r.categories.ServiceCategories(ctx)
+}
+
+func (r *mongoRepository) WithinTx(ctx context.Context, fn func(repo service.Repository) error) error {
+	return r.Storage.WithinTx(ctx, func(tx *mongodb.Storage) error {
+		return fn(&mongoRepository{Storage: tx, categories: r.categories})
+	})
+}
+
+// resolveCategory replaces the category filter with the services of the
+// category. It reports false when nothing can match: the category is unknown
+// or shares no service with the service filter.
+func (r *mongoRepository) resolveCategory(ctx context.Context, filter domain.ListFilter) (domain.ListFilter, bool, error) {
+	if filter.CategoryID.IsNil() {
+		return filter, true, nil
+	}
+
+	cat, err := r.categories.GetCategory(ctx, filter.CategoryID)
+	if err != nil {
+		if errors.Is(err, category.ErrNotFound) {
+			return filter, false, nil
+		}
+		return filter, false, err
+	}
+
+	services := cat.Services
+	if len(filter.ServiceNames) > 0 {
+		services = slices.DeleteFunc(slices.Clone(services), func(name string) bool {
+			return !slices.Contains(filter.ServiceNames, name)
+		})
+	}
+
+	if len(services) == 0 {
+		return filter, false, nil
+	}
+
+	filter.CategoryID = ""
+	filter.ServiceNames = services
+
+	return filter, true, nil
+}
+
+// mongoSuggestions keeps suggestions in PostgreSQL and stores the
+// subscription of a confirmed suggestion in MongoDB.
+type mongoSuggestions struct {
+	*postgresql.Storage
+	subscriptions *mongodb.Storage
+}
+
+func (r *mongoSuggestions) ConfirmSuggestion(ctx context.Context, id uuid.UUID, input domain.CreateInput) (domain.Subscription, error) {
+	var created *domain.Subscription
+	sub, err := r.Storage.ConfirmSuggestionWith(ctx, id, func(ctx context.Context) (domain.Subscription, error) {
+		sub, err := r.subscriptions.CreateSubscription(ctx, input)
+		if err == nil {
+			created = &sub
+		}
+		return sub, err
+	})
+	if err != nil && created != nil {
+		// The suggestion is still pending, so the subscription is removed
+		// and the suggestion can be confirmed again.
+		if deleteErr := r.subscriptions.DeleteSubscription(context.WithoutCancel(ctx), created.ID); deleteErr != nil {
+			return domain.Subscription{}, errors.Join(err, deleteErr)
+		}
+	}
+
+	return sub, err
+}
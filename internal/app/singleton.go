@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+)
+
+// singleton runs a background job on a single app instance at a time. The
+// instance holding the job's advisory lock runs it; the others retry every
+// retry interval and take over when the lock is released or its connection
+// is lost.
+func singleton(db *postgresql.Storage, name string, retry time.Duration, log *slog.Logger, run func(ctx context.Context)) func(ctx context.Context) {
+	log = log.With(slog.String("job", name))
+
+	return func(ctx context.Context) {
+		for {
+			lock, err := db.TryAdvisoryLock(ctx, "job:"+name)
+			if err != nil && ctx.Err() == nil {
+				log.Error("failed to acquire job lock", slog.Any("error", err))
+			}
+
+			if lock != nil {
+				log.Info("acquired job lock")
+				lead(ctx, lock, retry, log, run)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+		}
+	}
+}
+
+// lead runs the job while lock stays held and releases the lock once the
+// job has stopped.
+func lead(ctx context.Context, lock *postgresql.AdvisoryLock, check time.Duration, log *slog.Logger, run func(ctx context.Context)) {
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		run(runCtx)
+	}()
+
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+watch:
+	for {
+		select {
+		case <-done:
+			break watch
+		case <-ticker.C:
+			if err := lock.Check(runCtx); err != nil && runCtx.Err() == nil {
+				log.Error("lost job lock", slog.Any("error", err))
+				break watch
+			}
+		}
+	}
+
+	cancel()
+	<-done
+
+	releaseCtx, cancelRelease := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelRelease()
+
+	if err := lock.Release(releaseCtx); err != nil {
+		log.Error("failed to release job lock", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/http/requestlog"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Fields describes the audited state of a resource, field name to value.
+type Fields map[string]any
+
+// Change is one changed field. From is nil for fields that were not set or
+// whose previous value is unknown.
+type Change struct {
+	Field string
+	From  any
+	To    any
+}
+
+// Changes lists the fields that differ between before and after, in field
+// order. A nil before, as on create, lists every field of after.
+func Changes(before, after Fields) []Change {
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var changes []Change
+	for _, name := range names {
+		from, to := before[name], after[name]
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, Change{Field: name, From: from, To: to})
+		}
+	}
+
+	return changes
+}
+
+// Logger writes the audit log. A Logger with output none records nothing.
+type Logger struct {
+	logger *slog.Logger
+	close  func() error
+}
+
+func New(cfg config.AuditConfig) (*Logger, error) {
+	const op = "audit.New"
+
+	var w io.Writer
+	l := &Logger{}
+
+	switch cfg.Output {
+	case "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	case "file":
+		f, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		w, l.close = f, f.Close
+	default:
+		return l, nil
+	}
+
+	if cfg.Format == "text" {
+		l.logger = slog.New(slog.NewTextHandler(w, nil))
+	} else {
+		l.logger = slog.New(slog.NewJSONHandler(w, nil))
+	}
+
+	return l, nil
+}
+
+// Enabled reports whether records go anywhere, so callers can skip loading
+// the previous state of a resource when nobody reads the audit log.
+func (l *Logger) Enabled() bool {
+	return l != nil && l.logger != nil
+}
+
+// Record writes one audit record for action on the resource with the given
+// ID. The actor is the API key of the request in ctx, anonymous for
+// requests without one, or system outside of requests.
+func (l *Logger) Record(ctx context.Context, action Action, resource, id string, changes ...Change) {
+	if !l.Enabled() {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("action", string(action)),
+		slog.String("resource", resource),
+		slog.String("target_id", id),
+		slog.String("actor", actor(ctx)),
+	}
+	if req, ok := requestlog.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("request_id", req.ID))
+	}
+
+	if len(changes) > 0 {
+		fields := make([]any, 0, len(changes))
+		for _, c := range changes {
+			values := []any{slog.Any("to", c.To)}
+			if c.From != nil {
+				values = append([]any{slog.Any("from", c.From)}, values...)
+			}
+			fields = append(fields, slog.Group(c.Field, values...))
+		}
+		attrs = append(attrs, slog.Group("changes", fields...))
+	}
+
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "audit", attrs...)
+}
+
+func actor(ctx context.Context) string {
+	if scope, ok := access.FromContext(ctx); ok {
+		return "api_key:" + scope.Name
+	}
+	if _, ok := requestlog.FromContext(ctx); ok {
+		return "anonymous"
+	}
+
+	return "system"
+}
+
+func (l *Logger) Close() error {
+	if l == nil || l.close == nil {
+		return nil
+	}
+
+	return l.close()
+}
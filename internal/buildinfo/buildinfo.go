@@ -0,0 +1,108 @@
+package buildinfo
+
+import (
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/Kulibyka/effective-mobile/internal/metrics"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/Kulibyka/effective-mobile/internal/buildinfo.version=v1.4.0
+//	  -X github.com/Kulibyka/effective-mobile/internal/buildinfo.commit=$(git rev-parse HEAD)
+//	  -X github.com/Kulibyka/effective-mobile/internal/buildinfo.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Whatever is left unset is taken from the VCS data the Go toolchain embeds.
+var (
+	version string
+	commit  string
+	date    string
+)
+
+const unknown = "unknown"
+
+// Info describes the running binary.
+type Info struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	GoVersion string
+}
+
+// Get returns the build info of the running binary.
+var Get = sync.OnceValue(func() Info {
+	info := Info{Version: version, Commit: commit, BuildDate: date, GoVersion: runtime.Version()}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+
+		var revision string
+		var modified bool
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if info.Commit == "" && revision != "" {
+			info.Commit = revision
+			if modified {
+				info.Commit += "-dirty"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = unknown
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = unknown
+	}
+
+	return info
+})
+
+func (i Info) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("version", i.Version),
+		slog.String("commit", i.Commit),
+		slog.String("build_date", i.BuildDate),
+		slog.String("go_version", i.GoVersion),
+	)
+}
+
+// RegisterMetrics exposes the build info as the labels of a constant gauge.
+func RegisterMetrics(registry *metrics.Registry) {
+	info := Get()
+
+	registry.Register(metrics.Family{
+		Name: "build_info",
+		Help: "Build of the running binary; always 1.",
+		Type: metrics.TypeGauge,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{
+				Labels: metrics.Labels{
+					"version":    info.Version,
+					"commit":     info.Commit,
+					"build_date": info.BuildDate,
+					"go_version": info.GoVersion,
+				},
+				Value: 1,
+			}}
+		},
+	})
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	_ "embed"
+	"io"
+	"strings"
+)
+
+//go:embed example.yaml
+var example string
+
+// WriteExample writes a commented config file with every setting at its
+// default, as a starting point for a new environment.
+func WriteExample(w io.Writer) error {
+	_, err := io.WriteString(w, strings.ReplaceAll(example, "\r\n", "\n"))
+	return err
+}
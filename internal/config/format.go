@@ -0,0 +1,54 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile fills cfg from the config file, picking the format by extension,
+// and then from the environment.
+//
+// JSON and TOML are decoded generically and converted to YAML, so every
+// format uses the yaml tags of Config and the same key names.
+func readFile(configPath string, cfg *Config) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(configPath)); ext {
+	case ".yaml", ".yml":
+	case ".json":
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("config file parsing error: %w", err)
+		}
+		if data, err = yaml.Marshal(doc); err != nil {
+			return err
+		}
+	case ".toml":
+		var doc map[string]any
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("config file parsing error: %w", err)
+		}
+		if data, err = yaml.Marshal(doc); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported config format %q, use .yaml, .yml, .json or .toml", ext)
+	}
+
+	if err := cleanenv.ParseYAML(bytes.NewReader(data), cfg); err != nil {
+		return fmt.Errorf("config file parsing error: %w", err)
+	}
+
+	return cleanenv.ReadEnv(cfg)
+}
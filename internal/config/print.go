@@ -0,0 +1,99 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+// Print writes cfg as YAML in the layout of the config files, with durations
+// in their usual form and the values of fields tagged secret replaced.
+// secret:"url" keeps a URL readable and hides only its password.
+func Print(w io.Writer, cfg *Config) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(printNode(reflect.ValueOf(*cfg), "")); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func printNode(v reflect.Value, secret string) *yaml.Node {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: d.String()}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			if opts == "omitempty" && v.Field(i).IsZero() {
+				continue
+			}
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+				printNode(v.Field(i), field.Tag.Get("secret")),
+			)
+		}
+		return node
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Style: flowIfEmpty(len(keys))}
+		for _, key := range keys {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: key.String()},
+				printNode(v.MapIndex(key), secret),
+			)
+		}
+		return node
+	case reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: flowIfEmpty(v.Len())}
+		for i := 0; i < v.Len(); i++ {
+			node.Content = append(node.Content, printNode(v.Index(i), secret))
+		}
+		return node
+	case reflect.String:
+		if secret != "" && v.String() != "" {
+			return &yaml.Node{Kind: yaml.ScalarNode, Value: redact(v.String(), secret)}
+		}
+	}
+
+	node := &yaml.Node{}
+	_ = node.Encode(v.Interface())
+	return node
+}
+
+// redact hides value entirely unless it is a URL tagged secret:"url".
+// Keyword/value DSNs are not URLs and are hidden entirely.
+func redact(value, secret string) string {
+	if secret == "url" {
+		if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+			return u.Redacted()
+		}
+	}
+	return redacted
+}
+
+func flowIfEmpty(n int) yaml.Style {
+	if n == 0 {
+		return yaml.FlowStyle
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/secrets"
+)
+
+// secretRefsTimeout bounds resolving all secret references of one load.
+const secretRefsTimeout = 30 * time.Second
+
+// resolveSecretRefs replaces every string value that references a secret
+// manager, such as "sm://prod/db-password", with the secret itself. Errors
+// name the setting the reference came from.
+func resolveSecretRefs(cfg *Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretRefsTimeout)
+	defer cancel()
+
+	var errs []error
+	resolveSecretRefsIn(ctx, reflect.ValueOf(cfg).Elem(), "", &errs)
+
+	return errors.Join(errs...)
+}
+
+func resolveSecretRefsIn(ctx context.Context, v reflect.Value, path string, errs *[]error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			resolveSecretRefsIn(ctx, v.Field(i), name, errs)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			resolveSecretRefsIn(ctx, v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			value := v.MapIndex(key).String()
+			if !secrets.IsReference(value) {
+				continue
+			}
+			resolved, err := secrets.Resolve(ctx, value)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("%s.%s: %w", path, key.String(), err))
+				continue
+			}
+			v.SetMapIndex(key, reflect.ValueOf(resolved).Convert(v.Type().Elem()))
+		}
+	case reflect.String:
+		if !secrets.IsReference(v.String()) {
+			return
+		}
+		resolved, err := secrets.Resolve(ctx, v.String())
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+		v.SetString(resolved)
+	}
+}
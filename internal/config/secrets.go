@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// fileSuffix marks the variant of an env variable that names a file holding
+// the value, as with Docker and Kubernetes secrets mounted into the container.
+const fileSuffix = "_FILE"
+
+// applySecretFiles sets every string field with an env tag NAME from the
+// file named by NAME_FILE, when that is set. Setting both NAME and NAME_FILE
+// is an error, since it is unclear which one was meant.
+func applySecretFiles(cfg *Config) error {
+	return applySecretFilesTo(reflect.ValueOf(cfg).Elem())
+}
+
+func applySecretFilesTo(v reflect.Value) error {
+	var errs []error
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			errs = append(errs, applySecretFilesTo(value))
+			continue
+		}
+
+		for _, name := range strings.Split(field.Tag.Get("env"), ",") {
+			if name == "" {
+				continue
+			}
+
+			path := os.Getenv(name + fileSuffix)
+			if path == "" {
+				continue
+			}
+
+			if field.Type.Kind() != reflect.String {
+				errs = append(errs, fmt.Errorf("%s%s: only string settings can be read from a file", name, fileSuffix))
+				break
+			}
+			if os.Getenv(name) != "" {
+				errs = append(errs, fmt.Errorf("%s and %s%s are both set, use one of them", name, name, fileSuffix))
+				break
+			}
+
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s%s: %w", name, fileSuffix, err))
+				break
+			}
+
+			// Files written by editors and "echo" end with a newline that is
+			// not part of the secret.
+			value.SetString(strings.TrimRight(string(contents), "\r\n"))
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,498 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// docker is what config/docker.yaml runs with; the logger treats it as
+	// plain text at info level.
+	knownEnvs           = []string{"local", "dev", "prod", "docker"}
+	knownBackends       = []string{"postgresql", "mongodb"}
+	knownPublishers     = []string{"", "none", "nats", "rabbitmq"}
+	knownEmailProviders = []string{"log", "smtp"}
+	knownSSLModes       = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	knownVaultAuth      = []string{"token", "kubernetes"}
+	knownLogExporters   = []string{"none", "otlp", "file"}
+	knownAuditOutputs   = []string{"none", "stdout", "stderr", "file"}
+	knownAuditFormats   = []string{"json", "text"}
+	knownLogLevels      = []string{"", "debug", "info", "warn", "error"}
+	knownQueryExecModes = []string{"", "cache_statement", "cache_describe", "describe_exec", "exec", "simple_protocol"}
+)
+
+// validator collects every problem found in a config, each prefixed with the
+// yaml path of the field it concerns.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) addf(field, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) oneOf(field, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf(field, "unknown value %q, expected one of %q", value, allowed)
+}
+
+func (v *validator) required(field, value string) {
+	if value == "" {
+		v.addf(field, "must not be empty")
+	}
+}
+
+func (v *validator) positive(field string, d time.Duration) {
+	if d <= 0 {
+		v.addf(field, "must be positive, got %s", d)
+	}
+}
+
+func (v *validator) nonNegative(field string, d time.Duration) {
+	if d < 0 {
+		v.addf(field, "must not be negative, got %s", d)
+	}
+}
+
+func (v *validator) port(field string, port int) {
+	if port < 1 || port > 65535 {
+		v.addf(field, "must be a port between 1 and 65535, got %d", port)
+	}
+}
+
+func (v *validator) address(field, address string) {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		v.addf(field, "must be host:port, got %q", address)
+		return
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		v.addf(field, "invalid port %q", port)
+		return
+	}
+	v.port(field, n)
+}
+
+func (v *validator) clock(field, value string) {
+	if _, err := time.Parse("15:04", value); err != nil {
+		v.addf(field, "must be HH:MM, got %q", value)
+	}
+}
+
+func (v *validator) ratio(field string, value float64) {
+	if value <= 0 || value > 1 {
+		v.addf(field, "must be in (0, 1], got %g", value)
+	}
+}
+
+// sampleRate is like ratio but allows 0, which samples nothing.
+func (v *validator) sampleRate(field string, value float64) {
+	if value < 0 || value > 1 {
+		v.addf(field, "must be in [0, 1], got %g", value)
+	}
+}
+
+// Validate checks the loaded config and reports all problems at once.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.oneOf("env", c.Env, knownEnvs)
+
+	v.address("http_server.address", c.HTTPServer.Address)
+	if c.HTTPServer.MetricsAddress != "" {
+		v.address("http_server.metrics_address", c.HTTPServer.MetricsAddress)
+		if c.HTTPServer.MetricsAddress == c.HTTPServer.Address {
+			v.addf("http_server.metrics_address", "must differ from address")
+		}
+	}
+	v.positive("http_server.read_timeout", c.HTTPServer.ReadTimeout)
+	v.positive("http_server.read_header_timeout", c.HTTPServer.ReadHeaderTimeout)
+	if c.HTTPServer.ReadHeaderTimeout > c.HTTPServer.ReadTimeout {
+		v.addf("http_server.read_header_timeout", "must not exceed read_timeout, got %s", c.HTTPServer.ReadHeaderTimeout)
+	}
+	v.positive("http_server.write_timeout", c.HTTPServer.WriteTimeout)
+	v.nonNegative("http_server.idle_timeout", c.HTTPServer.IdleTimeout)
+	v.positive("http_server.shutdown_timeout", c.HTTPServer.ShutdownTimeout)
+	if c.HTTPServer.Timeout != 0 {
+		v.addf("http_server.timeout", "is no longer supported, set read_timeout, write_timeout and shutdown_timeout instead")
+	}
+	v.tls(c.HTTPServer)
+
+	v.postgres("postgresql", c.PostgreSQL, c.Vault.Enabled)
+	v.vault(c.Vault)
+	v.logExport(c.LogExport)
+	v.logFile(c.LogFile)
+	v.errorTracking(c.ErrorTracking)
+	v.audit(c.Audit)
+
+	v.oneOf("storage.backend", c.Storage.Backend, knownBackends)
+	if c.Storage.Backend == "mongodb" {
+		v.required("mongodb.uri", c.MongoDB.URI)
+		v.required("mongodb.database", c.MongoDB.Database)
+		v.positive("mongodb.timeout", c.MongoDB.Timeout)
+	}
+
+	if c.Cache.Enabled {
+		v.address("cache.address", c.Cache.Address)
+		v.positive("cache.timeout", c.Cache.Timeout)
+		v.nonNegative("cache.get_ttl", c.Cache.GetTTL)
+		v.nonNegative("cache.list_ttl", c.Cache.ListTTL)
+	}
+
+	v.migrations(c.Migrations)
+	v.notifications(c.Notifications)
+	v.jobs(c.Jobs)
+
+	v.oneOf("events.publisher", c.Events.Publisher, knownPublishers)
+	if c.Events.QueueSize <= 0 {
+		v.addf("events.queue_size", "must be positive, got %d", c.Events.QueueSize)
+	}
+	if c.Events.ChangeFeed.Enabled {
+		v.required("events.change_feed.channel", c.Events.ChangeFeed.Channel)
+		v.positive("events.change_feed.reconnect_backoff", c.Events.ChangeFeed.ReconnectBackoff)
+	}
+
+	v.positive("slo.window", c.SLO.Window)
+	if c.SLO.Buckets <= 0 {
+		v.addf("slo.buckets", "must be positive, got %d", c.SLO.Buckets)
+	}
+	v.ratio("slo.availability", c.SLO.Availability)
+	v.ratio("slo.latency_objective", c.SLO.LatencyObjective)
+
+	keys := make(map[string]bool, len(c.APIKeys.Keys))
+	for i, key := range c.APIKeys.Keys {
+		field := fmt.Sprintf("api_keys.keys[%d].key", i)
+		v.required(field, key.Key)
+		if keys[key.Key] && key.Key != "" {
+			v.addf(field, "duplicate key")
+		}
+		keys[key.Key] = true
+
+		if _, ok := c.Masking.Roles[key.Role]; key.Role != "" && !ok {
+			v.addf(fmt.Sprintf("api_keys.keys[%d].role", i), "unknown masking role %q", key.Role)
+		}
+	}
+
+	if _, ok := c.Masking.Roles[c.Masking.DefaultRole]; c.Masking.DefaultRole != "" && !ok {
+		v.addf("masking.default_role", "unknown masking role %q", c.Masking.DefaultRole)
+	}
+
+	if c.QueryGuard.Enabled && c.QueryGuard.BusinessHoursOnly {
+		hours := c.QueryGuard.BusinessHours
+		v.clock("query_guard.business_hours.start", hours.Start)
+		v.clock("query_guard.business_hours.end", hours.End)
+		if _, err := time.LoadLocation(hours.Timezone); err != nil {
+			v.addf("query_guard.business_hours.timezone", "unknown time zone %q", hours.Timezone)
+		}
+	}
+
+	if c.Reports.Enabled {
+		v.positive("reports.interval", c.Reports.Interval)
+		v.positive("reports.lease", c.Reports.Lease)
+		if c.Reports.BatchSize <= 0 {
+			v.addf("reports.batch_size", "must be positive, got %d", c.Reports.BatchSize)
+		}
+	}
+
+	v.runtime(c.Runtime)
+
+	return errors.Join(v.errs...)
+}
+
+func (v *validator) runtime(r RuntimeConfig) {
+	v.oneOf("runtime.log_level", strings.ToLower(r.LogLevel), knownLogLevels)
+	v.nonNegative("runtime.watch_interval", r.WatchInterval)
+
+	if r.LogSampling.Rate != nil {
+		v.sampleRate("runtime.log_sampling.rate", *r.LogSampling.Rate)
+	}
+	for route, rate := range r.LogSampling.Routes {
+		v.sampleRate("runtime.log_sampling.routes["+route+"]", rate)
+	}
+
+	if r.BodyLog.Enabled && r.BodyLog.MaxBytes <= 0 {
+		v.addf("runtime.body_log.max_bytes", "must be positive, got %d", r.BodyLog.MaxBytes)
+	}
+
+	if r.RateLimit.Enabled {
+		if r.RateLimit.RequestsPerSecond <= 0 {
+			v.addf("runtime.rate_limit.requests_per_second", "must be positive, got %g", r.RateLimit.RequestsPerSecond)
+		}
+		if r.RateLimit.Burst <= 0 {
+			v.addf("runtime.rate_limit.burst", "must be positive, got %d", r.RateLimit.Burst)
+		}
+	}
+	if _, err := r.RateLimit.TrustedProxyPrefixes(); err != nil {
+		v.addf("runtime.rate_limit.trusted_proxies", "%v", err)
+	}
+
+	for name := range r.Features {
+		v.oneOf("runtime.features", name, knownFeatures)
+	}
+
+	for i, origin := range r.CORS.AllowedOrigins {
+		if origin == "" {
+			v.addf(fmt.Sprintf("runtime.cors.allowed_origins[%d]", i), "must not be empty")
+		}
+	}
+}
+
+// postgres checks a connection config; with fromVault the user and password
+// come from Vault and may be left out.
+func (v *validator) postgres(prefix string, pg PostgreConfig, fromVault bool) {
+	if pg.DSN == "" {
+		v.required(prefix+".host", pg.Host)
+		v.port(prefix+".port", pg.Port)
+		v.required(prefix+".dbname", pg.DBName)
+		if !fromVault {
+			v.required(prefix+".user", pg.User)
+			// A client certificate can stand in for the password.
+			if pg.SSLCert == "" {
+				v.required(prefix+".password", pg.Password)
+			}
+		}
+		v.oneOf(prefix+".sslmode", pg.SSLMode, knownSSLModes)
+	} else if _, err := pgconn.ParseConfig(pg.DSN); err != nil {
+		// The driver leaves the password out of the message.
+		v.addf(prefix+".dsn", "%v", err)
+	}
+
+	v.oneOf(prefix+".query_exec_mode", pg.QueryExecMode, knownQueryExecModes)
+
+	v.nonNegative(prefix+".connect_timeout", pg.ConnectTimeout)
+	v.nonNegative(prefix+".read_timeout", pg.ReadTimeout)
+	v.nonNegative(prefix+".write_timeout", pg.WriteTimeout)
+	v.nonNegative(prefix+".aggregate_timeout", pg.AggregateTimeout)
+	v.nonNegative(prefix+".job_timeout", pg.JobTimeout)
+	v.nonNegative(prefix+".stream_timeout", pg.StreamTimeout)
+	v.nonNegative(prefix+".statement_timeout", pg.StatementTimeout)
+	v.nonNegative(prefix+".conn_max_lifetime", pg.ConnMaxLifetime)
+	v.nonNegative(prefix+".conn_max_idle_time", pg.ConnMaxIdleTime)
+
+	if pg.MaxOpenConns < 0 {
+		v.addf(prefix+".max_open_conns", "must not be negative, got %d", pg.MaxOpenConns)
+	}
+	if pg.MinIdleConns < 0 || (pg.MaxOpenConns > 0 && pg.MinIdleConns > pg.MaxOpenConns) {
+		v.addf(prefix+".min_idle_conns", "must be between 0 and max_open_conns, got %d", pg.MinIdleConns)
+	}
+	if pg.MaxIdleConns != 0 {
+		v.addf(prefix+".max_idle_conns", "is no longer supported, set min_idle_conns instead")
+	}
+}
+
+func (v *validator) tls(s HTTPServer) {
+	t := s.TLS
+	if !t.Enabled() {
+		if t.ClientCAFile != "" {
+			v.addf("http_server.tls.client_ca_file", "requires cert_file and key_file")
+		}
+		if t.RedirectAddress != "" {
+			v.addf("http_server.tls.redirect_address", "requires cert_file and key_file")
+		}
+		return
+	}
+
+	v.required("http_server.tls.cert_file", t.CertFile)
+	v.required("http_server.tls.key_file", t.KeyFile)
+
+	if t.RedirectAddress != "" {
+		v.address("http_server.tls.redirect_address", t.RedirectAddress)
+		if t.RedirectAddress == s.Address {
+			v.addf("http_server.tls.redirect_address", "must differ from http_server.address")
+		}
+	}
+}
+
+func (v *validator) logExport(c LogExportConfig) {
+	v.oneOf("log_export.exporter", c.Exporter, knownLogExporters)
+
+	switch c.Exporter {
+	case "otlp":
+		if u, err := url.Parse(c.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addf("log_export.endpoint", "must be an http:// or https:// URL, got %q", c.Endpoint)
+		}
+		v.positive("log_export.timeout", c.Timeout)
+	case "file":
+		v.required("log_export.file", c.File)
+	default:
+		return
+	}
+
+	if c.BatchSize <= 0 {
+		v.addf("log_export.batch_size", "must be positive, got %d", c.BatchSize)
+	}
+	if c.QueueSize < c.BatchSize {
+		v.addf("log_export.queue_size", "must be at least batch_size, got %d", c.QueueSize)
+	}
+	v.positive("log_export.flush_interval", c.FlushInterval)
+}
+
+func (v *validator) logFile(c LogFileConfig) {
+	if c.Path == "" {
+		return
+	}
+
+	if c.MaxSizeMB <= 0 {
+		v.addf("log_file.max_size_mb", "must be positive, got %d", c.MaxSizeMB)
+	}
+	if c.MaxBackups < 0 {
+		v.addf("log_file.max_backups", "must not be negative, got %d", c.MaxBackups)
+	}
+	v.nonNegative("log_file.rotate_interval", c.RotateInterval)
+	v.nonNegative("log_file.max_age", c.MaxAge)
+}
+
+func (v *validator) errorTracking(c ErrorTrackingConfig) {
+	if c.DSN == "" {
+		return
+	}
+
+	// The DSN embeds the project key, so it is not echoed back.
+	u, err := url.Parse(c.DSN)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User.Username() == "" || path.Base(u.Path) == "/" || path.Base(u.Path) == "." {
+		v.addf("error_tracking.dsn", "must be a Sentry DSN like https://<key>@<host>/<project>")
+	}
+	if c.QueueSize <= 0 {
+		v.addf("error_tracking.queue_size", "must be positive, got %d", c.QueueSize)
+	}
+	v.positive("error_tracking.timeout", c.Timeout)
+}
+
+func (v *validator) audit(c AuditConfig) {
+	v.oneOf("audit.output", c.Output, knownAuditOutputs)
+	if c.Output == "none" {
+		return
+	}
+
+	v.oneOf("audit.format", c.Format, knownAuditFormats)
+	if c.Output == "file" {
+		v.required("audit.file", c.File)
+	}
+}
+
+func (v *validator) vault(c VaultConfig) {
+	if !c.Enabled {
+		return
+	}
+
+	v.required("vault.address", c.Address)
+	v.required("vault.database_mount", c.DatabaseMount)
+	v.required("vault.role", c.Role)
+	v.positive("vault.timeout", c.Timeout)
+	v.positive("vault.retry_backoff", c.RetryBackoff)
+
+	v.oneOf("vault.auth_method", c.AuthMethod, knownVaultAuth)
+	switch c.AuthMethod {
+	case "token":
+		v.required("vault.token", c.Token)
+	case "kubernetes":
+		v.required("vault.auth_mount", c.AuthMount)
+		v.required("vault.auth_role", c.AuthRole)
+		v.required("vault.kubernetes_token_path", c.KubernetesTokenPath)
+	}
+}
+
+func (v *validator) migrations(m MigrationsConfig) {
+	v.positive("migrations.statement_timeout", m.StatementTimeout)
+	v.positive("migrations.source_timeout", m.SourceTimeout)
+	v.nonNegative("migrations.lock_timeout", m.LockTimeout)
+
+	// Targets are read from a list, which gets no env-default values, so
+	// only what has no sensible fallback is checked.
+	names := make(map[string]bool, len(m.Targets))
+	for i, target := range m.Targets {
+		prefix := fmt.Sprintf("migrations.targets[%d]", i)
+		v.required(prefix+".name", target.Name)
+		if names[target.Name] && target.Name != "" {
+			v.addf(prefix+".name", "duplicate target %q", target.Name)
+		}
+		names[target.Name] = true
+		v.required(prefix+".path", target.Path)
+		if target.PostgreSQL.DSN == "" {
+			v.required(prefix+".postgresql.host", target.PostgreSQL.Host)
+		}
+	}
+}
+
+func (v *validator) notifications(n NotificationsConfig) {
+	if n.Email.Enabled {
+		v.oneOf("notifications.email.provider", n.Email.Provider, knownEmailProviders)
+		if _, err := mail.ParseAddress(n.Email.From); err != nil {
+			v.addf("notifications.email.from", "must be an email address: %v", err)
+		}
+		if n.Email.Provider == "smtp" {
+			v.required("notifications.email.smtp.host", n.Email.SMTP.Host)
+			v.port("notifications.email.smtp.port", n.Email.SMTP.Port)
+			v.positive("notifications.email.smtp.timeout", n.Email.SMTP.Timeout)
+		}
+	}
+
+	if n.Telegram.Enabled {
+		v.required("notifications.telegram.token", n.Telegram.Token)
+		v.positive("notifications.telegram.request_timeout", n.Telegram.RequestTimeout)
+		if n.Telegram.Commands {
+			v.positive("notifications.telegram.link_token_ttl", n.Telegram.LinkTokenTTL)
+		}
+	}
+
+	if n.Slack.Enabled {
+		if n.Slack.WebhookURL != "" {
+			v.addf("notifications.slack.webhook_url", "is no longer supported, set user_webhooks instead")
+		}
+		if len(n.Slack.UserWebhooks) == 0 {
+			v.addf("notifications.slack.user_webhooks", "must not be empty")
+		}
+		v.positive("notifications.slack.timeout", n.Slack.Timeout)
+	}
+}
+
+func (v *validator) jobs(j JobsConfig) {
+	v.positive("jobs.lock_retry", j.LockRetry)
+
+	intervals := []struct {
+		field    string
+		enabled  bool
+		interval time.Duration
+	}{
+		{"jobs.expiration.interval", j.Expiration.Enabled, j.Expiration.Interval},
+		{"jobs.rollup.interval", j.Rollup.Enabled, j.Rollup.Interval},
+		{"jobs.prices.interval", j.Prices.Enabled, j.Prices.Interval},
+		{"jobs.budgets.interval", j.Budgets.Enabled, j.Budgets.Interval},
+		{"jobs.retention.interval", j.Retention.Enabled, j.Retention.Interval},
+		{"jobs.db_health.interval", j.DBHealth.Enabled, j.DBHealth.Interval},
+	}
+	for _, job := range intervals {
+		if job.enabled {
+			v.positive(job.field, job.interval)
+		}
+	}
+
+	if j.Retention.Enabled {
+		if j.Retention.KeepMonths <= 0 {
+			v.addf("jobs.retention.keep_months", "must be positive, got %d", j.Retention.KeepMonths)
+		}
+		if j.Retention.BatchSize <= 0 {
+			v.addf("jobs.retention.batch_size", "must be positive, got %d", j.Retention.BatchSize)
+		}
+	}
+
+	if j.DBHealth.Enabled {
+		v.positive("jobs.db_health.ping_timeout", j.DBHealth.PingTimeout)
+		v.ratio("jobs.db_health.saturation_threshold", j.DBHealth.SaturationThreshold)
+	}
+}
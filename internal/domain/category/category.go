@@ -0,0 +1,57 @@
+package category
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+var (
+	ErrNotFound       = errors.New("category not found")
+	ErrNameTaken      = errors.New("category name already taken")
+	ErrBudgetNotFound = errors.New("budget not found")
+)
+
+// Category groups services, e.g. streaming or fitness. A service belongs to
+// at most one category and its subscriptions inherit it.
+type Category struct {
+	ID        uuid.UUID
+	Name      string
+	Services  []string
+	CreatedAt time.Time
+}
+
+type Input struct {
+	Name     string
+	Services []string
+}
+
+// Budget caps what a user means to spend on a category in a month.
+// AlertedAt is when the user was last warned about going over it; setting
+// the budget again clears it.
+type Budget struct {
+	UserID       uuid.UUID
+	CategoryID   uuid.UUID
+	Category     string
+	MonthlyLimit int
+	AlertedAt    *time.Time
+	UpdatedAt    time.Time
+}
+
+type BudgetInput struct {
+	UserID       uuid.UUID
+	CategoryID   uuid.UUID
+	MonthlyLimit int
+}
+
+// BudgetStatus is a budget with what the user spends on its category in
+// one month.
+type BudgetStatus struct {
+	Budget
+	Spent int
+}
+
+func (s BudgetStatus) Exceeded() bool {
+	return s.Spent > s.MonthlyLimit
+}
@@ -0,0 +1,40 @@
+package notification
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+var (
+	ErrChatNotFound = errors.New("telegram chat not found")
+	// ErrLinkTokenInvalid is returned for unknown, used and expired link
+	// tokens alike.
+	ErrLinkTokenInvalid = errors.New("telegram link token is invalid or expired")
+	// ErrAlreadyLinked is returned when the user is linked to another chat
+	// and the token was not issued to replace that link.
+	ErrAlreadyLinked = errors.New("user is already linked to another telegram chat")
+)
+
+type TelegramChat struct {
+	UserID uuid.UUID
+	ChatID int64
+}
+
+// LinkToken is a one-time token a user sends to the bot as /start <token> to
+// link their chat. With Relink it replaces an existing link.
+type LinkToken struct {
+	Token     string
+	UserID    uuid.UUID
+	Relink    bool
+	ExpiresAt time.Time
+}
+
+// HashLinkToken is the form link tokens are stored in.
+func HashLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
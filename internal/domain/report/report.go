@@ -0,0 +1,77 @@
+package report
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+var (
+	ErrNotFound = errors.New("report not found")
+	ErrInvalid  = errors.New("invalid report definition")
+)
+
+type Grouping string
+
+const (
+	GroupingNone    Grouping = "none"
+	GroupingService Grouping = "service"
+	GroupingUser    Grouping = "user"
+	GroupingMonth   Grouping = "month"
+)
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+	FormatXLSX Format = "xlsx"
+)
+
+type Channel string
+
+const (
+	ChannelEmail   Channel = "email"
+	ChannelWebhook Channel = "webhook"
+	ChannelFile    Channel = "file"
+)
+
+// Report is a recurring summary definition. Every run covers the PeriodMonths
+// calendar months up to and including the month the run happens in.
+type Report struct {
+	ID           uuid.UUID
+	Name         string
+	UserID       uuid.UUID
+	ServiceName  *string
+	PeriodMonths int
+	Grouping     Grouping
+	Format       Format
+	Schedule     string
+	Channel      Channel
+	Target       string
+	NextRunAt    time.Time
+	LastRunAt    *time.Time
+	LastError    *string
+	CreatedAt    time.Time
+}
+
+type CreateInput struct {
+	Name         string
+	UserID       uuid.UUID
+	ServiceName  *string
+	PeriodMonths int
+	Grouping     Grouping
+	Format       Format
+	Schedule     string
+	Channel      Channel
+	Target       string
+	NextRunAt    time.Time
+}
+
+// Table is a generated report before it is encoded into its output format.
+// Cells are strings or ints.
+type Table struct {
+	Columns []string
+	Rows    [][]any
+}
@@ -7,10 +7,76 @@ import (
 	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
 )
 
-var ErrNotFound = errors.New("subscription not found")
+var (
+	ErrNotFound = errors.New("subscription not found")
+	ErrConflict = errors.New("subscription was modified concurrently")
+	// ErrAlreadyExists is returned together with the stored subscription when
+	// a create with a client-supplied ID is retried with the same content.
+	ErrAlreadyExists = errors.New("subscription already exists")
+	ErrQueryTooBroad = errors.New("query would scan too many subscriptions")
+	// ErrDuplicate is returned when a write would give the user a second
+	// subscription to the same service starting in the same month.
+	ErrDuplicate        = errors.New("subscription for this user, service and start month already exists")
+	ErrInvalidReference = errors.New("subscription refers to a missing record")
+	ErrInvalidValue     = errors.New("subscription violates a data constraint")
+	// ErrInvalidPriceChange is returned when a scheduled price change is
+	// refused by a storage constraint.
+	ErrInvalidPriceChange = errors.New("price change violates a data constraint")
+)
 
 const MonthLayout = "01-2006"
 
+// MonthKey is a calendar month encoded as YYYYMM. It is stored next to the
+// month dates so range filters and grouping do not depend on time zones.
+type MonthKey int
+
+func MonthKeyOf(t time.Time) MonthKey {
+	return MonthKey(t.Year()*100 + int(t.Month()))
+}
+
+func (k MonthKey) Year() int {
+	return int(k) / 100
+}
+
+func (k MonthKey) Month() time.Month {
+	return time.Month(int(k) % 100)
+}
+
+func (k MonthKey) Next() MonthKey {
+	if k.Month() == time.December {
+		return MonthKey((k.Year()+1)*100 + 1)
+	}
+	return k + 1
+}
+
+func (k MonthKey) Prev() MonthKey {
+	if k.Month() == time.January {
+		return MonthKey((k.Year()-1)*100 + 12)
+	}
+	return k - 1
+}
+
+func (k MonthKey) Time() time.Time {
+	return time.Date(k.Year(), k.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// MonthsUntil returns the number of months from k to end, counting both ends.
+func (k MonthKey) MonthsUntil(end MonthKey) int {
+	months := end.Year()*12 + int(end.Month()) - k.Year()*12 - int(k.Month()) + 1
+	if months < 0 {
+		return 0
+	}
+	return months
+}
+
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusExpired   Status = "expired"
+	StatusCancelled Status = "cancelled"
+)
+
 type Subscription struct {
 	ID          uuid.UUID
 	ServiceName string
@@ -18,9 +84,12 @@ type Subscription struct {
 	UserID      uuid.UUID
 	StartMonth  time.Time
 	EndMonth    *time.Time
+	Status      Status
+	Version     int
 }
 
 type CreateInput struct {
+	ID          *uuid.UUID
 	ServiceName string
 	Price       int
 	UserID      uuid.UUID
@@ -33,22 +102,121 @@ type UpdateInput struct {
 	Price       int
 	StartMonth  time.Time
 	EndMonth    *time.Time
+	// Version, when set, must match the stored version for the update to apply.
+	Version *int
 }
 
+// UpsertOutcome reports what an upsert did with the subscription identified
+// by user, service and start month.
+type UpsertOutcome int
+
+const (
+	UpsertCreated UpsertOutcome = iota + 1
+	UpsertUpdated
+	UpsertUnchanged
+)
+
+// ListFilter narrows a list query. Unset fields match everything; the ID
+// fields count as unset when they are Nil.
 type ListFilter struct {
-	UserID           *uuid.UUID
-	ServiceName      *string
+	UserID       uuid.UUID
+	UserIDs      []uuid.UUID
+	ServiceName  *string
+	ServiceNames []string
+	CategoryID   uuid.UUID
+	// Search matches service names fuzzily; results are ranked by similarity
+	// instead of start month.
+	Search           *string
 	StartMonthFrom   *time.Time
 	StartMonthTo     *time.Time
 	ActivePeriodFrom *time.Time
 	ActivePeriodTo   *time.Time
-	Limit            int
-	Offset           int
+	// AsOf reconstructs the state at the end of the given month.
+	AsOf   *time.Time
+	Limit  int
+	Offset int
+	// Force skips the query cost guard.
+	Force bool
+	// WithTotal also counts every subscription matching the filter,
+	// regardless of Limit and Offset.
+	WithTotal bool
+}
+
+// Page is the result of a list query. Total is set only when the filter asked
+// for it with WithTotal.
+type Page struct {
+	Subscriptions []Subscription
+	Total         *int64
 }
 
+// SummaryFilter narrows a summary like ListFilter does.
 type SummaryFilter struct {
-	UserID      *uuid.UUID
+	UserID      uuid.UUID
 	ServiceName *string
+	CategoryID  uuid.UUID
 	PeriodStart time.Time
 	PeriodEnd   time.Time
+	AsOf        *time.Time
+}
+
+// HistoryEntry is one version of a subscription with the interval it was
+// current for. The current version has no ValidTo and no Operation.
+type HistoryEntry struct {
+	Subscription
+	ValidFrom time.Time
+	ValidTo   *time.Time
+	Operation string
+}
+
+// PriceAdjustment sets a new price for every subscription to a service that
+// is still running in or after EffectiveMonth. A subscription that started
+// earlier keeps its price until then and continues as a new subscription.
+type PriceAdjustment struct {
+	ServiceName    string
+	Price          int
+	EffectiveMonth time.Time
+}
+
+// PriceChange is a price adjustment with a future effective month. It is
+// applied to the subscriptions once that month starts.
+type PriceChange struct {
+	ID             int64
+	ServiceName    string
+	Price          int
+	EffectiveMonth time.Time
+	CreatedAt      time.Time
+	NotifiedAt     *time.Time
+	AppliedAt      *time.Time
+}
+
+type PriceAdjustmentResult struct {
+	Affected  int
+	Scheduled *PriceChange
+}
+
+// UserSpend sums up the subscriptions a user has running in one month.
+type UserSpend struct {
+	UserID       uuid.UUID
+	ActiveCount  int
+	MonthlyTotal int
+}
+
+type MonthTotal struct {
+	Month MonthKey
+	Total int
+}
+
+// CategoryTotal is the spend on one category; a nil CategoryID collects the
+// services without a category.
+type CategoryTotal struct {
+	CategoryID *uuid.UUID
+	Category   string
+	Total      int
+}
+
+type ServiceStats struct {
+	ServiceName  string
+	Subscribers  int
+	AveragePrice float64
+	Rank         int
 }
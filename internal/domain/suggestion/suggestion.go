@@ -0,0 +1,70 @@
+package suggestion
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+var (
+	ErrNotFound        = errors.New("suggestion not found")
+	ErrAlreadyReviewed = errors.New("suggestion already reviewed")
+	ErrIncomplete      = errors.New("suggestion is missing required subscription fields")
+	// ErrConflict is returned when a detection reuses the source and
+	// external id of another user's suggestion.
+	ErrConflict = errors.New("external id already used for another user")
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusAccepted  Status = "accepted"
+	StatusDismissed Status = "dismissed"
+)
+
+type Suggestion struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	ServiceName    string
+	Price          *int
+	StartMonth     *time.Time
+	Confidence     float64
+	Source         string
+	ExternalID     *string
+	Raw            json.RawMessage
+	Status         Status
+	SubscriptionID *uuid.UUID
+	CreatedAt      time.Time
+	ReviewedAt     *time.Time
+	ReviewReason   *string
+}
+
+type CreateInput struct {
+	UserID      uuid.UUID
+	ServiceName string
+	Price       *int
+	StartMonth  *time.Time
+	Confidence  float64
+	Source      string
+	ExternalID  *string
+	Raw         json.RawMessage
+}
+
+type ConfirmInput struct {
+	ServiceName *string
+	Price       *int
+	StartMonth  *time.Time
+	EndMonth    *time.Time
+}
+
+type ListFilter struct {
+	UserID uuid.UUID
+	Status *Status
+	// ServiceNames, when set, limits the list to these services.
+	ServiceNames []string
+	Limit        int
+	Offset       int
+}
@@ -0,0 +1,181 @@
+package errortracker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/http/requestlog"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+const modulePath = "github.com/Kulibyka/effective-mobile/"
+
+// forwardedHeaders are the request headers sent along with an event; the
+// rest may carry credentials.
+var forwardedHeaders = []string{"Accept", "Content-Type", "User-Agent", "X-Request-ID"}
+
+// event is the subset of the Sentry event payload filled in here.
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Message     string            `json:"message,omitempty"`
+	Exception   *exceptions       `json:"exception,omitempty"`
+	Request     eventRequest      `json:"request"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	User        *eventUser        `json:"user,omitempty"`
+}
+
+type exceptions struct {
+	Values []exception `json:"values"`
+}
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type eventRequest struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+type eventUser struct {
+	ID string `json:"id"`
+}
+
+func (t *Tracker) newEvent(r *http.Request, level string, status int) event {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	e := event{
+		EventID:     strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       level,
+		ServerName:  t.server,
+		Environment: t.env,
+		Release:     t.release,
+		Request: eventRequest{
+			Method:      r.Method,
+			URL:         scheme + "://" + r.Host + r.URL.Path,
+			QueryString: r.URL.RawQuery,
+			Headers:     make(map[string]string),
+		},
+		Tags: map[string]string{"status": fmt.Sprint(status)},
+	}
+
+	for _, name := range forwardedHeaders {
+		if value := r.Header.Get(name); value != "" {
+			e.Request.Headers[name] = value
+		}
+	}
+
+	if req, ok := requestlog.FromContext(r.Context()); ok {
+		e.Tags["request_id"] = req.ID
+		e.Tags["route"] = req.Route
+		if req.UserID != "" {
+			e.User = &eventUser{ID: req.UserID}
+		}
+	}
+
+	return e
+}
+
+// errorException describes err by the type of its innermost cause, which is
+// what Sentry groups issues by.
+func errorException(err error, stack []uintptr) exception {
+	cause := err
+	for next := errors.Unwrap(cause); next != nil; next = errors.Unwrap(cause) {
+		cause = next
+	}
+
+	return exception{Type: fmt.Sprintf("%T", cause), Value: err.Error(), Stacktrace: framesOf(stack)}
+}
+
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 64)
+	return pcs[:runtime.Callers(skip+1, pcs)]
+}
+
+// framesOf converts a call stack into Sentry frames, which go from the
+// outermost call to the innermost.
+func framesOf(pcs []uintptr) *stacktrace {
+	var frames []frame
+
+	iter := runtime.CallersFrames(pcs)
+	for {
+		f, more := iter.Next()
+		if f.Function != "" {
+			frames = append(frames, frame{
+				Function: functionName(f.Function),
+				Module:   moduleName(f.Function),
+				Filename: trimFilename(f.File),
+				AbsPath:  f.File,
+				Lineno:   f.Line,
+				InApp:    strings.HasPrefix(f.Function, modulePath),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+
+	return &stacktrace{Frames: frames}
+}
+
+// moduleName and functionName split a fully qualified name such as
+// github.com/x/y/pkg.(*T).Method into the package path and the rest.
+func moduleName(name string) string {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+
+	return ""
+}
+
+func functionName(name string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(name, moduleName(name)), ".")
+}
+
+func trimFilename(file string) string {
+	if i := strings.LastIndex(file, "/internal/"); i >= 0 {
+		return file[i+1:]
+	}
+	if i := strings.LastIndex(file, "/cmd/"); i >= 0 {
+		return file[i+1:]
+	}
+
+	return file
+}
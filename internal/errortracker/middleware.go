@@ -0,0 +1,108 @@
+package errortracker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+type ctxKey struct{}
+
+// scope collects the errors recorded while one request is served.
+type scope struct {
+	mu     sync.Mutex
+	errors []exception
+}
+
+// Record notes err, with the current call stack, against the request in
+// ctx. If the request ends with a 5xx response, it is reported with the
+// recorded errors. Outside of a tracked request Record does nothing.
+func Record(ctx context.Context, err error) {
+	s, ok := ctx.Value(ctxKey{}).(*scope)
+	if !ok || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.errors = append(s.errors, errorException(err, callers(2)))
+	s.mu.Unlock()
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Middleware reports requests that panic or end with a 5xx response. A
+// panic is answered with 500 instead of dropping the connection.
+func (t *Tracker) Middleware(next http.Handler) http.Handler {
+	if !t.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := &scope{}
+		rec := &statusRecorder{ResponseWriter: w}
+		r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, s))
+
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			e := t.newEvent(r, "fatal", http.StatusInternalServerError)
+			e.Exception = &exceptions{Values: []exception{{
+				Type:       "panic",
+				Value:      fmt.Sprint(p),
+				Stacktrace: framesOf(callers(3)),
+			}}}
+			t.enqueue(e)
+
+			t.logger.ErrorContext(r.Context(), "handler panicked", slog.String("path", r.URL.Path), slog.Any("panic", p))
+			if rec.status == 0 {
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status < http.StatusInternalServerError {
+			return
+		}
+
+		e := t.newEvent(r, "error", rec.status)
+		s.mu.Lock()
+		if len(s.errors) > 0 {
+			e.Exception = &exceptions{Values: s.errors}
+		}
+		s.mu.Unlock()
+		if e.Exception == nil {
+			e.Message = fmt.Sprintf("%s %s responded with %d", r.Method, r.URL.Path, rec.status)
+		}
+		t.enqueue(e)
+	})
+}
@@ -0,0 +1,175 @@
+package errortracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/buildinfo"
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+const clientName = "effective-mobile/1.0"
+
+// Tracker sends error events to Sentry from a goroutine of its own, so a
+// slow or unreachable Sentry never holds up a response. A Tracker without a
+// DSN reports nothing.
+type Tracker struct {
+	endpoint string
+	auth     string
+	dsn      string
+	env      string
+	release  string
+	server   string
+	client   *http.Client
+	logger   *slog.Logger
+
+	queue chan event
+	done  chan struct{}
+
+	mu      sync.Mutex
+	stopped bool
+	dropped int
+}
+
+// New returns a Tracker for cfg; env is the environment reported when
+// cfg.Environment is empty.
+func New(cfg config.ErrorTrackingConfig, env string, logger *slog.Logger) (*Tracker, error) {
+	const op = "errortracker.New"
+
+	t := &Tracker{logger: logger.WithGroup("error_tracker")}
+	if cfg.DSN == "" {
+		return t, nil
+	}
+
+	u, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid dsn", op)
+	}
+	key, project := u.User.Username(), path.Base(u.Path)
+	if key == "" || project == "/" || project == "." {
+		return nil, fmt.Errorf("%s: dsn has no key or project", op)
+	}
+
+	t.endpoint = fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, strings.TrimSuffix(path.Dir(u.Path), "/"), project)
+	t.auth = fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", clientName, key)
+	t.dsn = cfg.DSN
+	t.env = cfg.Environment
+	if t.env == "" {
+		t.env = env
+	}
+	t.release = cfg.Release
+	if t.release == "" {
+		t.release = buildinfo.Get().Version
+	}
+	t.server, _ = os.Hostname()
+	t.client = &http.Client{Timeout: cfg.Timeout}
+	t.queue = make(chan event, cfg.QueueSize)
+	t.done = make(chan struct{})
+
+	go t.run()
+
+	return t, nil
+}
+
+// Enabled reports whether events are sent anywhere.
+func (t *Tracker) Enabled() bool {
+	return t.queue != nil
+}
+
+func (t *Tracker) enqueue(e event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return
+	}
+
+	select {
+	case t.queue <- e:
+	default:
+		t.dropped++
+	}
+}
+
+func (t *Tracker) run() {
+	defer close(t.done)
+
+	for e := range t.queue {
+		if err := t.send(e); err != nil {
+			t.logger.Warn("failed to send error event", slog.String("event_id", e.EventID), slog.Any("error", err))
+		}
+	}
+}
+
+func (t *Tracker) send(e event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	_ = enc.Encode(map[string]string{"event_id": e.EventID, "sent_at": time.Now().UTC().Format(time.RFC3339), "dsn": t.dsn})
+	_ = enc.Encode(map[string]any{"type": "event", "length": len(payload)})
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", t.auth)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("sentry responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Close sends the events still queued, waiting at most until ctx is done.
+func (t *Tracker) Close(ctx context.Context) error {
+	if !t.Enabled() {
+		return nil
+	}
+
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return nil
+	}
+	t.stopped = true
+	dropped := t.dropped
+	close(t.queue)
+	t.mu.Unlock()
+
+	if dropped > 0 {
+		t.logger.Warn("error events dropped, queue was full", slog.Int("count", dropped))
+	}
+
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("errortracker.Close: %w", ctx.Err())
+	}
+}
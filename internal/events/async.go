@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull       = errors.New("events: publish queue is full, event dropped")
+	ErrPublisherClosed = errors.New("events: publisher is closed")
+)
+
+// AsyncPublisher hands events to a background worker through a bounded
+// queue, so a slow or unreachable broker does not hold up the request that
+// emitted them. Events are published in the order they were queued; when
+// the queue is full Publish drops the event and returns ErrQueueFull.
+type AsyncPublisher struct {
+	next   Publisher
+	queue  chan queuedEvent
+	drain  time.Duration
+	done   chan struct{}
+	stop   context.Context
+	cancel context.CancelFunc
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type queuedEvent struct {
+	ctx   context.Context
+	event Event
+}
+
+// NewAsyncPublisher starts the worker publishing to next. Close waits up to
+// drain for the queued events to be published and drops the rest.
+func NewAsyncPublisher(next Publisher, size int, drain time.Duration, logger *slog.Logger) *AsyncPublisher {
+	stop, cancel := context.WithCancel(context.Background())
+
+	p := &AsyncPublisher{
+		next:   next,
+		queue:  make(chan queuedEvent, size),
+		drain:  drain,
+		done:   make(chan struct{}),
+		stop:   stop,
+		cancel: cancel,
+		logger: logger.WithGroup("event_publisher"),
+	}
+	go p.run()
+
+	return p
+}
+
+func (p *AsyncPublisher) Publish(ctx context.Context, event Event) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrPublisherClosed
+	}
+
+	// The request may finish before the event is published; keep its values
+	// for tracing but not its cancellation.
+	select {
+	case p.queue <- queuedEvent{ctx: context.WithoutCancel(ctx), event: event}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting events, publishes the queued ones and closes the
+// underlying publisher.
+func (p *AsyncPublisher) Close() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	timer := time.AfterFunc(p.drain, p.cancel)
+	<-p.done
+	timer.Stop()
+	p.cancel()
+
+	return p.next.Close()
+}
+
+func (p *AsyncPublisher) run() {
+	defer close(p.done)
+
+	dropped := 0
+	for item := range p.queue {
+		if p.stop.Err() != nil {
+			dropped++
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(item.ctx)
+		stop := context.AfterFunc(p.stop, cancel)
+		err := p.next.Publish(ctx, item.event)
+		stop()
+		cancel()
+
+		if err != nil {
+			p.logger.ErrorContext(item.ctx, "failed to publish event", slog.String("type", string(item.event.Type)), slog.String("subscription_id", item.event.SubscriptionID), slog.Any("error", err))
+		}
+	}
+
+	if dropped > 0 {
+		p.logger.Error("events dropped on shutdown", slog.Int("count", dropped))
+	}
+}
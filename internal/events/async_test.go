@@ -0,0 +1,162 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingPublisher records the events it is given; while gate is open it
+// waits for it to be closed, or for the context to be done.
+type blockingPublisher struct {
+	mu      sync.Mutex
+	events  []Event
+	started chan struct{}
+	gate    chan struct{}
+	closed  bool
+}
+
+func newBlockingPublisher() *blockingPublisher {
+	return &blockingPublisher{started: make(chan struct{}, 16), gate: make(chan struct{})}
+}
+
+func (p *blockingPublisher) Publish(ctx context.Context, event Event) error {
+	p.started <- struct{}{}
+
+	select {
+	case <-p.gate:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *blockingPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	return nil
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestAsyncPublisherQueuesInOrder(t *testing.T) {
+	next := newBlockingPublisher()
+	close(next.gate)
+
+	p := NewAsyncPublisher(next, 8, time.Second, discardLogger())
+	for _, id := range []string{"a", "b", "c"} {
+		if err := p.Publish(t.Context(), Event{Type: SubscriptionCreated, SubscriptionID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !next.closed {
+		t.Fatal("underlying publisher was not closed")
+	}
+	if len(next.events) != 3 {
+		t.Fatalf("expected 3 published events, got %d", len(next.events))
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		if next.events[i].SubscriptionID != id {
+			t.Fatalf("event %d: expected %q, got %q", i, id, next.events[i].SubscriptionID)
+		}
+	}
+
+	if err := p.Publish(t.Context(), Event{Type: SubscriptionCreated}); !errors.Is(err, ErrPublisherClosed) {
+		t.Fatalf("expected ErrPublisherClosed after close, got %v", err)
+	}
+}
+
+func TestAsyncPublisherDropsWhenFull(t *testing.T) {
+	next := newBlockingPublisher()
+	p := NewAsyncPublisher(next, 1, time.Second, discardLogger())
+
+	// The first event is taken by the worker, which blocks on it; the second
+	// fills the queue.
+	if err := p.Publish(t.Context(), Event{SubscriptionID: "in flight"}); err != nil {
+		t.Fatal(err)
+	}
+	<-next.started
+	if err := p.Publish(t.Context(), Event{SubscriptionID: "queued"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Publish(t.Context(), Event{SubscriptionID: "dropped"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	close(next.gate)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(next.events) != 2 {
+		t.Fatalf("expected 2 published events, got %d", len(next.events))
+	}
+}
+
+func TestAsyncPublisherOutlivesRequest(t *testing.T) {
+	next := newBlockingPublisher()
+	p := NewAsyncPublisher(next, 1, time.Second, discardLogger())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	if err := p.Publish(ctx, Event{SubscriptionID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	<-next.started
+	cancel()
+	close(next.gate)
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(next.events) != 1 {
+		t.Fatal("event was not published after its request finished")
+	}
+}
+
+func TestAsyncPublisherCloseGivesUpAfterDrain(t *testing.T) {
+	next := newBlockingPublisher()
+	p := NewAsyncPublisher(next, 8, 20*time.Millisecond, discardLogger())
+
+	for range 3 {
+		if err := p.Publish(t.Context(), Event{Type: SubscriptionCreated}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.Close() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("close did not return after the drain timeout")
+	}
+
+	if len(next.events) != 0 {
+		t.Fatalf("expected no published events, got %d", len(next.events))
+	}
+	if !next.closed {
+		t.Fatal("underlying publisher was not closed")
+	}
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+type Handler func(ctx context.Context, event Event) error
+
+type Emitter interface {
+	Emit(ctx context.Context, event Event)
+}
+
+type subscriber struct {
+	id      uint64
+	name    string
+	types   map[Type]struct{}
+	handler Handler
+}
+
+// Bus dispatches domain events to the handlers registered in-process.
+// Handlers run synchronously in registration order; a failing handler is
+// logged and does not prevent the others from running.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []subscriber
+	nextID      uint64
+	logger      *slog.Logger
+}
+
+func NewBus(logger *slog.Logger) *Bus {
+	return &Bus{logger: logger.WithGroup("event_bus")}
+}
+
+// Subscribe registers handler for the given event types, or for every event
+// when no types are passed. The returned function removes it again.
+func (b *Bus) Subscribe(name string, handler Handler, types ...Type) (unsubscribe func()) {
+	sub := subscriber{name: name, handler: handler}
+	if len(types) > 0 {
+		sub.types = make(map[Type]struct{}, len(types))
+		for _, t := range types {
+			sub.types[t] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	sub.id = b.nextID
+	// Emit iterates over the slice it read without holding the lock, so it
+	// is replaced rather than modified in place.
+	b.subscribers = append(slices.Clip(b.subscribers), sub)
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.subscribers = slices.DeleteFunc(slices.Clone(b.subscribers), func(s subscriber) bool { return s.id == sub.id })
+	}
+}
+
+func (b *Bus) Emit(ctx context.Context, event Event) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		if sub.types != nil {
+			if _, ok := sub.types[event.Type]; !ok {
+				continue
+			}
+		}
+
+		b.dispatch(ctx, sub, event)
+	}
+}
+
+func (b *Bus) dispatch(ctx context.Context, sub subscriber, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.ErrorContext(ctx, "event handler panicked", slog.String("handler", sub.name), slog.String("type", string(event.Type)), slog.Any("panic", r))
+		}
+	}()
+
+	if err := sub.handler(ctx, event); err != nil {
+		b.logger.ErrorContext(ctx, "event handler failed", slog.String("handler", sub.name), slog.String("type", string(event.Type)), slog.String("subscription_id", event.SubscriptionID), slog.Any("error", err))
+	}
+}
+
+// PublishTo returns a handler forwarding events to an external publisher.
+func PublishTo(publisher Publisher) Handler {
+	return publisher.Publish
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+// Notifier is a broadcast channel shared by every app instance, such as
+// PostgreSQL LISTEN/NOTIFY.
+type Notifier interface {
+	Notify(ctx context.Context, channel string, payload []byte) error
+	Listen(ctx context.Context, channel string, fn func(payload []byte)) error
+}
+
+// ChangeFeed relays committed subscription changes between app instances.
+// Publish broadcasts an event to all of them, and Run re-emits every
+// broadcast event, including the instance's own, on the feed bus. Streaming
+// handlers subscribe to the feed bus instead of the local one so that
+// clients see changes made through any instance.
+type ChangeFeed struct {
+	notifier Notifier
+	channel  string
+	backoff  time.Duration
+	bus      *Bus
+	logger   *slog.Logger
+}
+
+func NewChangeFeed(notifier Notifier, cfg config.ChangeFeedConfig, logger *slog.Logger) *ChangeFeed {
+	return &ChangeFeed{
+		notifier: notifier,
+		channel:  cfg.Channel,
+		backoff:  cfg.ReconnectBackoff,
+		bus:      NewBus(logger),
+		logger:   logger.WithGroup("change_feed"),
+	}
+}
+
+// Bus returns the bus receiving events from every instance.
+func (f *ChangeFeed) Bus() *Bus {
+	return f.bus
+}
+
+func (f *ChangeFeed) Publish(ctx context.Context, event Event) error {
+	const op = "events.ChangeFeed.Publish"
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := f.notifier.Notify(ctx, f.channel, payload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (f *ChangeFeed) Close() error {
+	return nil
+}
+
+// Run listens for changes until ctx is cancelled, reconnecting after
+// failures. Events broadcast while disconnected are lost.
+func (f *ChangeFeed) Run(ctx context.Context) {
+	f.logger.Info("starting change feed", slog.String("channel", f.channel))
+
+	for {
+		err := f.notifier.Listen(ctx, f.channel, func(payload []byte) {
+			f.dispatch(ctx, payload)
+		})
+		if ctx.Err() != nil {
+			f.logger.Info("stopping change feed")
+			return
+		}
+
+		f.logger.Error("change feed disconnected", slog.Any("error", err), slog.Duration("backoff", f.backoff))
+
+		select {
+		case <-ctx.Done():
+			f.logger.Info("stopping change feed")
+			return
+		case <-time.After(f.backoff):
+		}
+	}
+}
+
+func (f *ChangeFeed) dispatch(ctx context.Context, payload []byte) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		f.logger.Warn("skipping malformed change", slog.Any("error", err))
+		return
+	}
+
+	f.bus.Emit(ctx, event)
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+type Type string
+
+const (
+	SubscriptionCreated Type = "subscription.created"
+	SubscriptionUpdated Type = "subscription.updated"
+	SubscriptionDeleted Type = "subscription.deleted"
+	SubscriptionExpired Type = "subscription.expired"
+)
+
+type Event struct {
+	Type           Type          `json:"type"`
+	OccurredAt     time.Time     `json:"occurred_at"`
+	SubscriptionID string        `json:"subscription_id"`
+	UserID         string        `json:"user_id,omitempty"`
+	Subscription   *Subscription `json:"subscription,omitempty"`
+}
+
+type Subscription struct {
+	ServiceName string  `json:"service_name"`
+	Price       int     `json:"price"`
+	StartDate   string  `json:"start_date"`
+	EndDate     *string `json:"end_date,omitempty"`
+	Status      string  `json:"status"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+func NewSubscriptionEvent(eventType Type, sub domain.Subscription) Event {
+	payload := &Subscription{
+		ServiceName: sub.ServiceName,
+		Price:       sub.Price,
+		StartDate:   sub.StartMonth.Format(domain.MonthLayout),
+		Status:      string(sub.Status),
+	}
+
+	if sub.EndMonth != nil {
+		formatted := sub.EndMonth.Format(domain.MonthLayout)
+		payload.EndDate = &formatted
+	}
+
+	return Event{
+		Type:           eventType,
+		OccurredAt:     time.Now().UTC(),
+		SubscriptionID: sub.ID.String(),
+		UserID:         sub.UserID.String(),
+		Subscription:   payload,
+	}
+}
+
+type NoopPublisher struct{}
+
+func (NoopPublisher) Publish(context.Context, Event) error {
+	return nil
+}
+
+func (NoopPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+// JetStreamPublisher publishes events to a JetStream stream and waits for
+// the stream acknowledgement of every message. The stream capturing the
+// subjects is expected to exist.
+type JetStreamPublisher struct {
+	cfg    config.NATSConfig
+	prefix string
+
+	mu   sync.Mutex
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+func NewJetStreamPublisher(cfg config.NATSConfig, prefix string) *JetStreamPublisher {
+	return &JetStreamPublisher{cfg: cfg, prefix: prefix}
+}
+
+func (p *JetStreamPublisher) Publish(ctx context.Context, event Event) error {
+	const op = "events.JetStreamPublisher.Publish"
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.publish(ctx, p.subject(event.Type), payload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *JetStreamPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn, p.js = nil, nil
+	}
+
+	return nil
+}
+
+func (p *JetStreamPublisher) subject(eventType Type) string {
+	if p.prefix == "" {
+		return string(eventType)
+	}
+
+	return p.prefix + "." + string(eventType)
+}
+
+func (p *JetStreamPublisher) publish(ctx context.Context, subject string, payload []byte) error {
+	// The client reconnects on its own; a new connection is only needed
+	// when the first dial failed or reconnect attempts ran out.
+	if p.conn == nil || p.conn.IsClosed() {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+
+	_, err := p.js.Publish(ctx, subject, payload)
+	return err
+}
+
+func (p *JetStreamPublisher) connect() error {
+	opts := []nats.Option{
+		nats.Name(p.cfg.ClientName),
+		nats.Timeout(p.cfg.Timeout),
+	}
+	if p.cfg.User != "" {
+		opts = append(opts, nats.UserInfo(p.cfg.User, p.cfg.Password))
+	}
+	if p.cfg.Token != "" {
+		opts = append(opts, nats.Token(p.cfg.Token))
+	}
+	if p.cfg.TLS {
+		opts = append(opts, nats.Secure())
+	}
+
+	conn, err := nats.Connect(p.cfg.URL, opts...)
+	if err != nil {
+		return err
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	p.conn, p.js = conn, js
+
+	return nil
+}
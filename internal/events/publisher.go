@@ -0,0 +1,28 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+const (
+	PublisherNone     = "none"
+	PublisherNATS     = "nats"
+	PublisherRabbitMQ = "rabbitmq"
+)
+
+func NewPublisher(cfg config.EventsConfig) (Publisher, error) {
+	const op = "events.NewPublisher"
+
+	switch cfg.Publisher {
+	case "", PublisherNone:
+		return NoopPublisher{}, nil
+	case PublisherNATS:
+		return NewJetStreamPublisher(cfg.NATS, cfg.SubjectPrefix), nil
+	case PublisherRabbitMQ:
+		return NewRabbitMQPublisher(cfg.RabbitMQ), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown publisher %q", op, cfg.Publisher)
+	}
+}
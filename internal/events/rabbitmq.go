@@ -0,0 +1,157 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+var (
+	errNacked        = errors.New("amqp: message was rejected by the broker")
+	errChannelClosed = errors.New("amqp: channel closed before the broker confirmed the message")
+)
+
+// RabbitMQPublisher publishes events to an AMQP 0-9-1 exchange with publisher
+// confirms enabled, so Publish returns only after the broker has taken the
+// message.
+type RabbitMQPublisher struct {
+	cfg config.RabbitMQConfig
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func NewRabbitMQPublisher(cfg config.RabbitMQConfig) *RabbitMQPublisher {
+	return &RabbitMQPublisher{cfg: cfg}
+}
+
+func (p *RabbitMQPublisher) Publish(ctx context.Context, event Event) error {
+	const op = "events.RabbitMQPublisher.Publish"
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.publish(ctx, event, payload); err != nil {
+		p.closeConn()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *RabbitMQPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.closeConn()
+}
+
+func (p *RabbitMQPublisher) routingKey(eventType Type) string {
+	if key, ok := p.cfg.RoutingKeys[string(eventType)]; ok {
+		return key
+	}
+
+	return string(eventType)
+}
+
+func (p *RabbitMQPublisher) publish(ctx context.Context, event Event, payload []byte) error {
+	// A broker-side close leaves the channel unusable; connect again.
+	if p.channel == nil || p.channel.IsClosed() {
+		p.closeConn()
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+
+	confirm, err := p.channel.PublishWithDeferredConfirmWithContext(ctx, p.cfg.Exchange, p.routingKey(event.Type), false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    event.OccurredAt,
+		Type:         string(event.Type),
+		Body:         payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		// Confirms still pending when the channel closes are settled as
+		// nacks.
+		if p.channel.IsClosed() {
+			return errChannelClosed
+		}
+		return errNacked
+	}
+
+	return nil
+}
+
+func (p *RabbitMQPublisher) connect() error {
+	props := amqp.NewConnectionProperties()
+	props["product"] = p.cfg.ClientName
+	props.SetClientConnectionName(p.cfg.ClientName)
+
+	conn, err := amqp.DialConfig(p.cfg.URL, amqp.Config{
+		Properties: props,
+		Dial:       amqp.DefaultDial(p.cfg.Timeout),
+	})
+	if err != nil {
+		return err
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	if p.cfg.Declare {
+		if err := channel.ExchangeDeclare(p.cfg.Exchange, p.cfg.ExchangeType, true, false, false, false, nil); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	p.conn, p.channel = conn, channel
+
+	return nil
+}
+
+func (p *RabbitMQPublisher) closeConn() error {
+	if p.conn == nil {
+		return nil
+	}
+
+	err := p.conn.Close()
+	p.conn, p.channel = nil, nil
+
+	if errors.Is(err, amqp.ErrClosed) {
+		return nil
+	}
+
+	return err
+}
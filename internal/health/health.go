@@ -0,0 +1,195 @@
+// Package health reports the state of the dependencies of the server: the
+// ones that can be pinged are checked on every report, the others are judged
+// by the outcome of their last real call.
+package health
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	// checkTimeout bounds a single ping, so one hung dependency does not
+	// stall the report.
+	checkTimeout = 2 * time.Second
+
+	// reportTTL is how long a report is reused, so frequent callers do not
+	// ping every dependency each time.
+	reportTTL = 5 * time.Second
+)
+
+// urlPattern matches URLs in error messages. Their paths, queries and user
+// info may carry secrets, such as the bot token in Telegram API URLs or the
+// key in a Slack webhook URL.
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]*[^\s"'<>:.,;)]`)
+
+type Status string
+
+const (
+	StatusUp      Status = "up"
+	StatusDown    Status = "down"
+	StatusUnknown Status = "unknown"
+
+	// StatusDegraded is only reported for the whole server, when some
+	// dependency is down.
+	StatusDegraded Status = "degraded"
+)
+
+// Dependency is the state of one dependency. Latency and CheckedAt are of
+// the last ping or observed call; LastError stays after a recovery, so a
+// flapping dependency shows.
+type Dependency struct {
+	Name        string
+	Kind        string
+	Status      Status
+	Latency     time.Duration
+	CheckedAt   time.Time
+	LastError   string
+	LastErrorAt time.Time
+}
+
+type Report struct {
+	Status       Status
+	CheckedAt    time.Time
+	Dependencies []Dependency
+}
+
+// Probe keeps the state of one dependency.
+type Probe struct {
+	name string
+	kind string
+	ping func(ctx context.Context) error
+
+	mu    sync.Mutex
+	state Dependency
+}
+
+// Observe records the outcome of a call to the dependency that started at
+// start.
+func (p *Probe) Observe(start time.Time, err error) {
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state.Latency = now.Sub(start)
+	p.state.CheckedAt = now
+	if err != nil {
+		p.state.Status = StatusDown
+		p.state.LastError = errorMessage(err)
+		p.state.LastErrorAt = now
+		return
+	}
+	p.state.Status = StatusUp
+}
+
+func (p *Probe) snapshot() Dependency {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.state
+	state.Name, state.Kind = p.name, p.kind
+	if state.Status == "" {
+		state.Status = StatusUnknown
+	}
+	return state
+}
+
+type Checker struct {
+	mu     sync.Mutex
+	probes []*Probe
+
+	// reportMu is held while a report is built, so concurrent callers
+	// share one round of pings.
+	reportMu sync.Mutex
+	last     Report
+}
+
+func New() *Checker {
+	return &Checker{}
+}
+
+// Ping adds a dependency checked by calling ping on every report.
+func (c *Checker) Ping(name, kind string, ping func(ctx context.Context) error) {
+	c.add(&Probe{name: name, kind: kind, ping: ping})
+}
+
+// Observe adds a dependency whose state is what the returned probe last
+// observed; until then it is unknown.
+func (c *Checker) Observe(name, kind string) *Probe {
+	p := &Probe{name: name, kind: kind}
+	c.add(p)
+	return p
+}
+
+func (c *Checker) add(p *Probe) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probes = append(c.probes, p)
+}
+
+// Report pings the dependencies that can be pinged, concurrently, and
+// returns the state of all of them in the order they were added. A report
+// younger than reportTTL is returned as is.
+func (c *Checker) Report(ctx context.Context) Report {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+
+	if !c.last.CheckedAt.IsZero() && time.Since(c.last.CheckedAt) < reportTTL {
+		return c.last
+	}
+
+	// The report outlives the request that triggered it.
+	ctx = context.WithoutCancel(ctx)
+
+	c.mu.Lock()
+	probes := append([]*Probe(nil), c.probes...)
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range probes {
+		if p.ping == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			p.Observe(start, p.ping(ctx))
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, CheckedAt: time.Now(), Dependencies: make([]Dependency, 0, len(probes))}
+	for _, p := range probes {
+		dep := p.snapshot()
+		if dep.Status == StatusDown {
+			report.Status = StatusDegraded
+		}
+		report.Dependencies = append(report.Dependencies, dep)
+	}
+
+	c.last = report
+	return report
+}
+
+// errorMessage is err's message with every URL cut down to its scheme and
+// host.
+func errorMessage(err error) string {
+	return urlPattern.ReplaceAllStringFunc(err.Error(), func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return "<url>"
+		}
+		return u.Scheme + "://" + u.Host
+	})
+}
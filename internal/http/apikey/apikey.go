@@ -0,0 +1,86 @@
+package apikey
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+type Keys struct {
+	header string
+	scopes map[string]access.Scope
+	logger *slog.Logger
+}
+
+func New(cfg config.APIKeysConfig, logger *slog.Logger) (*Keys, error) {
+	scopes := make(map[string]access.Scope, len(cfg.Keys))
+	for _, key := range cfg.Keys {
+		scope := access.Scope{Name: key.Name, Services: key.Services, Admin: key.Admin, Role: key.Role}
+		for _, raw := range key.UserIDs {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				return nil, err
+			}
+			scope.UserIDs = append(scope.UserIDs, id)
+		}
+		scopes[key.Key] = scope
+	}
+
+	return &Keys{header: cfg.Header, scopes: scopes, logger: logger.WithGroup("apikey_http")}, nil
+}
+
+// Middleware attaches the scope of the presented API key to the request
+// context. Once any key is configured, requests without one are rejected
+// like unknown keys, except to the public paths; a public path ending in a
+// slash covers everything under it. With no key configured requests pass
+// through without a scope, which keeps them out of the admin API.
+func (k *Keys) Middleware(next http.Handler, public ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(k.header)
+		if key == "" {
+			if len(k.scopes) > 0 && !isPublic(r.URL.Path, public) {
+				k.logger.Warn("missing api key", slog.String("path", r.URL.Path))
+				http.Error(w, "missing api key", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scope, ok := k.scopes[key]
+		if !ok {
+			k.logger.Warn("unknown api key", slog.String("path", r.URL.Path))
+			http.Error(w, "invalid api key", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(access.NewContext(r.Context(), scope)))
+	})
+}
+
+func isPublic(path string, public []string) bool {
+	for _, p := range public {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAdmin serves next only to callers whose API key grants the admin
+// scope.
+func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !access.IsAdmin(r.Context()) {
+			http.Error(w, "admin api key required", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,212 @@
+package bodylog
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
+)
+
+const (
+	logGroup   = "bodylog_http"
+	pathPrefix = "/api/v1/"
+	redacted   = `"[REDACTED]"`
+)
+
+// sensitiveKeys are redacted whatever the config says; a key containing any
+// of them matches, so client_secret and access_token are covered too.
+const sensitiveKeys = `[^"]*(?:password|passwd|token|secret|api_key|apikey|authorization)[^"]*`
+
+// jsonValue matches a JSON string, possibly cut off by the size cap, or a
+// scalar.
+const jsonValue = `"(?:[^"\\]|\\.)*"?|[^,}\]\s]+`
+
+var builtinRedactor = regexp.MustCompile(`(?i)("` + sensitiveKeys + `"\s*:\s*)(?:` + jsonValue + `)`)
+
+// Logger logs the request and response bodies of /api/v1 routes at debug
+// level while the runtime config in the request context enables it, so it
+// must run after runtimeconfig.Store.Middleware.
+type Logger struct {
+	logger *slog.Logger
+
+	// redact is built for one runtime config snapshot; a reload stores a new
+	// snapshot, so the redactor is compiled again once per reload.
+	redact atomic.Pointer[snapshotRedactor]
+}
+
+type snapshotRedactor struct {
+	snapshot *config.RuntimeConfig
+	redact   func([]byte) []byte
+}
+
+func New(logger *slog.Logger) *Logger {
+	return &Logger{logger: logger.WithGroup(logGroup)}
+}
+
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := runtimeconfig.FromContext(r.Context())
+		cfg := snapshot.BodyLog
+		log := logger.FromContext(r.Context(), l.logger, logGroup)
+		if !cfg.Enabled || !strings.HasPrefix(r.URL.Path, pathPrefix) || !log.Enabled(r.Context(), slog.LevelDebug) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		req := &bodyReader{ReadCloser: r.Body, capture: capture{max: cfg.MaxBytes}}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = req
+		}
+		resp := &bodyWriter{ResponseWriter: w, capture: capture{max: cfg.MaxBytes}}
+
+		next.ServeHTTP(resp, r)
+
+		redact := l.redactor(snapshot)
+		log.DebugContext(r.Context(), "http bodies",
+			slog.Group("request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("content_type", r.Header.Get("Content-Type")),
+				req.capture.attrs(r.Header.Get("Content-Type"), redact),
+			),
+			slog.Group("response",
+				slog.Int("status", resp.status()),
+				slog.String("content_type", resp.Header().Get("Content-Type")),
+				resp.capture.attrs(resp.Header().Get("Content-Type"), redact),
+			),
+		)
+	})
+}
+
+// capture keeps the first max bytes passed through it and counts the rest.
+type capture struct {
+	max   int
+	buf   bytes.Buffer
+	total int
+}
+
+func (c *capture) write(b []byte) {
+	c.total += len(b)
+	if room := c.max - c.buf.Len(); room > 0 {
+		c.buf.Write(b[:min(room, len(b))])
+	}
+}
+
+func (c *capture) attrs(contentType string, redact func([]byte) []byte) slog.Attr {
+	attrs := []any{slog.Int("bytes", c.total)}
+	if c.total == 0 {
+		return slog.Group("body", attrs...)
+	}
+
+	if !textual(contentType) {
+		attrs = append(attrs, slog.String("omitted", "not a text body"))
+		return slog.Group("body", attrs...)
+	}
+
+	attrs = append(attrs,
+		slog.String("content", string(redact(c.buf.Bytes()))),
+		slog.Bool("truncated", c.total > c.buf.Len()),
+	)
+	return slog.Group("body", attrs...)
+}
+
+type bodyReader struct {
+	io.ReadCloser
+	capture capture
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.capture.write(p[:n])
+	return n, err
+}
+
+type bodyWriter struct {
+	http.ResponseWriter
+	capture capture
+	code    int
+}
+
+func (w *bodyWriter) WriteHeader(status int) {
+	if w.code == 0 {
+		w.code = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *bodyWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.capture.write(b[:n])
+	return n, err
+}
+
+func (w *bodyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *bodyWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// textual reports whether a body of contentType is worth logging as text.
+// A missing type is assumed to be JSON, as the API expects.
+func textual(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// redactor returns the redactor of snapshot, compiling it on the first
+// request after a reload.
+func (l *Logger) redactor(snapshot *config.RuntimeConfig) func([]byte) []byte {
+	if cached := l.redact.Load(); cached != nil && cached.snapshot == snapshot {
+		return cached.redact
+	}
+
+	compiled := &snapshotRedactor{snapshot: snapshot, redact: redactor(snapshot.BodyLog.RedactFields)}
+	l.redact.Store(compiled)
+
+	return compiled.redact
+}
+
+// redactor returns a function replacing the values of sensitive JSON keys
+// and of fields. It works on the raw bytes, so bodies cut off by the size
+// cap are redacted as well.
+func redactor(fields []string) func([]byte) []byte {
+	var extra *regexp.Regexp
+	if len(fields) > 0 {
+		quoted := make([]string, len(fields))
+		for i, field := range fields {
+			quoted[i] = regexp.QuoteMeta(field)
+		}
+		extra = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)(?:` + jsonValue + `)`)
+	}
+
+	return func(body []byte) []byte {
+		body = builtinRedactor.ReplaceAll(body, []byte("${1}"+redacted))
+		if extra != nil {
+			body = extra.ReplaceAll(body, []byte("${1}"+redacted))
+		}
+		return body
+	}
+}
@@ -0,0 +1,49 @@
+package cors
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
+)
+
+const (
+	allowedMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	maxAge         = "600"
+)
+
+// Middleware answers CORS preflight requests and adds the CORS headers for
+// origins allowed by the runtime config in the request context. Requests
+// from other origins get no CORS headers, which makes browsers block them.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+
+		allowed := runtimeconfig.FromContext(r.Context()).CORS.AllowedOrigins
+		if !slices.Contains(allowed, "*") && !slices.Contains(allowed, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", strings.TrimSpace(headers))
+			}
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
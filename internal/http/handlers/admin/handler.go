@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/http/apikey"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+	"github.com/Kulibyka/effective-mobile/internal/http/slo"
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
+	"github.com/Kulibyka/effective-mobile/internal/services/reports"
+	"github.com/Kulibyka/effective-mobile/internal/services/subscriptions"
+)
+
+const (
+	sloPath              = "/api/v1/admin/slo"
+	priceAdjustmentsPath = "/api/v1/admin/price-adjustments"
+	reportsPath          = "/api/v1/admin/reports"
+	userSpendPath        = "/api/v1/admin/user-spend"
+	logLevelPath         = "/api/v1/admin/log-level"
+)
+
+type Handler struct {
+	tracker       *slo.Tracker
+	subscriptions *subscriptions.Service
+	reports       *reports.Service
+	runtime       *runtimeconfig.Store
+	logger        *slog.Logger
+}
+
+func New(tracker *slo.Tracker, subscriptions *subscriptions.Service, reports *reports.Service, runtime *runtimeconfig.Store, logger *slog.Logger) *Handler {
+	return &Handler{tracker: tracker, subscriptions: subscriptions, reports: reports, runtime: runtime, logger: logger.WithGroup("admin_http")}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(sloPath, apikey.RequireAdmin(h.handleSLO))
+	mux.HandleFunc(priceAdjustmentsPath, apikey.RequireAdmin(h.handlePriceAdjustment))
+	mux.HandleFunc(reportsPath, apikey.RequireAdmin(h.handleReports))
+	mux.HandleFunc(reportsPath+"/", apikey.RequireAdmin(h.handleReport))
+	mux.HandleFunc(userSpendPath, apikey.RequireAdmin(h.handleUserSpend))
+	mux.HandleFunc(logLevelPath, apikey.RequireAdmin(h.handleLogLevel))
+}
+
+func (h *Handler) handleSLO(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, h.tracker.Report())
+}
+
+func (h *Handler) handlePriceAdjustment(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.handleUpcomingPriceChanges(w, r)
+		return
+	case http.MethodPost:
+	default:
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req priceAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("failed to decode price adjustment request", slog.Any("error", err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input, err := req.toDomain()
+	if err != nil {
+		h.logger.Warn("invalid price adjustment request", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.subscriptions.AdjustPrices(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, access.ErrForbidden) {
+			http.Error(w, "price adjustments are not allowed for this api key", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidPriceChange) {
+			h.logger.Warn("price change rejected", slog.Any("error", err), slog.String("service_name", input.ServiceName))
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		h.logger.Error("failed to adjust prices", slog.Any("error", err), slog.String("service_name", input.ServiceName))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to adjust prices", http.StatusInternalServerError)
+		return
+	}
+
+	if result.Scheduled != nil {
+		response.WriteJSON(w, http.StatusAccepted, priceChangeResponseFromDomain(*result.Scheduled))
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]int{"affected": result.Affected})
+}
+
+func (h *Handler) handleUpcomingPriceChanges(w http.ResponseWriter, r *http.Request) {
+	changes, err := h.subscriptions.UpcomingPriceChanges(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list price changes", slog.Any("error", err))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to list price changes", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]priceChangeResponse, 0, len(changes))
+	for _, change := range changes {
+		resp = append(resp, priceChangeResponseFromDomain(change))
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+type priceAdjustmentRequest struct {
+	ServiceName string `json:"service_name"`
+	Price       *int   `json:"price"`
+	StartDate   string `json:"start_date"`
+}
+
+func (r priceAdjustmentRequest) toDomain() (domain.PriceAdjustment, error) {
+	if r.ServiceName == "" {
+		return domain.PriceAdjustment{}, errors.New("service_name is required")
+	}
+
+	if r.Price == nil || *r.Price < 0 {
+		return domain.PriceAdjustment{}, errors.New("price must be a non-negative integer")
+	}
+
+	month, err := time.Parse(domain.MonthLayout, r.StartDate)
+	if err != nil {
+		return domain.PriceAdjustment{}, errors.New("invalid start_date format, expected MM-YYYY")
+	}
+
+	return domain.PriceAdjustment{ServiceName: r.ServiceName, Price: *r.Price, EffectiveMonth: month}, nil
+}
+
+type priceChangeResponse struct {
+	ID          int64      `json:"id"`
+	ServiceName string     `json:"service_name"`
+	Price       int        `json:"price"`
+	StartDate   string     `json:"start_date"`
+	CreatedAt   time.Time  `json:"created_at"`
+	NotifiedAt  *time.Time `json:"notified_at,omitempty"`
+}
+
+func priceChangeResponseFromDomain(change domain.PriceChange) priceChangeResponse {
+	return priceChangeResponse{
+		ID:          change.ID,
+		ServiceName: change.ServiceName,
+		Price:       change.Price,
+		StartDate:   change.EffectiveMonth.Format(domain.MonthLayout),
+		CreatedAt:   change.CreatedAt,
+		NotifiedAt:  change.NotifiedAt,
+	}
+}
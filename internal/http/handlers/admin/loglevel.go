@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+)
+
+// maxLogLevelOverride caps how long an override set over HTTP lasts, so a
+// forgotten debug level does not flood the logs.
+const maxLogLevelOverride = time.Hour
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+	// Duration, e.g. "15m", after which the configured level returns; at
+	// most maxLogLevelOverride.
+	Duration string `json:"duration"`
+}
+
+type logLevelResponse struct {
+	Level      string     `json:"level"`
+	Configured string     `json:"configured"`
+	Overridden bool       `json:"overridden"`
+	Until      *time.Time `json:"until,omitempty"`
+}
+
+// handleLogLevel reports the log level on GET, overrides it on PUT and
+// returns to the configured one on DELETE. The override only affects the
+// instance that receives the request.
+func (h *Handler) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req logLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.ToLower(req.Level))); err != nil || req.Level == "" {
+			http.Error(w, "level must be debug, info, warn or error", http.StatusBadRequest)
+			return
+		}
+
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 || d > maxLogLevelOverride {
+			http.Error(w, "duration must be a positive duration of at most "+maxLogLevelOverride.String()+", such as 15m", http.StatusBadRequest)
+			return
+		}
+
+		h.runtime.OverrideLogLevel(level, d)
+	case http.MethodDelete:
+		h.runtime.ResetLogLevel()
+	default:
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	level, override := h.runtime.LogLevel()
+	resp := logLevelResponse{
+		Level:      level.String(),
+		Configured: h.runtime.ConfiguredLogLevel().String(),
+		Overridden: override != nil,
+	}
+	if override != nil && !override.Until.IsZero() {
+		resp.Until = &override.Until
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,228 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/report"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+func (h *Handler) handleReports(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.handleListReports(w, r)
+	case http.MethodPost:
+		h.handleCreateReport(w, r)
+	default:
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, reportsPath+"/")
+	if idStr == "" || strings.Contains(idStr, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil || id.IsNil() {
+		h.logger.Warn("failed to parse report id", slog.String("report_id", idStr), slog.Any("error", err))
+		http.Error(w, "invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rep, err := h.reports.Get(r.Context(), id)
+		if err != nil {
+			h.writeReportError(w, r, err, "failed to get report")
+			return
+		}
+		response.WriteJSON(w, http.StatusOK, reportResponseFromDomain(rep))
+	case http.MethodDelete:
+		if err := h.reports.Delete(r.Context(), id); err != nil {
+			h.writeReportError(w, r, err, "failed to delete report")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleListReports(w http.ResponseWriter, r *http.Request) {
+	reps, err := h.reports.List(r.Context())
+	if err != nil {
+		h.writeReportError(w, r, err, "failed to list reports")
+		return
+	}
+
+	resp := make([]reportResponse, 0, len(reps))
+	for _, rep := range reps {
+		resp = append(resp, reportResponseFromDomain(rep))
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) handleCreateReport(w http.ResponseWriter, r *http.Request) {
+	var req reportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("failed to decode report request", slog.Any("error", err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input, err := req.toDomain()
+	if err != nil {
+		h.logger.Warn("invalid report request", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rep, err := h.reports.Create(r.Context(), input)
+	if err != nil {
+		h.writeReportError(w, r, err, "failed to create report")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, reportResponseFromDomain(rep))
+}
+
+func (h *Handler) writeReportError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		http.Error(w, "report not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, access.ErrForbidden):
+		http.Error(w, "reports are not available for this api key", http.StatusForbidden)
+	default:
+		h.logger.Error(msg, slog.Any("error", err))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+type reportRequest struct {
+	Name         string  `json:"name"`
+	UserID       *string `json:"user_id"`
+	ServiceName  *string `json:"service_name"`
+	PeriodMonths int     `json:"period_months"`
+	Grouping     string  `json:"grouping"`
+	Format       string  `json:"format"`
+	Schedule     string  `json:"schedule"`
+	Channel      string  `json:"channel"`
+	Target       string  `json:"target"`
+}
+
+func (r reportRequest) toDomain() (domain.CreateInput, error) {
+	if strings.TrimSpace(r.Name) == "" {
+		return domain.CreateInput{}, errors.New("name is required")
+	}
+
+	input := domain.CreateInput{
+		Name:         r.Name,
+		ServiceName:  r.ServiceName,
+		PeriodMonths: r.PeriodMonths,
+		Grouping:     domain.Grouping(r.Grouping),
+		Format:       domain.Format(r.Format),
+		Schedule:     r.Schedule,
+		Channel:      domain.Channel(r.Channel),
+		Target:       r.Target,
+	}
+
+	if r.UserID != nil {
+		id, err := uuid.Parse(*r.UserID)
+		if err != nil || id.IsNil() {
+			return domain.CreateInput{}, errors.New("invalid user_id")
+		}
+		input.UserID = id
+	}
+
+	if input.PeriodMonths == 0 {
+		input.PeriodMonths = 1
+	}
+	if input.PeriodMonths < 0 {
+		return domain.CreateInput{}, errors.New("period_months must be positive")
+	}
+
+	if input.Grouping == "" {
+		input.Grouping = domain.GroupingNone
+	}
+
+	switch input.Grouping {
+	case domain.GroupingNone, domain.GroupingService, domain.GroupingUser, domain.GroupingMonth:
+	default:
+		return domain.CreateInput{}, errors.New("grouping must be one of none, service, user, month")
+	}
+
+	switch input.Format {
+	case domain.FormatCSV, domain.FormatJSON, domain.FormatXLSX:
+	default:
+		return domain.CreateInput{}, errors.New("format must be one of csv, json, xlsx")
+	}
+
+	if input.Schedule == "" {
+		return domain.CreateInput{}, errors.New("schedule is required")
+	}
+
+	if input.Target == "" {
+		return domain.CreateInput{}, errors.New("target is required")
+	}
+
+	return input, nil
+}
+
+type reportResponse struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	UserID       *string    `json:"user_id,omitempty"`
+	ServiceName  *string    `json:"service_name,omitempty"`
+	PeriodMonths int        `json:"period_months"`
+	Grouping     string     `json:"grouping"`
+	Format       string     `json:"format"`
+	Schedule     string     `json:"schedule"`
+	Channel      string     `json:"channel"`
+	Target       string     `json:"target"`
+	NextRunAt    time.Time  `json:"next_run_at"`
+	LastRunAt    *time.Time `json:"last_run_at,omitempty"`
+	LastError    *string    `json:"last_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func reportResponseFromDomain(rep domain.Report) reportResponse {
+	resp := reportResponse{
+		ID:           rep.ID.String(),
+		Name:         rep.Name,
+		ServiceName:  rep.ServiceName,
+		PeriodMonths: rep.PeriodMonths,
+		Grouping:     string(rep.Grouping),
+		Format:       string(rep.Format),
+		Schedule:     rep.Schedule,
+		Channel:      string(rep.Channel),
+		Target:       rep.Target,
+		NextRunAt:    rep.NextRunAt,
+		LastRunAt:    rep.LastRunAt,
+		LastError:    rep.LastError,
+		CreatedAt:    rep.CreatedAt,
+	}
+
+	if !rep.UserID.IsNil() {
+		userID := rep.UserID.String()
+		resp.UserID = &userID
+	}
+
+	return resp
+}
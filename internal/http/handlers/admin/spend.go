@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+type userSpendResponse struct {
+	UserID       string `json:"user_id"`
+	ActiveCount  int    `json:"active_count"`
+	MonthlyTotal int    `json:"monthly_total"`
+}
+
+// handleUserSpend lists per-user totals for the month given as MM-YYYY,
+// the current one by default. Like every admin route it needs an admin key.
+func (h *Handler) handleUserSpend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	month := time.Now().UTC()
+	if value := query.Get("month"); value != "" {
+		parsed, err := time.Parse(domain.MonthLayout, value)
+		if err != nil {
+			http.Error(w, "invalid month format, expected MM-YYYY", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	var filter domain.ListFilter
+	if value := query.Get("service_name"); value != "" {
+		filter.ServiceName = &value
+	}
+
+	if value := query.Get("category_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil || parsed.IsNil() {
+			http.Error(w, "invalid category_id", http.StatusBadRequest)
+			return
+		}
+		filter.CategoryID = parsed
+	}
+
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+		filter.Offset = parsed
+	}
+
+	spend, err := h.subscriptions.UserSpend(r.Context(), month, filter)
+	if err != nil {
+		h.logger.Error("failed to aggregate user spend", slog.Any("error", err))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to aggregate user spend", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]userSpendResponse, 0, len(spend))
+	for _, s := range spend {
+		resp = append(resp, userSpendResponse{UserID: s.UserID.String(), ActiveCount: s.ActiveCount, MonthlyTotal: s.MonthlyTotal})
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
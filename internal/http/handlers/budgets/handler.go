@@ -0,0 +1,180 @@
+package budgets
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/category"
+	subdomain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/services/budgets"
+)
+
+// basePath is followed by the user ID, and by the category ID for a single
+// budget.
+const basePath = "/api/v1/budgets/"
+
+type Handler struct {
+	service *budgets.Service
+	logger  *slog.Logger
+}
+
+func New(service *budgets.Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger.WithGroup("budgets_http")}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(basePath, h.handle)
+}
+
+func (h *Handler) handle(w http.ResponseWriter, r *http.Request) {
+	userStr, categoryStr, single := strings.Cut(strings.TrimPrefix(r.URL.Path, basePath), "/")
+	if userStr == "" || (single && (categoryStr == "" || strings.Contains(categoryStr, "/"))) {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID, err := uuid.Parse(userStr)
+	if err != nil || userID.IsNil() {
+		h.logger.Warn("failed to parse user id", slog.String("user_id", userStr), slog.Any("error", err))
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	if !single {
+		if r.Method != http.MethodGet {
+			h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		h.handleList(w, r, userID)
+		return
+	}
+
+	categoryID, err := uuid.Parse(categoryStr)
+	if err != nil || categoryID.IsNil() {
+		h.logger.Warn("failed to parse category id", slog.String("category_id", categoryStr), slog.Any("error", err))
+		http.Error(w, "invalid category id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		h.handleSet(w, r, userID, categoryID)
+	case http.MethodDelete:
+		if err := h.service.Delete(r.Context(), userID, categoryID); err != nil {
+			h.writeError(w, r, err, "failed to delete budget")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if value := r.URL.Query().Get("month"); value != "" {
+		parsed, err := time.Parse(subdomain.MonthLayout, value)
+		if err != nil {
+			h.logger.Warn("invalid month", slog.String("month", value), slog.Any("error", err))
+			http.Error(w, "invalid month, expected MM-YYYY", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	statuses, err := h.service.Statuses(r.Context(), userID, month)
+	if err != nil {
+		h.writeError(w, r, err, "failed to list budgets")
+		return
+	}
+
+	resp := make([]budgetStatusResponse, 0, len(statuses))
+	for _, status := range statuses {
+		resp = append(resp, budgetStatusResponse{
+			budgetResponse: budgetResponseFromDomain(status.Budget),
+			Month:          month.Format(subdomain.MonthLayout),
+			Spent:          status.Spent,
+			Exceeded:       status.Exceeded(),
+		})
+	}
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) handleSet(w http.ResponseWriter, r *http.Request, userID, categoryID uuid.UUID) {
+	var req budgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("failed to decode budget request", slog.Any("error", err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.MonthlyLimit == nil || *req.MonthlyLimit < 0 {
+		h.logger.Warn("invalid budget request", slog.Any("monthly_limit", req.MonthlyLimit))
+		http.Error(w, "monthly_limit is required and must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.service.Set(r.Context(), domain.BudgetInput{UserID: userID, CategoryID: categoryID, MonthlyLimit: *req.MonthlyLimit})
+	if err != nil {
+		h.writeError(w, r, err, "failed to set budget")
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, budgetResponseFromDomain(budget))
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		http.Error(w, "category not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrBudgetNotFound):
+		http.Error(w, "budget not found", http.StatusNotFound)
+	case errors.Is(err, access.ErrForbidden):
+		http.Error(w, "user is outside of the api key scope", http.StatusForbidden)
+	default:
+		h.logger.Error(msg, slog.Any("error", err))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+type budgetRequest struct {
+	MonthlyLimit *int `json:"monthly_limit"`
+}
+
+type budgetResponse struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	CategoryID   uuid.UUID  `json:"category_id"`
+	Category     string     `json:"category"`
+	MonthlyLimit int        `json:"monthly_limit"`
+	AlertedAt    *time.Time `json:"alerted_at,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func budgetResponseFromDomain(budget domain.Budget) budgetResponse {
+	return budgetResponse{
+		UserID:       budget.UserID,
+		CategoryID:   budget.CategoryID,
+		Category:     budget.Category,
+		MonthlyLimit: budget.MonthlyLimit,
+		AlertedAt:    budget.AlertedAt,
+		UpdatedAt:    budget.UpdatedAt,
+	}
+}
+
+type budgetStatusResponse struct {
+	budgetResponse
+	Month    string `json:"month"`
+	Spent    int    `json:"spent"`
+	Exceeded bool   `json:"exceeded"`
+}
@@ -0,0 +1,182 @@
+package categories
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/category"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/services/categories"
+)
+
+const basePath = "/api/v1/categories"
+
+type Handler struct {
+	service *categories.Service
+	logger  *slog.Logger
+}
+
+func New(service *categories.Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger.WithGroup("categories_http")}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(basePath, h.handleBase)
+	mux.HandleFunc(basePath+"/", h.handleWithID)
+}
+
+func (h *Handler) handleBase(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		cats, err := h.service.List(r.Context())
+		if err != nil {
+			h.writeError(w, r, err, "failed to list categories")
+			return
+		}
+
+		resp := make([]categoryResponse, 0, len(cats))
+		for _, cat := range cats {
+			resp = append(resp, categoryResponseFromDomain(cat))
+		}
+		response.WriteJSON(w, http.StatusOK, resp)
+	case http.MethodPost:
+		input, ok := h.decodeInput(w, r)
+		if !ok {
+			return
+		}
+
+		cat, err := h.service.Create(r.Context(), input)
+		if err != nil {
+			h.writeError(w, r, err, "failed to create category")
+			return
+		}
+		response.WriteJSON(w, http.StatusCreated, categoryResponseFromDomain(cat))
+	default:
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleWithID(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, basePath+"/")
+	if idStr == "" || strings.Contains(idStr, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil || id.IsNil() {
+		h.logger.Warn("failed to parse category id", slog.String("category_id", idStr), slog.Any("error", err))
+		http.Error(w, "invalid category id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		cat, err := h.service.Get(r.Context(), id)
+		if err != nil {
+			h.writeError(w, r, err, "failed to get category")
+			return
+		}
+		response.WriteJSON(w, http.StatusOK, categoryResponseFromDomain(cat))
+	case http.MethodPut:
+		input, ok := h.decodeInput(w, r)
+		if !ok {
+			return
+		}
+
+		cat, err := h.service.Update(r.Context(), id, input)
+		if err != nil {
+			h.writeError(w, r, err, "failed to update category")
+			return
+		}
+		response.WriteJSON(w, http.StatusOK, categoryResponseFromDomain(cat))
+	case http.MethodDelete:
+		if err := h.service.Delete(r.Context(), id); err != nil {
+			h.writeError(w, r, err, "failed to delete category")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) decodeInput(w http.ResponseWriter, r *http.Request) (domain.Input, bool) {
+	var req categoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("failed to decode category request", slog.Any("error", err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return domain.Input{}, false
+	}
+
+	input, err := req.toDomain()
+	if err != nil {
+		h.logger.Warn("invalid category request", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return domain.Input{}, false
+	}
+
+	return input, true
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		http.Error(w, "category not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrNameTaken):
+		http.Error(w, "category name already taken", http.StatusConflict)
+	case errors.Is(err, access.ErrForbidden):
+		http.Error(w, "categories cannot be changed with this api key", http.StatusForbidden)
+	default:
+		h.logger.Error(msg, slog.Any("error", err))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+type categoryRequest struct {
+	Name     string   `json:"name"`
+	Services []string `json:"services"`
+}
+
+func (r categoryRequest) toDomain() (domain.Input, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return domain.Input{}, errors.New("name is required")
+	}
+
+	services := make([]string, 0, len(r.Services))
+	for _, service := range r.Services {
+		if service = strings.TrimSpace(service); service == "" {
+			return domain.Input{}, errors.New("service names must not be empty")
+		}
+		services = append(services, service)
+	}
+
+	return domain.Input{Name: name, Services: services}, nil
+}
+
+type categoryResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Services  []string  `json:"services"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func categoryResponseFromDomain(cat domain.Category) categoryResponse {
+	services := cat.Services
+	if services == nil {
+		services = []string{}
+	}
+
+	return categoryResponse{ID: cat.ID, Name: cat.Name, Services: services, CreatedAt: cat.CreatedAt}
+}
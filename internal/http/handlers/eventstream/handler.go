@@ -0,0 +1,200 @@
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/events"
+	"github.com/Kulibyka/effective-mobile/internal/http/masking"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
+)
+
+const (
+	// StreamPath is kept out of latency tracking, its requests last as long
+	// as the client stays connected.
+	StreamPath = "/api/v1/subscriptions/events"
+
+	// bufferSize is how far a client may fall behind before its stream is
+	// closed; it is expected to reconnect.
+	bufferSize = 64
+
+	keepAliveInterval = 15 * time.Second
+)
+
+// Handler streams subscription events to clients as server-sent events.
+// Given the change feed's bus, clients connected to any instance see the
+// changes made through all of them.
+type Handler struct {
+	bus    *events.Bus
+	logger *slog.Logger
+}
+
+func New(bus *events.Bus, logger *slog.Logger) *Handler {
+	return &Handler{bus: bus, logger: logger.WithGroup("event_stream_http")}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(StreamPath, h.handleStream)
+}
+
+func (h *Handler) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !runtimeconfig.FromContext(r.Context()).FeatureEnabled(config.FeatureEventStream) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The stream stays open far longer than the server's read and write
+	// timeouts.
+	rc := http.NewResponseController(w)
+	err := errors.Join(rc.SetReadDeadline(time.Time{}), rc.SetWriteDeadline(time.Time{}))
+	if err != nil {
+		h.logger.Error("event stream is not supported by the connection", slog.Any("error", err))
+		http.Error(w, "event stream is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	scope, scoped := access.FromContext(r.Context())
+	hidden := masking.FromContext(r.Context())
+
+	// The bus runs handlers synchronously in the emitting request, so events
+	// are only queued here and a client that falls behind is dropped.
+	queue := make(chan events.Event, bufferSize)
+	behind := make(chan struct{})
+	var once sync.Once
+	unsubscribe := h.bus.Subscribe("event_stream", func(_ context.Context, event events.Event) error {
+		select {
+		case queue <- event:
+		default:
+			once.Do(func() { close(behind) })
+		}
+		return nil
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-behind:
+			h.logger.Warn("event stream client fell behind, closing the stream")
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-queue:
+			if scoped && scope.Restricted() && !visible(scope, event) {
+				continue
+			}
+
+			data, marshalErr := json.Marshal(eventResponseFromDomain(event, hidden))
+			if marshalErr != nil {
+				h.logger.Error("failed to encode event", slog.Any("error", marshalErr))
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// visible reports whether a restricted API key may see the subscription the
+// event is about.
+func visible(scope access.Scope, event events.Event) bool {
+	if event.Subscription == nil {
+		return false
+	}
+
+	userID, err := uuid.Parse(event.UserID)
+	if err != nil {
+		return false
+	}
+
+	return scope.Allows(userID, event.Subscription.ServiceName)
+}
+
+type eventResponse struct {
+	Type           events.Type           `json:"type"`
+	OccurredAt     time.Time             `json:"occurred_at"`
+	SubscriptionID string                `json:"subscription_id"`
+	UserID         string                `json:"user_id,omitempty"`
+	Subscription   *subscriptionResponse `json:"subscription,omitempty"`
+}
+
+type subscriptionResponse struct {
+	ServiceName string  `json:"service_name,omitempty"`
+	Price       *int    `json:"price,omitempty"`
+	StartDate   string  `json:"start_date,omitempty"`
+	EndDate     *string `json:"end_date,omitempty"`
+	Status      string  `json:"status"`
+}
+
+func eventResponseFromDomain(event events.Event, hidden masking.Fields) eventResponse {
+	resp := eventResponse{
+		Type:           event.Type,
+		OccurredAt:     event.OccurredAt,
+		SubscriptionID: event.SubscriptionID,
+		UserID:         event.UserID,
+	}
+	if hidden.Hidden(masking.FieldUserID) {
+		resp.UserID = ""
+	}
+
+	if sub := event.Subscription; sub != nil {
+		price := sub.Price
+		resp.Subscription = &subscriptionResponse{
+			ServiceName: sub.ServiceName,
+			Price:       &price,
+			StartDate:   sub.StartDate,
+			EndDate:     sub.EndDate,
+			Status:      sub.Status,
+		}
+
+		if hidden.Hidden(masking.FieldServiceName) {
+			resp.Subscription.ServiceName = ""
+		}
+		if hidden.Hidden(masking.FieldPrice) {
+			resp.Subscription.Price = nil
+		}
+		if hidden.Hidden(masking.FieldStartDate) {
+			resp.Subscription.StartDate = ""
+		}
+		if hidden.Hidden(masking.FieldEndDate) {
+			resp.Subscription.EndDate = nil
+		}
+	}
+
+	return resp
+}
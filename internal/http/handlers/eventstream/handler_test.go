@@ -0,0 +1,111 @@
+package eventstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/events"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+func TestStreamOutlivesServerTimeouts(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	bus := events.NewBus(log)
+
+	mux := http.NewServeMux()
+	New(bus, log).Register(mux)
+
+	server := httptest.NewUnstartedServer(mux)
+	server.Config.ReadTimeout = 100 * time.Millisecond
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	t.Cleanup(server.Close)
+
+	resp, err := server.Client().Get(server.URL + StreamPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	userID := uuid.New().String()
+	bus.Emit(context.Background(), events.Event{
+		Type:           events.SubscriptionCreated,
+		SubscriptionID: "sub-1",
+		UserID:         userID,
+		Subscription:   &events.Subscription{ServiceName: "Yandex Plus", Price: 400, StartDate: "01-2025", Status: "active"},
+	})
+
+	eventType, data := readEvent(t, bufio.NewReader(resp.Body))
+	if eventType != string(events.SubscriptionCreated) {
+		t.Fatalf("unexpected event type %q", eventType)
+	}
+
+	var got eventResponse
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SubscriptionID != "sub-1" || got.UserID != userID || got.Subscription == nil || *got.Subscription.Price != 400 {
+		t.Fatalf("unexpected event %s", data)
+	}
+}
+
+func TestVisible(t *testing.T) {
+	userID := uuid.New()
+	scope := access.Scope{UserIDs: []uuid.UUID{userID}}
+
+	tests := []struct {
+		name  string
+		event events.Event
+		want  bool
+	}{
+		{name: "own subscription", event: events.Event{UserID: userID.String(), Subscription: &events.Subscription{ServiceName: "Netflix"}}, want: true},
+		{name: "other user", event: events.Event{UserID: uuid.New().String(), Subscription: &events.Subscription{ServiceName: "Netflix"}}},
+		{name: "no subscription", event: events.Event{UserID: userID.String()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := visible(scope, tt.event); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// readEvent returns the type and data of the next event, skipping comments.
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+
+	var eventType, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && data != "":
+			return eventType, data
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
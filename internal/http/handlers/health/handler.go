@@ -0,0 +1,76 @@
+package health
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/health"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+)
+
+const detailsPath = "/health/details"
+
+type Handler struct {
+	checker *health.Checker
+	logger  *slog.Logger
+}
+
+func New(checker *health.Checker, logger *slog.Logger) *Handler {
+	return &Handler{checker: checker, logger: logger.WithGroup("health_http")}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(detailsPath, h.handleDetails)
+}
+
+// handleDetails always answers 200: the report is meant for dashboards, which
+// read the statuses from the body.
+func (h *Handler) handleDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := h.checker.Report(r.Context())
+
+	resp := reportResponse{
+		Status:       string(report.Status),
+		CheckedAt:    report.CheckedAt,
+		Dependencies: make([]dependencyResponse, 0, len(report.Dependencies)),
+	}
+	for _, dep := range report.Dependencies {
+		resp.Dependencies = append(resp.Dependencies, dependencyResponseFromHealth(dep))
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+type reportResponse struct {
+	Status       string               `json:"status"`
+	CheckedAt    time.Time            `json:"checked_at"`
+	Dependencies []dependencyResponse `json:"dependencies"`
+}
+
+type dependencyResponse struct {
+	Name        string     `json:"name"`
+	Kind        string     `json:"kind"`
+	Status      string     `json:"status"`
+	LatencyMS   *float64   `json:"latency_ms,omitempty"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+func dependencyResponseFromHealth(dep health.Dependency) dependencyResponse {
+	resp := dependencyResponse{Name: dep.Name, Kind: dep.Kind, Status: string(dep.Status), LastError: dep.LastError}
+	if !dep.CheckedAt.IsZero() {
+		latency := float64(dep.Latency.Microseconds()) / 1000
+		resp.LatencyMS, resp.CheckedAt = &latency, &dep.CheckedAt
+	}
+	if !dep.LastErrorAt.IsZero() {
+		resp.LastErrorAt = &dep.LastErrorAt
+	}
+	return resp
+}
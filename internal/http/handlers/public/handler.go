@@ -0,0 +1,68 @@
+package public
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
+	"github.com/Kulibyka/effective-mobile/internal/services/stats"
+)
+
+const servicesStatsPath = "/api/v1/public/stats/services"
+
+type Handler struct {
+	service *stats.Service
+	logger  *slog.Logger
+}
+
+func New(service *stats.Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger.WithGroup("public_http")}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(servicesStatsPath, h.handleServiceStats)
+}
+
+func (h *Handler) handleServiceStats(w http.ResponseWriter, r *http.Request) {
+	if !runtimeconfig.FromContext(r.Context()).FeatureEnabled(config.FeaturePublicStats) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := h.service.ServiceStats(r.Context())
+	if err != nil {
+		h.logger.Error("failed to load service stats", slog.Any("error", err))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to load service stats", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]serviceStatsResponse, 0, len(stats))
+	for _, st := range stats {
+		resp = append(resp, serviceStatsResponse{
+			ServiceName:  st.ServiceName,
+			Rank:         st.Rank,
+			Subscribers:  st.Subscribers,
+			AveragePrice: st.AveragePrice,
+		})
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+type serviceStatsResponse struct {
+	ServiceName  string  `json:"service_name"`
+	Rank         int     `json:"rank"`
+	Subscribers  int     `json:"subscribers"`
+	AveragePrice float64 `json:"average_price"`
+}
@@ -9,14 +9,24 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Kulibyka/effective-mobile/internal/access"
 	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/http/masking"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
 	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
 	"github.com/Kulibyka/effective-mobile/internal/services/subscriptions"
 )
 
 const (
-	basePath    = "/api/v1/subscriptions"
-	summaryPath = basePath + "/summary"
+	basePath      = "/api/v1/subscriptions"
+	summaryPath   = basePath + "/summary"
+	namesPath     = basePath + "/service-names"
+	historySuffix = "/history"
+
+	groupByCategory = "category"
+
+	totalCountHeader = "X-Total-Count"
 )
 
 type Handler struct {
@@ -30,15 +40,17 @@ func New(service *subscriptions.Service, logger *slog.Logger) *Handler {
 
 func (h *Handler) Register(mux *http.ServeMux) {
 	mux.HandleFunc(summaryPath, h.handleSummary)
+	mux.HandleFunc(namesPath, h.handleServiceNames)
 	mux.HandleFunc(basePath, h.handleBase)
 	mux.HandleFunc(basePath+"/", h.handleWithID)
 }
 
 func (h *Handler) handleBase(w http.ResponseWriter, r *http.Request) {
-	h.logger.Debug("handling base route", slog.String("method", r.Method), slog.String("path", r.URL.Path))
 	switch r.Method {
 	case http.MethodPost:
 		h.handleCreate(w, r)
+	case http.MethodPut:
+		h.handleUpsert(w, r)
 	case http.MethodGet:
 		h.handleList(w, r)
 	default:
@@ -49,6 +61,7 @@ func (h *Handler) handleBase(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleWithID(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, basePath+"/")
+	idStr, history := strings.CutSuffix(idStr, historySuffix)
 	if idStr == "" {
 		h.logger.Warn("subscription id is required", slog.String("path", r.URL.Path))
 		http.NotFound(w, r)
@@ -56,13 +69,22 @@ func (h *Handler) handleWithID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	id, err := uuid.Parse(idStr)
-	if err != nil {
+	if err != nil || id.IsNil() {
 		h.logger.Warn("failed to parse subscription id", slog.String("subscription_id", idStr), slog.Any("error", err))
 		http.Error(w, "invalid subscription id", http.StatusBadRequest)
 		return
 	}
 
-	h.logger.Debug("handling request with subscription id", slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.String("subscription_id", id.String()))
+	if history {
+		if r.Method != http.MethodGet {
+			h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		h.handleHistory(w, r, id)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		h.handleGet(w, r, id)
@@ -91,20 +113,89 @@ func (h *Handler) handleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("creating subscription", slog.String("user_id", input.UserID.String()), slog.String("service_name", input.ServiceName))
 	sub, err := h.service.Create(r.Context(), input)
 	if err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			h.logger.Warn("subscription id already taken", slog.String("subscription_id", input.ID.String()))
+			http.Error(w, "subscription with this id already exists", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, access.ErrForbidden) {
+			h.logger.Warn("subscription outside of api key scope", slog.String("user_id", input.UserID.String()))
+			http.Error(w, "subscription is outside of the api key scope", http.StatusForbidden)
+			return
+		}
+		if h.writeRejection(w, err) {
+			return
+		}
 		h.logger.Error("failed to create subscription", slog.Any("error", err), slog.String("user_id", input.UserID.String()), slog.String("service_name", input.ServiceName))
+		errortracker.Record(r.Context(), err)
 		http.Error(w, "failed to create subscription", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("subscription created", slog.String("subscription_id", sub.ID.String()))
-	writeJSON(w, http.StatusCreated, subscriptionResponseFromDomain(sub))
+	response.WriteJSON(w, http.StatusCreated, subscriptionResponseFromDomain(sub, masking.FromContext(r.Context())))
+}
+
+// handleUpsert creates or updates the subscription identified by user_id,
+// service_name and start_date, answering 201 only when it was created.
+func (h *Handler) handleUpsert(w http.ResponseWriter, r *http.Request) {
+	var req subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("failed to decode upsert request", slog.Any("error", err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input, err := req.toCreateInput()
+	if err != nil {
+		h.logger.Warn("invalid upsert request", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, outcome, err := h.service.Upsert(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, access.ErrForbidden) {
+			h.logger.Warn("subscription outside of api key scope", slog.String("user_id", input.UserID.String()))
+			http.Error(w, "subscription is outside of the api key scope", http.StatusForbidden)
+			return
+		}
+		if h.writeRejection(w, err) {
+			return
+		}
+		h.logger.Error("failed to upsert subscription", slog.Any("error", err), slog.String("user_id", input.UserID.String()), slog.String("service_name", input.ServiceName))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to upsert subscription", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if outcome == domain.UpsertCreated {
+		status = http.StatusCreated
+	}
+
+	response.WriteJSON(w, status, subscriptionResponseFromDomain(sub, masking.FromContext(r.Context())))
+}
+
+// writeRejection answers writes refused by a storage constraint: 409 for a
+// duplicate subscription, 422 for invalid references and values. It reports
+// whether err was one of those.
+func (h *Handler) writeRejection(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, domain.ErrDuplicate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrInvalidReference), errors.Is(err, domain.ErrInvalidValue):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		return false
+	}
+
+	h.logger.Warn("subscription write rejected", slog.Any("error", err))
+	return true
 }
 
 func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
-	h.logger.Debug("getting subscription", slog.String("subscription_id", id.String()))
 	sub, err := h.service.Get(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
@@ -113,12 +204,12 @@ func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request, id uuid.UUID
 			return
 		}
 		h.logger.Error("failed to get subscription", slog.Any("error", err), slog.String("subscription_id", id.String()))
+		errortracker.Record(r.Context(), err)
 		http.Error(w, "failed to get subscription", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Debug("subscription fetched", slog.String("subscription_id", sub.ID.String()))
-	writeJSON(w, http.StatusOK, subscriptionResponseFromDomain(sub))
+	response.WriteJSON(w, http.StatusOK, subscriptionResponseFromDomain(sub, masking.FromContext(r.Context())))
 }
 
 func (h *Handler) handleUpdate(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
@@ -136,7 +227,6 @@ func (h *Handler) handleUpdate(w http.ResponseWriter, r *http.Request, id uuid.U
 		return
 	}
 
-	h.logger.Info("updating subscription", slog.String("subscription_id", id.String()))
 	sub, err := h.service.Update(r.Context(), id, input)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
@@ -144,17 +234,29 @@ func (h *Handler) handleUpdate(w http.ResponseWriter, r *http.Request, id uuid.U
 			http.Error(w, "subscription not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, domain.ErrConflict) {
+			h.logger.Warn("subscription version conflict", slog.String("subscription_id", id.String()))
+			http.Error(w, "subscription was modified by another request", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, access.ErrForbidden) {
+			h.logger.Warn("subscription outside of api key scope", slog.String("subscription_id", id.String()))
+			http.Error(w, "subscription is outside of the api key scope", http.StatusForbidden)
+			return
+		}
+		if h.writeRejection(w, err) {
+			return
+		}
 		h.logger.Error("failed to update subscription", slog.Any("error", err), slog.String("subscription_id", id.String()))
+		errortracker.Record(r.Context(), err)
 		http.Error(w, "failed to update subscription", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("subscription updated", slog.String("subscription_id", sub.ID.String()))
-	writeJSON(w, http.StatusOK, subscriptionResponseFromDomain(sub))
+	response.WriteJSON(w, http.StatusOK, subscriptionResponseFromDomain(sub, masking.FromContext(r.Context())))
 }
 
 func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
-	h.logger.Info("deleting subscription", slog.String("subscription_id", id.String()))
 	if err := h.service.Delete(r.Context(), id); err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
 			h.logger.Warn("subscription not found", slog.String("subscription_id", id.String()))
@@ -162,14 +264,42 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, id uuid.U
 			return
 		}
 		h.logger.Error("failed to delete subscription", slog.Any("error", err), slog.String("subscription_id", id.String()))
+		errortracker.Record(r.Context(), err)
 		http.Error(w, "failed to delete subscription", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("subscription deleted", slog.String("subscription_id", id.String()))
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) handleHistory(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	entries, err := h.service.History(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			h.logger.Warn("subscription not found", slog.String("subscription_id", id.String()))
+			http.Error(w, "subscription not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get subscription history", slog.Any("error", err), slog.String("subscription_id", id.String()))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to get subscription history", http.StatusInternalServerError)
+		return
+	}
+
+	hidden := masking.FromContext(r.Context())
+	resp := make([]historyEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		resp = append(resp, historyEntryResponse{
+			subscriptionResponse: subscriptionResponseFromDomain(entry.Subscription, hidden),
+			ValidFrom:            entry.ValidFrom,
+			ValidTo:              entry.ValidTo,
+			Operation:            entry.Operation,
+		})
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
 func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
 	filter, err := parseListFilter(r)
 	if err != nil {
@@ -178,21 +308,63 @@ func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Debug("listing subscriptions", slog.Any("filter", filter))
-	subs, err := h.service.List(r.Context(), filter)
+	page, err := h.service.List(r.Context(), filter)
 	if err != nil {
+		if errors.Is(err, domain.ErrQueryTooBroad) {
+			h.logger.Warn("list query too broad", slog.Any("filter", filter))
+			http.Error(w, "query would scan too many subscriptions; narrow the filters or pass force=true", http.StatusUnprocessableEntity)
+			return
+		}
 		h.logger.Error("failed to list subscriptions", slog.Any("error", err), slog.Any("filter", filter))
+		errortracker.Record(r.Context(), err)
 		http.Error(w, "failed to list subscriptions", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Debug("subscriptions listed", slog.Int("count", len(subs)))
-	resp := make([]subscriptionResponse, 0, len(subs))
-	for _, sub := range subs {
-		resp = append(resp, subscriptionResponseFromDomain(sub))
+	hidden := masking.FromContext(r.Context())
+	resp := make([]subscriptionResponse, 0, len(page.Subscriptions))
+	for _, sub := range page.Subscriptions {
+		resp = append(resp, subscriptionResponseFromDomain(sub, hidden))
+	}
+
+	if page.Total != nil {
+		w.Header().Set(totalCountHeader, strconv.FormatInt(*page.Total, 10))
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) handleServiceNames(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var userID uuid.UUID
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil || parsed.IsNil() {
+			h.logger.Warn("failed to parse user id", slog.String("user_id", value), slog.Any("error", err))
+			http.Error(w, "invalid user_id", http.StatusBadRequest)
+			return
+		}
+		userID = parsed
+	}
+
+	names, err := h.service.ServiceNames(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to list service names", slog.Any("error", err))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to list service names", http.StatusInternalServerError)
+		return
+	}
+
+	if names == nil {
+		names = []string{}
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	response.WriteJSON(w, http.StatusOK, names)
 }
 
 func (h *Handler) handleSummary(w http.ResponseWriter, r *http.Request) {
@@ -209,29 +381,108 @@ func (h *Handler) handleSummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Debug("calculating summary", slog.Any("filter", summaryFilter))
+	switch groupBy := r.URL.Query().Get("group_by"); groupBy {
+	case "":
+	case groupByCategory:
+		h.handleSummaryByCategory(w, r, summaryFilter)
+		return
+	default:
+		h.logger.Warn("invalid summary grouping", slog.String("group_by", groupBy))
+		http.Error(w, "invalid group_by, expected category", http.StatusBadRequest)
+		return
+	}
+
+	if response.Negotiate(r, response.ContentTypeJSON, response.ContentTypeCSV) == response.ContentTypeCSV {
+		h.handleSummaryCSV(w, r, summaryFilter)
+		return
+	}
+
 	total, err := h.service.Sum(r.Context(), summaryFilter)
 	if err != nil {
 		h.logger.Error("failed to calculate summary", slog.Any("error", err), slog.Any("filter", summaryFilter))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to calculate summary", http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]int{"total": total})
+}
+
+func (h *Handler) handleSummaryCSV(w http.ResponseWriter, r *http.Request, summaryFilter domain.SummaryFilter) {
+	totals, err := h.service.MonthlyTotals(r.Context(), summaryFilter)
+	if err != nil {
+		h.logger.Error("failed to calculate summary", slog.Any("error", err), slog.Any("filter", summaryFilter))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to calculate summary", http.StatusInternalServerError)
+		return
+	}
+
+	rows := make([][]string, 0, len(totals))
+	for _, t := range totals {
+		rows = append(rows, []string{t.Month.Time().Format(domain.MonthLayout), strconv.Itoa(t.Total)})
+	}
+
+	response.WriteCSV(w, http.StatusOK, []string{"month", "total"}, rows)
+}
+
+func (h *Handler) handleSummaryByCategory(w http.ResponseWriter, r *http.Request, summaryFilter domain.SummaryFilter) {
+	totals, err := h.service.CategoryTotals(r.Context(), summaryFilter)
+	if err != nil {
+		h.logger.Error("failed to calculate summary", slog.Any("error", err), slog.Any("filter", summaryFilter))
+		errortracker.Record(r.Context(), err)
 		http.Error(w, "failed to calculate summary", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("summary calculated", slog.Int("total", total))
-	writeJSON(w, http.StatusOK, map[string]int{"total": total})
+	if response.Negotiate(r, response.ContentTypeJSON, response.ContentTypeCSV) == response.ContentTypeCSV {
+		rows := make([][]string, 0, len(totals))
+		for _, t := range totals {
+			id := ""
+			if t.CategoryID != nil {
+				id = t.CategoryID.String()
+			}
+			rows = append(rows, []string{id, t.Category, strconv.Itoa(t.Total)})
+		}
+
+		response.WriteCSV(w, http.StatusOK, []string{"category_id", "category", "total"}, rows)
+		return
+	}
+
+	resp := categorySummaryResponse{Categories: make([]categoryTotalResponse, 0, len(totals))}
+	for _, t := range totals {
+		resp.Total += t.Total
+		resp.Categories = append(resp.Categories, categoryTotalResponse{CategoryID: t.CategoryID, Category: t.Category, Total: t.Total})
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+type categorySummaryResponse struct {
+	Total      int                     `json:"total"`
+	Categories []categoryTotalResponse `json:"categories"`
+}
+
+// categoryTotalResponse leaves category_id and category empty for the spend
+// on services without a category.
+type categoryTotalResponse struct {
+	CategoryID *uuid.UUID `json:"category_id"`
+	Category   string     `json:"category"`
+	Total      int        `json:"total"`
 }
 
 type subscriptionRequest struct {
+	ID          *string `json:"id,omitempty"`
 	ServiceName string  `json:"service_name"`
 	Price       int     `json:"price"`
 	UserID      string  `json:"user_id"`
 	StartDate   string  `json:"start_date"`
 	EndDate     *string `json:"end_date,omitempty"`
+	Version     *int    `json:"version,omitempty"`
 }
 
 func (r subscriptionRequest) toCreateInput() (domain.CreateInput, error) {
 	userID, err := uuid.Parse(r.UserID)
-	if err != nil {
+	if err != nil || userID.IsNil() {
 		return domain.CreateInput{}, errors.New("invalid user_id")
 	}
 
@@ -240,6 +491,15 @@ func (r subscriptionRequest) toCreateInput() (domain.CreateInput, error) {
 		return domain.CreateInput{}, errors.New("invalid start_date format, expected MM-YYYY")
 	}
 
+	var id *uuid.UUID
+	if r.ID != nil {
+		parsed, err := uuid.Parse(*r.ID)
+		if err != nil || parsed.IsNil() {
+			return domain.CreateInput{}, errors.New("invalid id")
+		}
+		id = &parsed
+	}
+
 	var end *time.Time
 	if r.EndDate != nil {
 		if *r.EndDate == "" {
@@ -254,6 +514,7 @@ func (r subscriptionRequest) toCreateInput() (domain.CreateInput, error) {
 	}
 
 	return domain.CreateInput{
+		ID:          id,
 		ServiceName: r.ServiceName,
 		Price:       r.Price,
 		UserID:      userID,
@@ -273,25 +534,31 @@ func (r subscriptionRequest) toUpdateInput() (domain.UpdateInput, error) {
 		Price:       input.Price,
 		StartMonth:  input.StartMonth,
 		EndMonth:    input.EndMonth,
+		Version:     r.Version,
 	}, nil
 }
 
 type subscriptionResponse struct {
 	ID          uuid.UUID `json:"id"`
-	ServiceName string    `json:"service_name"`
-	Price       int       `json:"price"`
-	UserID      uuid.UUID `json:"user_id"`
-	StartDate   string    `json:"start_date"`
+	ServiceName string    `json:"service_name,omitempty"`
+	Price       *int      `json:"price,omitempty"`
+	UserID      uuid.UUID `json:"user_id,omitempty"`
+	StartDate   string    `json:"start_date,omitempty"`
 	EndDate     *string   `json:"end_date,omitempty"`
+	Status      string    `json:"status"`
+	Version     int       `json:"version"`
 }
 
-func subscriptionResponseFromDomain(sub domain.Subscription) subscriptionResponse {
+func subscriptionResponseFromDomain(sub domain.Subscription, hidden masking.Fields) subscriptionResponse {
+	price := sub.Price
 	resp := subscriptionResponse{
 		ID:          sub.ID,
 		ServiceName: sub.ServiceName,
-		Price:       sub.Price,
+		Price:       &price,
 		UserID:      sub.UserID,
 		StartDate:   sub.StartMonth.Format(domain.MonthLayout),
+		Status:      string(sub.Status),
+		Version:     sub.Version,
 	}
 
 	if sub.EndMonth != nil {
@@ -299,6 +566,22 @@ func subscriptionResponseFromDomain(sub domain.Subscription) subscriptionRespons
 		resp.EndDate = &formatted
 	}
 
+	if hidden.Hidden(masking.FieldServiceName) {
+		resp.ServiceName = ""
+	}
+	if hidden.Hidden(masking.FieldPrice) {
+		resp.Price = nil
+	}
+	if hidden.Hidden(masking.FieldUserID) {
+		resp.UserID = ""
+	}
+	if hidden.Hidden(masking.FieldStartDate) {
+		resp.StartDate = ""
+	}
+	if hidden.Hidden(masking.FieldEndDate) {
+		resp.EndDate = nil
+	}
+
 	return resp
 }
 
@@ -307,16 +590,26 @@ func parseListFilter(r *http.Request) (domain.ListFilter, error) {
 
 	if userID := r.URL.Query().Get("user_id"); userID != "" {
 		parsed, err := uuid.Parse(userID)
-		if err != nil {
+		if err != nil || parsed.IsNil() {
 			return domain.ListFilter{}, errors.New("invalid user_id")
 		}
-		filter.UserID = &parsed
+		filter.UserID = parsed
 	}
 
 	if serviceName := r.URL.Query().Get("service_name"); serviceName != "" {
 		filter.ServiceName = &serviceName
 	}
 
+	if search := strings.TrimSpace(r.URL.Query().Get("search")); search != "" {
+		filter.Search = &search
+	}
+
+	categoryID, err := parseCategoryID(r)
+	if err != nil {
+		return domain.ListFilter{}, err
+	}
+	filter.CategoryID = categoryID
+
 	if start := r.URL.Query().Get("start_date"); start != "" {
 		parsed, err := time.Parse(domain.MonthLayout, start)
 		if err != nil {
@@ -333,6 +626,28 @@ func parseListFilter(r *http.Request) (domain.ListFilter, error) {
 		filter.StartMonthTo = &parsed
 	}
 
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		return domain.ListFilter{}, err
+	}
+	filter.AsOf = asOf
+
+	if force := r.URL.Query().Get("force"); force != "" {
+		parsed, err := strconv.ParseBool(force)
+		if err != nil {
+			return domain.ListFilter{}, errors.New("invalid force")
+		}
+		filter.Force = parsed
+	}
+
+	if withTotal := r.URL.Query().Get("with_total"); withTotal != "" {
+		parsed, err := strconv.ParseBool(withTotal)
+		if err != nil {
+			return domain.ListFilter{}, errors.New("invalid with_total")
+		}
+		filter.WithTotal = parsed
+	}
+
 	if limit := r.URL.Query().Get("limit"); limit != "" {
 		parsed, err := strconv.Atoi(limit)
 		if err != nil || parsed < 0 {
@@ -381,23 +696,62 @@ func parseSummaryFilter(r *http.Request) (domain.SummaryFilter, error) {
 
 	if userID := r.URL.Query().Get("user_id"); userID != "" {
 		parsed, err := uuid.Parse(userID)
-		if err != nil {
+		if err != nil || parsed.IsNil() {
 			return domain.SummaryFilter{}, errors.New("invalid user_id")
 		}
-		filter.UserID = &parsed
+		filter.UserID = parsed
 	}
 
 	if serviceName := r.URL.Query().Get("service_name"); serviceName != "" {
 		filter.ServiceName = &serviceName
 	}
 
+	categoryID, err := parseCategoryID(r)
+	if err != nil {
+		return domain.SummaryFilter{}, err
+	}
+	filter.CategoryID = categoryID
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		return domain.SummaryFilter{}, err
+	}
+	filter.AsOf = asOf
+
 	return filter, nil
 }
 
-func writeJSON(w http.ResponseWriter, status int, body any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(body); err != nil {
-		slog.Default().Error("failed to encode response", slog.Any("error", err))
+func parseCategoryID(r *http.Request) (uuid.UUID, error) {
+	value := r.URL.Query().Get("category_id")
+	if value == "" {
+		return "", nil
+	}
+
+	parsed, err := uuid.Parse(value)
+	if err != nil || parsed.IsNil() {
+		return "", errors.New("invalid category_id")
 	}
+
+	return parsed, nil
+}
+
+func parseAsOf(r *http.Request) (*time.Time, error) {
+	value := r.URL.Query().Get("as_of")
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(domain.MonthLayout, value)
+	if err != nil {
+		return nil, errors.New("invalid as_of format, expected MM-YYYY")
+	}
+
+	return &parsed, nil
+}
+
+type historyEntryResponse struct {
+	subscriptionResponse
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+	Operation string     `json:"operation,omitempty"`
 }
@@ -0,0 +1,53 @@
+package subscriptions
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+func TestRejectsNilUUID(t *testing.T) {
+	// The service is never reached: every request below fails validation.
+	mux := http.NewServeMux()
+	New(nil, slog.New(slog.NewTextHandler(io.Discard, nil))).Register(mux)
+
+	nilID := uuid.Nil.String()
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   string
+	}{
+		{"get by id", http.MethodGet, basePath + "/" + nilID, "", "invalid subscription id"},
+		{"delete by id", http.MethodDelete, basePath + "/" + nilID, "", "invalid subscription id"},
+		{"history", http.MethodGet, basePath + "/" + nilID + historySuffix, "", "invalid subscription id"},
+		{"list by user", http.MethodGet, basePath + "?user_id=" + nilID, "", "invalid user_id"},
+		{"list by category", http.MethodGet, basePath + "?category_id=" + nilID, "", "invalid category_id"},
+		{"summary by user", http.MethodGet, summaryPath + "?start_date=01-2025&end_date=12-2025&user_id=" + nilID, "", "invalid user_id"},
+		{"summary by category", http.MethodGet, summaryPath + "?start_date=01-2025&end_date=12-2025&category_id=" + nilID, "", "invalid category_id"},
+		{"service names by user", http.MethodGet, namesPath + "?user_id=" + nilID, "", "invalid user_id"},
+		{"create for user", http.MethodPost, basePath, `{"service_name":"Yandex Plus","price":400,"user_id":"` + nilID + `","start_date":"07-2025"}`, "invalid user_id"},
+		{"create with id", http.MethodPost, basePath, `{"id":"` + nilID + `","service_name":"Yandex Plus","price":400,"user_id":"` + uuid.New().String() + `","start_date":"07-2025"}`, "invalid id"},
+		{"upsert for user", http.MethodPut, basePath, `{"service_name":"Yandex Plus","price":400,"user_id":"` + nilID + `","start_date":"07-2025"}`, "invalid user_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Fatalf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package suggestions
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	subdomain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/suggestion"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/http/masking"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/services/suggestions"
+)
+
+const (
+	basePath      = "/api/v1/suggestions"
+	confirmSuffix = "/confirm"
+	usersPath     = "/api/v1/users/"
+	userSuffix    = "/suggestions"
+
+	actionAccept  = "accept"
+	actionDismiss = "dismiss"
+)
+
+type Handler struct {
+	service *suggestions.Service
+	logger  *slog.Logger
+}
+
+func New(service *suggestions.Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger.WithGroup("suggestions_http")}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(basePath, h.handleIngest)
+	mux.HandleFunc(basePath+"/", h.handleConfirm)
+	mux.HandleFunc(usersPath, h.handleUserSuggestions)
+}
+
+func (h *Handler) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req detectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("failed to decode detection", slog.Any("error", err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input, err := req.toCreateInput()
+	if err != nil {
+		h.logger.Warn("invalid detection", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sug, err := h.service.Ingest(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, access.ErrForbidden) {
+			http.Error(w, "suggestion is outside of the api key scope", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, domain.ErrConflict) {
+			http.Error(w, "external_id is already used by this source for another user", http.StatusConflict)
+			return
+		}
+		h.logger.Error("failed to ingest detection", slog.Any("error", err), slog.String("source", input.Source))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to ingest detection", http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, suggestionResponseFromDomain(sug, masking.FromContext(r.Context())))
+}
+
+func (h *Handler) handleConfirm(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, basePath+"/")
+	idStr, ok := strings.CutSuffix(rest, confirmSuffix)
+	if !ok || idStr == "" || strings.Contains(idStr, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil || id.IsNil() {
+		h.logger.Warn("failed to parse suggestion id", slog.String("suggestion_id", idStr), slog.Any("error", err))
+		http.Error(w, "invalid suggestion id", http.StatusBadRequest)
+		return
+	}
+
+	var req confirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Warn("failed to decode confirm request", slog.String("suggestion_id", id.String()), slog.Any("error", err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input, err := req.toConfirmInput()
+	if err != nil {
+		h.logger.Warn("invalid confirm request", slog.String("suggestion_id", id.String()), slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.service.Confirm(r.Context(), id, input)
+	if err != nil {
+		h.writeReviewError(w, r, id, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, confirmResponse{SuggestionID: id, SubscriptionID: sub.ID})
+}
+
+func (h *Handler) handleUserSuggestions(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, usersPath)
+	userIDStr, ok := strings.CutSuffix(rest, userSuffix)
+	if !ok || userIDStr == "" || strings.Contains(userIDStr, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil || userID.IsNil() {
+		h.logger.Warn("failed to parse user id", slog.String("user_id", userIDStr), slog.Any("error", err))
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.handleList(w, r, userID)
+	case http.MethodPost:
+		h.handleReview(w, r, userID)
+	default:
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	filter, err := parseListFilter(r, userID)
+	if err != nil {
+		h.logger.Warn("invalid suggestions filter", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sugs, err := h.service.List(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list suggestions", slog.Any("error", err), slog.String("user_id", userID.String()))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to list suggestions", http.StatusInternalServerError)
+		return
+	}
+
+	hidden := masking.FromContext(r.Context())
+	resp := make([]suggestionResponse, 0, len(sugs))
+	for _, sug := range sugs {
+		resp = append(resp, suggestionResponseFromDomain(sug, hidden))
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) handleReview(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("failed to decode review request", slog.Any("error", err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	id, err := uuid.Parse(req.SuggestionID)
+	if err != nil || id.IsNil() {
+		http.Error(w, "invalid suggestion_id", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Action {
+	case actionAccept:
+		input, err := req.confirmRequest.toConfirmInput()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sub, err := h.service.Accept(r.Context(), userID, id, input)
+		if err != nil {
+			h.writeReviewError(w, r, id, err)
+			return
+		}
+
+		response.WriteJSON(w, http.StatusCreated, confirmResponse{SuggestionID: id, SubscriptionID: sub.ID})
+	case actionDismiss:
+		sug, err := h.service.Dismiss(r.Context(), userID, id, req.Reason)
+		if err != nil {
+			h.writeReviewError(w, r, id, err)
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, suggestionResponseFromDomain(sug, masking.FromContext(r.Context())))
+	default:
+		http.Error(w, "action must be accept or dismiss", http.StatusBadRequest)
+	}
+}
+
+func (h *Handler) writeReviewError(w http.ResponseWriter, r *http.Request, id uuid.UUID, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		http.Error(w, "suggestion not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrAlreadyReviewed):
+		http.Error(w, "suggestion already reviewed", http.StatusConflict)
+	case errors.Is(err, domain.ErrIncomplete):
+		http.Error(w, "service_name and price are required to confirm this suggestion", http.StatusBadRequest)
+	case errors.Is(err, access.ErrForbidden):
+		http.Error(w, "subscription is outside of the api key scope", http.StatusForbidden)
+	default:
+		h.logger.Error("failed to review suggestion", slog.Any("error", err), slog.String("suggestion_id", id.String()))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to review suggestion", http.StatusInternalServerError)
+	}
+}
+
+type detectionRequest struct {
+	UserID      string          `json:"user_id"`
+	ServiceName string          `json:"service_name"`
+	Price       *int            `json:"price,omitempty"`
+	StartDate   *string         `json:"start_date,omitempty"`
+	Confidence  float64         `json:"confidence"`
+	Source      string          `json:"source"`
+	ExternalID  *string         `json:"external_id,omitempty"`
+	Raw         json.RawMessage `json:"raw,omitempty"`
+}
+
+func (r detectionRequest) toCreateInput() (domain.CreateInput, error) {
+	userID, err := uuid.Parse(r.UserID)
+	if err != nil || userID.IsNil() {
+		return domain.CreateInput{}, errors.New("invalid user_id")
+	}
+
+	if strings.TrimSpace(r.ServiceName) == "" {
+		return domain.CreateInput{}, errors.New("service_name is required")
+	}
+
+	if strings.TrimSpace(r.Source) == "" {
+		return domain.CreateInput{}, errors.New("source is required")
+	}
+
+	if r.Confidence < 0 || r.Confidence > 1 {
+		return domain.CreateInput{}, errors.New("confidence must be between 0 and 1")
+	}
+
+	if r.Price != nil && *r.Price < 0 {
+		return domain.CreateInput{}, errors.New("price must not be negative")
+	}
+
+	start, err := parseOptionalMonth(r.StartDate)
+	if err != nil {
+		return domain.CreateInput{}, errors.New("invalid start_date format, expected MM-YYYY")
+	}
+
+	return domain.CreateInput{
+		UserID:      userID,
+		ServiceName: strings.TrimSpace(r.ServiceName),
+		Price:       r.Price,
+		StartMonth:  start,
+		Confidence:  r.Confidence,
+		Source:      r.Source,
+		ExternalID:  r.ExternalID,
+		Raw:         r.Raw,
+	}, nil
+}
+
+type confirmRequest struct {
+	ServiceName *string `json:"service_name,omitempty"`
+	Price       *int    `json:"price,omitempty"`
+	StartDate   *string `json:"start_date,omitempty"`
+	EndDate     *string `json:"end_date,omitempty"`
+}
+
+func (r confirmRequest) toConfirmInput() (domain.ConfirmInput, error) {
+	if r.Price != nil && *r.Price < 0 {
+		return domain.ConfirmInput{}, errors.New("price must not be negative")
+	}
+
+	start, err := parseOptionalMonth(r.StartDate)
+	if err != nil {
+		return domain.ConfirmInput{}, errors.New("invalid start_date format, expected MM-YYYY")
+	}
+
+	end, err := parseOptionalMonth(r.EndDate)
+	if err != nil {
+		return domain.ConfirmInput{}, errors.New("invalid end_date format, expected MM-YYYY")
+	}
+
+	return domain.ConfirmInput{
+		ServiceName: r.ServiceName,
+		Price:       r.Price,
+		StartMonth:  start,
+		EndMonth:    end,
+	}, nil
+}
+
+type reviewRequest struct {
+	SuggestionID string  `json:"suggestion_id"`
+	Action       string  `json:"action"`
+	Reason       *string `json:"reason,omitempty"`
+	confirmRequest
+}
+
+type suggestionResponse struct {
+	ID             uuid.UUID       `json:"id"`
+	UserID         uuid.UUID       `json:"user_id,omitempty"`
+	ServiceName    string          `json:"service_name,omitempty"`
+	Price          *int            `json:"price,omitempty"`
+	StartDate      *string         `json:"start_date,omitempty"`
+	Confidence     float64         `json:"confidence"`
+	Source         string          `json:"source"`
+	ExternalID     *string         `json:"external_id,omitempty"`
+	Raw            json.RawMessage `json:"raw,omitempty"`
+	Status         string          `json:"status"`
+	SubscriptionID *uuid.UUID      `json:"subscription_id,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	ReviewedAt     *time.Time      `json:"reviewed_at,omitempty"`
+	ReviewReason   *string         `json:"review_reason,omitempty"`
+}
+
+func suggestionResponseFromDomain(sug domain.Suggestion, hidden masking.Fields) suggestionResponse {
+	resp := suggestionResponse{
+		ID:             sug.ID,
+		UserID:         sug.UserID,
+		ServiceName:    sug.ServiceName,
+		Price:          sug.Price,
+		Confidence:     sug.Confidence,
+		Source:         sug.Source,
+		ExternalID:     sug.ExternalID,
+		Raw:            sug.Raw,
+		Status:         string(sug.Status),
+		SubscriptionID: sug.SubscriptionID,
+		CreatedAt:      sug.CreatedAt,
+		ReviewedAt:     sug.ReviewedAt,
+		ReviewReason:   sug.ReviewReason,
+	}
+
+	if sug.StartMonth != nil {
+		formatted := sug.StartMonth.Format(subdomain.MonthLayout)
+		resp.StartDate = &formatted
+	}
+
+	if hidden.Hidden(masking.FieldServiceName) {
+		resp.ServiceName = ""
+	}
+	if hidden.Hidden(masking.FieldPrice) {
+		resp.Price = nil
+	}
+	if hidden.Hidden(masking.FieldUserID) {
+		resp.UserID = ""
+	}
+	if hidden.Hidden(masking.FieldStartDate) {
+		resp.StartDate = nil
+	}
+	if hidden.Hidden(masking.FieldRaw) {
+		resp.Raw = nil
+	}
+
+	return resp
+}
+
+type confirmResponse struct {
+	SuggestionID   uuid.UUID `json:"suggestion_id"`
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+}
+
+func parseListFilter(r *http.Request, userID uuid.UUID) (domain.ListFilter, error) {
+	filter := domain.ListFilter{UserID: userID}
+
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+		pending := domain.StatusPending
+		filter.Status = &pending
+	case "all":
+	case string(domain.StatusPending), string(domain.StatusAccepted), string(domain.StatusDismissed):
+		parsed := domain.Status(status)
+		filter.Status = &parsed
+	default:
+		return domain.ListFilter{}, errors.New("invalid status")
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 0 {
+			return domain.ListFilter{}, errors.New("invalid limit")
+		}
+		filter.Limit = parsed
+	}
+
+	if offset := r.URL.Query().Get("offset"); offset != "" {
+		parsed, err := strconv.Atoi(offset)
+		if err != nil || parsed < 0 {
+			return domain.ListFilter{}, errors.New("invalid offset")
+		}
+		filter.Offset = parsed
+	}
+
+	return filter, nil
+}
+
+func parseOptionalMonth(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(subdomain.MonthLayout, *value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
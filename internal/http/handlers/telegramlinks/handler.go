@@ -0,0 +1,80 @@
+package telegramlinks
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/errortracker"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/services/telegramlinks"
+)
+
+const basePath = "/api/v1/telegram/link-tokens"
+
+type Handler struct {
+	service *telegramlinks.Service
+	logger  *slog.Logger
+}
+
+func New(service *telegramlinks.Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger.WithGroup("telegram_links_http")}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(basePath, h.handleIssue)
+}
+
+func (h *Handler) handleIssue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req linkTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("failed to decode link token request", slog.Any("error", err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil || userID.IsNil() {
+		http.Error(w, "invalid user_id", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.service.Issue(r.Context(), userID, req.Relink)
+	if err != nil {
+		if errors.Is(err, access.ErrForbidden) {
+			http.Error(w, "user is outside of the api key scope", http.StatusForbidden)
+			return
+		}
+		h.logger.Error("failed to issue link token", slog.Any("error", err), slog.String("user_id", userID.String()))
+		errortracker.Record(r.Context(), err)
+		http.Error(w, "failed to issue link token", http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, linkTokenResponse{
+		Token:        token.Token,
+		StartCommand: "/start " + token.Token,
+		ExpiresAt:    token.ExpiresAt,
+	})
+}
+
+type linkTokenRequest struct {
+	UserID string `json:"user_id"`
+	Relink bool   `json:"relink"`
+}
+
+type linkTokenResponse struct {
+	Token        string    `json:"token"`
+	StartCommand string    `json:"start_command"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -0,0 +1,46 @@
+package version
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Kulibyka/effective-mobile/internal/buildinfo"
+	"github.com/Kulibyka/effective-mobile/internal/http/response"
+)
+
+const versionPath = "/version"
+
+type Handler struct {
+	logger *slog.Logger
+}
+
+func New(logger *slog.Logger) *Handler {
+	return &Handler{logger: logger.WithGroup("version_http")}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(versionPath, h.handleVersion)
+}
+
+func (h *Handler) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	info := buildinfo.Get()
+	response.WriteJSON(w, http.StatusOK, versionResponse{
+		Version:   info.Version,
+		Commit:    info.Commit,
+		BuildDate: info.BuildDate,
+		GoVersion: info.GoVersion,
+	})
+}
+
+type versionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
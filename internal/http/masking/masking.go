@@ -0,0 +1,93 @@
+package masking
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+const (
+	FieldPrice       = "price"
+	FieldUserID      = "user_id"
+	FieldServiceName = "service_name"
+	FieldStartDate   = "start_date"
+	FieldEndDate     = "end_date"
+	FieldRaw         = "raw"
+)
+
+// allFields is hidden from callers whose role has no entry in the policy.
+var allFields = Fields{
+	FieldPrice:       {},
+	FieldUserID:      {},
+	FieldServiceName: {},
+	FieldStartDate:   {},
+	FieldEndDate:     {},
+	FieldRaw:         {},
+}
+
+type ctxKey struct{}
+
+type Fields map[string]struct{}
+
+func (f Fields) Hidden(field string) bool {
+	_, ok := f[field]
+	return ok
+}
+
+type Policy struct {
+	defaultRole string
+	roles       map[string]Fields
+}
+
+func NewPolicy(cfg config.MaskingConfig) *Policy {
+	roles := make(map[string]Fields, len(cfg.Roles))
+	for role, fields := range cfg.Roles {
+		set := make(Fields, len(fields))
+		for _, field := range fields {
+			set[field] = struct{}{}
+		}
+		roles[role] = set
+	}
+
+	return &Policy{defaultRole: cfg.DefaultRole, roles: roles}
+}
+
+// Middleware takes the caller role from its API key scope, or the default
+// role for keys without one, and stores the fields hidden for it in the
+// request context for the DTO mappers. A role missing from the policy has
+// every field hidden; no role at all hides nothing.
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := p.defaultRole
+		if scope, ok := access.FromContext(r.Context()); ok && scope.Role != "" {
+			role = scope.Role
+		}
+
+		fields := p.hidden(role)
+		if len(fields) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, fields))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *Policy) hidden(role string) Fields {
+	if role == "" {
+		return nil
+	}
+
+	fields, ok := p.roles[role]
+	if !ok {
+		return allFields
+	}
+
+	return fields
+}
+
+func FromContext(ctx context.Context) Fields {
+	fields, _ := ctx.Value(ctxKey{}).(Fields)
+	return fields
+}
@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
+)
+
+// idleAfter is how long a client's bucket is kept without requests; by then
+// it has refilled for any sane limit and is the same as a new one.
+const idleAfter = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// Limiter keeps a token bucket per client IP. The rate and burst are read
+// from the runtime config in the request context, so a reload applies to the
+// next request.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	logger    *slog.Logger
+
+	// proxies are the trusted proxies of one runtime config snapshot,
+	// parsed again once per reload.
+	proxies atomic.Pointer[snapshotProxies]
+}
+
+type snapshotProxies struct {
+	snapshot *config.RuntimeConfig
+	prefixes []netip.Prefix
+}
+
+func New(logger *slog.Logger) *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket), logger: logger.WithGroup("ratelimit_http")}
+}
+
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := runtimeconfig.FromContext(r.Context())
+		cfg := snapshot.RateLimit
+		if !cfg.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := clientIP(r, l.trustedProxies(snapshot))
+		if wait, ok := l.allow(client, cfg.RequestsPerSecond, float64(cfg.Burst), time.Now()); !ok {
+			l.logger.Warn("rate limit exceeded", slog.String("client", client), slog.String("path", r.URL.Path))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token from the bucket of client, or returns how long until
+// one is available.
+func (l *Limiter) allow(client string, rate, burst float64, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > idleAfter {
+		for key, b := range l.buckets {
+			if now.Sub(b.seen) > idleAfter {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: burst, seen: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = min(burst, b.tokens+now.Sub(b.seen).Seconds()*rate)
+	b.seen = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rate * float64(time.Second)), false
+	}
+
+	b.tokens--
+	return 0, true
+}
+
+// trustedProxies returns the parsed trusted proxies of snapshot.
+func (l *Limiter) trustedProxies(snapshot *config.RuntimeConfig) []netip.Prefix {
+	if cached := l.proxies.Load(); cached != nil && cached.snapshot == snapshot {
+		return cached.prefixes
+	}
+
+	// Validation rejects entries that do not parse.
+	prefixes, _ := snapshot.RateLimit.TrustedProxyPrefixes()
+	l.proxies.Store(&snapshotProxies{snapshot: snapshot, prefixes: prefixes})
+
+	return prefixes
+}
+
+// clientIP is the address the request came from or, when that is a trusted
+// proxy, the rightmost X-Forwarded-For address that is not one. Clients can
+// put anything in the header, so only the entries added by trusted proxies
+// count.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrusted(host, trusted) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr != "" && !isTrusted(addr, trusted) {
+			return addr
+		}
+	}
+	return host
+}
+
+func isTrusted(addr string, trusted []netip.Prefix) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+
+	ip = ip.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.1/32")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		trusted    []netip.Prefix
+		want       string
+	}{
+		{
+			name:       "no trusted proxies",
+			remoteAddr: "10.0.0.5:4000",
+			forwarded:  []string{"203.0.113.7"},
+			want:       "10.0.0.5",
+		},
+		{
+			name:       "untrusted peer",
+			remoteAddr: "198.51.100.3:4000",
+			forwarded:  []string{"203.0.113.7"},
+			trusted:    trusted,
+			want:       "198.51.100.3",
+		},
+		{
+			name:       "trusted peer",
+			remoteAddr: "10.0.0.5:4000",
+			forwarded:  []string{"203.0.113.7"},
+			trusted:    trusted,
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "spoofed entries left of the client",
+			remoteAddr: "10.0.0.5:4000",
+			forwarded:  []string{"1.1.1.1, 203.0.113.7", "192.0.2.1"},
+			trusted:    trusted,
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "only proxies",
+			remoteAddr: "10.0.0.5:4000",
+			forwarded:  []string{"10.1.1.1"},
+			trusted:    trusted,
+			want:       "10.0.0.5",
+		},
+		{
+			name:       "ipv4-mapped peer",
+			remoteAddr: "[::ffff:10.0.0.5]:4000",
+			forwarded:  []string{"203.0.113.7"},
+			trusted:    trusted,
+			want:       "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := clientIP(r, tt.trusted); got != tt.want {
+				t.Fatalf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package requestlog
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/traceparent"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+	"github.com/Kulibyka/effective-mobile/internal/runtimeconfig"
+)
+
+const (
+	header       = "X-Request-ID"
+	maxRequestID = 128
+)
+
+// Request identifies the request being served, for code that reports it
+// elsewhere than the log.
+type Request struct {
+	ID     string
+	Route  string
+	UserID string
+}
+
+type ctxKey struct{}
+
+// FromContext returns the request stored by the middleware.
+func FromContext(ctx context.Context) (Request, bool) {
+	req, ok := ctx.Value(ctxKey{}).(Request)
+	return req, ok
+}
+
+type Logger struct {
+	mux    *http.ServeMux
+	quiet  []string
+	logger *slog.Logger
+}
+
+// New returns middleware that resolves routes against mux, so log lines
+// carry the registered pattern rather than the raw path. Successful
+// requests to the quiet patterns, such as metrics scrapes, are logged at
+// debug level only.
+func New(mux *http.ServeMux, logger *slog.Logger, quiet ...string) *Logger {
+	return &Logger{mux: mux, quiet: quiet, logger: logger}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Middleware stores a logger with request_id, route and, when the request
+// names one, user_id in the request context. The request ID is taken from
+// the X-Request-ID header or generated, and echoed in the response. Once
+// the request is served, it writes one access log line: at error level for
+// 5xx responses, warn for 4xx and info otherwise.
+//
+// Requests left out by the log sampling of the runtime config, which must
+// be in the request context, get a logger that drops debug and info lines,
+// and no access line unless answered with 4xx or 5xx.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get(header)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set(header, requestID)
+
+		_, pattern := l.mux.Handler(r)
+		if pattern == "" {
+			pattern = r.URL.Path
+		}
+
+		req := Request{ID: requestID, Route: r.Method + " " + pattern}
+		attrs := []any{
+			slog.String("request_id", req.ID),
+			slog.String("route", req.Route),
+		}
+		if userID, err := uuid.Parse(r.URL.Query().Get("user_id")); err == nil {
+			req.UserID = userID.String()
+			attrs = append(attrs, slog.String("user_id", req.UserID))
+		}
+
+		rate := runtimeconfig.FromContext(r.Context()).LogSampling.SampleRate(req.Route)
+		sampled := rate >= 1 || rand.Float64() < rate
+
+		log := l.logger.With(attrs...)
+		ctx := context.WithValue(r.Context(), ctxKey{}, req)
+		if sampled {
+			ctx = logger.NewContext(ctx, log)
+		} else {
+			ctx = logger.NewContext(ctx, slog.New(warnHandler{log.Handler()}))
+		}
+		if sc, ok := traceparent.Parse(r.Header); ok {
+			ctx = traceparent.NewContext(ctx, sc)
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case !sampled:
+			return
+		case slices.Contains(l.quiet, pattern):
+			level = slog.LevelDebug
+		}
+
+		fields := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("user_agent", r.UserAgent()),
+		}
+		// Lets log queries scale sampled counts back up.
+		if rate < 1 && status < http.StatusBadRequest {
+			fields = append(fields, slog.Float64("sample_rate", rate))
+		}
+
+		log.LogAttrs(ctx, level, "request served", fields...)
+	})
+}
+
+// warnHandler drops the debug and info records of requests left out by
+// sampling.
+type warnHandler struct {
+	slog.Handler
+}
+
+func (h warnHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn && h.Handler.Enabled(ctx, level)
+}
+
+func (h warnHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h warnHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return warnHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h warnHandler) WithGroup(name string) slog.Handler {
+	return warnHandler{h.Handler.WithGroup(name)}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
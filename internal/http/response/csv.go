@@ -0,0 +1,43 @@
+package response
+
+import (
+	"bytes"
+	"encoding/csv"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const ContentTypeCSV = "text/csv"
+
+// WriteCSV writes a header row followed by rows, buffered like WriteJSON so
+// the status is only sent once the whole body is ready.
+func WriteCSV(w http.ResponseWriter, status int, header []string, rows [][]string) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	cw := csv.NewWriter(buf)
+	_ = cw.Write(header)
+	_ = cw.WriteAll(rows)
+	if err := cw.Error(); err != nil {
+		slog.Default().Error("failed to encode csv response", slog.Any("error", err))
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+
+	n, err := w.Write(buf.Bytes())
+	if err != nil {
+		slog.Default().Warn("failed to write response", slog.Any("error", err))
+	}
+
+	responseSize.Observe(float64(n))
+}
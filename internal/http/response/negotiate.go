@@ -0,0 +1,51 @@
+package response
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const ContentTypeJSON = "application/json"
+
+// Negotiate picks the offer the Accept header prefers, honouring q-values and
+// wildcards. The first offer is the default when nothing matches.
+func Negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+
+	best, bestQ := offers[0], 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		for _, offer := range offers {
+			if q > bestQ && matchesMediaType(mediaType, offer) {
+				best, bestQ = offer, q
+			}
+		}
+	}
+
+	return best
+}
+
+func matchesMediaType(pattern, offer string) bool {
+	if pattern == "*/*" || pattern == offer {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(pattern, "/*")
+	return ok && strings.HasPrefix(offer, prefix+"/")
+}
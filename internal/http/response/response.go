@@ -0,0 +1,66 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/Kulibyka/effective-mobile/internal/metrics"
+)
+
+// Buffers that grew past this size are dropped instead of being pooled so a
+// single large response does not pin memory.
+const maxPooledBufferSize = 64 << 10
+
+var (
+	bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+	responseSize = metrics.NewHistogram(metrics.ExponentialBuckets(64, 4, 8)...)
+)
+
+type errorEnvelope struct {
+	Error string `json:"error"`
+}
+
+// WriteJSON encodes body before anything is sent, so an encoding failure is
+// reported as a 500 instead of a truncated success response.
+func WriteJSON(w http.ResponseWriter, status int, body any) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if err := json.NewEncoder(buf).Encode(body); err != nil {
+		slog.Default().Error("failed to encode response", slog.Any("error", err))
+
+		buf.Reset()
+		_ = json.NewEncoder(buf).Encode(errorEnvelope{Error: "failed to encode response"})
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+
+	n, err := w.Write(buf.Bytes())
+	if err != nil {
+		slog.Default().Warn("failed to write response", slog.Any("error", err))
+	}
+
+	responseSize.Observe(float64(n))
+}
+
+func RegisterMetrics(registry *metrics.Registry) {
+	registry.Register(metrics.Family{
+		Name:    "http_response_size_bytes",
+		Help:    "Size of JSON response bodies written by the API.",
+		Type:    metrics.TypeHistogram,
+		Collect: responseSize.Samples,
+	})
+}
@@ -0,0 +1,467 @@
+package slo
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/lib/traceparent"
+	"github.com/Kulibyka/effective-mobile/internal/metrics"
+)
+
+var latencyBounds = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+type counts struct {
+	total  uint64
+	errors uint64
+	slow   uint64
+	hist   []uint64
+}
+
+func newCounts() counts {
+	return counts{hist: make([]uint64, len(latencyBounds)+1)}
+}
+
+func (c *counts) add(other counts) {
+	c.total += other.total
+	c.errors += other.errors
+	c.slow += other.slow
+	for i := range c.hist {
+		c.hist[i] += other.hist[i]
+	}
+}
+
+type bucket struct {
+	start  time.Time
+	all    counts
+	routes []counts
+}
+
+type route struct {
+	name     string
+	method   string
+	segments []string
+	target   time.Duration
+}
+
+func (r route) matches(req *http.Request) bool {
+	if r.method != "" && r.method != req.Method {
+		return false
+	}
+
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) != len(r.segments) {
+		return false
+	}
+
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Tracker keeps rolling request statistics in fixed time buckets and
+// evaluates them against the configured availability and latency objectives.
+type Tracker struct {
+	availability     float64
+	latencyObjective float64
+	window           time.Duration
+	step             time.Duration
+	routes           []route
+
+	// durations holds one latency histogram per route plus a last one for
+	// requests matching no configured route.
+	durations []*metrics.Histogram
+
+	mu      sync.Mutex
+	buckets []bucket
+	now     func() time.Time
+}
+
+func NewTracker(cfg config.SLOConfig) *Tracker {
+	buckets := max(cfg.Buckets, 1)
+
+	t := &Tracker{
+		availability:     cfg.Availability,
+		latencyObjective: cfg.LatencyObjective,
+		window:           cfg.Window,
+		step:             max(cfg.Window/time.Duration(buckets), time.Second),
+		buckets:          make([]bucket, buckets),
+		now:              time.Now,
+	}
+
+	for _, r := range cfg.Routes {
+		method, path, found := strings.Cut(r.Route, " ")
+		if !found {
+			method, path = "", r.Route
+		}
+
+		t.routes = append(t.routes, route{
+			name:     r.Route,
+			method:   method,
+			segments: strings.Split(strings.Trim(path, "/"), "/"),
+			target:   r.LatencyP95,
+		})
+	}
+
+	bounds := make([]float64, len(latencyBounds))
+	for i, bound := range latencyBounds {
+		bounds[i] = bound.Seconds()
+	}
+
+	t.durations = make([]*metrics.Histogram, len(t.routes)+1)
+	for i := range t.durations {
+		t.durations[i] = metrics.NewHistogram(bounds...)
+	}
+
+	return t
+}
+
+// Middleware records every request except those to the skip paths.
+func (t *Tracker) Middleware(next http.Handler, skip ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(skip, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(rec, r)
+
+		t.Record(r, rec.status, time.Since(start))
+	})
+}
+
+func (t *Tracker) Record(r *http.Request, status int, elapsed time.Duration) {
+	routeIdx := -1
+	for i, rt := range t.routes {
+		if rt.matches(r) {
+			routeIdx = i
+			break
+		}
+	}
+
+	var exemplar metrics.Labels
+	if traceID, ok := traceparent.TraceID(r.Header); ok {
+		exemplar = metrics.Labels{"trace_id": traceID}
+	}
+
+	if routeIdx >= 0 {
+		t.durations[routeIdx].ObserveWithExemplar(elapsed.Seconds(), exemplar)
+	} else {
+		t.durations[len(t.routes)].ObserveWithExemplar(elapsed.Seconds(), exemplar)
+	}
+
+	failed := status >= http.StatusInternalServerError
+	histIdx := len(latencyBounds)
+	for i, bound := range latencyBounds {
+		if elapsed <= bound {
+			histIdx = i
+			break
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.current()
+	record(&b.all, failed, false, histIdx)
+
+	if routeIdx >= 0 {
+		record(&b.routes[routeIdx], failed, elapsed > t.routes[routeIdx].target, histIdx)
+	}
+}
+
+func record(c *counts, failed, slow bool, histIdx int) {
+	c.total++
+	c.hist[histIdx]++
+	if failed {
+		c.errors++
+	}
+	if slow {
+		c.slow++
+	}
+}
+
+// current returns the bucket for the present step, resetting it when it
+// still holds data from a previous window. Callers must hold t.mu.
+func (t *Tracker) current() *bucket {
+	start := t.now().Truncate(t.step)
+	b := &t.buckets[(start.UnixNano()/int64(t.step))%int64(len(t.buckets))]
+
+	if !b.start.Equal(start) {
+		b.start = start
+		b.all = newCounts()
+		b.routes = make([]counts, len(t.routes))
+		for i := range b.routes {
+			b.routes[i] = newCounts()
+		}
+	}
+
+	return b
+}
+
+type Objective struct {
+	Target               float64 `json:"target"`
+	Compliance           float64 `json:"compliance"`
+	Total                uint64  `json:"total"`
+	Bad                  uint64  `json:"bad"`
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+	Met                  bool    `json:"met"`
+}
+
+type RouteReport struct {
+	Route         string    `json:"route"`
+	LatencyTarget string    `json:"latency_target"`
+	P95           string    `json:"p95"`
+	Availability  Objective `json:"availability"`
+	Latency       Objective `json:"latency"`
+
+	p95 time.Duration
+}
+
+type Report struct {
+	Window       string        `json:"window"`
+	Availability Objective     `json:"availability"`
+	Routes       []RouteReport `json:"routes"`
+	Met          bool          `json:"met"`
+}
+
+func (t *Tracker) Report() Report {
+	all, routes := t.snapshot()
+
+	report := Report{
+		Window:       t.window.String(),
+		Availability: objective(t.availability, all.total, all.errors),
+		Routes:       make([]RouteReport, 0, len(t.routes)),
+	}
+	report.Met = report.Availability.Met
+
+	for i, rt := range t.routes {
+		p95 := percentile(routes[i].hist, 0.95)
+
+		rr := RouteReport{
+			Route:         rt.name,
+			LatencyTarget: rt.target.String(),
+			P95:           p95.String(),
+			Availability:  objective(t.availability, routes[i].total, routes[i].errors),
+			Latency:       objective(t.latencyObjective, routes[i].total, routes[i].slow),
+			p95:           p95,
+		}
+
+		report.Met = report.Met && rr.Availability.Met && rr.Latency.Met
+		report.Routes = append(report.Routes, rr)
+	}
+
+	return report
+}
+
+func (t *Tracker) snapshot() (counts, []counts) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	all := newCounts()
+	routes := make([]counts, len(t.routes))
+	for i := range routes {
+		routes[i] = newCounts()
+	}
+
+	from := t.now().Add(-t.window)
+	for _, b := range t.buckets {
+		if b.start.IsZero() || !b.start.After(from) {
+			continue
+		}
+
+		all.add(b.all)
+		for i := range routes {
+			routes[i].add(b.routes[i])
+		}
+	}
+
+	return all, routes
+}
+
+func objective(target float64, total, bad uint64) Objective {
+	o := Objective{Target: target, Compliance: 1, Total: total, Bad: bad, ErrorBudgetRemaining: 1, Met: true}
+	if total == 0 {
+		return o
+	}
+
+	o.Compliance = 1 - float64(bad)/float64(total)
+	o.Met = o.Compliance >= target
+
+	if allowed := (1 - target) * float64(total); allowed > 0 {
+		o.ErrorBudgetRemaining = 1 - float64(bad)/allowed
+	} else if bad > 0 {
+		o.ErrorBudgetRemaining = 0
+	}
+
+	return o
+}
+
+// percentile estimates the q-quantile from the latency histogram by linear
+// interpolation inside the bucket containing it.
+func percentile(hist []uint64, q float64) time.Duration {
+	var total uint64
+	for _, n := range hist {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	var cumulative float64
+	for i, n := range hist {
+		if n == 0 {
+			continue
+		}
+		if cumulative+float64(n) < rank {
+			cumulative += float64(n)
+			continue
+		}
+
+		if i == len(latencyBounds) {
+			return latencyBounds[len(latencyBounds)-1]
+		}
+
+		var lower time.Duration
+		if i > 0 {
+			lower = latencyBounds[i-1]
+		}
+
+		fraction := (rank - cumulative) / float64(n)
+		return lower + time.Duration(fraction*float64(latencyBounds[i]-lower))
+	}
+
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+func (t *Tracker) RegisterMetrics(registry *metrics.Registry) {
+	registry.Register(metrics.Family{
+		Name: "http_request_duration_seconds",
+		Help: "Latency of HTTP requests per configured SLO route.",
+		Type: metrics.TypeHistogram,
+		Collect: func() []metrics.Sample {
+			var samples []metrics.Sample
+			for i, histogram := range t.durations {
+				route := ""
+				if i < len(t.routes) {
+					route = t.routes[i].name
+				}
+
+				for _, sample := range histogram.Samples() {
+					if sample.Labels == nil {
+						sample.Labels = metrics.Labels{}
+					}
+					sample.Labels["route"] = route
+					samples = append(samples, sample)
+				}
+			}
+			return samples
+		},
+	})
+
+	registry.Register(metrics.Family{
+		Name: "slo_compliance_ratio",
+		Help: "Share of good requests in the rolling SLO window.",
+		Type: metrics.TypeGauge,
+		Collect: func() []metrics.Sample {
+			return t.samples(func(o Objective) float64 { return o.Compliance })
+		},
+	})
+
+	registry.Register(metrics.Family{
+		Name: "slo_error_budget_remaining_ratio",
+		Help: "Remaining share of the error budget in the rolling SLO window.",
+		Type: metrics.TypeGauge,
+		Collect: func() []metrics.Sample {
+			return t.samples(func(o Objective) float64 { return o.ErrorBudgetRemaining })
+		},
+	})
+
+	registry.Register(metrics.Family{
+		Name: "slo_latency_p95_seconds",
+		Help: "Estimated P95 request latency per route in the rolling SLO window.",
+		Type: metrics.TypeGauge,
+		Collect: func() []metrics.Sample {
+			report := t.Report()
+
+			samples := make([]metrics.Sample, 0, len(report.Routes))
+			for _, rr := range report.Routes {
+				samples = append(samples, metrics.Sample{
+					Labels: metrics.Labels{"route": rr.Route},
+					Value:  rr.p95.Seconds(),
+				})
+			}
+			return samples
+		},
+	})
+}
+
+func (t *Tracker) samples(value func(Objective) float64) []metrics.Sample {
+	report := t.Report()
+
+	samples := []metrics.Sample{{
+		Labels: metrics.Labels{"slo": "availability"},
+		Value:  value(report.Availability),
+	}}
+
+	for _, rr := range report.Routes {
+		samples = append(samples,
+			metrics.Sample{Labels: metrics.Labels{"slo": "availability", "route": rr.Route}, Value: value(rr.Availability)},
+			metrics.Sample{Labels: metrics.Labels{"slo": "latency", "route": rr.Route}, Value: value(rr.Latency)},
+		)
+	}
+
+	return samples
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,51 @@
+package budgetalerts
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Alerter interface {
+	Alert(ctx context.Context, now time.Time) (int, error)
+}
+
+// Job warns users whose spend on a category went over their budget for it.
+type Job struct {
+	alerter  Alerter
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func New(alerter Alerter, interval time.Duration, logger *slog.Logger) *Job {
+	return &Job{alerter: alerter, interval: interval, logger: logger.WithGroup("budget_alerts_job")}
+}
+
+func (j *Job) Run(ctx context.Context) {
+	j.logger.Info("starting budget alerts job", slog.Duration("interval", j.interval))
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	j.runOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			j.logger.Info("stopping budget alerts job")
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *Job) runOnce(ctx context.Context) {
+	sent, err := j.alerter.Alert(ctx, time.Now())
+	if err != nil {
+		j.logger.Error("budget alerts run failed", slog.Any("error", err))
+		return
+	}
+
+	j.logger.Debug("budget alerts run finished", slog.Int("sent", sent))
+}
@@ -0,0 +1,125 @@
+package dbhealth
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/metrics"
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+)
+
+type Database interface {
+	Stats() postgresql.PoolStats
+	Ping(ctx context.Context) error
+}
+
+// Monitor periodically pings the database and checks pool saturation, so a
+// connection leak shows up in the logs and metrics before requests start
+// timing out on acquire.
+type Monitor struct {
+	db      Database
+	cfg     config.DBHealthJobConfig
+	latency *metrics.Histogram
+	logger  *slog.Logger
+}
+
+func New(db Database, cfg config.DBHealthJobConfig, logger *slog.Logger) *Monitor {
+	return &Monitor{
+		db:      db,
+		cfg:     cfg,
+		latency: metrics.NewHistogram(metrics.ExponentialBuckets(0.001, 2, 12)...),
+		logger:  logger.WithGroup("db_health"),
+	}
+}
+
+// RegisterMetrics exports the pool counters, read at scrape time, and the
+// ping latency observed by Run.
+func (m *Monitor) RegisterMetrics(registry *metrics.Registry) {
+	registry.Register(metrics.Family{
+		Name: "db_pool_connections",
+		Help: "Connections of the primary pool by state.",
+		Type: metrics.TypeGauge,
+		Collect: func() []metrics.Sample {
+			stats := m.db.Stats()
+			return []metrics.Sample{
+				{Labels: metrics.Labels{"state": "max"}, Value: float64(stats.MaxConns)},
+				{Labels: metrics.Labels{"state": "total"}, Value: float64(stats.TotalConns)},
+				{Labels: metrics.Labels{"state": "idle"}, Value: float64(stats.IdleConns)},
+				{Labels: metrics.Labels{"state": "acquired"}, Value: float64(stats.AcquiredConns)},
+			}
+		},
+	})
+
+	registry.Register(metrics.Family{
+		Name: "db_pool_empty_acquires_total",
+		Help: "Acquires that found no idle connection in the primary pool.",
+		Type: metrics.TypeCounter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(m.db.Stats().EmptyAcquireCount)}}
+		},
+	})
+
+	registry.Register(metrics.Family{
+		Name: "db_pool_acquire_seconds_total",
+		Help: "Total time spent acquiring connections from the primary pool.",
+		Type: metrics.TypeCounter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: m.db.Stats().AcquireDuration.Seconds()}}
+		},
+	})
+
+	registry.Register(metrics.Family{
+		Name:    "db_ping_duration_seconds",
+		Help:    "Latency of the periodic database ping.",
+		Type:    metrics.TypeHistogram,
+		Collect: m.latency.Samples,
+	})
+}
+
+func (m *Monitor) Run(ctx context.Context) {
+	m.logger.Info("starting database health monitor", slog.Duration("interval", m.cfg.Interval))
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.logger.Info("stopping database health monitor")
+			return
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, m.cfg.PingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := m.db.Ping(pingCtx)
+	took := time.Since(start)
+	m.latency.Observe(took.Seconds())
+
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.Error("database ping failed", slog.Duration("took", took), slog.Any("error", err))
+		}
+		return
+	}
+
+	stats := m.db.Stats()
+	if stats.MaxConns > 0 && float64(stats.AcquiredConns)/float64(stats.MaxConns) >= m.cfg.SaturationThreshold {
+		m.logger.Warn("database pool is saturated",
+			slog.Int("acquired", int(stats.AcquiredConns)),
+			slog.Int("max", int(stats.MaxConns)),
+			slog.Int64("empty_acquires", stats.EmptyAcquireCount),
+			slog.Duration("ping", took))
+		return
+	}
+
+	m.logger.Debug("database is healthy", slog.Int("acquired", int(stats.AcquiredConns)), slog.Int("idle", int(stats.IdleConns)), slog.Duration("ping", took))
+}
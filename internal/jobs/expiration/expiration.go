@@ -0,0 +1,52 @@
+package expiration
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+type Expirer interface {
+	ExpireOverdue(ctx context.Context, now time.Time) ([]domain.Subscription, error)
+}
+
+type Job struct {
+	expirer  Expirer
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func New(expirer Expirer, interval time.Duration, logger *slog.Logger) *Job {
+	return &Job{expirer: expirer, interval: interval, logger: logger.WithGroup("expiration_job")}
+}
+
+func (j *Job) Run(ctx context.Context) {
+	j.logger.Info("starting expiration job", slog.Duration("interval", j.interval))
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	j.runOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			j.logger.Info("stopping expiration job")
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *Job) runOnce(ctx context.Context) {
+	expired, err := j.expirer.ExpireOverdue(ctx, time.Now().UTC())
+	if err != nil {
+		j.logger.Error("expiration run failed", slog.Any("error", err))
+		return
+	}
+
+	j.logger.Debug("expiration run finished", slog.Int("expired", len(expired)))
+}
@@ -0,0 +1,65 @@
+package prices
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Notifier interface {
+	NotifyUpcoming(ctx context.Context, now time.Time) (int, error)
+}
+
+type Applier interface {
+	ApplyDuePriceChanges(ctx context.Context, now time.Time) (int, error)
+}
+
+// Job announces upcoming price increases and applies scheduled price changes
+// once their month starts. Notices go out first so owners hear about a change
+// before it shows up on their subscription.
+type Job struct {
+	notifier Notifier
+	applier  Applier
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func New(notifier Notifier, applier Applier, interval time.Duration, logger *slog.Logger) *Job {
+	return &Job{notifier: notifier, applier: applier, interval: interval, logger: logger.WithGroup("prices_job")}
+}
+
+func (j *Job) Run(ctx context.Context) {
+	j.logger.Info("starting prices job", slog.Duration("interval", j.interval))
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	j.runOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			j.logger.Info("stopping prices job")
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *Job) runOnce(ctx context.Context) {
+	now := time.Now().UTC()
+
+	notified, err := j.notifier.NotifyUpcoming(ctx, now)
+	if err != nil {
+		j.logger.Error("price notices run failed", slog.Any("error", err))
+	}
+
+	applied, err := j.applier.ApplyDuePriceChanges(ctx, now)
+	if err != nil {
+		j.logger.Error("price changes run failed", slog.Any("error", err))
+		return
+	}
+
+	j.logger.Debug("prices run finished", slog.Int("notified", notified), slog.Int("updated", applied))
+}
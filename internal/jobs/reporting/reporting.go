@@ -0,0 +1,50 @@
+package reporting
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Runner interface {
+	RunDue(ctx context.Context, now time.Time) (int, error)
+}
+
+type Job struct {
+	runner   Runner
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func New(runner Runner, interval time.Duration, logger *slog.Logger) *Job {
+	return &Job{runner: runner, interval: interval, logger: logger.WithGroup("reporting_job")}
+}
+
+func (j *Job) Run(ctx context.Context) {
+	j.logger.Info("starting reporting job", slog.Duration("interval", j.interval))
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	j.runOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			j.logger.Info("stopping reporting job")
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *Job) runOnce(ctx context.Context) {
+	ran, err := j.runner.RunDue(ctx, time.Now().UTC())
+	if err != nil {
+		j.logger.Error("reporting run failed", slog.Any("error", err))
+		return
+	}
+
+	j.logger.Debug("reporting run finished", slog.Int("reports", ran))
+}
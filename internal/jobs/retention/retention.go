@@ -0,0 +1,108 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/metrics"
+)
+
+// Purger removes ended subscriptions through the service, so every removal
+// is published, audited and dropped from the cache like a regular delete.
+type Purger interface {
+	PurgeEnded(ctx context.Context, before domain.MonthKey, limit int, archive bool) ([]domain.Subscription, error)
+}
+
+// Job periodically removes subscriptions that ended longer ago than the
+// retention period. Rows go in batches with a pause between them so a large
+// backlog does not hold locks or saturate the database.
+type Job struct {
+	purger Purger
+	cfg    config.RetentionJobConfig
+	logger *slog.Logger
+
+	purged atomic.Int64
+	runs   atomic.Int64
+}
+
+func New(purger Purger, cfg config.RetentionJobConfig, logger *slog.Logger) *Job {
+	return &Job{purger: purger, cfg: cfg, logger: logger.WithGroup("retention_job")}
+}
+
+func (j *Job) RegisterMetrics(registry *metrics.Registry) {
+	registry.Register(metrics.Family{
+		Name: "retention_purged_subscriptions_total",
+		Help: "Ended subscriptions removed by the retention job.",
+		Type: metrics.TypeCounter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(j.purged.Load())}}
+		},
+	})
+
+	registry.Register(metrics.Family{
+		Name: "retention_runs_total",
+		Help: "Completed retention job runs.",
+		Type: metrics.TypeCounter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(j.runs.Load())}}
+		},
+	})
+}
+
+func (j *Job) Run(ctx context.Context) {
+	j.logger.Info("starting retention job", slog.Duration("interval", j.cfg.Interval), slog.Int("keep_months", j.cfg.KeepMonths), slog.Bool("archive", j.cfg.Archive))
+
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	j.runOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			j.logger.Info("stopping retention job")
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *Job) runOnce(ctx context.Context) {
+	before := domain.MonthKeyOf(time.Now().UTC().AddDate(0, -j.cfg.KeepMonths, 0))
+	start := time.Now()
+	total := 0
+
+	if j.cfg.BatchSize <= 0 {
+		j.logger.Error("retention run skipped: batch size must be positive", slog.Int("batch_size", j.cfg.BatchSize))
+		return
+	}
+
+	for {
+		purged, err := j.purger.PurgeEnded(ctx, before, j.cfg.BatchSize, j.cfg.Archive)
+		if err != nil {
+			j.logger.Error("retention run failed", slog.Int("purged", total), slog.Any("error", err))
+			return
+		}
+
+		total += len(purged)
+		j.purged.Add(int64(len(purged)))
+
+		if len(purged) < j.cfg.BatchSize {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(j.cfg.BatchPause):
+		}
+	}
+
+	j.runs.Add(1)
+	j.logger.Info("retention run finished", slog.Int("before", int(before)), slog.Int("purged", total), slog.Duration("took", time.Since(start)))
+}
@@ -0,0 +1,65 @@
+package rollup
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+type Rebuilder interface {
+	RebuildMonthlySpend(ctx context.Context, horizon domain.MonthKey) error
+}
+
+// Job periodically rebuilds the monthly spend rollup and moves its horizon
+// forward so open-ended subscriptions stay covered.
+type Job struct {
+	rebuilder     Rebuilder
+	interval      time.Duration
+	horizonMonths int
+	logger        *slog.Logger
+}
+
+func New(rebuilder Rebuilder, interval time.Duration, horizonMonths int, logger *slog.Logger) *Job {
+	return &Job{
+		rebuilder:     rebuilder,
+		interval:      interval,
+		horizonMonths: horizonMonths,
+		logger:        logger.WithGroup("rollup_job"),
+	}
+}
+
+func (j *Job) Run(ctx context.Context) {
+	j.logger.Info("starting rollup job", slog.Duration("interval", j.interval), slog.Int("horizon_months", j.horizonMonths))
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	j.runOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			j.logger.Info("stopping rollup job")
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *Job) runOnce(ctx context.Context) {
+	horizon := domain.MonthKeyOf(time.Now().UTC())
+	for range j.horizonMonths {
+		horizon = horizon.Next()
+	}
+
+	start := time.Now()
+	if err := j.rebuilder.RebuildMonthlySpend(ctx, horizon); err != nil {
+		j.logger.Error("rollup run failed", slog.Any("error", err))
+		return
+	}
+
+	j.logger.Debug("rollup run finished", slog.Int("horizon", int(horizon)), slog.Duration("took", time.Since(start)))
+}
@@ -0,0 +1,96 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"too few fields", "0 0 * *"},
+		{"too many fields", "0 0 * * * *"},
+		{"minute out of range", "60 * * * *"},
+		{"hour out of range", "0 24 * * *"},
+		{"day of month zero", "0 0 0 * *"},
+		{"month out of range", "0 0 1 13 *"},
+		{"day of week out of range", "0 0 * * 8"},
+		{"reversed range", "0 5-1 * * *"},
+		{"zero step", "*/0 * * * *"},
+		{"bad step", "*/x * * * *"},
+		{"bad value", "a * * * *"},
+		{"bad range end", "0 1-x * * *"},
+		{"unknown shorthand", "@often"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.expr); !errors.Is(err, ErrInvalidSchedule) {
+				t.Fatalf("Parse(%q) error = %v, want ErrInvalidSchedule", tt.expr, err)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2025-01-15 is a Wednesday.
+	from := time.Date(2025, time.January, 15, 10, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2025, time.January, 15, 10, 31, 0, 0, time.UTC)},
+		{"hourly", "@hourly", time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{"daily", "@daily", time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{"weekly", "@weekly", time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{"monthly", "@monthly", time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly", "@yearly", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"later today", "45 10 * * *", time.Date(2025, time.January, 15, 10, 45, 0, 0, time.UTC)},
+		{"tomorrow", "15 9 * * *", time.Date(2025, time.January, 16, 9, 15, 0, 0, time.UTC)},
+		{"step", "*/20 * * * *", time.Date(2025, time.January, 15, 10, 40, 0, 0, time.UTC)},
+		{"step from value", "5/20 * * * *", time.Date(2025, time.January, 15, 10, 45, 0, 0, time.UTC)},
+		{"list", "0 8,12,18 * * *", time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)},
+		{"range", "0 9-17 * * 1-5", time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{"weekday skips weekend", "0 9 * * 1-5", time.Date(2025, time.January, 16, 9, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{"day of month", "0 0 31 * *", time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"skips short months", "0 0 31 2-4 *", time.Date(2025, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matching fires.
+		{"day of month or week", "0 0 20 * 5", time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{"never", "0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextKeepsLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+
+	schedule, err := Parse("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := schedule.Next(time.Date(2025, time.January, 15, 10, 0, 0, 0, loc))
+	want := time.Date(2025, time.January, 16, 9, 0, 0, 0, loc)
+	if !got.Equal(want) || got.Location() != loc {
+		t.Errorf("Next = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package traceparent
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const Header = "traceparent"
+
+// SpanContext identifies the trace a request belongs to and the span of
+// the caller that sent it.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// Parse reads a W3C traceparent header ("00-<trace id>-<parent id>-<flags>").
+// It reports false for malformed headers and the all-zero trace id.
+func Parse(h http.Header) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(h.Get(Header)), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+
+	traceID := strings.ToLower(parts[1])
+	if len(traceID) != 32 || !isHex(traceID) || strings.Trim(traceID, "0") == "" {
+		return SpanContext{}, false
+	}
+
+	spanID := strings.ToLower(parts[2])
+	if len(spanID) != 16 || !isHex(spanID) {
+		return SpanContext{}, false
+	}
+
+	return SpanContext{TraceID: traceID, SpanID: spanID}, true
+}
+
+// TraceID extracts the trace id from a W3C traceparent header.
+func TraceID(h http.Header) (string, bool) {
+	sc, ok := Parse(h)
+	return sc.TraceID, ok
+}
+
+type ctxKey struct{}
+
+func NewContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, ctxKey{}, sc)
+}
+
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(ctxKey{}).(SpanContext)
+	return sc, ok
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -1,10 +1,15 @@
 package uuid
 
 import (
+	"crypto/rand"
 	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 )
 
@@ -12,6 +17,9 @@ var ErrInvalidUUID = errors.New("invalid uuid format")
 
 type UUID string
 
+// Nil is the all-zero UUID. Like the empty zero value, it stands for no ID.
+const Nil UUID = "00000000-0000-0000-0000-000000000000"
+
 func Parse(s string) (UUID, error) {
 	if len(s) != 36 {
 		return "", ErrInvalidUUID
@@ -34,12 +42,131 @@ func Parse(s string) (UUID, error) {
 	return UUID(lower), nil
 }
 
+// New returns a random (version 4) UUID.
+func New() UUID {
+	return NewV4()
+}
+
+// NewV4 returns a random (version 4) UUID read from crypto/rand, which
+// never fails since Go 1.24.
+func NewV4() UUID {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return format(b)
+}
+
+// v7 is the state that keeps the UUIDs of one process strictly increasing.
+var v7 struct {
+	mu      sync.Mutex
+	ms      int64
+	counter uint16
+}
+
+// NewV7 returns a time-ordered (version 7) UUID: the first 48 bits hold the
+// Unix time in milliseconds, so IDs generated later sort after earlier ones.
+// Within a millisecond the 12 bits after the version are a counter, started
+// at a random value below 2048 (RFC 9562, method 1); when it runs out, or
+// the clock goes back, the timestamp is advanced past the last one instead.
+func NewV7() UUID {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+
+	ms, counter := nextV7(time.Now().UnixMilli(), binary.BigEndian.Uint16(b[6:8])&0x07ff)
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(ms))
+	copy(b[0:6], ts[2:])
+
+	b[6] = 0x70 | byte(counter>>8)
+	b[7] = byte(counter)
+	b[8] = b[8]&0x3f | 0x80
+
+	return format(b)
+}
+
+func nextV7(now int64, seed uint16) (int64, uint16) {
+	v7.mu.Lock()
+	defer v7.mu.Unlock()
+
+	switch {
+	case now > v7.ms:
+		v7.ms, v7.counter = now, seed
+	case v7.counter < 0x0fff:
+		v7.counter++
+	default:
+		v7.ms, v7.counter = v7.ms+1, seed
+	}
+
+	return v7.ms, v7.counter
+}
+
+// FromBytes returns the UUID held in its 16-byte binary form, as some
+// drivers return uuid columns.
+func FromBytes(b []byte) (UUID, error) {
+	if len(b) != 16 {
+		return "", fmt.Errorf("%w: %d bytes, want 16", ErrInvalidUUID, len(b))
+	}
+
+	return format([16]byte(b)), nil
+}
+
+// Bytes returns the 16-byte binary form of u.
+func (u UUID) Bytes() ([16]byte, error) {
+	var b [16]byte
+	if _, err := Parse(string(u)); err != nil {
+		return b, err
+	}
+
+	if _, err := hex.Decode(b[:], []byte(strings.ReplaceAll(string(u), "-", ""))); err != nil {
+		return b, fmt.Errorf("%w: %w", ErrInvalidUUID, err)
+	}
+
+	return b, nil
+}
+
+func (u UUID) MarshalBinary() ([]byte, error) {
+	b, err := u.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return b[:], nil
+}
+
+func (u *UUID) UnmarshalBinary(data []byte) error {
+	parsed, err := FromBytes(data)
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+func format(b [16]byte) UUID {
+	return UUID(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]))
+}
+
 func (u UUID) String() string {
 	return string(u)
 }
 
+// IsZero reports whether u is the zero value, i.e. unset.
+func (u UUID) IsZero() bool {
+	return u == ""
+}
+
+// IsNil reports whether u holds no ID: it is unset or Nil.
+func (u UUID) IsNil() bool {
+	return u == "" || u == Nil
+}
+
+// Value stores both forms of no ID as NULL, which Scan reads back as the
+// zero value.
 func (u UUID) Value() (driver.Value, error) {
-	if u == "" {
+	if u.IsNil() {
 		return nil, nil
 	}
 
@@ -61,12 +188,21 @@ func (u *UUID) Scan(src any) error {
 		*u = parsed
 		return nil
 	case []byte:
+		// Binary uuid columns come as 16 raw bytes, text ones as the
+		// 36-character form.
+		if len(v) == 16 {
+			return u.UnmarshalBinary(v)
+		}
+
 		parsed, err := Parse(string(v))
 		if err != nil {
 			return err
 		}
 		*u = parsed
 		return nil
+	case [16]byte:
+		*u = format(v)
+		return nil
 	default:
 		return fmt.Errorf("%w: unexpected type %T", ErrInvalidUUID, src)
 	}
@@ -0,0 +1,223 @@
+package uuid
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// sample is a fixed version 7 UUID and its 16 bytes.
+var (
+	sample      = UUID("01890a5d-ac96-774b-bcce-b302099a8057")
+	sampleBytes = []byte{
+		0x01, 0x89, 0x0a, 0x5d, 0xac, 0x96, 0x77, 0x4b,
+		0xbc, 0xce, 0xb3, 0x02, 0x09, 0x9a, 0x80, 0x57,
+	}
+)
+
+// resetV7 starts the version 7 state over at ms with counter.
+func resetV7(t *testing.T, ms int64, counter uint16) {
+	t.Helper()
+
+	v7.mu.Lock()
+	v7.ms, v7.counter = ms, counter
+	v7.mu.Unlock()
+}
+
+func TestNextV7(t *testing.T) {
+	tests := []struct {
+		name        string
+		ms          int64
+		counter     uint16
+		now         int64
+		seed        uint16
+		wantMs      int64
+		wantCounter uint16
+	}{
+		{"new millisecond starts at the seed", 1000, 42, 1001, 7, 1001, 7},
+		{"same millisecond counts up", 1000, 42, 1000, 7, 1000, 43},
+		{"counter overflow moves to the next millisecond", 1000, 0x0fff, 1000, 7, 1001, 7},
+		{"clock going back counts up", 2000, 42, 1500, 7, 2000, 43},
+		{"clock going back on a full counter moves past the last millisecond", 2000, 0x0fff, 1500, 7, 2001, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetV7(t, tt.ms, tt.counter)
+
+			ms, counter := nextV7(tt.now, tt.seed)
+			if ms != tt.wantMs || counter != tt.wantCounter {
+				t.Fatalf("nextV7(%d, %d) = %d, %d, want %d, %d", tt.now, tt.seed, ms, counter, tt.wantMs, tt.wantCounter)
+			}
+		})
+	}
+}
+
+func TestNewV7StrictlyIncreasing(t *testing.T) {
+	// Thousands of IDs land in the same millisecond and overflow the counter
+	// at least once.
+	prev := NewV7()
+	for range 10000 {
+		next := NewV7()
+		if next <= prev {
+			t.Fatalf("%s generated after %s", next, prev)
+		}
+		prev = next
+	}
+}
+
+func TestNewV7Layout(t *testing.T) {
+	id := NewV7()
+	if _, err := Parse(id.String()); err != nil {
+		t.Fatalf("Parse(%s): %v", id, err)
+	}
+
+	b, err := id.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version := b[6] >> 4; version != 7 {
+		t.Fatalf("version = %d, want 7", version)
+	}
+	if variant := b[8] >> 6; variant != 0b10 {
+		t.Fatalf("variant bits = %02b, want 10", variant)
+	}
+}
+
+func TestBytesRoundTrip(t *testing.T) {
+	id, err := FromBytes(sampleBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != sample {
+		t.Fatalf("FromBytes = %s, want %s", id, sample)
+	}
+
+	b, err := id.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[:], sampleBytes) {
+		t.Fatalf("Bytes = %x, want %x", b, sampleBytes)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	data, err := sample.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, sampleBytes) {
+		t.Fatalf("MarshalBinary = %x, want %x", data, sampleBytes)
+	}
+
+	var id UUID
+	if err := id.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if id != sample {
+		t.Fatalf("UnmarshalBinary = %s, want %s", id, sample)
+	}
+}
+
+func TestBinaryInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"FromBytes", func() error {
+			_, err := FromBytes(sampleBytes[:15])
+			return err
+		}},
+		{"UnmarshalBinary", func() error {
+			var id UUID
+			return id.UnmarshalBinary(append(sampleBytes[:16:16], 0))
+		}},
+		{"Bytes", func() error {
+			_, err := UUID("01890a5d-ac96").Bytes()
+			return err
+		}},
+		{"MarshalBinary", func() error {
+			_, err := UUID("not-a-uuid").MarshalBinary()
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); !errors.Is(err, ErrInvalidUUID) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidUUID)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     any
+		want    UUID
+		wantErr bool
+	}{
+		{"nil", nil, "", false},
+		{"string", sample.String(), sample, false},
+		{"text bytes", []byte(sample.String()), sample, false},
+		{"binary bytes", sampleBytes, sample, false},
+		{"array", [16]byte(sampleBytes), sample, false},
+		{"short bytes", sampleBytes[:15], "", true},
+		{"invalid string", "not-a-uuid", "", true},
+		{"unexpected type", 42, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := New()
+			err := id.Scan(tt.src)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidUUID) {
+					t.Fatalf("err = %v, want %v", err, ErrInvalidUUID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.want {
+				t.Fatalf("Scan(%v) = %s, want %s", tt.src, id, tt.want)
+			}
+		})
+	}
+}
+
+func TestNil(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        UUID
+		wantZero  bool
+		wantNil   bool
+		wantValue any
+	}{
+		{"empty", "", true, true, nil},
+		{"nil uuid", Nil, false, true, nil},
+		{"set", sample, false, false, sample.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.id.IsZero(); got != tt.wantZero {
+				t.Fatalf("IsZero = %v, want %v", got, tt.wantZero)
+			}
+			if got := tt.id.IsNil(); got != tt.wantNil {
+				t.Fatalf("IsNil = %v, want %v", got, tt.wantNil)
+			}
+
+			value, err := tt.id.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != tt.wantValue {
+				t.Fatalf("Value = %v, want %v", value, tt.wantValue)
+			}
+		})
+	}
+}
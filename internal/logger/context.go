@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying the request-scoped logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger from ctx under the given
+// group, or fallback when ctx carries none (background jobs, migrations).
+func FromContext(ctx context.Context, fallback *slog.Logger, group string) *slog.Logger {
+	logger, ok := ctx.Value(ctxKey{}).(*slog.Logger)
+	if !ok {
+		return fallback
+	}
+
+	return logger.WithGroup(group)
+}
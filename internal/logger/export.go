@@ -0,0 +1,399 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/lib/traceparent"
+)
+
+const (
+	exporterOTLP = "otlp"
+	exporterFile = "file"
+)
+
+var (
+	exportersMu sync.Mutex
+	exporters   []*exporter
+)
+
+// Shutdown sends the logs still queued for export, stops the exporters
+// started by New and closes the log files. Call it before the process exits.
+func Shutdown(ctx context.Context) error {
+	exportersMu.Lock()
+	started := exporters
+	exporters = nil
+	exportersMu.Unlock()
+
+	var errs []error
+	for _, e := range started {
+		errs = append(errs, e.shutdown(ctx))
+	}
+
+	filesMu.Lock()
+	opened := files
+	files = nil
+	filesMu.Unlock()
+
+	for _, f := range opened {
+		errs = append(errs, f.close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// otlpRecord is a log record in the OTLP JSON encoding.
+type otlpRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpValue      `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID        string         `json:"traceId,omitempty"`
+	SpanID         string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// exporter batches records and sends them from a goroutine of its own, so
+// logging never waits for the collector.
+type exporter struct {
+	cfg   config.LogExportConfig
+	send  func(ctx context.Context, body []byte) error
+	close func() error
+	queue chan otlpRecord
+	done  chan struct{}
+
+	mu      sync.Mutex
+	stopped bool
+	dropped int
+}
+
+func newExporter(cfg config.LogExportConfig) (*exporter, error) {
+	e := &exporter{
+		cfg:   cfg,
+		queue: make(chan otlpRecord, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+
+	switch cfg.Exporter {
+	case exporterOTLP:
+		client := &http.Client{Timeout: cfg.Timeout}
+		e.send = func(ctx context.Context, body []byte) error {
+			return postOTLP(ctx, client, cfg, body)
+		}
+	case exporterFile:
+		f, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("log export: %w", err)
+		}
+		e.send = func(_ context.Context, body []byte) error {
+			_, err := f.Write(append(body, '\n'))
+			return err
+		}
+		e.close = f.Close
+	default:
+		return nil, nil
+	}
+
+	go e.run()
+
+	exportersMu.Lock()
+	exporters = append(exporters, e)
+	exportersMu.Unlock()
+
+	return e, nil
+}
+
+func (e *exporter) enqueue(r otlpRecord) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return
+	}
+
+	select {
+	case e.queue <- r:
+	default:
+		e.dropped++
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]otlpRecord, 0, e.cfg.BatchSize)
+	for {
+		select {
+		case r, ok := <-e.queue:
+			if !ok {
+				e.flush(batch)
+				if e.close != nil {
+					_ = e.close()
+				}
+				return
+			}
+			batch = append(batch, r)
+			if len(batch) < e.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		e.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush sends batch. Failures go to stderr, since logging them would feed
+// them back into the exporter.
+func (e *exporter) flush(batch []otlpRecord) {
+	e.mu.Lock()
+	dropped := e.dropped
+	e.dropped = 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "log export: queue full, dropped %d log records\n", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(e.request(batch))
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), e.cfg.Timeout)
+		err = e.send(ctx, body)
+		cancel()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log export: failed to send %d log records: %v\n", len(batch), err)
+	}
+}
+
+// request wraps batch into an ExportLogsServiceRequest.
+func (e *exporter) request(batch []otlpRecord) any {
+	serviceName := e.cfg.ServiceName
+
+	return map[string]any{
+		"resourceLogs": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpKeyValue{{Key: "service.name", Value: otlpValue{StringValue: &serviceName}}},
+			},
+			"scopeLogs": []any{map[string]any{
+				"scope":      map[string]any{"name": "github.com/Kulibyka/effective-mobile"},
+				"logRecords": batch,
+			}},
+		}},
+	}
+}
+
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.stopped {
+		e.stopped = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("log export: %w", ctx.Err())
+	}
+}
+
+func postOTLP(ctx context.Context, client *http.Client, cfg config.LogExportConfig, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// exportHandler turns records into OTLP log records. Groups become dotted
+// attribute keys, as OpenTelemetry names attributes.
+type exportHandler struct {
+	exporter *exporter
+	level    slog.Leveler
+	attrs    []otlpKeyValue
+	prefix   string
+}
+
+func (h *exportHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *exportHandler) Handle(ctx context.Context, r slog.Record) error {
+	msg := r.Message
+	record := otlpRecord{
+		TimeUnixNano:   strconv.FormatInt(r.Time.UnixNano(), 10),
+		SeverityNumber: severityNumber(r.Level),
+		SeverityText:   r.Level.String(),
+		Body:           otlpValue{StringValue: &msg},
+		Attributes:     append([]otlpKeyValue(nil), h.attrs...),
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		record.Attributes = appendAttr(record.Attributes, h.prefix, a)
+		return true
+	})
+	if sc, ok := traceparent.FromContext(ctx); ok {
+		record.TraceID, record.SpanID = sc.TraceID, sc.SpanID
+	}
+
+	h.exporter.enqueue(record)
+	return nil
+}
+
+func (h *exportHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]otlpKeyValue(nil), h.attrs...)
+	for _, a := range attrs {
+		next.attrs = appendAttr(next.attrs, h.prefix, a)
+	}
+	return &next
+}
+
+func (h *exportHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+func appendAttr(kvs []otlpKeyValue, prefix string, a slog.Attr) []otlpKeyValue {
+	v := a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+
+	if v.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			kvs = appendAttr(kvs, groupPrefix, ga)
+		}
+		return kvs
+	}
+
+	var value otlpValue
+	switch v.Kind() {
+	case slog.KindBool:
+		b := v.Bool()
+		value.BoolValue = &b
+	case slog.KindInt64:
+		i := strconv.FormatInt(v.Int64(), 10)
+		value.IntValue = &i
+	case slog.KindUint64:
+		i := strconv.FormatUint(v.Uint64(), 10)
+		value.IntValue = &i
+	case slog.KindFloat64:
+		f := v.Float64()
+		value.DoubleValue = &f
+	default:
+		s := v.String()
+		if err, ok := v.Any().(error); ok {
+			s = err.Error()
+		}
+		value.StringValue = &s
+	}
+
+	return append(kvs, otlpKeyValue{Key: prefix + a.Key, Value: value})
+}
+
+// severityNumber maps slog levels onto the OpenTelemetry severity ranges:
+// DEBUG 5, INFO 9, WARN 13, ERROR 17.
+func severityNumber(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return 5
+	case level < slog.LevelWarn:
+		return 9
+	case level < slog.LevelError:
+		return 13
+	default:
+		return 17
+	}
+}
+
+// fanout passes every record to all of its handlers.
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := make(fanout, len(f))
+	for i, h := range f {
+		next[i] = h.WithAttrs(attrs)
+	}
+	return next
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	next := make(fanout, len(f))
+	for i, h := range f {
+		next[i] = h.WithGroup(name)
+	}
+	return next
+}
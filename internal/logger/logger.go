@@ -1,8 +1,13 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
 )
 
 const (
@@ -11,23 +16,107 @@ const (
 	EnvProd  = "prod"
 )
 
-func New(env string) *slog.Logger {
+type Option func(*options)
+
+type options struct {
+	export config.LogExportConfig
+	file   config.LogFileConfig
+}
+
+// WithExport also ships the logs as cfg describes, next to stdout. Call
+// Shutdown before exiting so queued logs are sent.
+func WithExport(cfg config.LogExportConfig) Option {
+	return func(o *options) {
+		o.export = cfg
+	}
+}
+
+// WithFile also writes the logs, in the stdout format, to the rotated file
+// cfg describes. Call Shutdown before exiting so the file is closed.
+func WithFile(cfg config.LogFileConfig) Option {
+	return func(o *options) {
+		o.file = cfg
+	}
+}
+
+func New(env string, opts ...Option) *slog.Logger {
+	level := new(slog.LevelVar)
+	level.Set(DefaultLevel(env))
+
+	return NewWithLevel(env, level, opts...)
+}
+
+// NewWithLevel is New with the level held in level, so it can be changed
+// while the logger is in use.
+func NewWithLevel(env string, level *slog.LevelVar, opts ...Option) *slog.Logger {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	handlers := fanout{newHandler(env, os.Stdout, level)}
+
+	var fileErr error
+	if o.file.Path != "" {
+		var f *rotatingFile
+		if f, fileErr = openRotatingFile(o.file); f != nil {
+			handlers = append(handlers, newHandler(env, f, level))
+		}
+	}
+
+	exp, exportErr := newExporter(o.export)
+	if exp != nil {
+		handlers = append(handlers, &exportHandler{exporter: exp, level: level})
+	}
+
+	var handler slog.Handler = handlers
+	if len(handlers) == 1 {
+		handler = handlers[0]
+	}
+	log := slog.New(newTraceHandler(handler))
+
+	if fileErr != nil {
+		log.Error("log file disabled", slog.Any("error", fileErr))
+	}
+	if exportErr != nil {
+		log.Error("log export disabled", slog.Any("error", exportErr))
+	}
+
+	return log
+}
+
+func newHandler(env string, w io.Writer, level slog.Leveler) slog.Handler {
 	switch env {
 	case EnvLocal:
-		return slog.New(
-			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
-	case EnvDev:
-		return slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
-	case EnvProd:
-		return slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+		return newPrettyHandler(w, level)
+	case EnvDev, EnvProd:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
 	default:
-		return slog.New(
-			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+		return slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})
 	}
 }
+
+// DefaultLevel is the level env logs at unless configured otherwise.
+func DefaultLevel(env string) slog.Level {
+	switch env {
+	case EnvLocal, EnvDev:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// ParseLevel reads a configured level: debug, info, warn or error. Empty
+// means the default of env.
+func ParseLevel(env, level string) (slog.Level, error) {
+	if level == "" {
+		return DefaultLevel(env), nil
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+
+	return l, nil
+}
@@ -0,0 +1,192 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	prettyTimeLayout = "15:04:05.000"
+	// prettyMessageWidth pads short messages so the attrs of consecutive
+	// lines start in the same column.
+	prettyMessageWidth = 40
+
+	colorReset  = "\x1b[0m"
+	colorDim    = "\x1b[2m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+// prettyHandler writes one human-readable line per record for local
+// development: the time of day, a short level, the message and then the
+// attrs as key=value, colored when writing to a terminal.
+type prettyHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Leveler
+	color bool
+
+	attrs  string
+	prefix string
+}
+
+func newPrettyHandler(w io.Writer, level slog.Leveler) *prettyHandler {
+	return &prettyHandler{mu: &sync.Mutex{}, w: w, level: level, color: isTerminal(w)}
+}
+
+// isTerminal reports whether w is a terminal that colors can be sent to,
+// honouring NO_COLOR.
+func isTerminal(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+
+	if !r.Time.IsZero() {
+		b.WriteString(h.paint(colorDim, r.Time.Format(prettyTimeLayout)))
+		b.WriteByte(' ')
+	}
+	b.WriteString(h.levelLabel(r.Level))
+	b.WriteByte(' ')
+	b.WriteString(r.Message)
+
+	var attrs strings.Builder
+	attrs.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(&attrs, h.prefix, a)
+		return true
+	})
+
+	if attrs.Len() > 0 {
+		if pad := prettyMessageWidth - utf8.RuneCountInString(r.Message); pad > 0 {
+			b.WriteString(strings.Repeat(" ", pad))
+		}
+		b.WriteString(attrs.String())
+	}
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		h.appendAttr(&b, h.prefix, a)
+	}
+
+	next := *h
+	next.attrs = b.String()
+
+	return &next
+}
+
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	next := *h
+	next.prefix = h.prefix + name + "."
+
+	return &next
+}
+
+func (h *prettyHandler) levelLabel(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return h.paint(colorRed, "ERR")
+	case level >= slog.LevelWarn:
+		return h.paint(colorYellow, "WRN")
+	case level >= slog.LevelInfo:
+		return h.paint(colorGreen, "INF")
+	default:
+		return h.paint(colorDim, "DBG")
+	}
+}
+
+func (h *prettyHandler) appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(b, prefix, ga)
+		}
+		return
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(h.paint(colorCyan, prefix+a.Key+"="))
+
+	value := prettyValue(a.Value)
+	if a.Key == "error" || a.Key == "err" {
+		value = h.paint(colorRed, value)
+	}
+	b.WriteString(value)
+}
+
+func prettyValue(v slog.Value) string {
+	var s string
+	switch v.Kind() {
+	case slog.KindString:
+		s = v.String()
+	case slog.KindTime:
+		s = v.Time().Format("2006-01-02T15:04:05.000Z07:00")
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			s = err.Error()
+		} else {
+			s = fmt.Sprint(v.Any())
+		}
+	default:
+		return v.String()
+	}
+
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+func (h *prettyHandler) paint(color, s string) string {
+	if !h.color {
+		return s
+	}
+
+	return color + s + colorReset
+}
@@ -0,0 +1,242 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+const backupTimeLayout = "2006-01-02T15-04-05.000"
+
+var (
+	filesMu sync.Mutex
+	files   []*rotatingFile
+)
+
+// rotatingFile is an append-only log file that is moved aside, to a name
+// carrying the rotation time, once it grows past MaxSizeMB or gets older
+// than RotateInterval. Rotated files are compressed and pruned in the
+// background.
+type rotatingFile struct {
+	cfg config.LogFileConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	cleanupMu sync.Mutex
+	cleanups  sync.WaitGroup
+}
+
+func openRotatingFile(cfg config.LogFileConfig) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("log file: %w", err)
+	}
+
+	r := &rotatingFile{cfg: cfg}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	filesMu.Lock()
+	files = append(files, r)
+	filesMu.Unlock()
+
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("log file: %w", err)
+	}
+
+	r.file, r.size, r.openedAt = f, info.Size(), time.Now()
+
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	tooBig := r.size > 0 && r.size+int64(len(p)) > int64(r.cfg.MaxSizeMB)<<20
+	tooOld := r.cfg.RotateInterval > 0 && time.Since(r.openedAt) >= r.cfg.RotateInterval
+	if tooBig || tooOld {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+
+	backup := r.backupName(time.Now().UTC())
+	if err := os.Rename(r.cfg.Path, backup); err != nil {
+		// Keep appending to the file that could not be moved aside; the
+		// next write tries to rotate it again.
+		if openErr := r.open(); openErr != nil {
+			r.file = nil
+			return errors.Join(fmt.Errorf("log file: %w", err), openErr)
+		}
+		return fmt.Errorf("log file: %w", err)
+	}
+
+	if err := r.open(); err != nil {
+		r.file = nil
+		return err
+	}
+
+	r.cleanups.Add(1)
+	go func() {
+		defer r.cleanups.Done()
+		r.cleanup(backup)
+	}()
+
+	return nil
+}
+
+// backupName places the rotation time between the file name and its
+// extension: app.log becomes app-2006-01-02T15-04-05.000.log.
+func (r *rotatingFile) backupName(at time.Time) string {
+	ext := filepath.Ext(r.cfg.Path)
+	return strings.TrimSuffix(r.cfg.Path, ext) + "-" + at.Format(backupTimeLayout) + ext
+}
+
+// cleanup compresses the backup just rotated and removes the backups beyond
+// MaxBackups or older than MaxAge. Failures only cost disk space, so they
+// are reported on stderr rather than through the logger being rotated.
+func (r *rotatingFile) cleanup(backup string) {
+	r.cleanupMu.Lock()
+	defer r.cleanupMu.Unlock()
+
+	if r.cfg.Compress {
+		if err := compress(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "log file: compress %s: %v\n", backup, err)
+		}
+	}
+
+	if err := r.prune(time.Now().UTC()); err != nil {
+		fmt.Fprintf(os.Stderr, "log file: prune backups: %v\n", err)
+	}
+}
+
+func (r *rotatingFile) prune(now time.Time) error {
+	if r.cfg.MaxBackups <= 0 && r.cfg.MaxAge <= 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(r.cfg.Path)
+	ext := filepath.Ext(r.cfg.Path)
+	prefix := strings.TrimSuffix(filepath.Base(r.cfg.Path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	type backup struct {
+		name string
+		at   time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, ".gz"), ext)
+		if at, err := time.Parse(backupTimeLayout, stamp); err == nil {
+			backups = append(backups, backup{name: name, at: at})
+		}
+	}
+
+	slices.SortFunc(backups, func(a, b backup) int { return b.at.Compare(a.at) })
+
+	var errs []error
+	for i, b := range backups {
+		expired := r.cfg.MaxAge > 0 && now.Sub(b.at) > r.cfg.MaxAge
+		surplus := r.cfg.MaxBackups > 0 && i >= r.cfg.MaxBackups
+		if expired || surplus {
+			errs = append(errs, os.Remove(filepath.Join(dir, b.name)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+func (r *rotatingFile) close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+
+	r.cleanups.Wait()
+
+	return err
+}
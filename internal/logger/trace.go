@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/traceparent"
+)
+
+// traceHandler adds trace_id and span_id to records whose context carries
+// a trace, so logs link to traces. They go at the top level, ahead of any
+// groups, which is why the handler keeps the groups and attrs added to it
+// and reapplies them on top of the trace attrs for traced records.
+type traceHandler struct {
+	root slog.Handler
+	next slog.Handler
+	ops  []func(slog.Handler) slog.Handler
+}
+
+func newTraceHandler(h slog.Handler) *traceHandler {
+	return &traceHandler{root: h, next: h}
+}
+
+func (h *traceHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	sc, ok := traceparent.FromContext(ctx)
+	if !ok {
+		return h.next.Handle(ctx, r)
+	}
+
+	traced := h.root.WithAttrs([]slog.Attr{
+		slog.String("trace_id", sc.TraceID),
+		slog.String("span_id", sc.SpanID),
+	})
+	for _, op := range h.ops {
+		traced = op(traced)
+	}
+
+	return traced.Handle(ctx, r)
+}
+
+func (h *traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *traceHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *traceHandler) with(op func(slog.Handler) slog.Handler) *traceHandler {
+	return &traceHandler{
+		root: h.root,
+		next: op(h.next),
+		ops:  append(slices.Clip(h.ops), op),
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Histogram counts observations into cumulative buckets with the given upper
+// bounds, matching the Prometheus histogram layout.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+
+	// exemplars keeps the latest exemplar per bucket; the last one belongs to
+	// the +Inf bucket.
+	exemplars []*Exemplar
+}
+
+func NewHistogram(bounds ...float64) *Histogram {
+	return &Histogram{
+		bounds:    bounds,
+		counts:    make([]uint64, len(bounds)),
+		exemplars: make([]*Exemplar, len(bounds)+1),
+	}
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.ObserveWithExemplar(value, nil)
+}
+
+// ObserveWithExemplar records value and, when labels are given, keeps it as
+// the exemplar of the bucket it falls into.
+func (h *Histogram) ObserveWithExemplar(value float64, labels Labels) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bucket := len(h.bounds)
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.counts[i]++
+			bucket = min(bucket, i)
+		}
+	}
+	h.sum += value
+	h.count++
+
+	if len(labels) > 0 {
+		h.exemplars[bucket] = &Exemplar{Labels: labels, Value: value, Timestamp: time.Now()}
+	}
+}
+
+func (h *Histogram) Samples() []Sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	samples := make([]Sample, 0, len(h.bounds)+3)
+	for i, bound := range h.bounds {
+		samples = append(samples, Sample{
+			Suffix:   "_bucket",
+			Labels:   Labels{"le": strconv.FormatFloat(bound, 'g', -1, 64)},
+			Value:    float64(h.counts[i]),
+			Exemplar: h.exemplars[i],
+		})
+	}
+
+	samples = append(samples,
+		Sample{Suffix: "_bucket", Labels: Labels{"le": "+Inf"}, Value: float64(h.count), Exemplar: h.exemplars[len(h.bounds)]},
+		Sample{Suffix: "_sum", Value: h.sum},
+		Sample{Suffix: "_count", Value: float64(h.count)},
+	)
+
+	return samples
+}
+
+// ExponentialBuckets returns count bounds starting at start, each factor
+// times the previous one.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	bounds := make([]float64, count)
+	for i := range bounds {
+		bounds[i] = start * math.Pow(factor, float64(i))
+	}
+	return bounds
+}
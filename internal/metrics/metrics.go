@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"bufio"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Type string
+
+const (
+	TypeCounter   Type = "counter"
+	TypeGauge     Type = "gauge"
+	TypeHistogram Type = "histogram"
+)
+
+type Labels map[string]string
+
+type Sample struct {
+	// Suffix is appended to the family name, e.g. "_bucket" for histograms.
+	Suffix string
+	Labels Labels
+	Value  float64
+
+	// Exemplar is only exposed in the OpenMetrics format.
+	Exemplar *Exemplar
+}
+
+// Exemplar links a sample to a representative observation, typically carrying
+// the trace id of the request that produced it.
+type Exemplar struct {
+	Labels    Labels
+	Value     float64
+	Timestamp time.Time
+}
+
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	Collect func() []Sample
+}
+
+// Registry exposes registered metric families in the Prometheus text format.
+type Registry struct {
+	mu       sync.RWMutex
+	families []Family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(family Family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.families = append(r.families, family)
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.mu.RLock()
+		families := append([]Family(nil), r.families...)
+		r.mu.RUnlock()
+
+		openMetrics := strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+		if openMetrics {
+			w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		}
+
+		bw := bufio.NewWriter(w)
+		for _, family := range families {
+			writeFamily(bw, family, openMetrics)
+		}
+		if openMetrics {
+			bw.WriteString("# EOF\n")
+		}
+		_ = bw.Flush()
+	})
+}
+
+func writeFamily(w *bufio.Writer, family Family, openMetrics bool) {
+	name, base := family.Name, family.Name
+	if family.Type == TypeCounter {
+		// Counter samples end in _total whether or not the family was
+		// registered with it; OpenMetrics names the family without it.
+		base = strings.TrimSuffix(family.Name, "_total")
+		name = base + "_total"
+		if openMetrics {
+			name = base
+		}
+	}
+
+	w.WriteString("# HELP " + name + " " + escape(family.Help, false) + "\n")
+	w.WriteString("# TYPE " + name + " " + string(family.Type) + "\n")
+
+	for _, sample := range family.Collect() {
+		suffix := sample.Suffix
+		if family.Type == TypeCounter && suffix == "" {
+			suffix = "_total"
+		}
+		w.WriteString(base + suffix)
+		writeLabels(w, sample.Labels)
+		w.WriteString(" " + strconv.FormatFloat(sample.Value, 'g', -1, 64))
+		if openMetrics && sample.Exemplar != nil {
+			writeExemplar(w, sample.Exemplar)
+		}
+		w.WriteString("\n")
+	}
+}
+
+func writeExemplar(w *bufio.Writer, exemplar *Exemplar) {
+	w.WriteString(" # ")
+	if len(exemplar.Labels) == 0 {
+		w.WriteString("{}")
+	} else {
+		writeLabels(w, exemplar.Labels)
+	}
+	w.WriteString(" " + strconv.FormatFloat(exemplar.Value, 'g', -1, 64))
+	if !exemplar.Timestamp.IsZero() {
+		w.WriteString(" " + strconv.FormatFloat(float64(exemplar.Timestamp.UnixMilli())/1000, 'f', 3, 64))
+	}
+}
+
+func writeLabels(w *bufio.Writer, labels Labels) {
+	if len(labels) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(key + `="` + escape(labels[key], true) + `"`)
+	}
+	w.WriteByte('}')
+}
+
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(Family{
+		Name: "jobs_runs_total",
+		Help: "Job runs.",
+		Type: TypeCounter,
+		Collect: func() []Sample {
+			return []Sample{{Labels: Labels{"job": "prices"}, Value: 3}}
+		},
+	})
+	r.Register(Family{
+		Name: "jobs_failures",
+		Help: "Job failures,\nby job.",
+		Type: TypeCounter,
+		Collect: func() []Sample {
+			return []Sample{{Labels: Labels{"job": `a"b\c`}, Value: 1}}
+		},
+	})
+	r.Register(Family{
+		Name: "queue_depth",
+		Help: "Queued events.",
+		Type: TypeGauge,
+		Collect: func() []Sample {
+			return []Sample{{Value: 2.5}}
+		},
+	})
+
+	h := NewHistogram(0.1, 1)
+	h.ObserveWithExemplar(0.05, Labels{"trace_id": "abc"})
+	h.exemplars[0].Timestamp = time.UnixMilli(1700000000123)
+	h.Observe(2)
+	r.Register(Family{
+		Name:    "request_seconds",
+		Help:    "Request latency.",
+		Type:    TypeHistogram,
+		Collect: h.Samples,
+	})
+
+	return r
+}
+
+func scrape(t *testing.T, r *Registry, accept string) (string, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, req)
+
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec.Header().Get("Content-Type"), string(body)
+}
+
+func TestPrometheusExposition(t *testing.T) {
+	contentType, body := scrape(t, newTestRegistry(), "")
+
+	if contentType != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("content type = %q", contentType)
+	}
+
+	want := `# HELP jobs_runs_total Job runs.
+# TYPE jobs_runs_total counter
+jobs_runs_total{job="prices"} 3
+# HELP jobs_failures_total Job failures,\nby job.
+# TYPE jobs_failures_total counter
+jobs_failures_total{job="a\"b\\c"} 1
+# HELP queue_depth Queued events.
+# TYPE queue_depth gauge
+queue_depth 2.5
+# HELP request_seconds Request latency.
+# TYPE request_seconds histogram
+request_seconds_bucket{le="0.1"} 1
+request_seconds_bucket{le="1"} 1
+request_seconds_bucket{le="+Inf"} 2
+request_seconds_sum 2.05
+request_seconds_count 2
+`
+	if body != want {
+		t.Errorf("exposition:\n%s\nwant:\n%s", body, want)
+	}
+}
+
+func TestOpenMetricsExposition(t *testing.T) {
+	contentType, body := scrape(t, newTestRegistry(), "application/openmetrics-text; version=1.0.0")
+
+	if contentType != "application/openmetrics-text; version=1.0.0; charset=utf-8" {
+		t.Errorf("content type = %q", contentType)
+	}
+
+	want := `# HELP jobs_runs Job runs.
+# TYPE jobs_runs counter
+jobs_runs_total{job="prices"} 3
+# HELP jobs_failures Job failures,\nby job.
+# TYPE jobs_failures counter
+jobs_failures_total{job="a\"b\\c"} 1
+# HELP queue_depth Queued events.
+# TYPE queue_depth gauge
+queue_depth 2.5
+# HELP request_seconds Request latency.
+# TYPE request_seconds histogram
+request_seconds_bucket{le="0.1"} 1 # {trace_id="abc"} 0.05 1700000000.123
+request_seconds_bucket{le="1"} 1
+request_seconds_bucket{le="+Inf"} 2
+request_seconds_sum 2.05
+request_seconds_count 2
+# EOF
+`
+	if body != want {
+		t.Errorf("exposition:\n%s\nwant:\n%s", body, want)
+	}
+}
+
+func TestHandlerRejectsOtherMethods(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestRegistry().Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
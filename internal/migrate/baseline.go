@@ -0,0 +1,123 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// Baseline records the pending migrations up to version to as
+// applied without running them, for databases whose schema was created some
+// other way, e.g. from a dump.
+func Baseline(db *sql.DB, migrationsPath string, to int, log *slog.Logger) (int, error) {
+	files, err := migrationFiles(migrationsPath)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return 0, err
+	}
+
+	if err := checkDirtyMigrations(ctx, db); err != nil {
+		return 0, err
+	}
+
+	applied, err := loadAppliedMigrations(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(execCtx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	count := 0
+	for _, file := range files {
+		if to > 0 && migrationNumber(file) > to {
+			break
+		}
+
+		version := migrationVersion(file)
+		if _, ok := applied[version]; ok {
+			continue
+		}
+
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		if err := recordMigration(execCtx, tx, version, string(contents), nil, false); err != nil {
+			return 0, err
+		}
+		log.Info("migration marked as applied", slog.String("version", version))
+
+		count++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to record baseline: %w", err)
+	}
+
+	return count, nil
+}
+
+// Force records a single migration as applied and clears its dirty
+// flag, once the operator has brought the schema in line with it by hand.
+// version is either the file name without .up.sql or its number.
+func Force(db *sql.DB, migrationsPath, version string, log *slog.Logger) (int, error) {
+	files, err := migrationFiles(migrationsPath)
+	if err != nil {
+		return 0, err
+	}
+
+	resolved := ""
+	n, numeric := strconv.Atoi(version)
+	for _, file := range files {
+		if migrationVersion(file) == version || (numeric == nil && migrationNumber(file) == n) {
+			resolved = file
+			break
+		}
+	}
+	if resolved == "" {
+		return 0, fmt.Errorf("no migration %s in %s", version, migrationsPath)
+	}
+	version = migrationVersion(resolved)
+
+	contents, err := os.ReadFile(resolved)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migration %s: %w", resolved, err)
+	}
+
+	ctx := context.Background()
+
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return 0, err
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	const query = "INSERT INTO " + migrationsTable + ` (version, checksum, applied_by, applied_host)
+VALUES ($1, $2, current_user, NULLIF($3, ''))
+ON CONFLICT (version) DO UPDATE SET dirty = FALSE`
+	if _, err := db.ExecContext(execCtx, query, version, checksum(string(contents)), migrationHost); err != nil {
+		return 0, fmt.Errorf("failed to force migration %s: %w", version, err)
+	}
+	log.Info("migration forced to applied", slog.String("version", version))
+
+	return 1, nil
+}
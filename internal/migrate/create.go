@@ -0,0 +1,48 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Create writes empty up and down files for name numbered after the
+// highest migration in migrationsPath and returns their paths.
+func Create(migrationsPath, name string) ([]string, error) {
+	if !migrationNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q: use lowercase letters, digits and underscores", name)
+	}
+
+	files, err := migrationFiles(migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	next := 1
+	for _, file := range files {
+		next = max(next, migrationNumber(file)+1)
+	}
+
+	base := filepath.Join(migrationsPath, strconv.Itoa(next)+"_"+name)
+	paths := []string{base + ".up.sql", base + ".down.sql"}
+
+	for i, path := range paths {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			for _, created := range paths[:i] {
+				_ = os.Remove(created)
+			}
+			return nil, fmt.Errorf("failed to create migration file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to create migration file: %w", err), os.Remove(path))
+		}
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,146 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Down reverts applied migrations, latest first, by running their .down.sql
+// files. options.To keeps the migrations numbered up to and including it and
+// options.Steps bounds how many are reverted; zero means no bound, so both
+// zero reverts everything. Repeatable migrations are left alone.
+func Down(db *sql.DB, migrationsPath string, options Options, log *slog.Logger) (int, error) {
+	if _, err := migrationFiles(migrationsPath); err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return 0, err
+	}
+
+	if err := checkDirtyMigrations(ctx, db); err != nil {
+		return 0, err
+	}
+
+	applied, err := loadAppliedMigrations(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	versions := make([]string, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return migrationLess(versions[j], versions[i])
+	})
+
+	count := 0
+	for _, version := range versions {
+		if options.To > 0 && migrationNumber(version) <= options.To {
+			break
+		}
+		if options.Steps > 0 && count == options.Steps {
+			break
+		}
+
+		file := filepath.Join(migrationsPath, version+".down.sql")
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return count, fmt.Errorf("no down migration for %s in %s", version, migrationsPath)
+			}
+			return count, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		opts, err := parseMigrationOptions(string(contents), options.Timeout)
+		if err != nil {
+			return count, fmt.Errorf("invalid migration %s: %w", file, err)
+		}
+
+		log.Info("reverting migration",
+			slog.String("version", version),
+			slog.String("file", file),
+			slog.Duration("timeout", opts.timeout),
+			slog.Bool("transaction", !opts.noTransaction),
+		)
+
+		if opts.noTransaction {
+			err = revertMigrationNoTx(ctx, db, version, string(contents), opts.timeout)
+		} else {
+			err = revertMigrationTx(ctx, db, version, string(contents), opts.timeout)
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to revert migration %s: %w", file, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func revertMigrationTx(ctx context.Context, db *sql.DB, version, contents string, timeout time.Duration) error {
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(execCtx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(execCtx, contents); err != nil {
+		return err
+	}
+
+	if err := forgetMigration(execCtx, tx, version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// revertMigrationNoTx marks the migration dirty while its down statements
+// run, so a failure part-way stops the next run the same way a failed up
+// migration does.
+func revertMigrationNoTx(ctx context.Context, db *sql.DB, version, contents string, timeout time.Duration) error {
+	metaCtx, metaCancel := context.WithTimeout(ctx, metadataTimeout)
+	defer metaCancel()
+
+	const markDirty = "UPDATE " + migrationsTable + " SET dirty = TRUE WHERE version = $1"
+	if _, err := db.ExecContext(metaCtx, markDirty, version); err != nil {
+		return fmt.Errorf("failed to mark migration %s as dirty: %w", version, err)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := execStatements(execCtx, db, contents); err != nil {
+		return err
+	}
+
+	forgetCtx, forgetCancel := context.WithTimeout(ctx, metadataTimeout)
+	defer forgetCancel()
+
+	return forgetMigration(forgetCtx, db, version)
+}
+
+func forgetMigration(ctx context.Context, db execer, version string) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM "+migrationsTable+" WHERE version = $1", version); err != nil {
+		return fmt.Errorf("failed to remove migration %s: %w", version, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql"
+)
+
+// lockName identifies the advisory lock that keeps migrators started at the
+// same time from applying migrations concurrently.
+const lockName = "schema_migrations"
+
+// Lock waits for other migrators on the same database, giving up after
+// timeout unless it is zero. The migrations they applied are then seen as
+// already applied.
+func Lock(storage *postgresql.Storage, timeout time.Duration, log *slog.Logger) (*postgresql.AdvisoryLock, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	lock, err := storage.TryAdvisoryLock(ctx, lockName)
+	if err != nil || lock != nil {
+		return lock, err
+	}
+
+	log.Info("another migrator is running, waiting for it to finish", slog.Duration("timeout", timeout))
+
+	return storage.AcquireAdvisoryLock(ctx, lockName)
+}
+
+// Record is a schema_migrations row; the audit columns are nil for rows
+// written before they existed or without running the migration.
+type Record struct {
+	Version     string
+	Checksum    *string
+	DurationMs  *int64
+	AppliedBy   *string
+	AppliedHost *string
+}
+
+// RecordedSince returns the migrations recorded at or after since, in the
+// order they were applied.
+func RecordedSince(db *sql.DB, since time.Time) ([]Record, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", migrationsTable).Scan(&exists); err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version, checksum, duration_ms, applied_by, applied_host
+FROM `+migrationsTable+`
+WHERE applied_at >= $1
+ORDER BY applied_at, version`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recorded []Record
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.Version, &r.Checksum, &r.DurationMs, &r.AppliedBy, &r.AppliedHost); err != nil {
+			return nil, err
+		}
+		recorded = append(recorded, r)
+	}
+
+	return recorded, rows.Err()
+}
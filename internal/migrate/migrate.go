@@ -0,0 +1,584 @@
+// Package migrate applies the SQL migrations of a directory to a PostgreSQL
+// database and keeps track of them in schema_migrations.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	migrationsTable = "schema_migrations"
+	metadataTimeout = 30 * time.Second
+
+	timeoutDirective       = "timeout:"
+	noTransactionDirective = "no-transaction"
+)
+
+type migrationOptions struct {
+	timeout       time.Duration
+	noTransaction bool
+}
+
+// Options bound an Up run. To is the highest migration number to apply and
+// Steps the most migrations to apply; zero means no bound. Timeout is the
+// default per migration, which a timeout directive in the file overrides.
+// AllowOutOfOrder lets pending migrations older than the latest applied one
+// run instead of failing the run.
+type Options struct {
+	To              int
+	Steps           int
+	Timeout         time.Duration
+	AllowOutOfOrder bool
+}
+
+// Up applies the pending migrations in migrationsPath in order, followed by
+// the repeatable ones, and returns how many it ran.
+func Up(db *sql.DB, migrationsPath string, options Options, log *slog.Logger) (int, error) {
+	files, err := migrationFiles(migrationsPath)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return 0, err
+	}
+
+	if err := checkDirtyMigrations(ctx, db); err != nil {
+		return 0, err
+	}
+
+	applied, err := loadAppliedMigrations(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	if !options.AllowOutOfOrder {
+		if err := checkMigrationOrder(files, applied); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	stopped := false
+	for _, file := range files {
+		version := migrationVersion(file)
+		if _, ok := applied[version]; ok {
+			log.Info("migration already applied", slog.String("version", version))
+			continue
+		}
+
+		if options.To > 0 && migrationNumber(file) > options.To {
+			log.Info("stopping at target version", slog.Int("to", options.To))
+			stopped = true
+			break
+		}
+		if options.Steps > 0 && count == options.Steps {
+			log.Info("step limit reached", slog.Int("steps", options.Steps))
+			stopped = true
+			break
+		}
+
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return count, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		opts, err := parseMigrationOptions(string(contents), options.Timeout)
+		if err != nil {
+			return count, fmt.Errorf("invalid migration %s: %w", file, err)
+		}
+
+		log.Info("applying migration",
+			slog.String("version", version),
+			slog.String("file", file),
+			slog.Duration("timeout", opts.timeout),
+			slog.Bool("transaction", !opts.noTransaction),
+		)
+
+		if opts.noTransaction {
+			err = execMigrationNoTx(ctx, db, version, string(contents), opts.timeout)
+		} else {
+			err = execMigrationTx(ctx, db, version, string(contents), opts.timeout)
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to apply migration %s: %w", file, err)
+		}
+
+		count++
+	}
+
+	// Repeatable migrations are written against the latest schema, so they
+	// wait until no versioned migration is left pending.
+	if stopped {
+		log.Info("skipping repeatable migrations until all versioned migrations are applied")
+		return count, nil
+	}
+
+	repeated, err := runRepeatableMigrations(ctx, db, migrationsPath, options.Timeout, log)
+	return count + repeated, err
+}
+
+// checkMigrationOrder fails when a pending migration sorts before the latest
+// applied one, which usually means branches were merged in a different order
+// than they were deployed.
+func checkMigrationOrder(files []string, applied map[string]struct{}) error {
+	latest := ""
+	for version := range applied {
+		if latest == "" || migrationLess(latest, version) {
+			latest = version
+		}
+	}
+	if latest == "" {
+		return nil
+	}
+
+	var behind []string
+	for _, file := range files {
+		version := migrationVersion(file)
+		if _, ok := applied[version]; !ok && migrationLess(version, latest) {
+			behind = append(behind, version)
+		}
+	}
+
+	if len(behind) > 0 {
+		return fmt.Errorf("pending migrations %s sort before the latest applied %s: renumber them or rerun with --allow-out-of-order",
+			strings.Join(behind, ", "), latest)
+	}
+
+	return nil
+}
+
+// migrationFiles returns the up migrations in migrationsPath in the order
+// they are applied.
+func migrationFiles(migrationsPath string) ([]string, error) {
+	info, err := os.Stat(migrationsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("migrations directory does not exist: %s", migrationsPath)
+		}
+
+		return nil, fmt.Errorf("failed to access migrations directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("migrations path is not a directory: %s", migrationsPath)
+	}
+
+	entries, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		if strings.HasSuffix(name, ".up.sql") {
+			files = append(files, filepath.Join(migrationsPath, name))
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return migrationLess(files[i], files[j])
+	})
+
+	return files, nil
+}
+
+func migrationLess(a, b string) bool {
+	na, nb := migrationNumber(a), migrationNumber(b)
+	if na != nb {
+		return na < nb
+	}
+	return a < b
+}
+
+func migrationVersion(file string) string {
+	return strings.TrimSuffix(filepath.Base(file), ".up.sql")
+}
+
+func ensureMigrationsTable(ctx context.Context, db *sql.DB) error {
+	execCtx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	const query = `CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (
+        version TEXT PRIMARY KEY,
+        applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`
+
+	if _, err := db.ExecContext(execCtx, query); err != nil {
+		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	// dirty marks a migration that ran outside a transaction and did not
+	// finish. The rest is an audit trail, left NULL for migrations recorded
+	// without running them. Tables created by older migrators lack these
+	// columns.
+	const alter = `ALTER TABLE ` + migrationsTable + `
+        ADD COLUMN IF NOT EXISTS dirty BOOLEAN NOT NULL DEFAULT FALSE,
+        ADD COLUMN IF NOT EXISTS checksum TEXT,
+        ADD COLUMN IF NOT EXISTS duration_ms BIGINT,
+        ADD COLUMN IF NOT EXISTS applied_by TEXT,
+        ADD COLUMN IF NOT EXISTS applied_host TEXT`
+
+	if _, err := db.ExecContext(execCtx, alter); err != nil {
+		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// checkDirtyMigrations refuses to migrate while a migration is recorded as
+// partially applied: what it left behind has to be checked by hand first.
+func checkDirtyMigrations(ctx context.Context, db *sql.DB) error {
+	queryCtx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(queryCtx, "SELECT version FROM "+migrationsTable+" WHERE dirty ORDER BY version")
+	if err != nil {
+		return fmt.Errorf("failed to load dirty migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var dirty []string
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return fmt.Errorf("failed to scan dirty migration: %w", err)
+		}
+		dirty = append(dirty, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate dirty migrations: %w", err)
+	}
+
+	if len(dirty) > 0 {
+		return fmt.Errorf("migrations %s failed part-way and are marked dirty: repair the schema, then delete their %s rows to rerun them or force them to keep them",
+			strings.Join(dirty, ", "), migrationsTable)
+	}
+
+	return nil
+}
+
+func loadAppliedMigrations(ctx context.Context, db *sql.DB) (map[string]struct{}, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(queryCtx, "SELECT version FROM "+migrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]struct{})
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+
+		applied[version] = struct{}{}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// migrationNumber returns the numeric prefix of a migration file so that
+// 10_x sorts after 9_x.
+func migrationNumber(file string) int {
+	prefix, _, _ := strings.Cut(filepath.Base(file), "_")
+	n, err := strconv.Atoi(prefix)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// migrationHost identifies the machine the migrator runs on in
+// schema_migrations.applied_host.
+var migrationHost = func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return host
+}()
+
+// checksum identifies the contents of a migration file; line endings do not
+// count as a change.
+func checksum(contents string) string {
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(contents, "\r\n", "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordMigration records version with the checksum of contents. duration is
+// nil for migrations recorded without being run or before they finish.
+func recordMigration(ctx context.Context, db execer, version, contents string, duration *time.Duration, dirty bool) error {
+	const query = "INSERT INTO " + migrationsTable + ` (version, dirty, checksum, duration_ms, applied_by, applied_host)
+VALUES ($1, $2, $3, $4, current_user, NULLIF($5, ''))`
+
+	var durationMs *int64
+	if duration != nil {
+		ms := duration.Milliseconds()
+		durationMs = &ms
+	}
+
+	if _, err := db.ExecContext(ctx, query, version, dirty, checksum(contents), durationMs, migrationHost); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", version, err)
+	}
+
+	return nil
+}
+
+func execMigrationTx(ctx context.Context, db *sql.DB, version, contents string, timeout time.Duration) error {
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(execCtx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	started := time.Now()
+	if _, err := tx.ExecContext(execCtx, contents); err != nil {
+		return err
+	}
+	duration := time.Since(started)
+
+	if err := recordMigration(execCtx, tx, version, contents, &duration, false); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execMigrationNoTx records the migration as dirty before running its
+// statements one by one and clears the flag once all of them succeeded, so a
+// failure part-way stays visible to the next run.
+func execMigrationNoTx(ctx context.Context, db *sql.DB, version, contents string, timeout time.Duration) error {
+	metaCtx, metaCancel := context.WithTimeout(ctx, metadataTimeout)
+	defer metaCancel()
+
+	if err := recordMigration(metaCtx, db, version, contents, nil, true); err != nil {
+		return err
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	if err := execStatements(execCtx, db, contents); err != nil {
+		return err
+	}
+	duration := time.Since(started)
+
+	clearCtx, clearCancel := context.WithTimeout(ctx, metadataTimeout)
+	defer clearCancel()
+
+	const markClean = "UPDATE " + migrationsTable + " SET dirty = FALSE, applied_at = NOW(), duration_ms = $2 WHERE version = $1"
+	if _, err := db.ExecContext(clearCtx, markClean, version, duration.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to mark migration %s as applied: %w", version, err)
+	}
+
+	return nil
+}
+
+// parseMigrationOptions reads directives from the leading comment block of a
+// migration file, e.g. "-- timeout: 10m" or "-- no-transaction".
+func parseMigrationOptions(contents string, defaultTimeout time.Duration) (migrationOptions, error) {
+	opts := migrationOptions{timeout: defaultTimeout}
+
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		directive := strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		switch {
+		case directive == noTransactionDirective:
+			opts.noTransaction = true
+		case strings.HasPrefix(directive, timeoutDirective):
+			value := strings.TrimSpace(strings.TrimPrefix(directive, timeoutDirective))
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				return migrationOptions{}, fmt.Errorf("invalid timeout directive %q", value)
+			}
+			opts.timeout = timeout
+		}
+	}
+
+	return opts, nil
+}
+
+// concurrentIndex matches a CREATE INDEX CONCURRENTLY IF NOT EXISTS
+// statement, after any leading comment lines, and captures the index name.
+var concurrentIndex = regexp.MustCompile(`(?i)^(?:\s*--[^\n]*\n)*\s*CREATE\s+(?:UNIQUE\s+)?INDEX\s+CONCURRENTLY\s+IF\s+NOT\s+EXISTS\s+("?[\w.]+"?)`)
+
+// execStatements runs the statements of a script outside of a transaction,
+// one by one. An interrupted concurrent index build leaves an invalid index
+// behind, which IF NOT EXISTS would keep, so such an index is dropped before
+// its build runs again.
+func execStatements(ctx context.Context, db *sql.DB, contents string) error {
+	for _, statement := range splitStatements(contents) {
+		if m := concurrentIndex.FindStringSubmatch(statement); m != nil {
+			if err := dropInvalidIndex(ctx, db, m[1]); err != nil {
+				return err
+			}
+		}
+
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func dropInvalidIndex(ctx context.Context, db *sql.DB, name string) error {
+	const query = "SELECT EXISTS (SELECT 1 FROM pg_index WHERE indexrelid = to_regclass($1) AND NOT indisvalid)"
+
+	var invalid bool
+	if err := db.QueryRowContext(ctx, query, name).Scan(&invalid); err != nil {
+		return fmt.Errorf("failed to check index %s: %w", name, err)
+	}
+	if !invalid {
+		return nil
+	}
+
+	if _, err := db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+name); err != nil {
+		return fmt.Errorf("failed to drop invalid index %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// splitStatements splits a script on top-level semicolons so statements that
+// cannot run inside an implicit transaction block (CREATE INDEX CONCURRENTLY)
+// are sent one by one. Quoted strings, identifiers, comments and dollar-quoted
+// bodies are left intact.
+func splitStatements(contents string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		dollarTag  string
+	)
+
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		if !isCommentOnly(statement) {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(contents); i++ {
+		c := contents[i]
+
+		if dollarTag != "" {
+			if strings.HasPrefix(contents[i:], dollarTag) {
+				current.WriteString(dollarTag)
+				i += len(dollarTag) - 1
+				dollarTag = ""
+				continue
+			}
+			current.WriteByte(c)
+			continue
+		}
+
+		switch {
+		case c == '-' && strings.HasPrefix(contents[i:], "--"):
+			end := strings.IndexByte(contents[i:], '\n')
+			if end < 0 {
+				end = len(contents) - i
+			}
+			current.WriteString(contents[i : i+end])
+			i += end - 1
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(contents[i+1:], c)
+			if end < 0 {
+				current.WriteString(contents[i:])
+				i = len(contents)
+				continue
+			}
+			current.WriteString(contents[i : i+end+2])
+			i += end + 1
+		case c == '$':
+			end := strings.IndexByte(contents[i+1:], '$')
+			if end >= 0 && isDollarTag(contents[i+1:i+1+end]) {
+				dollarTag = contents[i : i+end+2]
+				current.WriteString(dollarTag)
+				i += end + 1
+				continue
+			}
+			current.WriteByte(c)
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	flush()
+
+	return statements
+}
+
+func isDollarTag(tag string) bool {
+	for i, r := range tag {
+		if i == 0 && unicode.IsDigit(r) {
+			return false
+		}
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isCommentOnly(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,198 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	repeatableTable  = "schema_repeatable_migrations"
+	repeatablePrefix = "R__"
+)
+
+// repeatableMigration is an R__<name>.sql file. It holds definitions that
+// can be replaced wholesale, such as views and functions, and runs again
+// whenever its contents change.
+type repeatableMigration struct {
+	name     string
+	file     string
+	contents string
+	checksum string
+}
+
+type repeatableRecord struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+func repeatableFiles(migrationsPath string) ([]repeatableMigration, error) {
+	entries, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var migrations []repeatableMigration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, repeatablePrefix) || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		file := filepath.Join(migrationsPath, name)
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		migrations = append(migrations, repeatableMigration{
+			name:     strings.TrimSuffix(strings.TrimPrefix(name, repeatablePrefix), ".sql"),
+			file:     file,
+			contents: string(contents),
+			checksum: checksum(string(contents)),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].name < migrations[j].name
+	})
+
+	return migrations, nil
+}
+
+// runRepeatableMigrations runs, in name order, every repeatable migration
+// that is new or whose checksum changed since it last ran.
+func runRepeatableMigrations(ctx context.Context, db *sql.DB, migrationsPath string, defaultTimeout time.Duration, log *slog.Logger) (int, error) {
+	migrations, err := repeatableFiles(migrationsPath)
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+
+	if err := ensureRepeatableTable(ctx, db); err != nil {
+		return 0, err
+	}
+
+	records, err := loadRepeatableRecords(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range migrations {
+		if records[migration.name].checksum == migration.checksum {
+			continue
+		}
+
+		opts, err := parseMigrationOptions(migration.contents, defaultTimeout)
+		if err != nil {
+			return count, fmt.Errorf("invalid migration %s: %w", migration.file, err)
+		}
+		if opts.noTransaction {
+			return count, fmt.Errorf("invalid migration %s: repeatable migrations always run in a transaction", migration.file)
+		}
+
+		log.Info("applying repeatable migration",
+			slog.String("name", migration.name),
+			slog.String("file", migration.file),
+			slog.Duration("timeout", opts.timeout),
+		)
+
+		if err := execRepeatable(ctx, db, migration, opts.timeout); err != nil {
+			return count, fmt.Errorf("failed to apply migration %s: %w", migration.file, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func ensureRepeatableTable(ctx context.Context, db *sql.DB) error {
+	execCtx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	const query = `CREATE TABLE IF NOT EXISTS ` + repeatableTable + ` (
+        name TEXT PRIMARY KEY,
+        checksum TEXT NOT NULL,
+        applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`
+
+	if _, err := db.ExecContext(execCtx, query); err != nil {
+		return fmt.Errorf("failed to ensure repeatable migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// loadRepeatableRecords returns the checksum each repeatable migration last
+// ran with and when. A database the migrator never ran one against has none.
+func loadRepeatableRecords(ctx context.Context, db *sql.DB) (map[string]repeatableRecord, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	records := make(map[string]repeatableRecord)
+
+	var exists bool
+	if err := db.QueryRowContext(queryCtx, "SELECT to_regclass($1) IS NOT NULL", repeatableTable).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up repeatable migrations table: %w", err)
+	}
+	if !exists {
+		return records, nil
+	}
+
+	rows, err := db.QueryContext(queryCtx, "SELECT name, checksum, applied_at FROM "+repeatableTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load repeatable migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			name   string
+			record repeatableRecord
+		)
+		if err := rows.Scan(&name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan repeatable migration: %w", err)
+		}
+
+		records[name] = record
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate repeatable migrations: %w", err)
+	}
+
+	return records, nil
+}
+
+func execRepeatable(ctx context.Context, db *sql.DB, migration repeatableMigration, timeout time.Duration) error {
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(execCtx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(execCtx, migration.contents); err != nil {
+		return err
+	}
+
+	const query = `INSERT INTO ` + repeatableTable + ` (name, checksum) VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET checksum = EXCLUDED.checksum, applied_at = NOW()`
+
+	if _, err := tx.ExecContext(execCtx, query, migration.name, migration.checksum); err != nil {
+		return fmt.Errorf("failed to record repeatable migration %s: %w", migration.name, err)
+	}
+
+	return tx.Commit()
+}
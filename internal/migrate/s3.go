@@ -0,0 +1,61 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/awsv4"
+)
+
+// s3Getter reads objects under s3://bucket/prefix. Requests are signed when
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are set and anonymous
+// otherwise. AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL points it at an
+// S3-compatible store such as MinIO, addressed path-style.
+func s3Getter(source *url.URL) (func(ctx context.Context, name string) ([]byte, error), error) {
+	bucket := source.Host
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid migrations source %q: missing bucket", source.String())
+	}
+	prefix := strings.Trim(source.Path, "/")
+
+	region := awsv4.Region()
+
+	endpoint := &url.URL{Scheme: "https", Host: bucket + ".s3." + region + ".amazonaws.com"}
+	pathStyle := false
+	if custom := awsv4.FirstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); custom != "" {
+		var err error
+		endpoint, err = url.Parse(custom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 endpoint %q: %w", custom, err)
+		}
+		pathStyle = true
+	}
+
+	creds, signed := awsv4.CredentialsFromEnv()
+
+	return func(ctx context.Context, name string) ([]byte, error) {
+		u := *endpoint
+		key := path.Join(prefix, name)
+		if pathStyle {
+			u.Path = path.Join("/", endpoint.Path, bucket, key)
+		} else {
+			u.Path = "/" + key
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if signed {
+			awsv4.Sign(req, "s3", region, creds, awsv4.UnsignedPayload, time.Now())
+		}
+
+		return download(req)
+	}, nil
+}
@@ -0,0 +1,84 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Seed executes every .sql file in seedsPath, each in its own
+// transaction and in migration order. Seeds are not recorded anywhere, so
+// they have to be safe to run again. A "-- timeout:" directive overrides
+// timeout for its file like it does for a migration.
+func Seed(db *sql.DB, seedsPath string, timeout time.Duration, log *slog.Logger) (int, error) {
+	entries, err := os.ReadDir(seedsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("seeds directory does not exist: %s", seedsPath)
+		}
+
+		return 0, fmt.Errorf("failed to read seeds directory: %w", err)
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			files = append(files, filepath.Join(seedsPath, entry.Name()))
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return migrationLess(files[i], files[j])
+	})
+
+	ctx := context.Background()
+
+	count := 0
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return count, fmt.Errorf("failed to read seed %s: %w", file, err)
+		}
+
+		opts, err := parseMigrationOptions(string(contents), timeout)
+		if err != nil {
+			return count, fmt.Errorf("invalid seed %s: %w", file, err)
+		}
+
+		log.Info("loading seed", slog.String("file", file), slog.Duration("timeout", opts.timeout))
+
+		if err := execSeed(ctx, db, string(contents), opts.timeout); err != nil {
+			return count, fmt.Errorf("failed to load seed %s: %w", file, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func execSeed(ctx context.Context, db *sql.DB, contents string, timeout time.Duration) error {
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(execCtx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(execCtx, contents); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,184 @@
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// manifestName is the file listing the migrations of a remote source in
+	// sha256sum format, one "<sha256>  <file>" line per file.
+	manifestName = "SHA256SUMS"
+
+	maxSourceFileSize = 16 << 20
+)
+
+// IsRemote reports whether source is a URL for Fetch rather than a directory.
+func IsRemote(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "s3://")
+}
+
+// Fetch makes the migrations at source available in a local directory. A
+// directory is returned as is. For an https:// or s3:// source the files in
+// its SHA256SUMS manifest are downloaded into a temporary directory, which
+// cleanup removes, and each is checked against its checksum. The source has
+// to pin the manifest itself with a "#sha256=<hex>" fragment; otherwise
+// whoever can write to the bucket or host decides what runs against the
+// database.
+func Fetch(ctx context.Context, source string) (string, func(), error) {
+	if !IsRemote(source) {
+		return source, func() {}, nil
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid migrations source %q: %w", source, err)
+	}
+
+	pinned, ok := strings.CutPrefix(u.Fragment, "sha256=")
+	if !ok {
+		return "", nil, fmt.Errorf("migrations source %q must pin its %s manifest with #sha256=<hex>", u.Redacted(), manifestName)
+	}
+	if _, err := hex.DecodeString(pinned); err != nil || len(pinned) != sha256.Size*2 {
+		return "", nil, fmt.Errorf("invalid migrations source %q: #sha256= needs a %d character hex checksum", u.Redacted(), sha256.Size*2)
+	}
+	u.Fragment = ""
+
+	var get func(ctx context.Context, name string) ([]byte, error)
+	switch u.Scheme {
+	case "https":
+		get = httpsGetter(u)
+	case "s3":
+		get, err = s3Getter(u)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	manifest, err := get(ctx, manifestName)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch migrations manifest: %w", err)
+	}
+	if !strings.EqualFold(sha256Hex(manifest), pinned) {
+		return "", nil, fmt.Errorf("migrations manifest checksum mismatch: expected %s", pinned)
+	}
+
+	files, err := parseManifest(manifest)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "migrations-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(dir)
+	}
+
+	for name, sum := range files {
+		contents, err := get(ctx, name)
+		if err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to fetch migration %s: %w", name, err)
+		}
+		if sha256Hex(contents) != sum {
+			cleanup()
+			return "", nil, fmt.Errorf("migration %s does not match its checksum in %s", name, manifestName)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, name), contents, 0o600); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to write migration %s: %w", name, err)
+		}
+	}
+
+	return dir, cleanup, nil
+}
+
+// parseManifest returns the checksum of every file in a sha256sum listing.
+// Only plain .sql file names are accepted, so the listing cannot write
+// outside the download directory.
+func parseManifest(manifest []byte) (map[string]string, error) {
+	files := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		sum, name, ok := strings.Cut(line, " ")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		if _, err := hex.DecodeString(sum); !ok || err != nil || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid %s line %q", manifestName, line)
+		}
+		if name != path.Base(name) || strings.ContainsRune(name, '\\') || !strings.HasSuffix(name, ".sql") {
+			return nil, fmt.Errorf("invalid file name %q in %s", name, manifestName)
+		}
+
+		files[name] = strings.ToLower(sum)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", manifestName, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s lists no migrations", manifestName)
+	}
+
+	return files, nil
+}
+
+func httpsGetter(base *url.URL) func(ctx context.Context, name string) ([]byte, error) {
+	return func(ctx context.Context, name string) ([]byte, error) {
+		u := *base
+		u.Path = path.Join("/", base.Path, name)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return download(req)
+	}
+}
+
+func download(req *http.Request) ([]byte, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxSourceFileSize {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxSourceFileSize)
+	}
+
+	return body, nil
+}
+
+func sha256Hex(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
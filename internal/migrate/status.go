@@ -0,0 +1,160 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// States reported by Status. A repeatable migration is changed when its file
+// differs from the version that last ran.
+const (
+	StateApplied       = "applied"
+	StateDirty         = "dirty"
+	StatePending       = "pending"
+	StateChanged       = "changed"
+	StateMissingOnDisk = "missing on disk"
+)
+
+type MigrationStatus struct {
+	Version   string
+	State     string
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	appliedAt time.Time
+	dirty     bool
+}
+
+// Status returns every migration in migrationsPath, and every one recorded in
+// db whose file is gone, with its state. It does not change the database.
+func Status(db *sql.DB, migrationsPath string) ([]MigrationStatus, error) {
+	files, err := migrationFiles(migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := loadAppliedAt(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(files))
+	for _, file := range files {
+		version := migrationVersion(file)
+		status := MigrationStatus{Version: version, State: StatePending}
+		if record, ok := applied[version]; ok {
+			status.State = StateApplied
+			if record.dirty {
+				status.State = StateDirty
+			}
+			status.AppliedAt = &record.appliedAt
+			delete(applied, version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Whatever is left was applied from a file that is no longer on disk.
+	missing := make([]string, 0, len(applied))
+	for version := range applied {
+		missing = append(missing, version)
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return migrationLess(missing[i], missing[j])
+	})
+	for _, version := range missing {
+		record := applied[version]
+		statuses = append(statuses, MigrationStatus{Version: version, State: StateMissingOnDisk, AppliedAt: &record.appliedAt})
+	}
+
+	repeatables, err := repeatableFiles(migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(repeatables) == 0 {
+		return statuses, nil
+	}
+
+	records, err := loadRepeatableRecords(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range repeatables {
+		status := MigrationStatus{Version: repeatablePrefix + migration.name, State: StatePending}
+		if record, ok := records[migration.name]; ok {
+			status.State = StateApplied
+			if record.checksum != migration.checksum {
+				status.State = StateChanged
+			}
+			status.AppliedAt = &record.appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Version returns the latest applied migration and whether it is dirty, or
+// an empty version for a database no migration was applied to.
+func Version(db *sql.DB) (string, bool, error) {
+	applied, err := loadAppliedAt(context.Background(), db)
+	if err != nil {
+		return "", false, err
+	}
+
+	latest := ""
+	for version := range applied {
+		if latest == "" || migrationLess(latest, version) {
+			latest = version
+		}
+	}
+
+	return latest, applied[latest].dirty, nil
+}
+
+// loadAppliedAt returns the applied versions with their timestamps and dirty
+// flags. A database the migrator never ran against has none.
+func loadAppliedAt(ctx context.Context, db *sql.DB) (map[string]appliedMigration, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	var exists bool
+	if err := db.QueryRowContext(queryCtx, "SELECT to_regclass($1) IS NOT NULL", migrationsTable).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up migrations table: %w", err)
+	}
+
+	applied := make(map[string]appliedMigration)
+	if !exists {
+		return applied, nil
+	}
+
+	// Status does not alter the table, so the dirty column is read through
+	// to_jsonb to also cope with tables from before it was added.
+	rows, err := db.QueryContext(queryCtx, "SELECT version, applied_at, COALESCE((to_jsonb(m) ->> 'dirty')::boolean, FALSE) FROM "+migrationsTable+" m")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			version string
+			record  appliedMigration
+		)
+		if err := rows.Scan(&version, &record.appliedAt, &record.dirty); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+
+		applied[version] = record
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,29 @@
+package notifications
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+const (
+	ProviderSMTP = "smtp"
+	ProviderLog  = "log"
+)
+
+func NewEmailSender(cfg config.EmailConfig, logger *slog.Logger) (Sender, error) {
+	const op = "notifications.NewEmailSender"
+
+	var sender Sender
+	switch cfg.Provider {
+	case ProviderSMTP:
+		sender = NewSMTPSender(cfg.SMTP, cfg.From)
+	case ProviderLog:
+		sender = NewLogSender(logger)
+	default:
+		return nil, fmt.Errorf("%s: unknown email provider %q", op, cfg.Provider)
+	}
+
+	return NewRetrySender(sender, cfg.Retry.Attempts, cfg.Retry.Backoff), nil
+}
@@ -0,0 +1,24 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+)
+
+type LogSender struct {
+	logger *slog.Logger
+}
+
+func NewLogSender(logger *slog.Logger) *LogSender {
+	return &LogSender{logger: logger.WithGroup("log_sender")}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	attachments := make([]string, 0, len(msg.Attachments))
+	for _, a := range msg.Attachments {
+		attachments = append(attachments, a.Name)
+	}
+
+	s.logger.InfoContext(ctx, "notification", slog.String("to", msg.To), slog.String("subject", msg.Subject), slog.String("body", msg.Body), slog.Any("attachments", attachments))
+	return nil
+}
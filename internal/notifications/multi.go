@@ -0,0 +1,36 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+)
+
+// MultiSender delivers a message through every sender, e.g. all channels a
+// user can be reached on by user ID. It succeeds when at least one sender
+// does and otherwise returns the joined errors.
+type MultiSender struct {
+	senders []Sender
+}
+
+func NewMultiSender(senders ...Sender) *MultiSender {
+	return &MultiSender{senders: senders}
+}
+
+func (m *MultiSender) Send(ctx context.Context, msg Message) error {
+	if len(m.senders) == 0 {
+		return ErrPermanent
+	}
+
+	var errs []error
+	for _, sender := range m.senders {
+		if err := sender.Send(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == len(m.senders) {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var (
+	ErrPermanent = errors.New("permanent delivery failure")
+	ErrTemporary = errors.New("temporary delivery failure")
+	// ErrNoRecipient means the user has no address on the sender's channel,
+	// so nothing was sent. It is returned together with ErrPermanent.
+	ErrNoRecipient = errors.New("recipient is not reachable on this channel")
+)
+
+type Message struct {
+	To          string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Notifier struct {
+	sender    Sender
+	templates *Templates
+	logger    *slog.Logger
+}
+
+func New(sender Sender, templates *Templates, logger *slog.Logger) *Notifier {
+	return &Notifier{sender: sender, templates: templates, logger: logger.WithGroup("notifications")}
+}
+
+func (n *Notifier) SendPriceIncrease(ctx context.Context, to string, data PriceIncrease) error {
+	return n.send(ctx, to, KindPriceIncrease, data)
+}
+
+func (n *Notifier) SendBudgetAlert(ctx context.Context, to string, data BudgetAlert) error {
+	return n.send(ctx, to, KindBudgetAlert, data)
+}
+
+func (n *Notifier) send(ctx context.Context, to string, kind Kind, data any) error {
+	const op = "notifications.Notifier.send"
+
+	subject, body, err := n.templates.Render(kind, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n.logger.DebugContext(ctx, "sending notification", slog.String("kind", string(kind)), slog.String("to", to))
+	if err := n.sender.Send(ctx, Message{To: to, Subject: subject, Body: body}); err != nil {
+		n.logger.ErrorContext(ctx, "failed to send notification", slog.String("kind", string(kind)), slog.String("to", to), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"time"
+)
+
+type RetrySender struct {
+	next     Sender
+	attempts int
+	backoff  time.Duration
+}
+
+func NewRetrySender(next Sender, attempts int, backoff time.Duration) *RetrySender {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	return &RetrySender{next: next, attempts: attempts, backoff: backoff}
+}
+
+func (r *RetrySender) Send(ctx context.Context, msg Message) error {
+	var err error
+	delay := r.backoff
+
+	for attempt := 1; attempt <= r.attempts; attempt++ {
+		err = r.next.Send(ctx, msg)
+		if err == nil || !isTransient(err) || attempt == r.attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+
+	return err
+}
+
+func isTransient(err error) bool {
+	if errors.Is(err, ErrPermanent) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, ErrTemporary) {
+		return true
+	}
+
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 400 && protoErr.Code < 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
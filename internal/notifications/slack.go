@@ -0,0 +1,68 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+// SlackSender posts to Slack-compatible incoming webhooks (Slack, Mattermost).
+// Message.To is the user ID used to pick the user's webhook; users without
+// one are not sent anything.
+type SlackSender struct {
+	cfg        config.SlackConfig
+	httpClient *http.Client
+}
+
+func NewSlackSender(cfg config.SlackConfig) *SlackSender {
+	return &SlackSender{cfg: cfg, httpClient: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (s *SlackSender) Send(ctx context.Context, msg Message) error {
+	const op = "notifications.SlackSender.Send"
+
+	url := s.cfg.UserWebhooks[msg.To]
+	if url == "" {
+		return fmt.Errorf("%s: %w: %w: no webhook configured for %q", op, ErrPermanent, ErrNoRecipient, msg.To)
+	}
+
+	payload := struct {
+		Text     string `json:"text"`
+		Username string `json:"username,omitempty"`
+	}{
+		Text:     "*" + msg.Subject + "*\n" + msg.Body,
+		Username: s.cfg.Username,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", op, ErrPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%s: %w: webhook returned %d", op, ErrTemporary, resp.StatusCode)
+	default:
+		return fmt.Errorf("%s: %w: webhook returned %d", op, ErrPermanent, resp.StatusCode)
+	}
+}
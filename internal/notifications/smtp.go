@@ -0,0 +1,155 @@
+package notifications
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+type SMTPSender struct {
+	cfg  config.SMTPConfig
+	from string
+}
+
+func NewSMTPSender(cfg config.SMTPConfig, from string) *SMTPSender {
+	return &SMTPSender{cfg: cfg, from: from}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	const op = "notifications.SMTPSender.Send"
+
+	// Addresses are parsed rather than written into the headers as given, so
+	// a recipient cannot smuggle in headers of its own.
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("%s: %w: invalid sender %q: %w", op, ErrPermanent, s.from, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("%s: %w: invalid recipient %q: %w", op, ErrPermanent, msg.To, err)
+	}
+
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer client.Close()
+
+	if s.cfg.StartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if s.cfg.Username != "" {
+		auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := w.Write(buildMessage(from, to, msg)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return client.Quit()
+}
+
+func buildMessage(from, to *mail.Address, msg Message) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if len(msg.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+		return []byte(b.String())
+	}
+
+	boundary := newBoundary()
+	b.WriteString("Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n")
+	b.WriteString("\r\n")
+
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n") + "\r\n")
+
+	for _, a := range msg.Attachments {
+		b.WriteString("--" + boundary + "\r\n")
+		b.WriteString("Content-Type: " + a.ContentType + "\r\n")
+		b.WriteString("Content-Transfer-Encoding: base64\r\n")
+		b.WriteString("Content-Disposition: " + mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}) + "\r\n")
+		b.WriteString("\r\n")
+
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\r\n")
+	}
+
+	b.WriteString("--" + boundary + "--\r\n")
+
+	return []byte(b.String())
+}
+
+func newBoundary() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
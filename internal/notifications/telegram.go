@@ -0,0 +1,57 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Kulibyka/effective-mobile/internal/domain/notification"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/telegram"
+)
+
+type ChatResolver interface {
+	GetTelegramChatID(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// TelegramSender delivers messages to the chat linked with a user, so
+// Message.To carries the user ID rather than an address.
+type TelegramSender struct {
+	client *telegram.Client
+	chats  ChatResolver
+}
+
+func NewTelegramSender(client *telegram.Client, chats ChatResolver) *TelegramSender {
+	return &TelegramSender{client: client, chats: chats}
+}
+
+func (s *TelegramSender) Send(ctx context.Context, msg Message) error {
+	const op = "notifications.TelegramSender.Send"
+
+	userID, err := uuid.Parse(msg.To)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", op, ErrPermanent, err)
+	}
+
+	chatID, err := s.chats.GetTelegramChatID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, notification.ErrChatNotFound) {
+			return fmt.Errorf("%s: %w: %w: %w", op, ErrPermanent, ErrNoRecipient, err)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.client.SendMessage(ctx, chatID, msg.Subject+"\n\n"+msg.Body); err != nil {
+		var apiErr *telegram.APIError
+		if errors.As(err, &apiErr) {
+			if apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError {
+				return fmt.Errorf("%s: %w: %w", op, ErrTemporary, err)
+			}
+			return fmt.Errorf("%s: %w: %w", op, ErrPermanent, err)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindPriceIncrease Kind = "price_increase"
+	KindBudgetAlert   Kind = "budget_alert"
+)
+
+type PriceIncrease struct {
+	ServiceName    string
+	OldPrice       int
+	NewPrice       int
+	EffectiveMonth time.Time
+}
+
+type BudgetAlert struct {
+	Category string
+	Limit    int
+	Spent    int
+	Month    time.Time
+}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+type Templates struct {
+	set *template.Template
+}
+
+func NewTemplates() (*Templates, error) {
+	const op = "notifications.NewTemplates"
+
+	set, err := template.New("").Funcs(template.FuncMap{
+		"month": func(t time.Time) string { return t.Format("01-2006") },
+	}).ParseFS(templateFS, "templates/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Templates{set: set}, nil
+}
+
+func (t *Templates) Render(kind Kind, data any) (string, string, error) {
+	subject, err := t.execute(string(kind)+"_subject", data)
+	if err != nil {
+		return "", "", err
+	}
+
+	body, err := t.execute(string(kind)+"_body", data)
+	if err != nil {
+		return "", "", err
+	}
+
+	return subject, body, nil
+}
+
+func (t *Templates) execute(name string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.set.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("render template %s: %w", name, err)
+	}
+
+	return buf.String(), nil
+}
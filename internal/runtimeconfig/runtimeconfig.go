@@ -0,0 +1,270 @@
+// Package runtimeconfig holds the settings that can change while the server
+// runs. Each request sees one snapshot, swapped atomically on reload.
+package runtimeconfig
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+)
+
+type ctxKey struct{}
+
+// Loader rereads the config; the runtime section of the result replaces the
+// current snapshot.
+type Loader func() (*config.Config, error)
+
+type Store struct {
+	current atomic.Pointer[config.RuntimeConfig]
+	env     string
+	level   *slog.LevelVar
+	load    Loader
+	logger  *slog.Logger
+
+	// override is a log level set at runtime, kept across reloads until
+	// it expires or is reset.
+	mu       sync.Mutex
+	override *LevelOverride
+	revert   *time.Timer
+}
+
+// LevelOverride is a log level set with OverrideLogLevel. Until is zero when
+// it lasts until reset.
+type LevelOverride struct {
+	Level slog.Level
+	Until time.Time
+}
+
+// New applies cfg, setting level to its log level, and rereads the config
+// through load on Reload.
+func New(env string, cfg config.RuntimeConfig, level *slog.LevelVar, load Loader, logger *slog.Logger) *Store {
+	s := &Store{env: env, level: level, load: load, logger: logger.WithGroup("runtime_config")}
+	s.apply(cfg)
+
+	return s
+}
+
+// Current returns the snapshot in effect. It must not be modified.
+func (s *Store) Current() *config.RuntimeConfig {
+	return s.current.Load()
+}
+
+// Reload rereads the config and swaps in its runtime section. An invalid
+// config keeps the current snapshot.
+func (s *Store) Reload() error {
+	cfg, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	s.apply(cfg.Runtime)
+	s.logger.Info("runtime config reloaded",
+		slog.String("log_level", s.level.Level().String()),
+		slog.Bool("rate_limit", cfg.Runtime.RateLimit.Enabled),
+		slog.Int("cors_origins", len(cfg.Runtime.CORS.AllowedOrigins)),
+		slog.Bool("body_log", cfg.Runtime.BodyLog.Enabled),
+		slog.Float64("log_sample_rate", cfg.Runtime.LogSampling.SampleRate("")),
+		slog.Int("features", len(cfg.Runtime.Features)),
+	)
+
+	return nil
+}
+
+func (s *Store) apply(cfg config.RuntimeConfig) {
+	s.current.Store(&cfg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.applyLevel()
+}
+
+// applyLevel sets the log level to the override or, without one, to the
+// configured level. s.mu must be held.
+func (s *Store) applyLevel() {
+	if s.override != nil {
+		s.level.Set(s.override.Level)
+		return
+	}
+
+	s.level.Set(s.ConfiguredLogLevel())
+}
+
+// ConfiguredLogLevel is the log level of the current snapshot, in effect
+// when no override is.
+func (s *Store) ConfiguredLogLevel() slog.Level {
+	level, err := logger.ParseLevel(s.env, s.Current().LogLevel)
+	if err != nil {
+		// Validation rejects unknown levels, so this only guards against
+		// configs built without it.
+		return logger.DefaultLevel(s.env)
+	}
+	return level
+}
+
+// LogLevel returns the log level in effect and the override behind it, if
+// any.
+func (s *Store) LogLevel() (slog.Level, *LevelOverride) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.override == nil {
+		return s.level.Level(), nil
+	}
+	override := *s.override
+	return s.level.Level(), &override
+}
+
+// OverrideLogLevel switches the log level regardless of the config, for d or,
+// when d is zero, until ResetLogLevel. Config reloads keep the override.
+func (s *Store) OverrideLogLevel(level slog.Level, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.revert != nil {
+		s.revert.Stop()
+		s.revert = nil
+	}
+
+	override := &LevelOverride{Level: level}
+	if d > 0 {
+		override.Until = time.Now().Add(d)
+		s.revert = time.AfterFunc(d, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			// A later override replaced this one and its timer.
+			if s.override != override {
+				return
+			}
+			s.override, s.revert = nil, nil
+			s.applyLevel()
+			s.logger.Info("log level override expired", slog.String("log_level", s.level.Level().String()))
+		})
+	}
+	s.override = override
+	s.applyLevel()
+
+	s.logger.Info("log level overridden", slog.String("log_level", level.String()), slog.Duration("duration", d))
+}
+
+// ResetLogLevel drops the override and returns to the configured level.
+func (s *Store) ResetLogLevel() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.revert != nil {
+		s.revert.Stop()
+		s.revert = nil
+	}
+	s.override = nil
+	s.applyLevel()
+
+	s.logger.Info("log level reset", slog.String("log_level", s.level.Level().String()))
+}
+
+// Watch starts reloading on SIGHUP and, when the runtime config sets a watch
+// interval, whenever the modification time of path changes, until ctx is
+// cancelled. SIGUSR1 switches to debug logging until SIGUSR2 resets the
+// level. Signals are handled from the moment it returns.
+func (s *Store) Watch(ctx context.Context, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	usr := make(chan os.Signal, 1)
+	signal.Notify(usr, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go s.watch(ctx, path, hup, usr)
+}
+
+func (s *Store) watch(ctx context.Context, path string, hup, usr chan os.Signal) {
+	defer signal.Stop(hup)
+	defer signal.Stop(usr)
+
+	modified := modTime(path)
+
+	// The ticker is rebuilt after each reload so a changed interval takes
+	// effect; a nil channel never fires.
+	var (
+		ticker *time.Ticker
+		tick   <-chan time.Time
+	)
+	resetTicker := func() {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+		if interval := s.Current().WatchInterval; interval > 0 {
+			ticker = time.NewTicker(interval)
+			tick = ticker.C
+		}
+	}
+	resetTicker()
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-usr:
+			if sig == syscall.SIGUSR1 {
+				s.OverrideLogLevel(slog.LevelDebug, 0)
+			} else {
+				s.ResetLogLevel()
+			}
+			continue
+		case <-hup:
+			s.logger.Info("reloading runtime config on SIGHUP")
+		case <-tick:
+			current := modTime(path)
+			if current.Equal(modified) {
+				continue
+			}
+			s.logger.Info("config file changed, reloading runtime config")
+		}
+
+		modified = modTime(path)
+		if err := s.Reload(); err != nil {
+			s.logger.Error("failed to reload runtime config, keeping the current one", slog.Any("error", err))
+		}
+		resetTicker()
+	}
+}
+
+// Middleware attaches the current snapshot to the request context, so the
+// middleware and handlers after it see the same settings for the whole
+// request.
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, s.Current())))
+	})
+}
+
+// FromContext returns the snapshot attached by Middleware, or an empty one.
+func FromContext(ctx context.Context) *config.RuntimeConfig {
+	if cfg, ok := ctx.Value(ctxKey{}).(*config.RuntimeConfig); ok {
+		return cfg
+	}
+	return &config.RuntimeConfig{}
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
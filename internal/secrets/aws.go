@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/awsv4"
+)
+
+// awsSecretsManager reads secrets from AWS Secrets Manager by name or ARN,
+// with credentials and region from the standard AWS variables.
+// AWS_ENDPOINT_URL_SECRETS_MANAGER or AWS_ENDPOINT_URL points it elsewhere,
+// e.g. at LocalStack.
+type awsSecretsManager struct{}
+
+func (awsSecretsManager) Fetch(ctx context.Context, id string) (string, error) {
+	creds, ok := awsv4.CredentialsFromEnv()
+	if !ok {
+		return "", errors.New("aws secrets manager: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+
+	region := awsv4.Region()
+	// A full ARN names the region the secret lives in.
+	if parts := strings.Split(id, ":"); len(parts) > 3 && parts[0] == "arn" && parts[3] != "" {
+		region = parts[3]
+	}
+
+	endpoint := awsv4.FirstEnv("AWS_ENDPOINT_URL_SECRETS_MANAGER", "AWS_ENDPOINT_URL")
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	awsv4.Sign(req, "secretsmanager", region, creds, awsv4.PayloadHash(body), time.Now())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("aws secrets manager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("aws secrets manager: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(payload, &apiErr)
+		if apiErr.Type == "" {
+			return "", fmt.Errorf("aws secrets manager: unexpected status %s", resp.Status)
+		}
+		// The type comes as "<namespace>#AccessDeniedException" or bare.
+		_, errType, found := strings.Cut(apiErr.Type, "#")
+		if !found {
+			errType = apiErr.Type
+		}
+		return "", fmt.Errorf("aws secrets manager: %s: %s", errType, apiErr.Message)
+	}
+
+	var secret struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary string  `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(payload, &secret); err != nil {
+		return "", fmt.Errorf("aws secrets manager: %w", err)
+	}
+	if secret.SecretString != nil {
+		return *secret.SecretString, nil
+	}
+
+	value, err := base64.StdEncoding.DecodeString(secret.SecretBinary)
+	if err != nil {
+		return "", fmt.Errorf("aws secrets manager: %w", err)
+	}
+
+	return string(value), nil
+}
@@ -0,0 +1,238 @@
+package secrets
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const gcpScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// gcpSecretManager reads secrets from GCP Secret Manager, given as
+// "<project>/<secret>[/<version>]" or as the full resource name; the version
+// defaults to latest. It authenticates with the service account key named by
+// GOOGLE_APPLICATION_CREDENTIALS or, without it, through the metadata server
+// (GCE_METADATA_HOST overrides its address).
+type gcpSecretManager struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (g *gcpSecretManager) Fetch(ctx context.Context, id string) (string, error) {
+	name, err := gcpResourceName(id)
+	if err != nil {
+		return "", fmt.Errorf("gcp secret manager: %w", err)
+	}
+
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("gcp secret manager: authentication failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://secretmanager.googleapis.com/v1/"+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := gcpDo(req, &resp); err != nil {
+		return "", fmt.Errorf("gcp secret manager: %w", err)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("gcp secret manager: %w", err)
+	}
+
+	return string(value), nil
+}
+
+func gcpResourceName(id string) (string, error) {
+	if strings.HasPrefix(id, "projects/") {
+		if !strings.Contains(id, "/versions/") {
+			id += "/versions/latest"
+		}
+		return id, nil
+	}
+
+	parts := strings.Split(id, "/")
+	switch {
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return "projects/" + parts[0] + "/secrets/" + parts[1] + "/versions/latest", nil
+	case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+		return "projects/" + parts[0] + "/secrets/" + parts[1] + "/versions/" + parts[2], nil
+	default:
+		return "", fmt.Errorf("invalid secret %q, want <project>/<secret>[/<version>]", id)
+	}
+}
+
+func (g *gcpSecretManager) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.token != "" && time.Now().Before(g.expires) {
+		return g.token, nil
+	}
+
+	var (
+		req *http.Request
+		err error
+	)
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		req, err = serviceAccountTokenRequest(ctx, path)
+	} else {
+		host := os.Getenv("GCE_METADATA_HOST")
+		if host == "" {
+			host = "metadata.google.internal"
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+		if err == nil {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := gcpDo(req, &resp); err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("no access token in response")
+	}
+
+	// Renew a minute early so the token does not expire mid-request.
+	g.token = resp.AccessToken
+	g.expires = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+
+	return g.token, nil
+}
+
+// serviceAccountTokenRequest builds the OAuth 2.0 JWT bearer grant for the
+// service account key file at path.
+func serviceAccountTokenRequest(ctx context.Context, path string) (*http.Request, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(contents, &key); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if key.Type != "service_account" {
+		return nil, fmt.Errorf("%s: unsupported credentials type %q, only service_account keys are", path, key.Type)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("%s: invalid private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: private key is not RSA", path)
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   key.ClientEmail,
+		"scope": gcpScope,
+		"aud":   key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	assertion := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req, nil
+}
+
+// gcpDo sends req and decodes a JSON response into out, turning Google API
+// errors into their message.
+func gcpDo(req *http.Request, out any) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"error"`
+			// The OAuth token endpoint reports errors in this form.
+			Description string `json:"error_description"`
+		}
+		_ = json.Unmarshal(payload, &apiErr)
+		switch {
+		case apiErr.Error.Message != "":
+			return fmt.Errorf("%s: %s", apiErr.Error.Status, apiErr.Error.Message)
+		case apiErr.Description != "":
+			return errors.New(apiErr.Description)
+		default:
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+	}
+
+	return json.Unmarshal(payload, out)
+}
@@ -0,0 +1,170 @@
+package secrets
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGCPResourceName(t *testing.T) {
+	tests := []struct {
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{id: "proj/db", want: "projects/proj/secrets/db/versions/latest"},
+		{id: "proj/db/3", want: "projects/proj/secrets/db/versions/3"},
+		{id: "projects/proj/secrets/db", want: "projects/proj/secrets/db/versions/latest"},
+		{id: "projects/proj/secrets/db/versions/3", want: "projects/proj/secrets/db/versions/3"},
+		{id: "db", wantErr: true},
+		{id: "proj//3", wantErr: true},
+		{id: "a/b/c/d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := gcpResourceName(tt.id)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestServiceAccountTokenRequest checks the JWT bearer grant against RFC 7523:
+// an RS256 signature over the encoded header and claims that verifies with
+// the service account's public key.
+func TestServiceAccountTokenRequest(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "app@proj.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	})
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(path, credentials, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := serviceAccountTokenRequest(t.Context(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.String() != "https://oauth2.googleapis.com/token" {
+		t.Fatalf("unexpected token uri %s", req.URL)
+	}
+	if err := req.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	if grant := req.PostForm.Get("grant_type"); grant != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		t.Fatalf("unexpected grant type %q", grant)
+	}
+
+	parts := strings.Split(req.PostForm.Get("assertion"), ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a three-part JWT, got %d parts", len(parts))
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+
+	var header map[string]string
+	decodeSegment(t, parts[0], &header)
+	if header["alg"] != "RS256" || header["typ"] != "JWT" {
+		t.Fatalf("unexpected header %v", header)
+	}
+
+	var claims struct {
+		Iss   string `json:"iss"`
+		Scope string `json:"scope"`
+		Aud   string `json:"aud"`
+		Iat   int64  `json:"iat"`
+		Exp   int64  `json:"exp"`
+	}
+	decodeSegment(t, parts[1], &claims)
+	if claims.Iss != "app@proj.iam.gserviceaccount.com" || claims.Scope != gcpScope || claims.Aud != "https://oauth2.googleapis.com/token" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if claims.Exp-claims.Iat != 3600 {
+		t.Fatalf("expected a one-hour assertion, got %ds", claims.Exp-claims.Iat)
+	}
+}
+
+func TestGCPAccessTokenFromMetadata(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"token-1","expires_in":3600}`))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	g := &gcpSecretManager{}
+	for range 2 {
+		token, err := g.accessToken(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "token-1" {
+			t.Fatalf("unexpected token %q", token)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected the token to be reused, got %d requests", requests)
+	}
+}
+
+func decodeSegment(t *testing.T, segment string, out any) {
+	t.Helper()
+
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+}
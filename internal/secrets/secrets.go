@@ -0,0 +1,123 @@
+// Package secrets resolves references to secrets kept in an external secret
+// manager, written as "<scheme>://<secret id>[#<json key>]". With a fragment
+// the secret is parsed as a JSON object and the named string field is used.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheTTL is how long a fetched secret is reused. Fields sharing a secret
+// and reloads within it do not fetch it again; a later reload picks up a
+// rotated value.
+const cacheTTL = time.Minute
+
+// Provider reads the current value of a secret from one secret manager.
+type Provider interface {
+	Fetch(ctx context.Context, id string) (string, error)
+}
+
+var (
+	mu        sync.Mutex
+	providers = map[string]Provider{}
+	cache     = map[string]cachedSecret{}
+
+	now = time.Now
+)
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+func init() {
+	aws := &awsSecretsManager{}
+	Register("sm", aws)
+	Register("awssm", aws)
+	Register("gcpsm", &gcpSecretManager{})
+}
+
+// Register makes references with scheme resolve through p, replacing the
+// provider registered for it before.
+func Register(scheme string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	providers[scheme] = p
+}
+
+// IsReference reports whether value refers to a registered provider.
+func IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return false
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	_, ok = providers[scheme]
+	return ok
+}
+
+// Resolve returns the value ref refers to.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	scheme, rest, ok := strings.Cut(ref, "://")
+	if !ok {
+		return "", fmt.Errorf("invalid secret reference %q", ref)
+	}
+	id, key, _ := strings.Cut(rest, "#")
+	if id == "" {
+		return "", fmt.Errorf("invalid secret reference %q: missing secret id", ref)
+	}
+
+	value, err := fetch(ctx, scheme, id)
+	if err != nil {
+		return "", fmt.Errorf("%s://%s: %w", scheme, id, err)
+	}
+
+	if key == "" {
+		return value, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("%s://%s: secret is not a JSON object, cannot select %q", scheme, id, key)
+	}
+	field, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("%s://%s: no string field %q in secret", scheme, id, key)
+	}
+
+	return field, nil
+}
+
+func fetch(ctx context.Context, scheme, id string) (string, error) {
+	mu.Lock()
+	p, ok := providers[scheme]
+	entry, cached := cache[scheme+"://"+id]
+	mu.Unlock()
+
+	if !ok {
+		return "", fmt.Errorf("no secret provider for %q", scheme)
+	}
+	if cached && now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err := p.Fetch(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	mu.Lock()
+	cache[scheme+"://"+id] = cachedSecret{value: value, expires: now().Add(cacheTTL)}
+	mu.Unlock()
+
+	return value, nil
+}
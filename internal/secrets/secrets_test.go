@@ -0,0 +1,88 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// countingProvider returns value and counts how often it is asked for it.
+type countingProvider struct {
+	value   string
+	fetches int
+}
+
+func (p *countingProvider) Fetch(context.Context, string) (string, error) {
+	p.fetches++
+	return p.value, nil
+}
+
+func TestResolveRefetchesAfterTTL(t *testing.T) {
+	current := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+
+	p := &countingProvider{value: "old"}
+	Register("test-ttl", p)
+
+	for range 2 {
+		value, err := Resolve(t.Context(), "test-ttl://db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != "old" {
+			t.Fatalf("expected %q, got %q", "old", value)
+		}
+	}
+	if p.fetches != 1 {
+		t.Fatalf("expected 1 fetch within the TTL, got %d", p.fetches)
+	}
+
+	p.value = "rotated"
+	current = current.Add(cacheTTL)
+
+	value, err := Resolve(t.Context(), "test-ttl://db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "rotated" {
+		t.Fatalf("expected the rotated value after the TTL, got %q", value)
+	}
+	if p.fetches != 2 {
+		t.Fatalf("expected 2 fetches, got %d", p.fetches)
+	}
+}
+
+func TestResolveJSONKey(t *testing.T) {
+	Register("test-json", &countingProvider{value: `{"user":"app","port":5432}`})
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "test-json://creds#user", want: "app"},
+		{ref: "test-json://creds#port", wantErr: true},
+		{ref: "test-json://creds#missing", wantErr: true},
+		{ref: "test-json://#user", wantErr: true},
+		{ref: "unknown://creds", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			value, err := Resolve(t.Context(), tt.ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, value)
+			}
+		})
+	}
+}
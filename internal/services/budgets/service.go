@@ -0,0 +1,237 @@
+package budgets
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/audit"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/category"
+	subdomain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+	"github.com/Kulibyka/effective-mobile/internal/notifications"
+)
+
+const (
+	logGroup      = "budgets_service"
+	auditResource = "category_budget"
+)
+
+type Repository interface {
+	SetBudget(ctx context.Context, input domain.BudgetInput) (domain.Budget, error)
+	ListBudgets(ctx context.Context, userID uuid.UUID) ([]domain.Budget, error)
+	DeleteBudget(ctx context.Context, userID, categoryID uuid.UUID) error
+	MarkBudgetAlerted(ctx context.Context, userID, categoryID uuid.UUID, at time.Time) error
+}
+
+// Spender splits what users spend by category.
+type Spender interface {
+	CategoryTotals(ctx context.Context, input subdomain.SummaryFilter) ([]subdomain.CategoryTotal, error)
+}
+
+type Notifier interface {
+	SendBudgetAlert(ctx context.Context, to string, data notifications.BudgetAlert) error
+}
+
+// Service keeps the monthly category budgets of users and warns them when
+// their spend on a category goes over its budget.
+type Service struct {
+	repo     Repository
+	spender  Spender
+	notifier Notifier
+	audit    *audit.Logger
+	logger   *slog.Logger
+}
+
+// New returns the service; notifier is only used by Alert and may be nil when
+// nothing calls it.
+func New(repo Repository, spender Spender, notifier Notifier, auditLog *audit.Logger, logger *slog.Logger) *Service {
+	return &Service{repo: repo, spender: spender, notifier: notifier, audit: auditLog, logger: logger.WithGroup(logGroup)}
+}
+
+func (s *Service) Set(ctx context.Context, input domain.BudgetInput) (domain.Budget, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "setting budget", slog.String("user_id", input.UserID.String()), slog.String("category_id", input.CategoryID.String()), slog.Int("monthly_limit", input.MonthlyLimit))
+
+	if err := s.checkAccess(ctx, input.UserID); err != nil {
+		return domain.Budget{}, err
+	}
+
+	var before audit.Fields
+	if s.audit.Enabled() {
+		if current, err := s.find(ctx, input.UserID, input.CategoryID); err == nil {
+			before = auditFields(current)
+		}
+	}
+
+	budget, err := s.repo.SetBudget(ctx, input)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to set budget", slog.String("user_id", input.UserID.String()), slog.String("category_id", input.CategoryID.String()), slog.Any("error", err))
+		return domain.Budget{}, err
+	}
+
+	action := audit.ActionUpdate
+	if before == nil {
+		action = audit.ActionCreate
+	}
+	s.audit.Record(ctx, action, auditResource, auditID(budget.UserID, budget.CategoryID), audit.Changes(before, auditFields(budget))...)
+
+	return budget, nil
+}
+
+// Statuses returns the budgets of the user with what the user spends on each
+// category in month.
+func (s *Service) Statuses(ctx context.Context, userID uuid.UUID, month time.Time) ([]domain.BudgetStatus, error) {
+	if err := s.checkAccess(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	budgets, err := s.repo.ListBudgets(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list budgets", slog.String("user_id", userID.String()), slog.Any("error", err))
+		return nil, err
+	}
+
+	return s.statuses(ctx, userID, budgets, month)
+}
+
+func (s *Service) Delete(ctx context.Context, userID, categoryID uuid.UUID) error {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "deleting budget", slog.String("user_id", userID.String()), slog.String("category_id", categoryID.String()))
+
+	if err := s.checkAccess(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteBudget(ctx, userID, categoryID); err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to delete budget", slog.String("user_id", userID.String()), slog.String("category_id", categoryID.String()), slog.Any("error", err))
+		return err
+	}
+
+	s.audit.Record(ctx, audit.ActionDelete, auditResource, auditID(userID, categoryID))
+
+	return nil
+}
+
+// Alert warns every user over a budget in the month of now, once a month
+// per budget, and returns the number of alerts sent. An alert that cannot be
+// delivered for good, such as to a user without an address, is not retried
+// on later runs either.
+func (s *Service) Alert(ctx context.Context, now time.Time) (int, error) {
+	now = now.UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	budgets, err := s.repo.ListBudgets(ctx, uuid.Nil)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list budgets", slog.Any("error", err))
+		return 0, err
+	}
+
+	sent := 0
+	// Budgets come ordered by user, so each user's spend is summed up once.
+	for start := 0; start < len(budgets); {
+		userID := budgets[start].UserID
+		end := start + 1
+		for end < len(budgets) && budgets[end].UserID == userID {
+			end++
+		}
+
+		statuses, err := s.statuses(ctx, userID, budgets[start:end], month)
+		if err != nil {
+			return sent, err
+		}
+		start = end
+
+		for _, status := range statuses {
+			if !status.Exceeded() || alertedIn(status.Budget, month) {
+				continue
+			}
+
+			err := s.notifier.SendBudgetAlert(ctx, userID.String(), notifications.BudgetAlert{
+				Category: status.Category,
+				Limit:    status.MonthlyLimit,
+				Spent:    status.Spent,
+				Month:    month,
+			})
+			if err != nil {
+				logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "failed to send budget alert", slog.String("user_id", userID.String()), slog.String("category_id", status.CategoryID.String()), slog.Any("error", err))
+				if !errors.Is(err, notifications.ErrPermanent) {
+					continue
+				}
+			} else {
+				sent++
+			}
+
+			if err := s.repo.MarkBudgetAlerted(ctx, userID, status.CategoryID, now); err != nil {
+				logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to mark budget alerted", slog.String("user_id", userID.String()), slog.String("category_id", status.CategoryID.String()), slog.Any("error", err))
+				return sent, err
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+func (s *Service) statuses(ctx context.Context, userID uuid.UUID, budgets []domain.Budget, month time.Time) ([]domain.BudgetStatus, error) {
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+
+	totals, err := s.spender.CategoryTotals(ctx, subdomain.SummaryFilter{UserID: userID, PeriodStart: month, PeriodEnd: month})
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to sum up category spend", slog.String("user_id", userID.String()), slog.Any("error", err))
+		return nil, err
+	}
+
+	spent := make(map[uuid.UUID]int, len(totals))
+	for _, total := range totals {
+		if total.CategoryID != nil {
+			spent[*total.CategoryID] = total.Total
+		}
+	}
+
+	result := make([]domain.BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		result = append(result, domain.BudgetStatus{Budget: budget, Spent: spent[budget.CategoryID]})
+	}
+
+	return result, nil
+}
+
+func (s *Service) find(ctx context.Context, userID, categoryID uuid.UUID) (domain.Budget, error) {
+	budgets, err := s.repo.ListBudgets(ctx, userID)
+	if err != nil {
+		return domain.Budget{}, err
+	}
+
+	for _, budget := range budgets {
+		if budget.CategoryID == categoryID {
+			return budget, nil
+		}
+	}
+	return domain.Budget{}, domain.ErrBudgetNotFound
+}
+
+func (s *Service) checkAccess(ctx context.Context, userID uuid.UUID) error {
+	if scope, ok := access.FromContext(ctx); ok && !scope.AllowsUser(userID) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "user outside of api key scope", slog.String("api_key", scope.Name), slog.String("user_id", userID.String()))
+		return access.ErrForbidden
+	}
+	return nil
+}
+
+// alertedIn reports whether the user was already warned about budget in
+// month.
+func alertedIn(budget domain.Budget, month time.Time) bool {
+	return budget.AlertedAt != nil && subdomain.MonthKeyOf(budget.AlertedAt.UTC()) >= subdomain.MonthKeyOf(month)
+}
+
+func auditID(userID, categoryID uuid.UUID) string {
+	return userID.String() + "/" + categoryID.String()
+}
+
+// auditFields is the audited state of a budget.
+func auditFields(budget domain.Budget) audit.Fields {
+	return audit.Fields{"category": budget.Category, "monthly_limit": budget.MonthlyLimit}
+}
@@ -0,0 +1,114 @@
+package budgets
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/category"
+	subdomain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/notifications"
+)
+
+type fakeRepo struct {
+	budgets []domain.Budget
+}
+
+func (r *fakeRepo) SetBudget(context.Context, domain.BudgetInput) (domain.Budget, error) {
+	panic("not used")
+}
+
+func (r *fakeRepo) ListBudgets(_ context.Context, userID uuid.UUID) ([]domain.Budget, error) {
+	if !userID.IsNil() {
+		panic("not used")
+	}
+	return r.budgets, nil
+}
+
+func (r *fakeRepo) DeleteBudget(context.Context, uuid.UUID, uuid.UUID) error {
+	panic("not used")
+}
+
+func (r *fakeRepo) MarkBudgetAlerted(_ context.Context, userID, categoryID uuid.UUID, at time.Time) error {
+	for i := range r.budgets {
+		if r.budgets[i].UserID == userID && r.budgets[i].CategoryID == categoryID {
+			r.budgets[i].AlertedAt = &at
+		}
+	}
+	return nil
+}
+
+type fakeSpender map[uuid.UUID][]subdomain.CategoryTotal
+
+func (s fakeSpender) CategoryTotals(_ context.Context, input subdomain.SummaryFilter) ([]subdomain.CategoryTotal, error) {
+	return s[input.UserID], nil
+}
+
+// fakeNotifier fails the deliveries to the users in errs.
+type fakeNotifier struct {
+	sent []notifications.BudgetAlert
+	errs map[string]error
+}
+
+func (n *fakeNotifier) SendBudgetAlert(_ context.Context, to string, data notifications.BudgetAlert) error {
+	if err := n.errs[to]; err != nil {
+		return err
+	}
+	n.sent = append(n.sent, data)
+	return nil
+}
+
+func TestAlert(t *testing.T) {
+	streaming, cloud := uuid.New(), uuid.New()
+	over, under, unreachable, flaky := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	repo := &fakeRepo{budgets: []domain.Budget{
+		{UserID: over, CategoryID: streaming, Category: "streaming", MonthlyLimit: 500},
+		{UserID: over, CategoryID: cloud, Category: "cloud", MonthlyLimit: 1000},
+		{UserID: under, CategoryID: streaming, Category: "streaming", MonthlyLimit: 500},
+		{UserID: unreachable, CategoryID: streaming, Category: "streaming", MonthlyLimit: 0},
+		{UserID: flaky, CategoryID: streaming, Category: "streaming", MonthlyLimit: 0},
+	}}
+	spender := fakeSpender{
+		over:        {{CategoryID: &streaming, Category: "streaming", Total: 700}, {CategoryID: &cloud, Category: "cloud", Total: 300}},
+		under:       {{CategoryID: &streaming, Category: "streaming", Total: 500}},
+		unreachable: {{CategoryID: &streaming, Category: "streaming", Total: 100}},
+		flaky:       {{CategoryID: &streaming, Category: "streaming", Total: 100}},
+	}
+	notifier := &fakeNotifier{errs: map[string]error{
+		unreachable.String(): fmt.Errorf("%w: %w", notifications.ErrPermanent, notifications.ErrNoRecipient),
+		flaky.String():       notifications.ErrTemporary,
+	}}
+
+	s := New(repo, spender, notifier, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Date(2025, time.July, 15, 10, 0, 0, 0, time.UTC)
+
+	sent, err := s.Alert(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 || len(notifier.sent) != 1 {
+		t.Fatalf("sent %d alerts %+v, want 1", sent, notifier.sent)
+	}
+	want := notifications.BudgetAlert{Category: "streaming", Limit: 500, Spent: 700, Month: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)}
+	if notifier.sent[0] != want {
+		t.Fatalf("alert = %+v, want %+v", notifier.sent[0], want)
+	}
+
+	// The permanent failure is not retried, the temporary one is; the
+	// delivered alert is not repeated within the month.
+	delete(notifier.errs, flaky.String())
+	if sent, err := s.Alert(context.Background(), now.Add(time.Hour)); err != nil || sent != 1 {
+		t.Fatalf("second run sent %d, %v, want 1 for the temporary failure", sent, err)
+	}
+
+	// A new month brings new alerts for budgets still exceeded.
+	delete(notifier.errs, unreachable.String())
+	if sent, err := s.Alert(context.Background(), now.AddDate(0, 1, 0)); err != nil || sent != 3 {
+		t.Fatalf("next month sent %d, %v, want 3", sent, err)
+	}
+}
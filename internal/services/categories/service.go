@@ -0,0 +1,128 @@
+package categories
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/audit"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/category"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+)
+
+const logGroup = "categories_service"
+
+type Repository interface {
+	CreateCategory(ctx context.Context, input domain.Input) (domain.Category, error)
+	GetCategory(ctx context.Context, id uuid.UUID) (domain.Category, error)
+	ListCategories(ctx context.Context) ([]domain.Category, error)
+	UpdateCategory(ctx context.Context, id uuid.UUID, input domain.Input) (domain.Category, error)
+	DeleteCategory(ctx context.Context, id uuid.UUID) error
+}
+
+type Service struct {
+	repo   Repository
+	audit  *audit.Logger
+	logger *slog.Logger
+}
+
+func New(repo Repository, auditLog *audit.Logger, logger *slog.Logger) *Service {
+	return &Service{repo: repo, audit: auditLog, logger: logger.WithGroup(logGroup)}
+}
+
+func (s *Service) Create(ctx context.Context, input domain.Input) (domain.Category, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "creating category", slog.String("name", input.Name))
+
+	if err := checkWriteAccess(ctx); err != nil {
+		return domain.Category{}, err
+	}
+
+	cat, err := s.repo.CreateCategory(ctx, input)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to create category", slog.String("name", input.Name), slog.Any("error", err))
+		return domain.Category{}, err
+	}
+
+	s.audit.Record(ctx, audit.ActionCreate, auditResource, cat.ID.String(), audit.Changes(nil, auditFields(cat))...)
+
+	return cat, nil
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (domain.Category, error) {
+	cat, err := s.repo.GetCategory(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to get category", slog.String("category_id", id.String()), slog.Any("error", err))
+		return domain.Category{}, err
+	}
+
+	return cat, nil
+}
+
+func (s *Service) List(ctx context.Context) ([]domain.Category, error) {
+	cats, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list categories", slog.Any("error", err))
+		return nil, err
+	}
+
+	return cats, nil
+}
+
+func (s *Service) Update(ctx context.Context, id uuid.UUID, input domain.Input) (domain.Category, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "updating category", slog.String("category_id", id.String()))
+
+	if err := checkWriteAccess(ctx); err != nil {
+		return domain.Category{}, err
+	}
+
+	var before audit.Fields
+	if s.audit.Enabled() {
+		if current, err := s.repo.GetCategory(ctx, id); err == nil {
+			before = auditFields(current)
+		}
+	}
+
+	cat, err := s.repo.UpdateCategory(ctx, id, input)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to update category", slog.String("category_id", id.String()), slog.Any("error", err))
+		return domain.Category{}, err
+	}
+
+	s.audit.Record(ctx, audit.ActionUpdate, auditResource, cat.ID.String(), audit.Changes(before, auditFields(cat))...)
+
+	return cat, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "deleting category", slog.String("category_id", id.String()))
+
+	if err := checkWriteAccess(ctx); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteCategory(ctx, id); err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to delete category", slog.String("category_id", id.String()), slog.Any("error", err))
+		return err
+	}
+
+	s.audit.Record(ctx, audit.ActionDelete, auditResource, id.String())
+
+	return nil
+}
+
+// checkWriteAccess keeps API keys restricted to some users or services from
+// changing the shared taxonomy.
+func checkWriteAccess(ctx context.Context) error {
+	if scope, ok := access.FromContext(ctx); ok && scope.Restricted() {
+		return access.ErrForbidden
+	}
+	return nil
+}
+
+const auditResource = "category"
+
+// auditFields is the audited state of a category.
+func auditFields(cat domain.Category) audit.Fields {
+	return audit.Fields{"name": cat.Name, "services": cat.Services}
+}
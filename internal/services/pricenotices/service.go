@@ -0,0 +1,91 @@
+package pricenotices
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+	"github.com/Kulibyka/effective-mobile/internal/notifications"
+)
+
+const logGroup = "price_notices_service"
+
+type Repository interface {
+	PendingPriceChanges(ctx context.Context, through domain.MonthKey) ([]domain.PriceChange, error)
+	PriceIncreaseRecipients(ctx context.Context, change domain.PriceChange) ([]domain.Subscription, error)
+	MarkPriceChangeNotified(ctx context.Context, id int64) error
+}
+
+type Notifier interface {
+	SendPriceIncrease(ctx context.Context, to string, data notifications.PriceIncrease) error
+}
+
+// Service warns subscription owners about scheduled price increases once the
+// effective month is within the configured lead time.
+type Service struct {
+	repo     Repository
+	notifier Notifier
+	lead     time.Duration
+	logger   *slog.Logger
+}
+
+func New(repo Repository, notifier Notifier, lead time.Duration, logger *slog.Logger) *Service {
+	return &Service{repo: repo, notifier: notifier, lead: lead, logger: logger.WithGroup(logGroup)}
+}
+
+// NotifyUpcoming sends the notices for every scheduled change starting within
+// the lead time of now that has not been announced yet, and returns the
+// number of notices sent. Owners get one notice per change however many of
+// their subscriptions it affects; owners that cannot be reached are skipped.
+func (s *Service) NotifyUpcoming(ctx context.Context, now time.Time) (int, error) {
+	changes, err := s.repo.PendingPriceChanges(ctx, domain.MonthKeyOf(now.UTC().Add(s.lead)))
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list upcoming price changes", slog.Any("error", err))
+		return 0, err
+	}
+
+	sent := 0
+	for _, change := range changes {
+		if change.NotifiedAt != nil {
+			continue
+		}
+
+		subs, err := s.repo.PriceIncreaseRecipients(ctx, change)
+		if err != nil {
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list price increase recipients", slog.Int64("price_change_id", change.ID), slog.Any("error", err))
+			return sent, err
+		}
+
+		notified := make(map[uuid.UUID]struct{}, len(subs))
+		for _, sub := range subs {
+			if _, ok := notified[sub.UserID]; ok {
+				continue
+			}
+			notified[sub.UserID] = struct{}{}
+
+			err := s.notifier.SendPriceIncrease(ctx, sub.UserID.String(), notifications.PriceIncrease{
+				ServiceName:    change.ServiceName,
+				OldPrice:       sub.Price,
+				NewPrice:       change.Price,
+				EffectiveMonth: change.EffectiveMonth,
+			})
+			if err != nil {
+				logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "failed to notify about price increase", slog.String("user_id", sub.UserID.String()), slog.Int64("price_change_id", change.ID), slog.Any("error", err))
+				continue
+			}
+			sent++
+		}
+
+		if err := s.repo.MarkPriceChangeNotified(ctx, change.ID); err != nil {
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to mark price change notified", slog.Int64("price_change_id", change.ID), slog.Any("error", err))
+			return sent, err
+		}
+
+		logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "price increase announced", slog.Int64("price_change_id", change.ID), slog.Int("recipients", len(notified)))
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,169 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/report"
+	"github.com/Kulibyka/effective-mobile/internal/notifications"
+)
+
+// Delivery sends rendered reports through the channel configured on each
+// report. The email channel is only available when email is configured, the
+// webhook channel only for the allowed hosts.
+type Delivery struct {
+	email        notifications.Sender
+	client       *http.Client
+	directory    string
+	webhookHosts []string
+}
+
+func NewDelivery(cfg config.ReportsConfig, email notifications.Sender) *Delivery {
+	d := &Delivery{
+		email:        email,
+		directory:    cfg.Directory,
+		webhookHosts: cfg.WebhookHosts,
+	}
+
+	d.client = &http.Client{
+		Timeout: cfg.WebhookTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return d.validateWebhook(req.URL)
+		},
+	}
+
+	return d
+}
+
+// Validate checks that target is usable for channel.
+func (d *Delivery) Validate(channel domain.Channel, target string) error {
+	switch channel {
+	case domain.ChannelEmail:
+		if d.email == nil {
+			return errors.New("email delivery is not configured")
+		}
+		if _, err := mail.ParseAddress(target); err != nil {
+			return errors.New("target must be an email address")
+		}
+	case domain.ChannelWebhook:
+		u, err := url.Parse(target)
+		if err != nil {
+			return errors.New("target must be an http(s) url")
+		}
+		return d.validateWebhook(u)
+	case domain.ChannelFile:
+		if !filepath.IsLocal(target) {
+			return errors.New("target must be a relative directory inside the reports directory")
+		}
+	default:
+		return fmt.Errorf("unknown channel %q", channel)
+	}
+
+	return nil
+}
+
+// validateWebhook accepts http(s) URLs whose host is on the allowlist.
+func (d *Delivery) validateWebhook(u *url.URL) error {
+	if len(d.webhookHosts) == 0 {
+		return errors.New("webhook delivery is not configured")
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("target must be an http(s) url")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range d.webhookHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed {
+			return nil
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("webhook host %q is not allowed", host)
+}
+
+func (d *Delivery) Deliver(ctx context.Context, rep domain.Report, file File) error {
+	switch rep.Channel {
+	case domain.ChannelEmail:
+		return d.deliverEmail(ctx, rep, file)
+	case domain.ChannelWebhook:
+		return d.deliverWebhook(ctx, rep, file)
+	case domain.ChannelFile:
+		return d.deliverFile(rep, file)
+	default:
+		return fmt.Errorf("unknown channel %q", rep.Channel)
+	}
+}
+
+func (d *Delivery) deliverEmail(ctx context.Context, rep domain.Report, file File) error {
+	if d.email == nil {
+		return errors.New("email delivery is not configured")
+	}
+
+	return d.email.Send(ctx, notifications.Message{
+		To:      rep.Target,
+		Subject: "Report: " + rep.Name,
+		Body:    fmt.Sprintf("The scheduled report %q is attached as %s.\n", rep.Name, file.Name),
+		Attachments: []notifications.Attachment{{
+			Name:        file.Name,
+			ContentType: file.ContentType,
+			Data:        file.Data,
+		}},
+	})
+}
+
+func (d *Delivery) deliverWebhook(ctx context.Context, rep domain.Report, file File) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rep.Target, bytes.NewReader(file.Data))
+	if err != nil {
+		return err
+	}
+
+	// Reports defined before the allowlist changed are checked again.
+	if err := d.validateWebhook(req.URL); err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", file.ContentType)
+	req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	req.Header.Set("X-Report-ID", rep.ID.String())
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (d *Delivery) deliverFile(rep domain.Report, file File) error {
+	dir := filepath.Join(d.directory, rep.Target)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, file.Name), file.Data, 0o644)
+}
@@ -0,0 +1,44 @@
+package reports
+
+import (
+	"testing"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/report"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	d := NewDelivery(config.ReportsConfig{WebhookHosts: []string{"hooks.example.com", "*.partner.io"}}, nil)
+
+	tests := []struct {
+		target string
+		ok     bool
+	}{
+		{"https://hooks.example.com/reports", true},
+		{"http://HOOKS.example.com:8080/reports", true},
+		{"https://a.partner.io/x", true},
+		{"https://a.b.partner.io/x", true},
+		{"https://partner.io/x", false},
+		{"https://evilpartner.io/x", false},
+		{"https://example.com/x", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://localhost:8081/api/v1/admin/log-level", false},
+		{"ftp://hooks.example.com/x", false},
+		{"hooks.example.com/x", false},
+	}
+
+	for _, tt := range tests {
+		err := d.Validate(domain.ChannelWebhook, tt.target)
+		if (err == nil) != tt.ok {
+			t.Errorf("Validate(%q) error = %v, want ok %v", tt.target, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateWebhookWithoutAllowlist(t *testing.T) {
+	d := NewDelivery(config.ReportsConfig{}, nil)
+
+	if err := d.Validate(domain.ChannelWebhook, "https://hooks.example.com/reports"); err == nil {
+		t.Error("webhook accepted without an allowlist")
+	}
+}
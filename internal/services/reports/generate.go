@@ -0,0 +1,108 @@
+package reports
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/report"
+	subdomain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+// generate builds the report table for the period ending with the month of
+// now, using the same month arithmetic as the summary endpoint.
+func (s *Service) generate(ctx context.Context, rep domain.Report, now time.Time) (domain.Table, error) {
+	periodStart := time.Date(now.Year(), now.Month()-time.Month(rep.PeriodMonths-1), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	startKey := subdomain.MonthKeyOf(periodStart)
+	endKey := subdomain.MonthKeyOf(periodEnd)
+
+	type group struct {
+		subscriptions int
+		total         int
+	}
+	groups := make(map[string]*group)
+	months := make(map[subdomain.MonthKey]int)
+	total, count := 0, 0
+
+	filter := subdomain.ListFilter{
+		UserID:           rep.UserID,
+		ServiceName:      rep.ServiceName,
+		ActivePeriodFrom: &periodStart,
+		ActivePeriodTo:   &periodEnd,
+	}
+
+	err := s.source.ListSubscriptionsFunc(ctx, filter, func(sub subdomain.Subscription) error {
+		overlapStart := max(subdomain.MonthKeyOf(sub.StartMonth), startKey)
+
+		overlapEnd := endKey
+		if sub.EndMonth != nil {
+			overlapEnd = min(subdomain.MonthKeyOf(*sub.EndMonth), endKey)
+		}
+
+		spent := sub.Price * overlapStart.MonthsUntil(overlapEnd)
+		total += spent
+		count++
+
+		for month := overlapStart; month <= overlapEnd; month = month.Next() {
+			months[month] += sub.Price
+		}
+
+		var key string
+		switch rep.Grouping {
+		case domain.GroupingService:
+			key = sub.ServiceName
+		case domain.GroupingUser:
+			key = sub.UserID.String()
+		default:
+			return nil
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{}
+			groups[key] = g
+		}
+		g.subscriptions++
+		g.total += spent
+
+		return nil
+	})
+	if err != nil {
+		return domain.Table{}, err
+	}
+
+	switch rep.Grouping {
+	case domain.GroupingService, domain.GroupingUser:
+		column := "service_name"
+		if rep.Grouping == domain.GroupingUser {
+			column = "user_id"
+		}
+
+		table := domain.Table{Columns: []string{column, "subscriptions", "total"}}
+		for _, key := range slices.Sorted(maps.Keys(groups)) {
+			table.Rows = append(table.Rows, []any{key, groups[key].subscriptions, groups[key].total})
+		}
+		return table, nil
+
+	case domain.GroupingMonth:
+		table := domain.Table{Columns: []string{"month", "total"}}
+		for month := startKey; month <= endKey; month = month.Next() {
+			table.Rows = append(table.Rows, []any{month.Time().Format(subdomain.MonthLayout), months[month]})
+		}
+		return table, nil
+
+	default:
+		return domain.Table{
+			Columns: []string{"period_start", "period_end", "subscriptions", "total"},
+			Rows: [][]any{{
+				periodStart.Format(subdomain.MonthLayout),
+				periodEnd.Format(subdomain.MonthLayout),
+				count,
+				total,
+			}},
+		}, nil
+	}
+}
@@ -0,0 +1,88 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/report"
+)
+
+// File is an encoded report ready for delivery.
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+func render(rep domain.Report, table domain.Table, now time.Time) (File, error) {
+	name := fileName(rep.Name) + "-" + now.Format("20060102-1504") + "." + string(rep.Format)
+
+	switch rep.Format {
+	case domain.FormatCSV:
+		data, err := renderCSV(table)
+		return File{Name: name, ContentType: "text/csv; charset=utf-8", Data: data}, err
+	case domain.FormatJSON:
+		data, err := renderJSON(table)
+		return File{Name: name, ContentType: "application/json", Data: data}, err
+	case domain.FormatXLSX:
+		data, err := renderXLSX(table)
+		return File{Name: name, ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Data: data}, err
+	default:
+		return File{}, fmt.Errorf("unsupported report format %q", rep.Format)
+	}
+}
+
+func renderCSV(table domain.Table) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(table.Columns); err != nil {
+		return nil, err
+	}
+
+	record := make([]string, len(table.Columns))
+	for _, row := range table.Rows {
+		for i, cell := range row {
+			record[i] = fmt.Sprint(cell)
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func renderJSON(table domain.Table) ([]byte, error) {
+	rows := make([]map[string]any, 0, len(table.Rows))
+	for _, row := range table.Rows {
+		obj := make(map[string]any, len(table.Columns))
+		for i, column := range table.Columns {
+			obj[column] = row[i]
+		}
+		rows = append(rows, obj)
+	}
+
+	return json.Marshal(rows)
+}
+
+// fileName turns a report name into a safe file name stem.
+func fileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(name))
+
+	if name == "" {
+		return "report"
+	}
+	return name
+}
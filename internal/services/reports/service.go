@@ -0,0 +1,213 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/audit"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/report"
+	subdomain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/cron"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+)
+
+const logGroup = "reports_service"
+
+type Repository interface {
+	CreateReport(ctx context.Context, input domain.CreateInput) (domain.Report, error)
+	GetReport(ctx context.Context, id uuid.UUID) (domain.Report, error)
+	ListReports(ctx context.Context) ([]domain.Report, error)
+	DeleteReport(ctx context.Context, id uuid.UUID) error
+	ClaimDueReports(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.Report, error)
+	CompleteReportRun(ctx context.Context, id uuid.UUID, ranAt, nextRunAt time.Time, runErr *string) error
+}
+
+type SubscriptionSource interface {
+	ListSubscriptionsFunc(ctx context.Context, filter subdomain.ListFilter, fn func(subdomain.Subscription) error) error
+}
+
+type Service struct {
+	repo      Repository
+	source    SubscriptionSource
+	delivery  *Delivery
+	batchSize int
+	lease     time.Duration
+	audit     *audit.Logger
+	logger    *slog.Logger
+}
+
+func New(repo Repository, source SubscriptionSource, delivery *Delivery, batchSize int, lease time.Duration, auditLog *audit.Logger, logger *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		source:    source,
+		delivery:  delivery,
+		batchSize: batchSize,
+		lease:     lease,
+		audit:     auditLog,
+		logger:    logger.WithGroup(logGroup),
+	}
+}
+
+func (s *Service) Create(ctx context.Context, input domain.CreateInput) (domain.Report, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "creating report", slog.String("name", input.Name), slog.String("schedule", input.Schedule))
+
+	if err := checkAccess(ctx); err != nil {
+		return domain.Report{}, err
+	}
+
+	schedule, err := cron.Parse(input.Schedule)
+	if err != nil {
+		return domain.Report{}, fmt.Errorf("%w: %w", domain.ErrInvalid, err)
+	}
+
+	if err := s.delivery.Validate(input.Channel, input.Target); err != nil {
+		return domain.Report{}, fmt.Errorf("%w: %w", domain.ErrInvalid, err)
+	}
+
+	input.NextRunAt = schedule.Next(time.Now().UTC())
+	if input.NextRunAt.IsZero() {
+		return domain.Report{}, fmt.Errorf("%w: schedule never fires", domain.ErrInvalid)
+	}
+
+	rep, err := s.repo.CreateReport(ctx, input)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to create report", slog.String("name", input.Name), slog.Any("error", err))
+		return domain.Report{}, err
+	}
+
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "report created", slog.String("report_id", rep.ID.String()), slog.Time("next_run_at", rep.NextRunAt))
+	s.audit.Record(ctx, audit.ActionCreate, "report", rep.ID.String(), audit.Changes(nil, auditFields(rep))...)
+
+	return rep, nil
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (domain.Report, error) {
+	if err := checkAccess(ctx); err != nil {
+		return domain.Report{}, err
+	}
+
+	rep, err := s.repo.GetReport(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to get report", slog.String("report_id", id.String()), slog.Any("error", err))
+		return domain.Report{}, err
+	}
+
+	return rep, nil
+}
+
+func (s *Service) List(ctx context.Context) ([]domain.Report, error) {
+	if err := checkAccess(ctx); err != nil {
+		return nil, err
+	}
+
+	reps, err := s.repo.ListReports(ctx)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list reports", slog.Any("error", err))
+		return nil, err
+	}
+
+	return reps, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "deleting report", slog.String("report_id", id.String()))
+
+	if err := checkAccess(ctx); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteReport(ctx, id); err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to delete report", slog.String("report_id", id.String()), slog.Any("error", err))
+		return err
+	}
+
+	s.audit.Record(ctx, audit.ActionDelete, "report", id.String())
+
+	return nil
+}
+
+// RunDue generates and delivers the reports that are due at now and returns
+// how many of them ran. A failed report is retried at its next scheduled time.
+func (s *Service) RunDue(ctx context.Context, now time.Time) (int, error) {
+	reps, err := s.repo.ClaimDueReports(ctx, now, now.Add(s.lease), s.batchSize)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to claim due reports", slog.Any("error", err))
+		return 0, err
+	}
+
+	for _, rep := range reps {
+		var runErr *string
+		if err := s.run(ctx, rep, now); err != nil {
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "report run failed", slog.String("report_id", rep.ID.String()), slog.Any("error", err))
+			msg := err.Error()
+			runErr = &msg
+		}
+
+		next := now.Add(s.lease)
+		if schedule, err := cron.Parse(rep.Schedule); err == nil {
+			if n := schedule.Next(now); !n.IsZero() {
+				next = n
+			}
+		}
+
+		if err := s.repo.CompleteReportRun(ctx, rep.ID, now, next, runErr); err != nil {
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to record report run", slog.String("report_id", rep.ID.String()), slog.Any("error", err))
+		}
+	}
+
+	return len(reps), nil
+}
+
+func (s *Service) run(ctx context.Context, rep domain.Report, now time.Time) error {
+	const op = "services.reports.run"
+
+	table, err := s.generate(ctx, rep, now)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	file, err := render(rep, table, now)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.delivery.Deliver(ctx, rep, file); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "report delivered", slog.String("report_id", rep.ID.String()), slog.String("channel", string(rep.Channel)), slog.Int("rows", len(table.Rows)))
+
+	return nil
+}
+
+func checkAccess(ctx context.Context) error {
+	if scope, ok := access.FromContext(ctx); ok && scope.Restricted() {
+		return access.ErrForbidden
+	}
+	return nil
+}
+
+// auditFields is the audited state of a report. The delivery target is left
+// out, as webhook URLs may carry credentials.
+func auditFields(rep domain.Report) audit.Fields {
+	fields := audit.Fields{
+		"name":          rep.Name,
+		"period_months": rep.PeriodMonths,
+		"grouping":      string(rep.Grouping),
+		"format":        string(rep.Format),
+		"schedule":      rep.Schedule,
+		"channel":       string(rep.Channel),
+	}
+	if !rep.UserID.IsNil() {
+		fields["user_id"] = rep.UserID.String()
+	}
+	if rep.ServiceName != nil {
+		fields["service_name"] = *rep.ServiceName
+	}
+
+	return fields
+}
@@ -0,0 +1,150 @@
+package reports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/report"
+)
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{1, "B"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, tt := range tests {
+		if got := columnName(tt.index); got != tt.want {
+			t.Errorf("columnName(%d) = %q, want %q", tt.index, got, tt.want)
+		}
+	}
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     string `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestRenderXLSX(t *testing.T) {
+	tests := []struct {
+		name  string
+		table domain.Table
+		// want holds every cell as "ref=value", numbers prefixed with #.
+		want [][]string
+	}{
+		{
+			name:  "header only",
+			table: domain.Table{Columns: []string{"user_id", "total"}},
+			want:  [][]string{{"A1=user_id", "B1=total"}},
+		},
+		{
+			name: "numbers and strings",
+			table: domain.Table{
+				Columns: []string{"service", "price"},
+				Rows: [][]any{
+					{"Yandex Plus", 400},
+					{"Netflix", 0},
+				},
+			},
+			want: [][]string{
+				{"A1=service", "B1=price"},
+				{"A2=Yandex Plus", "B2=#400"},
+				{"A3=Netflix", "B3=#0"},
+			},
+		},
+		{
+			name: "escaped text",
+			table: domain.Table{
+				Columns: []string{"name"},
+				Rows:    [][]any{{`<Tom & "Jerry">`}},
+			},
+			want: [][]string{{"A1=name"}, {`A2=<Tom & "Jerry">`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := renderXLSX(tt.table)
+			if err != nil {
+				t.Fatalf("renderXLSX: %v", err)
+			}
+
+			parts := readZip(t, data)
+			for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+				if _, ok := parts[name]; !ok {
+					t.Errorf("missing part %s", name)
+				}
+			}
+
+			var sheet xlsxSheet
+			if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+				t.Fatalf("sheet is not valid XML: %v", err)
+			}
+
+			if len(sheet.Rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(sheet.Rows), len(tt.want))
+			}
+
+			for i, row := range sheet.Rows {
+				if len(row.Cells) != len(tt.want[i]) {
+					t.Fatalf("row %s: got %d cells, want %d", row.R, len(row.Cells), len(tt.want[i]))
+				}
+
+				for j, cell := range row.Cells {
+					got := cell.R + "=" + cell.Inline
+					if cell.T != "inlineStr" {
+						got = cell.R + "=#" + cell.Value
+					}
+					if got != tt.want[i][j] {
+						t.Errorf("cell %d/%d = %q, want %q", i, j, got, tt.want[i][j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+
+	parts := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		parts[f.Name] = content
+	}
+
+	return parts
+}
@@ -0,0 +1,49 @@
+package stats
+
+import (
+	"context"
+	"log/slog"
+	"math"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+)
+
+const logGroup = "stats_service"
+
+type Repository interface {
+	ServiceStats(ctx context.Context, minSubscribers int) ([]domain.ServiceStats, error)
+}
+
+type Service struct {
+	repo          Repository
+	minCohortSize int
+	logger        *slog.Logger
+}
+
+func New(repo Repository, minCohortSize int, logger *slog.Logger) *Service {
+	return &Service{repo: repo, minCohortSize: minCohortSize, logger: logger.WithGroup(logGroup)}
+}
+
+// ServiceStats returns per-service aggregates, omitting every service whose
+// cohort is smaller than the configured minimum so that individual
+// subscriptions cannot be inferred from the numbers.
+func (s *Service) ServiceStats(ctx context.Context) ([]domain.ServiceStats, error) {
+	stats, err := s.repo.ServiceStats(ctx, s.minCohortSize)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to load service stats", slog.Any("error", err))
+		return nil, err
+	}
+
+	result := make([]domain.ServiceStats, 0, len(stats))
+	for _, st := range stats {
+		if st.Subscribers < s.minCohortSize {
+			continue
+		}
+
+		st.AveragePrice = math.Round(st.AveragePrice*100) / 100
+		result = append(result, st)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package subscriptions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+// QueryGuard refuses list queries whose estimated row count is above the
+// limit, optionally only during business hours.
+type QueryGuard struct {
+	maxRows       int64
+	businessHours bool
+	start, end    time.Duration
+	location      *time.Location
+	weekdays      bool
+}
+
+func NewQueryGuard(cfg config.QueryGuardConfig) (*QueryGuard, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	location, err := time.LoadLocation(cfg.BusinessHours.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid business hours timezone: %w", err)
+	}
+
+	start, err := parseClock(cfg.BusinessHours.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := parseClock(cfg.BusinessHours.End)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryGuard{
+		maxRows:       cfg.MaxEstimatedRows,
+		businessHours: cfg.BusinessHoursOnly,
+		start:         start,
+		end:           end,
+		location:      location,
+		weekdays:      cfg.BusinessHours.Weekdays,
+	}, nil
+}
+
+func (g *QueryGuard) active(now time.Time) bool {
+	if g == nil {
+		return false
+	}
+
+	if !g.businessHours {
+		return true
+	}
+
+	local := now.In(g.location)
+	if g.weekdays && (local.Weekday() == time.Saturday || local.Weekday() == time.Sunday) {
+		return false
+	}
+
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	return sinceMidnight >= g.start && sinceMidnight < g.end
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid business hours time %q, expected HH:MM", value)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
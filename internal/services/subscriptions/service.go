@@ -1,146 +1,702 @@
 package subscriptions
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/audit"
+	"github.com/Kulibyka/effective-mobile/internal/domain/category"
 	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/events"
 	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
 )
 
+const logGroup = "subscriptions_service"
+
 type Repository interface {
 	CreateSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, error)
+	UpsertSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, domain.UpsertOutcome, error)
 	GetSubscription(ctx context.Context, id uuid.UUID) (domain.Subscription, error)
 	UpdateSubscription(ctx context.Context, id uuid.UUID, input domain.UpdateInput) (domain.Subscription, error)
 	DeleteSubscription(ctx context.Context, id uuid.UUID) error
-	ListSubscriptions(ctx context.Context, filter domain.ListFilter) ([]domain.Subscription, error)
+	ListSubscriptions(ctx context.Context, filter domain.ListFilter) (domain.Page, error)
+	ServiceNames(ctx context.Context, filter domain.ListFilter) ([]string, error)
+	ExpireSubscriptions(ctx context.Context, before domain.MonthKey) ([]domain.Subscription, error)
+	SubscriptionHistory(ctx context.Context, id uuid.UUID) ([]domain.HistoryEntry, error)
+	AdjustPrices(ctx context.Context, input domain.PriceAdjustment) ([]domain.Subscription, error)
+	EstimateSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error)
+	MonthlySpend(ctx context.Context, filter domain.ListFilter) ([]domain.MonthTotal, bool, error)
+	UserSpend(ctx context.Context, month domain.MonthKey, filter domain.ListFilter) ([]domain.UserSpend, error)
+	SchedulePriceChange(ctx context.Context, input domain.PriceAdjustment) (domain.PriceChange, error)
+	PendingPriceChanges(ctx context.Context, through domain.MonthKey) ([]domain.PriceChange, error)
+	ApplyPriceChange(ctx context.Context, id int64) ([]domain.Subscription, error)
+	PurgeEndedSubscriptions(ctx context.Context, before domain.MonthKey, limit int, archive bool) ([]domain.Subscription, error)
+	ServiceCategories(ctx context.Context) (map[string]category.Category, error)
+
+	// WithinTx runs fn with a repository whose writes are committed together
+	// when fn returns nil.
+	WithinTx(ctx context.Context, fn func(repo Repository) error) error
+}
+
+// Summer is implemented by repositories that compute the period cost
+// themselves. Sum falls back to adding up the listed subscriptions otherwise.
+type Summer interface {
+	SumSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error)
 }
 
+// lastMonth is used to list every pending price change regardless of date.
+const lastMonth = domain.MonthKey(999912)
+
 type Service struct {
-	repo   Repository
-	logger *slog.Logger
+	repo    Repository
+	emitter events.Emitter
+	guard   *QueryGuard
+	audit   *audit.Logger
+	logger  *slog.Logger
 }
 
-func New(repo Repository, logger *slog.Logger) *Service {
-	return &Service{repo: repo, logger: logger.WithGroup("subscriptions_service")}
+func New(repo Repository, emitter events.Emitter, guard *QueryGuard, auditLog *audit.Logger, logger *slog.Logger) *Service {
+	return &Service{repo: repo, emitter: emitter, guard: guard, audit: auditLog, logger: logger.WithGroup(logGroup)}
 }
 
 func (s *Service) Create(ctx context.Context, input domain.CreateInput) (domain.Subscription, error) {
-	s.logger.InfoContext(ctx, "creating subscription", slog.String("service", input.ServiceName), slog.String("user_id", input.UserID.String()))
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "creating subscription", slog.String("service", input.ServiceName), slog.String("user_id", input.UserID.String()))
+
+	if scope, ok := access.FromContext(ctx); ok && !scope.Allows(input.UserID, input.ServiceName) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription outside of api key scope", slog.String("api_key", scope.Name), slog.String("user_id", input.UserID.String()))
+		return domain.Subscription{}, access.ErrForbidden
+	}
+
+	if input.ID == nil {
+		id := uuid.NewV7()
+		input.ID = &id
+	}
 
 	sub, err := s.repo.CreateSubscription(ctx, input)
+	if errors.Is(err, domain.ErrAlreadyExists) {
+		logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "subscription already created", slog.String("subscription_id", sub.ID.String()))
+		return sub, nil
+	}
+	if errors.Is(err, domain.ErrConflict) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription id already taken", slog.String("subscription_id", input.ID.String()))
+		return domain.Subscription{}, err
+	}
+	if rejected(err) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription rejected by storage", slog.String("user_id", input.UserID.String()), slog.Any("error", err))
+		return domain.Subscription{}, err
+	}
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to create subscription", slog.String("user_id", input.UserID.String()), slog.Any("error", err))
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to create subscription", slog.String("user_id", input.UserID.String()), slog.Any("error", err))
 		return domain.Subscription{}, err
 	}
 
+	s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionCreated, sub))
+	s.audit.Record(ctx, audit.ActionCreate, auditResource, sub.ID.String(), audit.Changes(nil, auditFields(sub))...)
+
 	return sub, nil
 }
 
+// Upsert creates the subscription or updates the one with the same user,
+// service and start month. input.ID is ignored: a fresh ID is used if the
+// subscription gets created.
+func (s *Service) Upsert(ctx context.Context, input domain.CreateInput) (domain.Subscription, domain.UpsertOutcome, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "upserting subscription", slog.String("service", input.ServiceName), slog.String("user_id", input.UserID.String()))
+
+	if scope, ok := access.FromContext(ctx); ok && !scope.Allows(input.UserID, input.ServiceName) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription outside of api key scope", slog.String("api_key", scope.Name), slog.String("user_id", input.UserID.String()))
+		return domain.Subscription{}, 0, access.ErrForbidden
+	}
+
+	id := uuid.NewV7()
+	input.ID = &id
+	sub, outcome, err := s.repo.UpsertSubscription(ctx, input)
+	if rejected(err) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription rejected by storage", slog.String("user_id", input.UserID.String()), slog.Any("error", err))
+		return domain.Subscription{}, 0, err
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to upsert subscription", slog.String("user_id", input.UserID.String()), slog.Any("error", err))
+		return domain.Subscription{}, 0, err
+	}
+
+	switch outcome {
+	case domain.UpsertCreated:
+		s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionCreated, sub))
+		s.audit.Record(ctx, audit.ActionCreate, auditResource, sub.ID.String(), audit.Changes(nil, auditFields(sub))...)
+	case domain.UpsertUpdated:
+		s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionUpdated, sub))
+		s.audit.Record(ctx, audit.ActionUpdate, auditResource, sub.ID.String(), audit.Changes(s.previous(ctx, sub), auditFields(sub))...)
+	}
+
+	return sub, outcome, nil
+}
+
 func (s *Service) Get(ctx context.Context, id uuid.UUID) (domain.Subscription, error) {
 	sub, err := s.repo.GetSubscription(ctx, id)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
-			s.logger.WarnContext(ctx, "subscription not found", slog.String("subscription_id", id.String()))
+			logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription not found", slog.String("subscription_id", id.String()))
 		} else {
-			s.logger.ErrorContext(ctx, "failed to get subscription", slog.String("subscription_id", id.String()), slog.Any("error", err))
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to get subscription", slog.String("subscription_id", id.String()), slog.Any("error", err))
 		}
 		return domain.Subscription{}, err
 	}
 
+	if scope, ok := access.FromContext(ctx); ok && !scope.Allows(sub.UserID, sub.ServiceName) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription outside of api key scope", slog.String("api_key", scope.Name), slog.String("subscription_id", id.String()))
+		return domain.Subscription{}, domain.ErrNotFound
+	}
+
 	return sub, nil
 }
 
 func (s *Service) Update(ctx context.Context, id uuid.UUID, input domain.UpdateInput) (domain.Subscription, error) {
-	s.logger.InfoContext(ctx, "updating subscription", slog.String("subscription_id", id.String()))
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "updating subscription", slog.String("subscription_id", id.String()))
+
+	// The previous state is only needed for scope checks and the audit log.
+	var before audit.Fields
+	if scope, ok := access.FromContext(ctx); ok && scope.Restricted() {
+		current, err := s.Get(ctx, id)
+		if err != nil {
+			return domain.Subscription{}, err
+		}
+		if !scope.Allows(current.UserID, input.ServiceName) {
+			logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription outside of api key scope", slog.String("api_key", scope.Name), slog.String("subscription_id", id.String()))
+			return domain.Subscription{}, access.ErrForbidden
+		}
+		before = auditFields(current)
+	} else if s.audit.Enabled() {
+		if current, err := s.repo.GetSubscription(ctx, id); err == nil {
+			before = auditFields(current)
+		}
+	}
 
 	sub, err := s.repo.UpdateSubscription(ctx, id, input)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
-			s.logger.WarnContext(ctx, "subscription not found", slog.String("subscription_id", id.String()))
+			logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription not found", slog.String("subscription_id", id.String()))
+		} else if errors.Is(err, domain.ErrConflict) {
+			logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription version conflict", slog.String("subscription_id", id.String()), slog.Int("version", *input.Version))
+		} else if rejected(err) {
+			logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription rejected by storage", slog.String("subscription_id", id.String()), slog.Any("error", err))
 		} else {
-			s.logger.ErrorContext(ctx, "failed to update subscription", slog.String("subscription_id", id.String()), slog.Any("error", err))
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to update subscription", slog.String("subscription_id", id.String()), slog.Any("error", err))
 		}
 		return domain.Subscription{}, err
 	}
 
+	s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionUpdated, sub))
+	s.audit.Record(ctx, audit.ActionUpdate, auditResource, sub.ID.String(), audit.Changes(before, auditFields(sub))...)
+
 	return sub, nil
 }
 
 func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
-	s.logger.InfoContext(ctx, "deleting subscription", slog.String("subscription_id", id.String()))
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "deleting subscription", slog.String("subscription_id", id.String()))
+
+	// The deleted subscription is loaded first for scope checks and so the
+	// event and the audit log name its user.
+	sub, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
 
 	if err := s.repo.DeleteSubscription(ctx, id); err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
-			s.logger.WarnContext(ctx, "subscription not found", slog.String("subscription_id", id.String()))
+			logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription not found", slog.String("subscription_id", id.String()))
 		} else {
-			s.logger.ErrorContext(ctx, "failed to delete subscription", slog.String("subscription_id", id.String()), slog.Any("error", err))
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to delete subscription", slog.String("subscription_id", id.String()), slog.Any("error", err))
 		}
 		return err
 	}
 
+	s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionDeleted, sub))
+	s.audit.Record(ctx, audit.ActionDelete, auditResource, id.String(), audit.Changes(auditFields(sub), nil)...)
+
 	return nil
 }
 
-func (s *Service) List(ctx context.Context, filter domain.ListFilter) ([]domain.Subscription, error) {
-	subs, err := s.repo.ListSubscriptions(ctx, filter)
+func (s *Service) History(ctx context.Context, id uuid.UUID) ([]domain.HistoryEntry, error) {
+	entries, err := s.repo.SubscriptionHistory(ctx, id)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to list subscriptions", slog.Any("error", err))
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to load subscription history", slog.String("subscription_id", id.String()), slog.Any("error", err))
 		return nil, err
 	}
 
-	return subs, nil
+	if len(entries) == 0 {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription not found", slog.String("subscription_id", id.String()))
+		return nil, domain.ErrNotFound
+	}
+
+	latest := entries[len(entries)-1]
+	if scope, ok := access.FromContext(ctx); ok && !scope.Allows(latest.UserID, latest.ServiceName) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription outside of api key scope", slog.String("api_key", scope.Name), slog.String("subscription_id", id.String()))
+		return nil, domain.ErrNotFound
+	}
+
+	return entries, nil
+}
+
+func (s *Service) List(ctx context.Context, filter domain.ListFilter) (domain.Page, error) {
+	filter, ok := scopeFilter(ctx, filter)
+	if !ok {
+		var page domain.Page
+		if filter.WithTotal {
+			page.Total = new(int64)
+		}
+		return page, nil
+	}
+
+	if !filter.Force && s.guard.active(time.Now()) {
+		estimate, err := s.repo.EstimateSubscriptions(ctx, filter)
+		if err != nil {
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to estimate list query", slog.Any("error", err))
+			return domain.Page{}, err
+		}
+
+		if estimate > s.guard.maxRows {
+			logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "list query refused by cost guard", slog.Int64("estimated_rows", estimate), slog.Int64("max_rows", s.guard.maxRows))
+			return domain.Page{}, domain.ErrQueryTooBroad
+		}
+	}
+
+	page, err := s.repo.ListSubscriptions(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list subscriptions", slog.Any("error", err))
+		return domain.Page{}, err
+	}
+
+	return page, nil
+}
+
+// ServiceNames returns the distinct service names in use, optionally for a
+// single user, limited to the caller's scope.
+func (s *Service) ServiceNames(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	filter, ok := scopeFilter(ctx, domain.ListFilter{UserID: userID})
+	if !ok {
+		return nil, nil
+	}
+
+	names, err := s.repo.ServiceNames(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list service names", slog.Any("error", err))
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// UserSpend returns what each user pays for the subscriptions running in
+// month, in one grouped query, limited to the caller's scope.
+func (s *Service) UserSpend(ctx context.Context, month time.Time, filter domain.ListFilter) ([]domain.UserSpend, error) {
+	filter, ok := scopeFilter(ctx, filter)
+	if !ok {
+		return nil, nil
+	}
+
+	spend, err := s.repo.UserSpend(ctx, domain.MonthKeyOf(month), filter)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to aggregate user spend", slog.String("month", month.Format(domain.MonthLayout)), slog.Any("error", err))
+		return nil, err
+	}
+
+	return spend, nil
 }
 
 func (s *Service) Sum(ctx context.Context, input domain.SummaryFilter) (int, error) {
-	listFilter := domain.ListFilter{
-		UserID:           input.UserID,
-		ServiceName:      input.ServiceName,
-		ActivePeriodFrom: &input.PeriodStart,
-		ActivePeriodTo:   &input.PeriodEnd,
+	totals, ok, err := s.rolledUpTotals(ctx, input)
+	if err != nil {
+		return 0, err
 	}
+	if ok {
+		total := 0
+		for _, month := range totals {
+			total += month.Total
+		}
+		return total, nil
+	}
+
+	if summer, ok := s.repo.(Summer); ok {
+		listFilter, ok := scopeFilter(ctx, summaryListFilter(input))
+		if !ok {
+			return 0, nil
+		}
 
-	subs, err := s.repo.ListSubscriptions(ctx, listFilter)
+		total, err := summer.SumSubscriptions(ctx, listFilter)
+		if err != nil {
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to sum subscriptions", slog.Any("error", err))
+			return 0, err
+		}
+
+		return int(total), nil
+	}
+
+	subs, err := s.listForSummary(ctx, input)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to list subscriptions for summary", slog.Any("error", err))
 		return 0, err
 	}
 
+	periodStart := domain.MonthKeyOf(input.PeriodStart)
+	periodEnd := domain.MonthKeyOf(input.PeriodEnd)
+
 	total := 0
 	for _, sub := range subs {
-		overlapStart := maxTime(sub.StartMonth, input.PeriodStart)
+		overlapStart := max(domain.MonthKeyOf(sub.StartMonth), periodStart)
+
+		overlapEnd := periodEnd
+		if sub.EndMonth != nil {
+			overlapEnd = min(domain.MonthKeyOf(*sub.EndMonth), periodEnd)
+		}
+
+		total += sub.Price * overlapStart.MonthsUntil(overlapEnd)
+	}
+
+	return total, nil
+}
+
+// MonthlyTotals breaks the summary for the period down by month; the totals
+// add up to the result of Sum.
+func (s *Service) MonthlyTotals(ctx context.Context, input domain.SummaryFilter) ([]domain.MonthTotal, error) {
+	totals, ok, err := s.rolledUpTotals(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return totals, nil
+	}
+
+	subs, err := s.listForSummary(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	periodStart := domain.MonthKeyOf(input.PeriodStart)
+	periodEnd := domain.MonthKeyOf(input.PeriodEnd)
+
+	result := emptyMonths(periodStart, periodEnd)
+
+	for _, sub := range subs {
+		overlapStart := max(domain.MonthKeyOf(sub.StartMonth), periodStart)
+
+		overlapEnd := periodEnd
+		if sub.EndMonth != nil {
+			overlapEnd = min(domain.MonthKeyOf(*sub.EndMonth), periodEnd)
+		}
+
+		for month := overlapStart; month <= overlapEnd; month = month.Next() {
+			result[periodStart.MonthsUntil(month)-1].Total += sub.Price
+		}
+	}
+
+	return result, nil
+}
+
+// CategoryTotals splits the summary for the period by service category, most
+// expensive first. Services without a category are reported together.
+func (s *Service) CategoryTotals(ctx context.Context, input domain.SummaryFilter) ([]domain.CategoryTotal, error) {
+	subs, err := s.listForSummary(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.repo.ServiceCategories(ctx)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to load service categories", slog.Any("error", err))
+		return nil, err
+	}
+
+	periodStart := domain.MonthKeyOf(input.PeriodStart)
+	periodEnd := domain.MonthKeyOf(input.PeriodEnd)
+
+	var result []domain.CategoryTotal
+	index := make(map[string]int)
+
+	for _, sub := range subs {
+		overlapStart := max(domain.MonthKeyOf(sub.StartMonth), periodStart)
+
+		overlapEnd := periodEnd
+		if sub.EndMonth != nil {
+			overlapEnd = min(domain.MonthKeyOf(*sub.EndMonth), periodEnd)
+		}
+
+		var key string
+		var total domain.CategoryTotal
+		if cat, ok := categories[sub.ServiceName]; ok {
+			key = cat.ID.String()
+			total = domain.CategoryTotal{CategoryID: &cat.ID, Category: cat.Name}
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, total)
+		}
+
+		result[i].Total += sub.Price * overlapStart.MonthsUntil(overlapEnd)
+	}
+
+	slices.SortStableFunc(result, func(a, b domain.CategoryTotal) int {
+		return cmp.Compare(b.Total, a.Total)
+	})
+
+	return result, nil
+}
+
+// rolledUpTotals answers the summary from the monthly spend rollup. It reports
+// false when the rollup cannot be used: for as-of summaries, which need the
+// subscription history, and for periods past the rollup horizon.
+func (s *Service) rolledUpTotals(ctx context.Context, input domain.SummaryFilter) ([]domain.MonthTotal, bool, error) {
+	if input.AsOf != nil {
+		return nil, false, nil
+	}
+
+	listFilter, ok := scopeFilter(ctx, summaryListFilter(input))
+	if !ok {
+		return nil, false, nil
+	}
+
+	rolled, ok, err := s.repo.MonthlySpend(ctx, listFilter)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to read monthly spend rollup", slog.Any("error", err))
+		return nil, false, err
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	periodStart := domain.MonthKeyOf(input.PeriodStart)
+	periodEnd := domain.MonthKeyOf(input.PeriodEnd)
+
+	result := emptyMonths(periodStart, periodEnd)
+	for _, month := range rolled {
+		result[periodStart.MonthsUntil(month.Month)-1].Total = month.Total
+	}
 
-		subEnd := input.PeriodEnd
-		if sub.EndMonth != nil && sub.EndMonth.Before(subEnd) {
-			subEnd = *sub.EndMonth
+	return result, true, nil
+}
+
+func (s *Service) listForSummary(ctx context.Context, input domain.SummaryFilter) ([]domain.Subscription, error) {
+	listFilter, ok := scopeFilter(ctx, summaryListFilter(input))
+	if !ok {
+		return nil, nil
+	}
+
+	page, err := s.repo.ListSubscriptions(ctx, listFilter)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list subscriptions for summary", slog.Any("error", err))
+		return nil, err
+	}
+
+	return page.Subscriptions, nil
+}
+
+// AdjustPrices applies the new price right away when it takes effect in the
+// current month or earlier. Changes for a future month are scheduled and
+// applied by ApplyDuePriceChanges once the month starts.
+func (s *Service) AdjustPrices(ctx context.Context, input domain.PriceAdjustment) (domain.PriceAdjustmentResult, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "adjusting prices", slog.String("service", input.ServiceName), slog.Int("price", input.Price), slog.String("effective_month", input.EffectiveMonth.Format(domain.MonthLayout)))
+
+	if scope, ok := access.FromContext(ctx); ok && scope.Restricted() {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "price adjustment not allowed for scoped api key", slog.String("api_key", scope.Name))
+		return domain.PriceAdjustmentResult{}, access.ErrForbidden
+	}
+
+	if domain.MonthKeyOf(input.EffectiveMonth) > domain.MonthKeyOf(time.Now().UTC()) {
+		change, err := s.repo.SchedulePriceChange(ctx, input)
+		if err != nil {
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to schedule price change", slog.String("service", input.ServiceName), slog.Any("error", err))
+			return domain.PriceAdjustmentResult{}, err
 		}
 
-		if overlapStart.After(subEnd) {
+		logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "price change scheduled", slog.String("service", input.ServiceName), slog.Int64("price_change_id", change.ID))
+		s.audit.Record(ctx, audit.ActionCreate, "price_change", strconv.FormatInt(change.ID, 10), audit.Changes(nil, audit.Fields{
+			"service_name":    input.ServiceName,
+			"price":           input.Price,
+			"effective_month": input.EffectiveMonth.Format(domain.MonthLayout),
+		})...)
+
+		return domain.PriceAdjustmentResult{Scheduled: &change}, nil
+	}
+
+	subs, err := s.repo.AdjustPrices(ctx, input)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to adjust prices", slog.String("service", input.ServiceName), slog.Any("error", err))
+		return domain.PriceAdjustmentResult{}, err
+	}
+
+	s.priceUpdated(ctx, subs)
+
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "prices adjusted", slog.String("service", input.ServiceName), slog.Int("affected", len(subs)))
+
+	return domain.PriceAdjustmentResult{Affected: len(subs)}, nil
+}
+
+func (s *Service) UpcomingPriceChanges(ctx context.Context) ([]domain.PriceChange, error) {
+	changes, err := s.repo.PendingPriceChanges(ctx, lastMonth)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list price changes", slog.Any("error", err))
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// ApplyDuePriceChanges applies the scheduled price changes whose month has
+// started and returns the number of updated subscriptions.
+func (s *Service) ApplyDuePriceChanges(ctx context.Context, now time.Time) (int, error) {
+	changes, err := s.repo.PendingPriceChanges(ctx, domain.MonthKeyOf(now.UTC()))
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list due price changes", slog.Any("error", err))
+		return 0, err
+	}
+
+	affected := 0
+	for _, change := range changes {
+		subs, err := s.repo.ApplyPriceChange(ctx, change.ID)
+		if err != nil {
+			logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to apply price change", slog.Int64("price_change_id", change.ID), slog.Any("error", err))
+			return affected, err
+		}
+
+		s.priceUpdated(ctx, subs)
+		affected += len(subs)
+
+		logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "price change applied", slog.Int64("price_change_id", change.ID), slog.String("service", change.ServiceName), slog.Int("affected", len(subs)))
+	}
+
+	return affected, nil
+}
+
+// priceUpdated emits and audits each of subs after a price change. The
+// subscriptions continuing an earlier one from the effective month are new
+// and recorded as created.
+func (s *Service) priceUpdated(ctx context.Context, subs []domain.Subscription) {
+	for _, sub := range subs {
+		if sub.Version == 1 {
+			s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionCreated, sub))
+			s.audit.Record(ctx, audit.ActionCreate, auditResource, sub.ID.String(), audit.Changes(nil, auditFields(sub))...)
 			continue
 		}
 
-		months := monthsBetween(overlapStart, subEnd)
-		total += sub.Price * months
+		s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionUpdated, sub))
+		s.audit.Record(ctx, audit.ActionUpdate, auditResource, sub.ID.String(), audit.Changes(s.previous(ctx, sub), auditFields(sub))...)
 	}
+}
 
-	return total, nil
+func (s *Service) ExpireOverdue(ctx context.Context, now time.Time) ([]domain.Subscription, error) {
+	subs, err := s.repo.ExpireSubscriptions(ctx, domain.MonthKeyOf(now.UTC()))
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to expire subscriptions", slog.Any("error", err))
+		return nil, err
+	}
+
+	for _, sub := range subs {
+		logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "subscription expired", slog.String("subscription_id", sub.ID.String()), slog.String("user_id", sub.UserID.String()))
+		s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionExpired, sub))
+		s.audit.Record(ctx, audit.ActionUpdate, auditResource, sub.ID.String(), audit.Changes(s.previous(ctx, sub), auditFields(sub))...)
+	}
+
+	return subs, nil
 }
 
-func maxTime(a, b time.Time) time.Time {
-	if a.After(b) {
-		return a
+// PurgeEnded removes up to limit subscriptions that ended before the given
+// month, keeping them in the history as deleted when archive is set.
+func (s *Service) PurgeEnded(ctx context.Context, before domain.MonthKey, limit int, archive bool) ([]domain.Subscription, error) {
+	subs, err := s.repo.PurgeEndedSubscriptions(ctx, before, limit, archive)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to purge ended subscriptions", slog.Int("before", int(before)), slog.Any("error", err))
+		return nil, err
 	}
-	return b
+
+	for _, sub := range subs {
+		s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionDeleted, sub))
+		s.audit.Record(ctx, audit.ActionDelete, auditResource, sub.ID.String(), audit.Changes(auditFields(sub), nil)...)
+	}
+
+	return subs, nil
 }
 
-func monthsBetween(start, end time.Time) int {
-	y := end.Year() - start.Year()
-	m := int(end.Month()) - int(start.Month())
-	months := y*12 + m + 1
-	if months < 0 {
-		return 0
+func summaryListFilter(input domain.SummaryFilter) domain.ListFilter {
+	return domain.ListFilter{
+		UserID:           input.UserID,
+		ServiceName:      input.ServiceName,
+		CategoryID:       input.CategoryID,
+		ActivePeriodFrom: &input.PeriodStart,
+		ActivePeriodTo:   &input.PeriodEnd,
+		AsOf:             input.AsOf,
+	}
+}
+
+func emptyMonths(from, to domain.MonthKey) []domain.MonthTotal {
+	result := make([]domain.MonthTotal, from.MonthsUntil(to))
+	for i, month := 0, from; i < len(result); i, month = i+1, month.Next() {
+		result[i].Month = month
 	}
-	return months
+
+	return result
+}
+
+// rejected reports whether err is a constraint violation caused by the input
+// rather than a storage failure.
+func rejected(err error) bool {
+	return errors.Is(err, domain.ErrDuplicate) || errors.Is(err, domain.ErrInvalidReference) || errors.Is(err, domain.ErrInvalidValue)
+}
+
+// scopeFilter applies the default filters of the caller's API key, if any.
+func scopeFilter(ctx context.Context, filter domain.ListFilter) (domain.ListFilter, bool) {
+	scope, ok := access.FromContext(ctx)
+	if !ok {
+		return filter, true
+	}
+
+	return scope.Apply(filter)
+}
+
+const auditResource = "subscription"
+
+// auditFields is the audited state of a subscription.
+func auditFields(sub domain.Subscription) audit.Fields {
+	fields := audit.Fields{
+		"service_name": sub.ServiceName,
+		"price":        sub.Price,
+		"user_id":      sub.UserID.String(),
+		"start_month":  sub.StartMonth.Format(domain.MonthLayout),
+		"status":       string(sub.Status),
+	}
+	if sub.EndMonth != nil {
+		fields["end_month"] = sub.EndMonth.Format(domain.MonthLayout)
+	}
+
+	return fields
+}
+
+// previous returns the audited state of sub before its latest change, read
+// from its history. It is nil when the audit log is disabled or the version
+// cannot be found, so the change is recorded without its old values.
+func (s *Service) previous(ctx context.Context, sub domain.Subscription) audit.Fields {
+	if !s.audit.Enabled() {
+		return nil
+	}
+
+	history, err := s.repo.SubscriptionHistory(ctx, sub.ID)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "failed to load previous subscription version for the audit log", slog.String("subscription_id", sub.ID.String()), slog.Any("error", err))
+		return nil
+	}
+
+	for _, entry := range slices.Backward(history) {
+		if entry.Version < sub.Version {
+			return auditFields(entry.Subscription)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,241 @@
+package suggestions
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/audit"
+	subdomain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/suggestion"
+	"github.com/Kulibyka/effective-mobile/internal/events"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+)
+
+const logGroup = "suggestions_service"
+
+type Repository interface {
+	CreateSuggestion(ctx context.Context, input domain.CreateInput) (domain.Suggestion, error)
+	GetSuggestion(ctx context.Context, id uuid.UUID) (domain.Suggestion, error)
+	ConfirmSuggestion(ctx context.Context, id uuid.UUID, input subdomain.CreateInput) (subdomain.Subscription, error)
+	ListSuggestions(ctx context.Context, filter domain.ListFilter) ([]domain.Suggestion, error)
+	DismissSuggestion(ctx context.Context, id uuid.UUID, reason *string) (domain.Suggestion, error)
+}
+
+type Service struct {
+	repo    Repository
+	emitter events.Emitter
+	audit   *audit.Logger
+	logger  *slog.Logger
+}
+
+func New(repo Repository, emitter events.Emitter, auditLog *audit.Logger, logger *slog.Logger) *Service {
+	return &Service{repo: repo, emitter: emitter, audit: auditLog, logger: logger.WithGroup(logGroup)}
+}
+
+func (s *Service) Ingest(ctx context.Context, input domain.CreateInput) (domain.Suggestion, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "ingesting suggestion", slog.String("source", input.Source), slog.String("user_id", input.UserID.String()))
+
+	if scope, ok := access.FromContext(ctx); ok && !scope.Allows(input.UserID, input.ServiceName) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "suggestion outside of api key scope", slog.String("api_key", scope.Name), slog.String("user_id", input.UserID.String()))
+		return domain.Suggestion{}, access.ErrForbidden
+	}
+
+	sug, err := s.repo.CreateSuggestion(ctx, input)
+	if errors.Is(err, domain.ErrConflict) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "external id already used for another user", slog.String("source", input.Source), slog.String("user_id", input.UserID.String()))
+		return domain.Suggestion{}, err
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to ingest suggestion", slog.String("source", input.Source), slog.Any("error", err))
+		return domain.Suggestion{}, err
+	}
+
+	s.audit.Record(ctx, audit.ActionCreate, "suggestion", sug.ID.String(), audit.Changes(nil, audit.Fields{
+		"user_id":      sug.UserID.String(),
+		"service_name": sug.ServiceName,
+		"source":       sug.Source,
+		"status":       string(sug.Status),
+	})...)
+
+	return sug, nil
+}
+
+func (s *Service) List(ctx context.Context, filter domain.ListFilter) ([]domain.Suggestion, error) {
+	if scope, ok := access.FromContext(ctx); ok {
+		if !scope.AllowsUser(filter.UserID) {
+			return nil, nil
+		}
+		filter.ServiceNames = scope.Services
+	}
+
+	sugs, err := s.repo.ListSuggestions(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to list suggestions", slog.String("user_id", filter.UserID.String()), slog.Any("error", err))
+		return nil, err
+	}
+
+	return sugs, nil
+}
+
+func (s *Service) Confirm(ctx context.Context, id uuid.UUID, input domain.ConfirmInput) (subdomain.Subscription, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "confirming suggestion", slog.String("suggestion_id", id.String()))
+
+	sug, err := s.repo.GetSuggestion(ctx, id)
+	if err != nil {
+		s.logReviewError(ctx, id, err)
+		return subdomain.Subscription{}, err
+	}
+
+	if !s.inScope(ctx, sug) {
+		return subdomain.Subscription{}, domain.ErrNotFound
+	}
+
+	return s.confirm(ctx, sug, input)
+}
+
+func (s *Service) Accept(ctx context.Context, userID, id uuid.UUID, input domain.ConfirmInput) (subdomain.Subscription, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "accepting suggestion", slog.String("suggestion_id", id.String()), slog.String("user_id", userID.String()))
+
+	sug, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return subdomain.Subscription{}, err
+	}
+
+	return s.confirm(ctx, sug, input)
+}
+
+func (s *Service) Dismiss(ctx context.Context, userID, id uuid.UUID, reason *string) (domain.Suggestion, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "dismissing suggestion", slog.String("suggestion_id", id.String()), slog.String("user_id", userID.String()))
+
+	if _, err := s.getOwned(ctx, userID, id); err != nil {
+		return domain.Suggestion{}, err
+	}
+
+	sug, err := s.repo.DismissSuggestion(ctx, id, reason)
+	if err != nil {
+		s.logReviewError(ctx, id, err)
+		return domain.Suggestion{}, err
+	}
+
+	s.audit.Record(ctx, audit.ActionUpdate, "suggestion", sug.ID.String(), audit.Change{Field: "status", From: string(domain.StatusPending), To: string(sug.Status)})
+
+	return sug, nil
+}
+
+func (s *Service) getOwned(ctx context.Context, userID, id uuid.UUID) (domain.Suggestion, error) {
+	sug, err := s.repo.GetSuggestion(ctx, id)
+	if err != nil {
+		s.logReviewError(ctx, id, err)
+		return domain.Suggestion{}, err
+	}
+
+	if sug.UserID != userID {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "suggestion belongs to another user", slog.String("suggestion_id", id.String()), slog.String("user_id", userID.String()))
+		return domain.Suggestion{}, domain.ErrNotFound
+	}
+
+	if !s.inScope(ctx, sug) {
+		return domain.Suggestion{}, domain.ErrNotFound
+	}
+
+	return sug, nil
+}
+
+// inScope reports whether the caller's API key may see sug.
+func (s *Service) inScope(ctx context.Context, sug domain.Suggestion) bool {
+	if scope, ok := access.FromContext(ctx); ok && !scope.Allows(sug.UserID, sug.ServiceName) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "suggestion outside of api key scope", slog.String("api_key", scope.Name), slog.String("suggestion_id", sug.ID.String()))
+		return false
+	}
+
+	return true
+}
+
+func (s *Service) confirm(ctx context.Context, sug domain.Suggestion, input domain.ConfirmInput) (subdomain.Subscription, error) {
+	if sug.Status != domain.StatusPending {
+		return subdomain.Subscription{}, domain.ErrAlreadyReviewed
+	}
+
+	createInput, err := subscriptionInput(sug, input)
+	if err != nil {
+		return subdomain.Subscription{}, err
+	}
+
+	// The service name may be overridden on confirmation.
+	if scope, ok := access.FromContext(ctx); ok && !scope.Allows(createInput.UserID, createInput.ServiceName) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "subscription outside of api key scope", slog.String("api_key", scope.Name), slog.String("suggestion_id", sug.ID.String()))
+		return subdomain.Subscription{}, access.ErrForbidden
+	}
+
+	sub, err := s.repo.ConfirmSuggestion(ctx, sug.ID, createInput)
+	if err != nil {
+		s.logReviewError(ctx, sug.ID, err)
+		return subdomain.Subscription{}, err
+	}
+
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "suggestion confirmed", slog.String("suggestion_id", sug.ID.String()), slog.String("subscription_id", sub.ID.String()))
+	s.emitter.Emit(ctx, events.NewSubscriptionEvent(events.SubscriptionCreated, sub))
+	s.audit.Record(ctx, audit.ActionUpdate, "suggestion", sug.ID.String(),
+		audit.Change{Field: "status", From: string(domain.StatusPending), To: string(domain.StatusAccepted)},
+		audit.Change{Field: "subscription_id", To: sub.ID.String()},
+	)
+	s.audit.Record(ctx, audit.ActionCreate, "subscription", sub.ID.String(), audit.Changes(nil, audit.Fields{
+		"service_name": sub.ServiceName,
+		"price":        sub.Price,
+		"user_id":      sub.UserID.String(),
+		"start_month":  sub.StartMonth.Format(subdomain.MonthLayout),
+		"status":       string(sub.Status),
+	})...)
+
+	return sub, nil
+}
+
+func (s *Service) logReviewError(ctx context.Context, id uuid.UUID, err error) {
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrAlreadyReviewed) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "suggestion cannot be reviewed", slog.String("suggestion_id", id.String()), slog.Any("error", err))
+		return
+	}
+
+	logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to review suggestion", slog.String("suggestion_id", id.String()), slog.Any("error", err))
+}
+
+func subscriptionInput(sug domain.Suggestion, input domain.ConfirmInput) (subdomain.CreateInput, error) {
+	result := subdomain.CreateInput{
+		ServiceName: sug.ServiceName,
+		UserID:      sug.UserID,
+		EndMonth:    input.EndMonth,
+	}
+
+	if input.ServiceName != nil {
+		result.ServiceName = *input.ServiceName
+	}
+
+	switch {
+	case input.Price != nil:
+		result.Price = *input.Price
+	case sug.Price != nil:
+		result.Price = *sug.Price
+	default:
+		return subdomain.CreateInput{}, domain.ErrIncomplete
+	}
+
+	switch {
+	case input.StartMonth != nil:
+		result.StartMonth = *input.StartMonth
+	case sug.StartMonth != nil:
+		result.StartMonth = *sug.StartMonth
+	default:
+		created := sug.CreatedAt.UTC()
+		result.StartMonth = time.Date(created.Year(), created.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	if result.ServiceName == "" {
+		return subdomain.CreateInput{}, domain.ErrIncomplete
+	}
+
+	return result, nil
+}
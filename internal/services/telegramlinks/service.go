@@ -0,0 +1,73 @@
+package telegramlinks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"log/slog"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/access"
+	"github.com/Kulibyka/effective-mobile/internal/audit"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/notification"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+)
+
+const (
+	logGroup      = "telegram_links_service"
+	auditResource = "telegram_link_token"
+)
+
+type Repository interface {
+	CreateTelegramLinkToken(ctx context.Context, token domain.LinkToken) error
+}
+
+// Service issues the one-time tokens users link their Telegram chat with.
+type Service struct {
+	repo   Repository
+	ttl    time.Duration
+	audit  *audit.Logger
+	logger *slog.Logger
+}
+
+func New(repo Repository, ttl time.Duration, auditLog *audit.Logger, logger *slog.Logger) *Service {
+	return &Service{repo: repo, ttl: ttl, audit: auditLog, logger: logger.WithGroup(logGroup)}
+}
+
+// Issue creates a token linking the chat that sends it to userID. With relink
+// the token replaces the chat the user is linked to, if any.
+func (s *Service) Issue(ctx context.Context, userID uuid.UUID, relink bool) (domain.LinkToken, error) {
+	logger.FromContext(ctx, s.logger, logGroup).InfoContext(ctx, "issuing telegram link token", slog.String("user_id", userID.String()), slog.Bool("relink", relink))
+
+	if scope, ok := access.FromContext(ctx); ok && !scope.AllowsUser(userID) {
+		logger.FromContext(ctx, s.logger, logGroup).WarnContext(ctx, "user outside of api key scope", slog.String("api_key", scope.Name), slog.String("user_id", userID.String()))
+		return domain.LinkToken{}, access.ErrForbidden
+	}
+
+	// 32 bytes encode to 43 characters, within the 64 Telegram allows for
+	// the start parameter of a deep link.
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return domain.LinkToken{}, err
+	}
+
+	token := domain.LinkToken{
+		Token:     base64.RawURLEncoding.EncodeToString(secret),
+		UserID:    userID,
+		Relink:    relink,
+		ExpiresAt: time.Now().Add(s.ttl).UTC(),
+	}
+
+	if err := s.repo.CreateTelegramLinkToken(ctx, token); err != nil {
+		logger.FromContext(ctx, s.logger, logGroup).ErrorContext(ctx, "failed to issue telegram link token", slog.String("user_id", userID.String()), slog.Any("error", err))
+		return domain.LinkToken{}, err
+	}
+
+	s.audit.Record(ctx, audit.ActionCreate, auditResource, userID.String(),
+		audit.Change{Field: "relink", To: relink},
+		audit.Change{Field: "expires_at", To: token.ExpiresAt},
+	)
+
+	return token, nil
+}
@@ -0,0 +1,298 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+	service "github.com/Kulibyka/effective-mobile/internal/services/subscriptions"
+)
+
+const logGroup = "subscriptions_cache"
+
+// keyVersion is part of every key; bump it whenever the cached encoding
+// changes so old entries are ignored instead of misread.
+const keyVersion = "v2"
+
+// Repository is a read-through cache in front of another repository.
+// GetSubscription results are cached per subscription and dropped when it
+// changes. ListSubscriptions results are keyed by a generation counter that
+// every write increments, so no stale page survives a write. A read racing a
+// write may still store the old version; the TTLs bound how long it lives.
+type Repository struct {
+	service.Repository
+	client  *redis.Client
+	prefix  string
+	getTTL  time.Duration
+	listTTL time.Duration
+	logger  *slog.Logger
+}
+
+// summingRepository keeps the Summer capability of the wrapped repository
+// visible to the service.
+type summingRepository struct {
+	*Repository
+	summer service.Summer
+}
+
+func (r *summingRepository) SumSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error) {
+	return r.summer.SumSubscriptions(ctx, filter)
+}
+
+// NewClient connects to Redis and checks that it answers.
+func NewClient(ctx context.Context, cfg config.CacheConfig) (*redis.Client, error) {
+	const op = "storage.cache.NewClient"
+
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Address,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		DialTimeout:  cfg.Timeout,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return client, nil
+}
+
+func New(repo service.Repository, client *redis.Client, cfg config.CacheConfig, logger *slog.Logger) service.Repository {
+	r := newRepository(repo, client, cfg, logger)
+
+	if summer, ok := repo.(service.Summer); ok {
+		return &summingRepository{Repository: r, summer: summer}
+	}
+
+	return r
+}
+
+func newRepository(repo service.Repository, client *redis.Client, cfg config.CacheConfig, logger *slog.Logger) *Repository {
+	return &Repository{
+		Repository: repo,
+		client:     client,
+		prefix:     cfg.KeyPrefix + ":" + keyVersion,
+		getTTL:     cfg.GetTTL,
+		listTTL:    cfg.ListTTL,
+		logger:     logger.WithGroup(logGroup),
+	}
+}
+
+func (r *Repository) GetSubscription(ctx context.Context, id uuid.UUID) (domain.Subscription, error) {
+	key := r.subscriptionKey(id)
+
+	var sub domain.Subscription
+	if r.load(ctx, key, &sub) {
+		return sub, nil
+	}
+
+	sub, err := r.Repository.GetSubscription(ctx, id)
+	if err != nil {
+		return sub, err
+	}
+
+	r.store(ctx, key, sub, r.getTTL)
+
+	return sub, nil
+}
+
+func (r *Repository) ListSubscriptions(ctx context.Context, filter domain.ListFilter) (domain.Page, error) {
+	key, ok := r.listKey(ctx, filter)
+	if !ok {
+		return r.Repository.ListSubscriptions(ctx, filter)
+	}
+
+	var page domain.Page
+	if r.load(ctx, key, &page) {
+		return page, nil
+	}
+
+	page, err := r.Repository.ListSubscriptions(ctx, filter)
+	if err != nil {
+		return page, err
+	}
+
+	r.store(ctx, key, page, r.listTTL)
+
+	return page, nil
+}
+
+func (r *Repository) CreateSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, error) {
+	sub, err := r.Repository.CreateSubscription(ctx, input)
+	if err == nil {
+		r.invalidate(ctx)
+	}
+
+	return sub, err
+}
+
+func (r *Repository) UpsertSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, domain.UpsertOutcome, error) {
+	sub, outcome, err := r.Repository.UpsertSubscription(ctx, input)
+	if err == nil && outcome != domain.UpsertUnchanged {
+		r.invalidate(ctx, sub.ID)
+	}
+
+	return sub, outcome, err
+}
+
+func (r *Repository) UpdateSubscription(ctx context.Context, id uuid.UUID, input domain.UpdateInput) (domain.Subscription, error) {
+	sub, err := r.Repository.UpdateSubscription(ctx, id, input)
+	if err == nil {
+		r.invalidate(ctx, id)
+	}
+
+	return sub, err
+}
+
+func (r *Repository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	err := r.Repository.DeleteSubscription(ctx, id)
+	if err == nil || errors.Is(err, domain.ErrNotFound) {
+		r.invalidate(ctx, id)
+	}
+
+	return err
+}
+
+func (r *Repository) ExpireSubscriptions(ctx context.Context, before domain.MonthKey) ([]domain.Subscription, error) {
+	subs, err := r.Repository.ExpireSubscriptions(ctx, before)
+	if err == nil && len(subs) > 0 {
+		r.invalidate(ctx, subscriptionIDs(subs)...)
+	}
+
+	return subs, err
+}
+
+func (r *Repository) AdjustPrices(ctx context.Context, input domain.PriceAdjustment) ([]domain.Subscription, error) {
+	subs, err := r.Repository.AdjustPrices(ctx, input)
+	if err == nil && len(subs) > 0 {
+		r.invalidate(ctx, subscriptionIDs(subs)...)
+	}
+
+	return subs, err
+}
+
+func (r *Repository) ApplyPriceChange(ctx context.Context, id int64) ([]domain.Subscription, error) {
+	subs, err := r.Repository.ApplyPriceChange(ctx, id)
+	if err == nil && len(subs) > 0 {
+		r.invalidate(ctx, subscriptionIDs(subs)...)
+	}
+
+	return subs, err
+}
+
+func (r *Repository) PurgeEndedSubscriptions(ctx context.Context, before domain.MonthKey, limit int, archive bool) ([]domain.Subscription, error) {
+	subs, err := r.Repository.PurgeEndedSubscriptions(ctx, before, limit, archive)
+	if err == nil && len(subs) > 0 {
+		r.invalidate(ctx, subscriptionIDs(subs)...)
+	}
+
+	return subs, err
+}
+
+// WithinTx bypasses the cache inside the transaction, so fn reads its own
+// writes, and invalidates what fn changed once the transaction commits.
+func (r *Repository) WithinTx(ctx context.Context, fn func(repo service.Repository) error) error {
+	changes := &txChanges{}
+
+	err := r.Repository.WithinTx(ctx, func(repo service.Repository) error {
+		return fn(&txRecorder{Repository: repo, changes: changes})
+	})
+	if err == nil && changes.dirty {
+		r.invalidate(ctx, changes.ids...)
+	}
+
+	return err
+}
+
+// invalidate drops the cached subscriptions and starts a new list
+// generation. Failures are logged: the TTLs still bound staleness.
+func (r *Repository) invalidate(ctx context.Context, ids ...uuid.UUID) {
+	pipe := r.client.TxPipeline()
+	pipe.Incr(ctx, r.generationKey())
+	for _, id := range ids {
+		pipe.Del(ctx, r.subscriptionKey(id))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.FromContext(ctx, r.logger, logGroup).WarnContext(ctx, "failed to invalidate cache", slog.Any("error", err))
+	}
+}
+
+// listKey derives the key of a list from the current generation and the
+// filter. It reports false when the generation cannot be read.
+func (r *Repository) listKey(ctx context.Context, filter domain.ListFilter) (string, bool) {
+	generation, err := r.client.Get(ctx, r.generationKey()).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.FromContext(ctx, r.logger, logGroup).WarnContext(ctx, "failed to read cache generation", slog.Any("error", err))
+		return "", false
+	}
+
+	filter.Force = false
+	raw, err := json.Marshal(filter)
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(raw)
+
+	return fmt.Sprintf("%s:list:%d:%s", r.prefix, generation, hex.EncodeToString(sum[:])), true
+}
+
+func (r *Repository) load(ctx context.Context, key string, dest any) bool {
+	raw, err := r.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.FromContext(ctx, r.logger, logGroup).WarnContext(ctx, "failed to read cache", slog.String("key", key), slog.Any("error", err))
+		}
+		return false
+	}
+
+	if err := json.Unmarshal(raw, dest); err != nil {
+		logger.FromContext(ctx, r.logger, logGroup).WarnContext(ctx, "failed to decode cached value", slog.String("key", key), slog.Any("error", err))
+		return false
+	}
+
+	return true
+}
+
+func (r *Repository) store(ctx context.Context, key string, value any, ttl time.Duration) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	if err := r.client.Set(ctx, key, raw, ttl).Err(); err != nil {
+		logger.FromContext(ctx, r.logger, logGroup).WarnContext(ctx, "failed to write cache", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+func (r *Repository) subscriptionKey(id uuid.UUID) string {
+	return r.prefix + ":subscription:" + id.String()
+}
+
+func (r *Repository) generationKey() string {
+	return r.prefix + ":list:generation"
+}
+
+func subscriptionIDs(subs []domain.Subscription) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(subs))
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	catdomain "github.com/Kulibyka/effective-mobile/internal/domain/category"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	categorysvc "github.com/Kulibyka/effective-mobile/internal/services/categories"
+	suggestionsvc "github.com/Kulibyka/effective-mobile/internal/services/suggestions"
+)
+
+// Suggestions invalidates the cached lists when confirming a suggestion
+// creates a subscription, which does not go through Repository.
+type Suggestions struct {
+	suggestionsvc.Repository
+	// cache wraps no repository and is only used to invalidate.
+	cache *Repository
+}
+
+func NewSuggestions(repo suggestionsvc.Repository, client *redis.Client, cfg config.CacheConfig, logger *slog.Logger) *Suggestions {
+	return &Suggestions{Repository: repo, cache: newRepository(nil, client, cfg, logger)}
+}
+
+func (r *Suggestions) ConfirmSuggestion(ctx context.Context, id uuid.UUID, input domain.CreateInput) (domain.Subscription, error) {
+	sub, err := r.Repository.ConfirmSuggestion(ctx, id, input)
+	if err == nil {
+		r.cache.invalidate(ctx)
+	}
+
+	return sub, err
+}
+
+// Categories invalidates the cached lists whenever a category changes:
+// lists filtered by category depend on which services belong to it.
+type Categories struct {
+	categorysvc.Repository
+	// cache wraps no repository and is only used to invalidate.
+	cache *Repository
+}
+
+func NewCategories(repo categorysvc.Repository, client *redis.Client, cfg config.CacheConfig, logger *slog.Logger) *Categories {
+	return &Categories{Repository: repo, cache: newRepository(nil, client, cfg, logger)}
+}
+
+func (r *Categories) CreateCategory(ctx context.Context, input catdomain.Input) (catdomain.Category, error) {
+	cat, err := r.Repository.CreateCategory(ctx, input)
+	if err == nil {
+		r.cache.invalidate(ctx)
+	}
+
+	return cat, err
+}
+
+func (r *Categories) UpdateCategory(ctx context.Context, id uuid.UUID, input catdomain.Input) (catdomain.Category, error) {
+	cat, err := r.Repository.UpdateCategory(ctx, id, input)
+	if err == nil {
+		r.cache.invalidate(ctx)
+	}
+
+	return cat, err
+}
+
+func (r *Categories) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	err := r.Repository.DeleteCategory(ctx, id)
+	if err == nil {
+		r.cache.invalidate(ctx)
+	}
+
+	return err
+}
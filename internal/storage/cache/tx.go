@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	service "github.com/Kulibyka/effective-mobile/internal/services/subscriptions"
+)
+
+// txChanges collects what a transaction wrote so the cache can be
+// invalidated after it commits.
+type txChanges struct {
+	ids   []uuid.UUID
+	dirty bool
+}
+
+func (c *txChanges) record(ids ...uuid.UUID) {
+	c.ids = append(c.ids, ids...)
+	c.dirty = true
+}
+
+type txRecorder struct {
+	service.Repository
+	changes *txChanges
+}
+
+func (r *txRecorder) CreateSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, error) {
+	sub, err := r.Repository.CreateSubscription(ctx, input)
+	if err == nil {
+		r.changes.record()
+	}
+
+	return sub, err
+}
+
+func (r *txRecorder) UpsertSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, domain.UpsertOutcome, error) {
+	sub, outcome, err := r.Repository.UpsertSubscription(ctx, input)
+	if err == nil && outcome != domain.UpsertUnchanged {
+		r.changes.record(sub.ID)
+	}
+
+	return sub, outcome, err
+}
+
+func (r *txRecorder) UpdateSubscription(ctx context.Context, id uuid.UUID, input domain.UpdateInput) (domain.Subscription, error) {
+	sub, err := r.Repository.UpdateSubscription(ctx, id, input)
+	if err == nil {
+		r.changes.record(id)
+	}
+
+	return sub, err
+}
+
+func (r *txRecorder) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	err := r.Repository.DeleteSubscription(ctx, id)
+	if err == nil {
+		r.changes.record(id)
+	}
+
+	return err
+}
+
+func (r *txRecorder) ExpireSubscriptions(ctx context.Context, before domain.MonthKey) ([]domain.Subscription, error) {
+	subs, err := r.Repository.ExpireSubscriptions(ctx, before)
+	if err == nil && len(subs) > 0 {
+		r.changes.record(subscriptionIDs(subs)...)
+	}
+
+	return subs, err
+}
+
+func (r *txRecorder) AdjustPrices(ctx context.Context, input domain.PriceAdjustment) ([]domain.Subscription, error) {
+	subs, err := r.Repository.AdjustPrices(ctx, input)
+	if err == nil && len(subs) > 0 {
+		r.changes.record(subscriptionIDs(subs)...)
+	}
+
+	return subs, err
+}
+
+func (r *txRecorder) ApplyPriceChange(ctx context.Context, id int64) ([]domain.Subscription, error) {
+	subs, err := r.Repository.ApplyPriceChange(ctx, id)
+	if err == nil && len(subs) > 0 {
+		r.changes.record(subscriptionIDs(subs)...)
+	}
+
+	return subs, err
+}
+
+func (r *txRecorder) WithinTx(ctx context.Context, fn func(repo service.Repository) error) error {
+	return r.Repository.WithinTx(ctx, func(repo service.Repository) error {
+		return fn(&txRecorder{Repository: repo, changes: r.changes})
+	})
+}
+
+func (r *txRecorder) PurgeEndedSubscriptions(ctx context.Context, before domain.MonthKey, limit int, archive bool) ([]domain.Subscription, error) {
+	subs, err := r.Repository.PurgeEndedSubscriptions(ctx, before, limit, archive)
+	if err == nil && len(subs) > 0 {
+		r.changes.record(subscriptionIDs(subs)...)
+	}
+
+	return subs, err
+}
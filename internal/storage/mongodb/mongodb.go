@@ -0,0 +1,151 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+const (
+	subscriptionsCollection = "subscriptions"
+	historyCollection       = "subscription_history"
+	priceChangesCollection  = "price_changes"
+	countersCollection      = "counters"
+
+	closeTimeout = 5 * time.Second
+)
+
+// Storage keeps subscriptions, their history and scheduled price changes as
+// documents. A version change and its history entry are written in one
+// transaction, so the deployment has to be a replica set or a sharded
+// cluster.
+type Storage struct {
+	client  *mongo.Client
+	db      *mongo.Database
+	session *mongo.Session
+}
+
+func New(ctx context.Context, cfg config.MongoConfig) (*Storage, error) {
+	const op = "storage.mongodb.New"
+
+	client, err := mongo.Connect(options.Client().ApplyURI(cfg.URI).SetTimeout(cfg.Timeout))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s := &Storage{client: client, db: client.Database(cfg.Database)}
+	if err := s.ensureIndexes(ctx); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+func (s *Storage) ensureIndexes(ctx context.Context) error {
+	indexes := map[string][]mongo.IndexModel{
+		subscriptionsCollection: {
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "start_month_key", Value: 1}}},
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "service_name", Value: 1}, {Key: "start_month_key", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "service_name", Value: 1}}},
+			{Keys: bson.D{{Key: "start_month_key", Value: 1}}},
+		},
+		historyCollection: {
+			{Keys: bson.D{{Key: "subscription_id", Value: 1}, {Key: "snapshot.version", Value: 1}}},
+			{Keys: bson.D{{Key: "valid_from", Value: 1}, {Key: "valid_to", Value: 1}}},
+		},
+		priceChangesCollection: {
+			{Keys: bson.D{{Key: "applied_at", Value: 1}, {Key: "effective_month_key", Value: 1}}},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := s.db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("create %s indexes: %w", collection, err)
+		}
+	}
+
+	return nil
+}
+
+// WithinTx runs fn against a Storage bound to one multi-document transaction.
+// Transactions need a replica set or a sharded cluster.
+func (s *Storage) WithinTx(ctx context.Context, fn func(tx *Storage) error) error {
+	const op = "storage.mongodb.WithinTx"
+
+	session, err := s.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, fn(&Storage{client: s.client, db: s.db, session: session})
+	})
+
+	return err
+}
+
+// atomically runs fn in a transaction of its own unless s is already bound
+// to one by WithinTx.
+func (s *Storage) atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.session != nil {
+		return fn(s.bind(ctx))
+	}
+
+	session, err := s.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+
+	return err
+}
+
+// bind attaches the transaction of a Storage returned by WithinTx to ctx.
+func (s *Storage) bind(ctx context.Context) context.Context {
+	if s.session == nil {
+		return ctx
+	}
+
+	return mongo.NewSessionContext(ctx, s.session)
+}
+
+func (s *Storage) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	return s.client.Disconnect(ctx)
+}
+
+// Ping checks that the deployment answers.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.mongodb.Ping"
+
+	if err := s.client.Ping(ctx, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
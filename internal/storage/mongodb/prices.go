@@ -0,0 +1,183 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+const priceChangeCounter = "price_changes"
+
+type priceChangeDoc struct {
+	ID             int64      `bson:"_id"`
+	ServiceName    string     `bson:"service_name"`
+	Price          int        `bson:"price"`
+	EffectiveMonth time.Time  `bson:"effective_month"`
+	EffectiveKey   int        `bson:"effective_month_key"`
+	CreatedAt      time.Time  `bson:"created_at"`
+	NotifiedAt     *time.Time `bson:"notified_at"`
+	AppliedAt      *time.Time `bson:"applied_at"`
+}
+
+func (s *Storage) SchedulePriceChange(ctx context.Context, input domain.PriceAdjustment) (domain.PriceChange, error) {
+	const op = "storage.mongodb.SchedulePriceChange"
+	ctx = s.bind(ctx)
+
+	id, err := s.nextID(ctx, priceChangeCounter)
+	if err != nil {
+		return domain.PriceChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	doc := priceChangeDoc{
+		ID:             id,
+		ServiceName:    input.ServiceName,
+		Price:          input.Price,
+		EffectiveMonth: input.EffectiveMonth,
+		EffectiveKey:   int(domain.MonthKeyOf(input.EffectiveMonth)),
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	if _, err := s.db.Collection(priceChangesCollection).InsertOne(ctx, doc); err != nil {
+		return domain.PriceChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return doc.toDomain(), nil
+}
+
+// PendingPriceChanges returns the changes not applied yet that take effect in
+// or before the through month, ordered by effective month.
+func (s *Storage) PendingPriceChanges(ctx context.Context, through domain.MonthKey) ([]domain.PriceChange, error) {
+	const op = "storage.mongodb.PendingPriceChanges"
+	ctx = s.bind(ctx)
+
+	filter := bson.D{
+		{Key: "applied_at", Value: nil},
+		{Key: "effective_month_key", Value: bson.D{{Key: "$lte", Value: int(through)}}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "effective_month_key", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := s.db.Collection(priceChangesCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var docs []priceChangeDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := make([]domain.PriceChange, 0, len(docs))
+	for _, doc := range docs {
+		result = append(result, doc.toDomain())
+	}
+
+	return result, nil
+}
+
+// PriceIncreaseRecipients returns the subscriptions whose price goes up when
+// change is applied.
+func (s *Storage) PriceIncreaseRecipients(ctx context.Context, change domain.PriceChange) ([]domain.Subscription, error) {
+	const op = "storage.mongodb.PriceIncreaseRecipients"
+
+	filter := bson.D{
+		{Key: "service_name", Value: change.ServiceName},
+		{Key: "price", Value: bson.D{{Key: "$lt", Value: change.Price}}},
+		runningSince("", domain.MonthKeyOf(change.EffectiveMonth)),
+	}
+
+	docs, err := s.findSubscriptions(s.bind(ctx), filter, options.Find().SetSort(bson.D{{Key: "user_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := make([]domain.Subscription, 0, len(docs))
+	for _, doc := range docs {
+		result = append(result, doc.toDomain())
+	}
+
+	return result, nil
+}
+
+func (s *Storage) MarkPriceChangeNotified(ctx context.Context, id int64) error {
+	const op = "storage.mongodb.MarkPriceChangeNotified"
+
+	_, err := s.db.Collection(priceChangesCollection).UpdateByID(s.bind(ctx), id,
+		bson.D{{Key: "$set", Value: bson.D{{Key: "notified_at", Value: time.Now().UTC()}}}})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ApplyPriceChange adjusts the subscriptions and then marks the change
+// applied. Outside WithinTx the two steps are separate writes; adjusting is
+// idempotent, so a change left pending by a failure is simply applied again.
+func (s *Storage) ApplyPriceChange(ctx context.Context, id int64) ([]domain.Subscription, error) {
+	const op = "storage.mongodb.ApplyPriceChange"
+	ctx = s.bind(ctx)
+
+	var doc priceChangeDoc
+	err := s.db.Collection(priceChangesCollection).FindOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "applied_at", Value: nil}}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, err := s.adjustPrices(ctx, domain.PriceAdjustment{
+		ServiceName:    doc.ServiceName,
+		Price:          doc.Price,
+		EffectiveMonth: doc.EffectiveMonth,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Collection(priceChangesCollection).UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: id}, {Key: "applied_at", Value: nil}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "applied_at", Value: time.Now().UTC()}}}})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// nextID hands out sequential int64 IDs per counter, matching the bigserial
+// IDs the PostgreSQL backend exposes.
+func (s *Storage) nextID(ctx context.Context, counter string) (int64, error) {
+	var doc struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	err := s.db.Collection(countersCollection).FindOneAndUpdate(ctx,
+		bson.D{{Key: "_id", Value: counter}},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "seq", Value: int64(1)}}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&doc)
+	if err != nil {
+		return 0, err
+	}
+
+	return doc.Seq, nil
+}
+
+func (d priceChangeDoc) toDomain() domain.PriceChange {
+	return domain.PriceChange{
+		ID:             d.ID,
+		ServiceName:    d.ServiceName,
+		Price:          d.Price,
+		EffectiveMonth: d.EffectiveMonth,
+		CreatedAt:      d.CreatedAt,
+		NotifiedAt:     d.NotifiedAt,
+		AppliedAt:      d.AppliedAt,
+	}
+}
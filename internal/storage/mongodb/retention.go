@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+// PurgeEndedSubscriptions deletes up to limit subscriptions that ended before
+// the given month and returns the removed ones. With archive the documents
+// are kept in the history as deleted; otherwise their history goes too.
+func (s *Storage) PurgeEndedSubscriptions(ctx context.Context, before domain.MonthKey, limit int, archive bool) ([]domain.Subscription, error) {
+	const op = "storage.mongodb.PurgeEndedSubscriptions"
+	ctx = s.bind(ctx)
+
+	ended := bson.D{{Key: "end_month_key", Value: bson.D{{Key: "$ne", Value: nil}, {Key: "$lt", Value: int(before)}}}}
+
+	candidates, err := s.findSubscriptions(ctx, ended,
+		options.Find().SetSort(bson.D{{Key: "end_month_key", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var result []domain.Subscription
+	for _, doc := range candidates {
+		err := s.atomically(ctx, func(ctx context.Context) error {
+			var prev subscriptionDoc
+			filter := append(bson.D{{Key: "_id", Value: doc.ID}}, ended...)
+			if err := s.db.Collection(subscriptionsCollection).FindOneAndDelete(ctx, filter).Decode(&prev); err != nil {
+				return err
+			}
+
+			if archive {
+				return s.archive(ctx, prev, historyDelete)
+			}
+
+			_, err := s.db.Collection(historyCollection).DeleteMany(ctx, bson.D{{Key: "subscription_id", Value: prev.ID}})
+			return err
+		})
+		if err != nil {
+			// Changed since it was listed and no longer ended.
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				continue
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		result = append(result, doc.toDomain())
+	}
+
+	return result, nil
+}
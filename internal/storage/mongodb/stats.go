@@ -0,0 +1,62 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+// ServiceStats matches the PostgreSQL backend: services ranked by their
+// number of distinct active subscribers, tied services sharing a rank.
+func (s *Storage) ServiceStats(ctx context.Context, minSubscribers int) ([]domain.ServiceStats, error) {
+	const op = "storage.mongodb.ServiceStats"
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "status", Value: string(domain.StatusActive)}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$service_name"},
+			{Key: "users", Value: bson.D{{Key: "$addToSet", Value: "$user_id"}}},
+			{Key: "average_price", Value: bson.D{{Key: "$avg", Value: "$price"}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "subscribers", Value: bson.D{{Key: "$size", Value: "$users"}}},
+			{Key: "average_price", Value: 1},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "subscribers", Value: bson.D{{Key: "$gte", Value: minSubscribers}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "subscribers", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := s.db.Collection(subscriptionsCollection).Aggregate(s.bind(ctx), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var docs []struct {
+		ServiceName  string  `bson:"_id"`
+		Subscribers  int     `bson:"subscribers"`
+		AveragePrice float64 `bson:"average_price"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := make([]domain.ServiceStats, 0, len(docs))
+	for i, doc := range docs {
+		rank := i + 1
+		if i > 0 && doc.Subscribers == result[i-1].Subscribers {
+			rank = result[i-1].Rank
+		}
+		result = append(result, domain.ServiceStats{
+			ServiceName:  doc.ServiceName,
+			Subscribers:  doc.Subscribers,
+			AveragePrice: doc.AveragePrice,
+			Rank:         rank,
+		})
+	}
+
+	return result, nil
+}
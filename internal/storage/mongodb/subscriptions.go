@@ -0,0 +1,851 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+const (
+	historyUpdate = "update"
+	historyDelete = "delete"
+)
+
+// errCategoryFilter guards against a category filter reaching the store:
+// categories live in PostgreSQL, so callers resolve them into service names.
+var errCategoryFilter = errors.New("category filter is not supported, resolve it into service names")
+
+// subscriptionDoc stores the month keys next to the month dates, as the
+// PostgreSQL schema does, so range filters compare plain integers.
+type subscriptionDoc struct {
+	ID          string     `bson:"_id"`
+	ServiceName string     `bson:"service_name"`
+	Price       int        `bson:"price"`
+	UserID      string     `bson:"user_id"`
+	StartMonth  time.Time  `bson:"start_month"`
+	StartKey    int        `bson:"start_month_key"`
+	EndMonth    *time.Time `bson:"end_month"`
+	EndKey      *int       `bson:"end_month_key"`
+	Status      string     `bson:"status"`
+	Version     int        `bson:"version"`
+	UpdatedAt   time.Time  `bson:"updated_at"`
+}
+
+type historyDoc struct {
+	SubscriptionID string          `bson:"subscription_id"`
+	Snapshot       subscriptionDoc `bson:"snapshot"`
+	ValidFrom      time.Time       `bson:"valid_from"`
+	ValidTo        time.Time       `bson:"valid_to"`
+	Operation      string          `bson:"operation"`
+}
+
+func (s *Storage) CreateSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, error) {
+	const op = "storage.mongodb.CreateSubscription"
+	ctx = s.bind(ctx)
+
+	id := uuid.NewV7()
+	if input.ID != nil {
+		id = *input.ID
+	}
+
+	doc := subscriptionDoc{
+		ID:          id.String(),
+		ServiceName: input.ServiceName,
+		Price:       input.Price,
+		UserID:      input.UserID.String(),
+		Status:      string(domain.StatusActive),
+		Version:     1,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	setMonths(&doc, input.StartMonth, input.EndMonth)
+
+	if _, err := s.db.Collection(subscriptionsCollection).InsertOne(ctx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// The clash is either on the client-supplied ID or on the
+			// user, service and start month.
+			if input.ID != nil {
+				sub, err := s.existingSubscription(ctx, op, input)
+				if !errors.Is(err, domain.ErrNotFound) {
+					return sub, err
+				}
+			}
+			return domain.Subscription{}, domain.ErrDuplicate
+		}
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return doc.toDomain(), nil
+}
+
+// existingSubscription resolves a create whose client-supplied ID is taken:
+// a retry with the same content gets the stored document, anything else
+// conflicts.
+func (s *Storage) existingSubscription(ctx context.Context, op string, input domain.CreateInput) (domain.Subscription, error) {
+	sub, err := s.GetSubscription(ctx, *input.ID)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sameEnd := (sub.EndMonth == nil && input.EndMonth == nil) ||
+		(sub.EndMonth != nil && input.EndMonth != nil && domain.MonthKeyOf(*sub.EndMonth) == domain.MonthKeyOf(*input.EndMonth))
+
+	if sub.ServiceName != input.ServiceName ||
+		sub.Price != input.Price ||
+		sub.UserID != input.UserID ||
+		domain.MonthKeyOf(sub.StartMonth) != domain.MonthKeyOf(input.StartMonth) ||
+		!sameEnd {
+		return domain.Subscription{}, domain.ErrConflict
+	}
+
+	return sub, domain.ErrAlreadyExists
+}
+
+// UpsertSubscription creates the subscription or updates the price and end
+// month of the one with the same user, service and start month. A create
+// racing with another one for the same key is retried as an update.
+func (s *Storage) UpsertSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, domain.UpsertOutcome, error) {
+	const op = "storage.mongodb.UpsertSubscription"
+
+	key := bson.D{
+		{Key: "user_id", Value: input.UserID.String()},
+		{Key: "service_name", Value: input.ServiceName},
+		{Key: "start_month_key", Value: int(domain.MonthKeyOf(input.StartMonth))},
+	}
+
+	for attempt := 0; ; attempt++ {
+		var doc subscriptionDoc
+		err := s.db.Collection(subscriptionsCollection).FindOne(s.bind(ctx), key).Decode(&doc)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			sub, err := s.CreateSubscription(ctx, domain.CreateInput{
+				ID:          input.ID,
+				ServiceName: input.ServiceName,
+				Price:       input.Price,
+				UserID:      input.UserID,
+				StartMonth:  input.StartMonth,
+				EndMonth:    input.EndMonth,
+			})
+			if errors.Is(err, domain.ErrDuplicate) && attempt == 0 {
+				continue
+			}
+			if err != nil {
+				return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+			}
+			return sub, domain.UpsertCreated, nil
+		}
+		if err != nil {
+			return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		current := doc.toDomain()
+		sameEnd := (current.EndMonth == nil && input.EndMonth == nil) ||
+			(current.EndMonth != nil && input.EndMonth != nil && domain.MonthKeyOf(*current.EndMonth) == domain.MonthKeyOf(*input.EndMonth))
+		if current.Price == input.Price && sameEnd {
+			return current, domain.UpsertUnchanged, nil
+		}
+
+		version := current.Version
+		sub, err := s.UpdateSubscription(ctx, current.ID, domain.UpdateInput{
+			ServiceName: input.ServiceName,
+			Price:       input.Price,
+			StartMonth:  input.StartMonth,
+			EndMonth:    input.EndMonth,
+			Version:     &version,
+		})
+		if (errors.Is(err, domain.ErrConflict) || errors.Is(err, domain.ErrNotFound)) && attempt == 0 {
+			continue
+		}
+		if err != nil {
+			return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		return sub, domain.UpsertUpdated, nil
+	}
+}
+
+func (s *Storage) GetSubscription(ctx context.Context, id uuid.UUID) (domain.Subscription, error) {
+	const op = "storage.mongodb.GetSubscription"
+
+	var doc subscriptionDoc
+	if err := s.db.Collection(subscriptionsCollection).FindOne(s.bind(ctx), bson.D{{Key: "_id", Value: id.String()}}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.Subscription{}, domain.ErrNotFound
+		}
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return doc.toDomain(), nil
+}
+
+func (s *Storage) UpdateSubscription(ctx context.Context, id uuid.UUID, input domain.UpdateInput) (domain.Subscription, error) {
+	const op = "storage.mongodb.UpdateSubscription"
+	ctx = s.bind(ctx)
+
+	filter := bson.D{{Key: "_id", Value: id.String()}}
+	if input.Version != nil {
+		filter = append(filter, bson.E{Key: "version", Value: *input.Version})
+	}
+
+	var next subscriptionDoc
+	setMonths(&next, input.StartMonth, input.EndMonth)
+	now := time.Now().UTC()
+
+	set := bson.D{
+		{Key: "service_name", Value: input.ServiceName},
+		{Key: "price", Value: input.Price},
+		{Key: "start_month", Value: next.StartMonth},
+		{Key: "start_month_key", Value: next.StartKey},
+		{Key: "end_month", Value: next.EndMonth},
+		{Key: "end_month_key", Value: next.EndKey},
+		{Key: "updated_at", Value: now},
+		{Key: "version", Value: bson.D{{Key: "$add", Value: bson.A{"$version", 1}}}},
+	}
+
+	// An expired subscription whose end moves to the current month or later
+	// becomes active again.
+	reactivate := next.EndKey == nil || *next.EndKey >= int(domain.MonthKeyOf(now))
+	if reactivate {
+		set = append(set, bson.E{Key: "status", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$eq", Value: bson.A{"$status", string(domain.StatusExpired)}}},
+			string(domain.StatusActive),
+			"$status",
+		}}}})
+	}
+
+	prev, err := s.modify(ctx, filter, mongo.Pipeline{{{Key: "$set", Value: set}}}, historyUpdate)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if input.Version != nil {
+				return domain.Subscription{}, s.versionMismatch(ctx, op, id)
+			}
+			return domain.Subscription{}, domain.ErrNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.Subscription{}, domain.ErrDuplicate
+		}
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	next.ID = prev.ID
+	next.UserID = prev.UserID
+	next.ServiceName = input.ServiceName
+	next.Price = input.Price
+	next.Status = prev.Status
+	if reactivate && prev.Status == string(domain.StatusExpired) {
+		next.Status = string(domain.StatusActive)
+	}
+	next.Version = prev.Version + 1
+	next.UpdatedAt = now
+
+	return next.toDomain(), nil
+}
+
+// versionMismatch tells a stale version apart from a missing subscription
+// after a versioned update matched nothing.
+func (s *Storage) versionMismatch(ctx context.Context, op string, id uuid.UUID) error {
+	count, err := s.db.Collection(subscriptionsCollection).CountDocuments(ctx, bson.D{{Key: "_id", Value: id.String()}})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if count > 0 {
+		return domain.ErrConflict
+	}
+
+	return domain.ErrNotFound
+}
+
+func (s *Storage) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.mongodb.DeleteSubscription"
+	ctx = s.bind(ctx)
+
+	err := s.atomically(ctx, func(ctx context.Context) error {
+		var prev subscriptionDoc
+		err := s.db.Collection(subscriptionsCollection).FindOneAndDelete(ctx, bson.D{{Key: "_id", Value: id.String()}}).Decode(&prev)
+		if err != nil {
+			return err
+		}
+
+		return s.archive(ctx, prev, historyDelete)
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.ErrNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ListSubscriptions(ctx context.Context, filter domain.ListFilter) (domain.Page, error) {
+	const op = "storage.mongodb.ListSubscriptions"
+	ctx = s.bind(ctx)
+
+	if !filter.CategoryID.IsNil() {
+		return domain.Page{}, fmt.Errorf("%s: %w", op, errCategoryFilter)
+	}
+
+	if filter.AsOf != nil {
+		page, err := s.listAsOf(ctx, filter)
+		if err != nil {
+			return domain.Page{}, fmt.Errorf("%s: %w", op, err)
+		}
+		return page, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "start_month_key", Value: 1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		opts.SetSkip(int64(filter.Offset))
+	}
+
+	conditions := listConditions(filter, "")
+
+	docs, err := s.findSubscriptions(ctx, conditions, opts)
+	if err != nil {
+		return domain.Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	page := domain.Page{Subscriptions: make([]domain.Subscription, 0, len(docs))}
+	for _, doc := range docs {
+		page.Subscriptions = append(page.Subscriptions, doc.toDomain())
+	}
+
+	if filter.WithTotal {
+		total, err := s.db.Collection(subscriptionsCollection).CountDocuments(ctx, conditions)
+		if err != nil {
+			return domain.Page{}, fmt.Errorf("%s: %w", op, err)
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// ListSubscriptionsFunc calls fn for every subscription matching filter while
+// iterating the cursor, so memory stays flat however many documents match.
+// As-of listings merge two collections and are still collected first.
+// WithTotal is ignored. An error from fn stops the iteration and is returned
+// as is.
+func (s *Storage) ListSubscriptionsFunc(ctx context.Context, filter domain.ListFilter, fn func(domain.Subscription) error) error {
+	const op = "storage.mongodb.ListSubscriptionsFunc"
+	ctx = s.bind(ctx)
+
+	if !filter.CategoryID.IsNil() {
+		return fmt.Errorf("%s: %w", op, errCategoryFilter)
+	}
+
+	if filter.AsOf != nil {
+		page, err := s.listAsOf(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, sub := range page.Subscriptions {
+			if err := fn(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "start_month_key", Value: 1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		opts.SetSkip(int64(filter.Offset))
+	}
+
+	cursor, err := s.db.Collection(subscriptionsCollection).Find(ctx, listConditions(filter, ""), opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc subscriptionDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := fn(doc.toDomain()); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ServiceNames returns the distinct service names of the subscriptions
+// matching filter, sorted.
+func (s *Storage) ServiceNames(ctx context.Context, filter domain.ListFilter) ([]string, error) {
+	const op = "storage.mongodb.ServiceNames"
+
+	if !filter.CategoryID.IsNil() {
+		return nil, fmt.Errorf("%s: %w", op, errCategoryFilter)
+	}
+
+	var names []string
+	if err := s.db.Collection(subscriptionsCollection).Distinct(s.bind(ctx), "service_name", listConditions(filter, "")).Decode(&names); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// UserSpend groups the subscriptions running in month by user, most
+// expensive first.
+func (s *Storage) UserSpend(ctx context.Context, month domain.MonthKey, filter domain.ListFilter) ([]domain.UserSpend, error) {
+	const op = "storage.mongodb.UserSpend"
+
+	if !filter.CategoryID.IsNil() {
+		return nil, fmt.Errorf("%s: %w", op, errCategoryFilter)
+	}
+
+	at := month.Time()
+	filter.ActivePeriodFrom, filter.ActivePeriodTo = &at, &at
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: listConditions(filter, "")}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user_id"},
+			{Key: "active_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "monthly_total", Value: bson.D{{Key: "$sum", Value: "$price"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "monthly_total", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	if filter.Offset > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: filter.Offset}})
+	}
+	if filter.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: filter.Limit}})
+	}
+
+	cursor, err := s.db.Collection(subscriptionsCollection).Aggregate(s.bind(ctx), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var docs []struct {
+		UserID       string `bson:"_id"`
+		ActiveCount  int    `bson:"active_count"`
+		MonthlyTotal int    `bson:"monthly_total"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := make([]domain.UserSpend, 0, len(docs))
+	for _, doc := range docs {
+		userID, err := uuid.Parse(doc.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, domain.UserSpend{UserID: userID, ActiveCount: doc.ActiveCount, MonthlyTotal: doc.MonthlyTotal})
+	}
+
+	return result, nil
+}
+
+// listAsOf rebuilds the subscriptions as they were just before the start of
+// the month following AsOf: current documents last changed before then plus
+// the history versions that were current at that instant.
+func (s *Storage) listAsOf(ctx context.Context, filter domain.ListFilter) (domain.Page, error) {
+	cutoff := domain.MonthKeyOf(*filter.AsOf).Next().Time()
+
+	current, err := s.findSubscriptions(ctx, append(listConditions(filter, ""),
+		bson.E{Key: "updated_at", Value: bson.D{{Key: "$lt", Value: cutoff}}}))
+	if err != nil {
+		return domain.Page{}, err
+	}
+
+	historyFilter := append(listConditions(filter, "snapshot."),
+		bson.E{Key: "valid_from", Value: bson.D{{Key: "$lt", Value: cutoff}}},
+		bson.E{Key: "valid_to", Value: bson.D{{Key: "$gte", Value: cutoff}}})
+
+	cursor, err := s.db.Collection(historyCollection).Find(ctx, historyFilter)
+	if err != nil {
+		return domain.Page{}, err
+	}
+
+	var versions []historyDoc
+	if err := cursor.All(ctx, &versions); err != nil {
+		return domain.Page{}, err
+	}
+
+	for _, version := range versions {
+		current = append(current, version.Snapshot)
+	}
+
+	sort.SliceStable(current, func(i, j int) bool { return current[i].StartKey < current[j].StartKey })
+
+	var page domain.Page
+	if filter.WithTotal {
+		total := int64(len(current))
+		page.Total = &total
+	}
+
+	if filter.Offset > 0 {
+		current = current[min(filter.Offset, len(current)):]
+	}
+	if filter.Limit > 0 && len(current) > filter.Limit {
+		current = current[:filter.Limit]
+	}
+
+	page.Subscriptions = make([]domain.Subscription, 0, len(current))
+	for _, doc := range current {
+		page.Subscriptions = append(page.Subscriptions, doc.toDomain())
+	}
+
+	return page, nil
+}
+
+// EstimateSubscriptions counts the documents matching filter. Unlike the
+// planner estimate in PostgreSQL it is exact, but still far cheaper than
+// fetching them.
+func (s *Storage) EstimateSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error) {
+	const op = "storage.mongodb.EstimateSubscriptions"
+
+	if !filter.CategoryID.IsNil() {
+		return 0, fmt.Errorf("%s: %w", op, errCategoryFilter)
+	}
+
+	count, err := s.db.Collection(subscriptionsCollection).CountDocuments(s.bind(ctx), listConditions(filter, ""))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// MonthlySpend always reports the rollup as unavailable; summaries are
+// computed from the listed subscriptions.
+func (s *Storage) MonthlySpend(context.Context, domain.ListFilter) ([]domain.MonthTotal, bool, error) {
+	return nil, false, nil
+}
+
+func (s *Storage) SubscriptionHistory(ctx context.Context, id uuid.UUID) ([]domain.HistoryEntry, error) {
+	const op = "storage.mongodb.SubscriptionHistory"
+	ctx = s.bind(ctx)
+
+	cursor, err := s.db.Collection(historyCollection).Find(ctx, bson.D{{Key: "subscription_id", Value: id.String()}},
+		options.Find().SetSort(bson.D{{Key: "snapshot.version", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var versions []historyDoc
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := make([]domain.HistoryEntry, 0, len(versions)+1)
+	for _, version := range versions {
+		validTo := version.ValidTo
+		result = append(result, domain.HistoryEntry{
+			Subscription: version.Snapshot.toDomain(),
+			ValidFrom:    version.ValidFrom,
+			ValidTo:      &validTo,
+			Operation:    version.Operation,
+		})
+	}
+
+	var doc subscriptionDoc
+	err = s.db.Collection(subscriptionsCollection).FindOne(ctx, bson.D{{Key: "_id", Value: id.String()}}).Decode(&doc)
+	switch {
+	case err == nil:
+		result = append(result, domain.HistoryEntry{Subscription: doc.toDomain(), ValidFrom: doc.UpdatedAt})
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) ExpireSubscriptions(ctx context.Context, before domain.MonthKey) ([]domain.Subscription, error) {
+	const op = "storage.mongodb.ExpireSubscriptions"
+	ctx = s.bind(ctx)
+
+	condition := bson.D{
+		{Key: "status", Value: string(domain.StatusActive)},
+		{Key: "end_month_key", Value: bson.D{{Key: "$ne", Value: nil}, {Key: "$lt", Value: int(before)}}},
+	}
+
+	result, err := s.modifyMatching(ctx, condition, func(doc *subscriptionDoc) bson.D {
+		doc.Status = string(domain.StatusExpired)
+		return bson.D{{Key: "status", Value: doc.Status}}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// AdjustPrices sets the price of every subscription to the service that is
+// still running in or after the effective month, keeping the old versions in
+// the history. A subscription that started before the effective month ends
+// the month before it and continues as a new subscription with the new price.
+func (s *Storage) AdjustPrices(ctx context.Context, input domain.PriceAdjustment) ([]domain.Subscription, error) {
+	const op = "storage.mongodb.AdjustPrices"
+
+	result, err := s.adjustPrices(s.bind(ctx), input)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) adjustPrices(ctx context.Context, input domain.PriceAdjustment) ([]domain.Subscription, error) {
+	effective := domain.MonthKeyOf(input.EffectiveMonth)
+	condition := bson.D{
+		{Key: "service_name", Value: input.ServiceName},
+		{Key: "price", Value: bson.D{{Key: "$ne", Value: input.Price}}},
+		runningSince("", effective),
+	}
+
+	ends := make(map[string]*time.Time)
+	lastMonth := effective.Prev().Time()
+	closed, err := s.modifyMatching(ctx, append(condition, bson.E{Key: "start_month_key", Value: bson.D{{Key: "$lt", Value: int(effective)}}}),
+		func(doc *subscriptionDoc) bson.D {
+			ends[doc.ID] = doc.EndMonth
+			setMonths(doc, doc.StartMonth, &lastMonth)
+			return bson.D{{Key: "end_month", Value: doc.EndMonth}, {Key: "end_month_key", Value: doc.EndKey}}
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	result := slices.Clone(closed)
+	for _, sub := range closed {
+		doc := subscriptionDoc{
+			ID:          uuid.NewV7().String(),
+			ServiceName: sub.ServiceName,
+			Price:       input.Price,
+			UserID:      sub.UserID.String(),
+			Status:      string(sub.Status),
+			Version:     1,
+			UpdatedAt:   time.Now().UTC(),
+		}
+		setMonths(&doc, effective.Time(), ends[sub.ID.String()])
+
+		if _, err := s.db.Collection(subscriptionsCollection).InsertOne(ctx, doc); err != nil {
+			// The user already has a subscription starting in the
+			// effective month; it is repriced below.
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			return nil, err
+		}
+		result = append(result, doc.toDomain())
+	}
+
+	repriced, err := s.modifyMatching(ctx, append(condition, bson.E{Key: "start_month_key", Value: bson.D{{Key: "$gte", Value: int(effective)}}}),
+		func(doc *subscriptionDoc) bson.D {
+			doc.Price = input.Price
+			return bson.D{{Key: "price", Value: doc.Price}}
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(result, repriced...), nil
+}
+
+// modifyMatching applies change to every document matching condition, one
+// document at a time. A document that stops matching before its turn is
+// skipped.
+func (s *Storage) modifyMatching(ctx context.Context, condition bson.D, change func(doc *subscriptionDoc) bson.D) ([]domain.Subscription, error) {
+	candidates, err := s.findSubscriptions(ctx, condition)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []domain.Subscription
+	for _, doc := range candidates {
+		set := change(&doc)
+		now := time.Now().UTC()
+		set = append(set,
+			bson.E{Key: "updated_at", Value: now},
+			bson.E{Key: "version", Value: bson.D{{Key: "$add", Value: bson.A{"$version", 1}}}})
+
+		filter := append(bson.D{{Key: "_id", Value: doc.ID}}, condition...)
+		prev, err := s.modify(ctx, filter, mongo.Pipeline{{{Key: "$set", Value: set}}}, historyUpdate)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				continue
+			}
+			return nil, err
+		}
+
+		doc.Version = prev.Version + 1
+		doc.UpdatedAt = now
+		result = append(result, doc.toDomain())
+	}
+
+	return result, nil
+}
+
+// modify updates the document matching filter and archives the version it
+// replaced, both in one transaction. It returns mongo.ErrNoDocuments when
+// nothing matched.
+func (s *Storage) modify(ctx context.Context, filter bson.D, update mongo.Pipeline, operation string) (subscriptionDoc, error) {
+	var prev subscriptionDoc
+	err := s.atomically(ctx, func(ctx context.Context) error {
+		err := s.db.Collection(subscriptionsCollection).FindOneAndUpdate(ctx, filter, update,
+			options.FindOneAndUpdate().SetReturnDocument(options.Before)).Decode(&prev)
+		if err != nil {
+			return err
+		}
+
+		return s.archive(ctx, prev, operation)
+	})
+	if err != nil {
+		return subscriptionDoc{}, err
+	}
+
+	return prev, nil
+}
+
+func (s *Storage) archive(ctx context.Context, prev subscriptionDoc, operation string) error {
+	_, err := s.db.Collection(historyCollection).InsertOne(ctx, historyDoc{
+		SubscriptionID: prev.ID,
+		Snapshot:       prev,
+		ValidFrom:      prev.UpdatedAt,
+		ValidTo:        time.Now().UTC(),
+		Operation:      operation,
+	})
+	return err
+}
+
+func (s *Storage) findSubscriptions(ctx context.Context, filter bson.D, opts ...options.Lister[options.FindOptions]) ([]subscriptionDoc, error) {
+	cursor, err := s.db.Collection(subscriptionsCollection).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []subscriptionDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// listConditions translates the list filter into a query on the fields under
+// prefix, which is empty for subscriptions and "snapshot." for history.
+func listConditions(filter domain.ListFilter, prefix string) bson.D {
+	conditions := bson.D{}
+
+	if !filter.UserID.IsNil() {
+		conditions = append(conditions, bson.E{Key: prefix + "user_id", Value: filter.UserID.String()})
+	}
+
+	if len(filter.UserIDs) > 0 {
+		ids := make([]string, 0, len(filter.UserIDs))
+		for _, id := range filter.UserIDs {
+			ids = append(ids, id.String())
+		}
+		conditions = append(conditions, bson.E{Key: prefix + "user_id", Value: bson.D{{Key: "$in", Value: ids}}})
+	}
+
+	if filter.ServiceName != nil {
+		conditions = append(conditions, bson.E{Key: prefix + "service_name", Value: *filter.ServiceName})
+	}
+
+	if len(filter.ServiceNames) > 0 {
+		conditions = append(conditions, bson.E{Key: prefix + "service_name", Value: bson.D{{Key: "$in", Value: filter.ServiceNames}}})
+	}
+
+	// MongoDB has no trigram similarity, so search falls back to a
+	// case-insensitive substring match in the usual order.
+	if filter.Search != nil {
+		conditions = append(conditions, bson.E{Key: prefix + "service_name", Value: bson.Regex{Pattern: regexp.QuoteMeta(*filter.Search), Options: "i"}})
+	}
+
+	startKey := bson.D{}
+	if filter.StartMonthFrom != nil {
+		startKey = append(startKey, bson.E{Key: "$gte", Value: int(domain.MonthKeyOf(*filter.StartMonthFrom))})
+	}
+	if filter.StartMonthTo != nil {
+		startKey = append(startKey, bson.E{Key: "$lte", Value: int(domain.MonthKeyOf(*filter.StartMonthTo))})
+	}
+
+	if filter.ActivePeriodFrom != nil && filter.ActivePeriodTo != nil {
+		startKey = append(startKey, bson.E{Key: "$lte", Value: int(domain.MonthKeyOf(*filter.ActivePeriodTo))})
+		conditions = append(conditions, runningSince(prefix, domain.MonthKeyOf(*filter.ActivePeriodFrom)))
+	}
+
+	if len(startKey) > 0 {
+		conditions = append(conditions, bson.E{Key: prefix + "start_month_key", Value: startKey})
+	}
+
+	// Every condition above is a separate key except user_id and
+	// service_name, which may appear twice; $and keeps both.
+	if len(conditions) == 0 {
+		return bson.D{}
+	}
+
+	and := make(bson.A, 0, len(conditions))
+	for _, condition := range conditions {
+		and = append(and, bson.D{condition})
+	}
+
+	return bson.D{{Key: "$and", Value: and}}
+}
+
+// runningSince matches subscriptions without an end month or ending in or
+// after month.
+func runningSince(prefix string, month domain.MonthKey) bson.E {
+	return bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: prefix + "end_month_key", Value: nil}},
+		bson.D{{Key: prefix + "end_month_key", Value: bson.D{{Key: "$gte", Value: int(month)}}}},
+	}}
+}
+
+func setMonths(doc *subscriptionDoc, start time.Time, end *time.Time) {
+	doc.StartMonth = start
+	doc.StartKey = int(domain.MonthKeyOf(start))
+	doc.EndMonth = nil
+	doc.EndKey = nil
+
+	if end != nil {
+		endMonth := *end
+		endKey := int(domain.MonthKeyOf(endMonth))
+		doc.EndMonth = &endMonth
+		doc.EndKey = &endKey
+	}
+}
+
+func (d subscriptionDoc) toDomain() domain.Subscription {
+	return domain.Subscription{
+		ID:          uuid.UUID(d.ID),
+		ServiceName: d.ServiceName,
+		Price:       d.Price,
+		UserID:      uuid.UUID(d.UserID),
+		StartMonth:  d.StartMonth,
+		EndMonth:    d.EndMonth,
+		Status:      domain.Status(d.Status),
+		Version:     d.Version,
+	}
+}
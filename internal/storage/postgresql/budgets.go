@@ -0,0 +1,120 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/category"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+const budgetSelect = `SELECT b.user_id, b.category_id, c.name, b.monthly_limit, b.alerted_at, b.updated_at
+FROM category_budgets b
+JOIN categories c ON c.id = b.category_id`
+
+// SetBudget creates the budget of the user for the category or replaces its
+// limit, clearing the alert so a user still over the new limit is warned
+// again.
+func (s *Storage) SetBudget(ctx context.Context, input domain.BudgetInput) (domain.Budget, error) {
+	const op = "storage.postgresql.SetBudget"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	query := `WITH b AS (
+    INSERT INTO category_budgets (user_id, category_id, monthly_limit)
+    VALUES ($1, $2, $3)
+    ON CONFLICT (user_id, category_id) DO UPDATE
+        SET monthly_limit = EXCLUDED.monthly_limit, alerted_at = NULL, updated_at = NOW()
+    RETURNING user_id, category_id, monthly_limit, alerted_at, updated_at
+)
+SELECT b.user_id, b.category_id, c.name, b.monthly_limit, b.alerted_at, b.updated_at
+FROM b
+JOIN categories c ON c.id = b.category_id`
+
+	budget, err := scanBudget(s.db.QueryRow(ctx, query, input.UserID, input.CategoryID, input.MonthlyLimit))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return domain.Budget{}, domain.ErrNotFound
+		}
+		return domain.Budget{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return budget, nil
+}
+
+// ListBudgets returns the budgets of userID or, when it is Nil, of every
+// user, ordered by user and category name.
+func (s *Storage) ListBudgets(ctx context.Context, userID uuid.UUID) ([]domain.Budget, error) {
+	const op = "storage.postgresql.ListBudgets"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	query := budgetSelect + `
+WHERE $1::uuid IS NULL OR b.user_id = $1
+ORDER BY b.user_id, c.name`
+
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []domain.Budget
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, budget)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) DeleteBudget(ctx context.Context, userID, categoryID uuid.UUID) error {
+	const op = "storage.postgresql.DeleteBudget"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, "DELETE FROM category_budgets WHERE user_id = $1 AND category_id = $2", userID, categoryID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return domain.ErrBudgetNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) MarkBudgetAlerted(ctx context.Context, userID, categoryID uuid.UUID, at time.Time) error {
+	const op = "storage.postgresql.MarkBudgetAlerted"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	if _, err := s.db.Exec(ctx, "UPDATE category_budgets SET alerted_at = $3 WHERE user_id = $1 AND category_id = $2", userID, categoryID, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanBudget(row rowScanner) (domain.Budget, error) {
+	var budget domain.Budget
+	err := row.Scan(&budget.UserID, &budget.CategoryID, &budget.Category, &budget.MonthlyLimit, &budget.AlertedAt, &budget.UpdatedAt)
+	return budget, err
+}
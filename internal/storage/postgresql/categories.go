@@ -0,0 +1,222 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/jackc/pgx/v5"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/category"
+)
+
+const (
+	categorySelect = `SELECT c.id, c.name, c.created_at,
+    COALESCE(array_agg(sc.service_name ORDER BY sc.service_name) FILTER (WHERE sc.service_name IS NOT NULL), '{}')
+FROM categories c
+LEFT JOIN service_categories sc ON sc.category_id = c.id`
+)
+
+func (s *Storage) CreateCategory(ctx context.Context, input domain.Input) (domain.Category, error) {
+	const op = "storage.postgresql.CreateCategory"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var id uuid.UUID
+	if err := tx.QueryRow(ctx, "INSERT INTO categories (name) VALUES ($1) RETURNING id", input.Name).Scan(&id); err != nil {
+		if isUniqueViolation(err) {
+			return domain.Category{}, domain.ErrNameTaken
+		}
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := setCategoryServices(ctx, tx, id, input.Services); err != nil {
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cat, err := scanCategory(tx.QueryRow(ctx, categorySelect+" WHERE c.id = $1 GROUP BY c.id", id))
+	if err != nil {
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cat, nil
+}
+
+func (s *Storage) GetCategory(ctx context.Context, id uuid.UUID) (domain.Category, error) {
+	const op = "storage.postgresql.GetCategory"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	cat, err := scanCategory(s.db.QueryRow(ctx, categorySelect+" WHERE c.id = $1 GROUP BY c.id", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Category{}, domain.ErrNotFound
+		}
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cat, nil
+}
+
+func (s *Storage) ListCategories(ctx context.Context) ([]domain.Category, error) {
+	const op = "storage.postgresql.ListCategories"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, categorySelect+" GROUP BY c.id ORDER BY c.name")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []domain.Category
+	for rows.Next() {
+		cat, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, cat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// UpdateCategory renames the category and replaces its services. Services
+// that belonged to another category are moved to this one.
+func (s *Storage) UpdateCategory(ctx context.Context, id uuid.UUID, input domain.Input) (domain.Category, error) {
+	const op = "storage.postgresql.UpdateCategory"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	res, err := tx.Exec(ctx, "UPDATE categories SET name = $1 WHERE id = $2", input.Name, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.Category{}, domain.ErrNameTaken
+		}
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return domain.Category{}, domain.ErrNotFound
+	}
+
+	if err := setCategoryServices(ctx, tx, id, input.Services); err != nil {
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cat, err := scanCategory(tx.QueryRow(ctx, categorySelect+" WHERE c.id = $1 GROUP BY c.id", id))
+	if err != nil {
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cat, nil
+}
+
+func (s *Storage) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.postgresql.DeleteCategory"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, "DELETE FROM categories WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// ServiceCategories maps every categorised service to its category. The
+// returned categories carry no service lists.
+func (s *Storage) ServiceCategories(ctx context.Context) (map[string]domain.Category, error) {
+	const op = "storage.postgresql.ServiceCategories"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	query := `SELECT sc.service_name, c.id, c.name, c.created_at
+FROM service_categories sc
+JOIN categories c ON c.id = sc.category_id`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]domain.Category)
+	for rows.Next() {
+		var service string
+		var cat domain.Category
+		if err := rows.Scan(&service, &cat.ID, &cat.Name, &cat.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result[service] = cat
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func setCategoryServices(ctx context.Context, tx pgx.Tx, id uuid.UUID, services []string) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM service_categories WHERE category_id = $1", id); err != nil {
+		return err
+	}
+
+	if len(services) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO service_categories (service_name, category_id)
+SELECT DISTINCT unnest($1::text[]), $2::uuid
+ON CONFLICT (service_name) DO UPDATE SET category_id = EXCLUDED.category_id`
+
+	_, err := tx.Exec(ctx, query, services, id)
+	return err
+}
+
+func scanCategory(row rowScanner) (domain.Category, error) {
+	var cat domain.Category
+	err := row.Scan(&cat.ID, &cat.Name, &cat.CreatedAt, &cat.Services)
+	return cat, err
+}
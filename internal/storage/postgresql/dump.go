@@ -0,0 +1,251 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type DumpFormat string
+
+const (
+	DumpNDJSON DumpFormat = "ndjson"
+	DumpCSV    DumpFormat = "csv"
+)
+
+// DumpTables lists the tables holding subscription data in an order that
+// satisfies their foreign keys on restore.
+var DumpTables = []string{
+	"categories",
+	"service_categories",
+	"subscriptions",
+	"subscription_history",
+	"subscription_suggestions",
+	"price_changes",
+	"reports",
+	"telegram_chats",
+	"monthly_spend",
+	"monthly_spend_state",
+}
+
+// ExportTables writes every table to the writer returned by open, all from a
+// single REPEATABLE READ snapshot. Generated columns are left out; restore
+// computes them again.
+func (s *Storage) ExportTables(ctx context.Context, tables []string, format DumpFormat, open func(table string) (io.WriteCloser, error)) error {
+	const op = "storage.postgresql.ExportTables"
+
+	ctx, cancel := s.withTimeout(ctx, opStream)
+	defer cancel()
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	for _, table := range tables {
+		w, err := open(table)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", op, table, err)
+		}
+
+		err = exportTable(ctx, tx, table, format, w)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", op, table, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func exportTable(ctx context.Context, tx pgx.Tx, table string, format DumpFormat, w io.Writer) error {
+	columns, err := dumpColumns(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+	selectRows := "SELECT " + columns + " FROM " + pgx.Identifier{table}.Sanitize()
+
+	switch format {
+	case DumpCSV:
+		_, err := tx.Conn().PgConn().CopyTo(ctx, w, "COPY ("+selectRows+") TO STDOUT WITH (FORMAT csv, HEADER)")
+		return err
+	case DumpNDJSON:
+		rows, err := tx.Query(ctx, "SELECT row_to_json(r)::text FROM ("+selectRows+") r")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	default:
+		return fmt.Errorf("unknown dump format %q", format)
+	}
+}
+
+// RestoreTables loads every table from the reader returned by open in one
+// transaction and moves serial sequences past the restored IDs. With
+// truncate the tables, and anything referencing them, are emptied first.
+func (s *Storage) RestoreTables(ctx context.Context, tables []string, format DumpFormat, truncate bool, open func(table string) (io.ReadCloser, error)) error {
+	const op = "storage.postgresql.RestoreTables"
+
+	ctx, cancel := s.withTimeout(ctx, opStream)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if truncate && len(tables) > 0 {
+		names := make([]string, len(tables))
+		for i, table := range tables {
+			names[i] = pgx.Identifier{table}.Sanitize()
+		}
+		if _, err := tx.Exec(ctx, "TRUNCATE "+strings.Join(names, ", ")+" CASCADE"); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	for _, table := range tables {
+		r, err := open(table)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", op, table, err)
+		}
+
+		err = restoreTable(ctx, tx, table, format, r)
+		_ = r.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", op, table, err)
+		}
+
+		if err := resetSequences(ctx, tx, table); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, table, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func restoreTable(ctx context.Context, tx pgx.Tx, table string, format DumpFormat, r io.Reader) error {
+	columns, err := dumpColumns(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+	ident := pgx.Identifier{table}.Sanitize()
+
+	switch format {
+	case DumpCSV:
+		_, err := tx.Conn().PgConn().CopyFrom(ctx, r, "COPY "+ident+" ("+columns+") FROM STDIN WITH (FORMAT csv, HEADER)")
+		return err
+	case DumpNDJSON:
+		query := "INSERT INTO " + ident + " (" + columns + ") SELECT " + columns +
+			" FROM json_populate_record(NULL::" + ident + ", $1::json)"
+
+		dec := json.NewDecoder(r)
+		for {
+			var row json.RawMessage
+			if err := dec.Decode(&row); errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, query, string(row)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown dump format %q", format)
+	}
+}
+
+// dumpColumns returns the quoted, comma-separated list of the table's
+// writable columns.
+func dumpColumns(ctx context.Context, tx pgx.Tx, table string) (string, error) {
+	rows, err := tx.Query(ctx, `SELECT column_name FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = $1 AND is_generated = 'NEVER'
+ORDER BY ordinal_position`, table)
+	if err != nil {
+		return "", err
+	}
+
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return "", err
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("table %s not found", table)
+	}
+
+	for i, column := range columns {
+		columns[i] = pgx.Identifier{column}.Sanitize()
+	}
+
+	return strings.Join(columns, ", "), nil
+}
+
+func resetSequences(ctx context.Context, tx pgx.Tx, table string) error {
+	rows, err := tx.Query(ctx, `SELECT column_name, pg_get_serial_sequence($1::text, column_name::text)
+FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = $1 AND pg_get_serial_sequence($1::text, column_name::text) IS NOT NULL`, table)
+	if err != nil {
+		return err
+	}
+
+	type serial struct {
+		column   string
+		sequence string
+	}
+	var serials []serial
+	for rows.Next() {
+		var sc serial
+		if err := rows.Scan(&sc.column, &sc.sequence); err != nil {
+			rows.Close()
+			return err
+		}
+		serials = append(serials, sc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, sc := range serials {
+		column := pgx.Identifier{sc.column}.Sanitize()
+		query := "SELECT setval($1, COALESCE(max(" + column + "), 1), max(" + column + ") IS NOT NULL) FROM " + pgx.Identifier{table}.Sanitize()
+		if _, err := tx.Exec(ctx, query, sc.sequence); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package postgresql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	checkViolation      = "23514"
+)
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// writeError translates constraint violations raised by a subscription write
+// into domain errors the handlers can answer with a client error, and wraps
+// anything else with op.
+func writeError(op string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return domain.ErrDuplicate
+		case foreignKeyViolation:
+			return domain.ErrInvalidReference
+		case checkViolation:
+			return domain.ErrInvalidValue
+		}
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// priceChangeWriteError is writeError for writes to price_changes, whose
+// only constraint is the price check.
+func priceChangeWriteError(op string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == checkViolation {
+		return domain.ErrInvalidPriceChange
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,87 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdvisoryLock is a session-level advisory lock. It lives as long as the
+// dedicated connection it was taken on, which is why it is checked
+// periodically while held.
+type AdvisoryLock struct {
+	conn *pgxpool.Conn
+	name string
+}
+
+// TryAdvisoryLock takes the advisory lock identified by name without
+// waiting. It returns nil when another session holds the lock.
+func (s *Storage) TryAdvisoryLock(ctx context.Context, name string) (*AdvisoryLock, error) {
+	const op = "storage.postgresql.TryAdvisoryLock"
+
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, name).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !acquired {
+		conn.Release()
+		return nil, nil
+	}
+
+	return &AdvisoryLock{conn: conn, name: name}, nil
+}
+
+// AcquireAdvisoryLock waits until it holds the advisory lock identified by
+// name or ctx is done.
+func (s *Storage) AcquireAdvisoryLock(ctx context.Context, name string) (*AdvisoryLock, error) {
+	const op = "storage.postgresql.AcquireAdvisoryLock"
+
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock(hashtext($1))`, name); err != nil {
+		// The lock may have been granted just as ctx was cancelled; closing
+		// the connection makes sure it is not left behind.
+		_ = conn.Hijack().Close(context.Background())
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &AdvisoryLock{conn: conn, name: name}, nil
+}
+
+// Check reports an error once the connection holding the lock is gone, after
+// which the lock may already belong to another session.
+func (l *AdvisoryLock) Check(ctx context.Context) error {
+	const op = "storage.postgresql.AdvisoryLock.Check"
+
+	if err := l.conn.Ping(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Release unlocks and returns the connection to the pool. If unlocking
+// fails the connection is closed instead, which drops the lock as well.
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	const op = "storage.postgresql.AdvisoryLock.Release"
+
+	if _, err := l.conn.Exec(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, l.name); err != nil {
+		_ = l.conn.Hijack().Close(ctx)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	l.conn.Release()
+
+	return nil
+}
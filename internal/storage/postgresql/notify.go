@@ -0,0 +1,55 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Notify sends payload on channel. Inside WithinTx the notification is
+// delivered only if the transaction commits.
+func (s *Storage) Notify(ctx context.Context, channel string, payload []byte) error {
+	const op = "storage.postgresql.Notify"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	if _, err := s.db.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, string(payload)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Listen holds a dedicated connection listening on channel and passes every
+// payload to fn until ctx is cancelled or the connection fails. The
+// connection is closed afterwards rather than returned to the pool, so it
+// never carries the LISTEN over to other callers.
+func (s *Storage) Listen(ctx context.Context, channel string, fn func(payload []byte)) error {
+	const op = "storage.postgresql.Listen"
+
+	pooled, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		fn([]byte(notification.Payload))
+	}
+}
@@ -0,0 +1,50 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PoolStats is a snapshot of the primary connection pool. The pool is
+// pgxpool rather than database/sql, so these are its counters and not
+// sql.DBStats, which would only describe the GetDB wrapper.
+type PoolStats struct {
+	MaxConns      int32
+	TotalConns    int32
+	IdleConns     int32
+	AcquiredConns int32
+
+	AcquireCount int64
+	// EmptyAcquireCount counts acquires that had to wait for or open a
+	// connection because none was idle.
+	EmptyAcquireCount    int64
+	CanceledAcquireCount int64
+	AcquireDuration      time.Duration
+}
+
+func (s *Storage) Stats() PoolStats {
+	stat := s.pool.Stat()
+
+	return PoolStats{
+		MaxConns:             stat.MaxConns(),
+		TotalConns:           stat.TotalConns(),
+		IdleConns:            stat.IdleConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireDuration:      stat.AcquireDuration(),
+	}
+}
+
+// Ping checks that the primary answers.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgresql.Ping"
+
+	if err := s.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
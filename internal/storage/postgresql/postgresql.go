@@ -4,39 +4,295 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"github.com/Kulibyka/effective-mobile/internal/config"
-	_ "github.com/lib/pq"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
+// dbtx is satisfied by both the pool and a transaction. Begin on a
+// transaction opens a savepoint, so methods that run their own transaction
+// stay atomic inside WithinTx.
+type dbtx interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type Storage struct {
-	db *sql.DB
+	db    dbtx
+	pool  *pgxpool.Pool
+	sqlDB *sql.DB
+
+	replica          *pgxpool.Pool
+	replicaRetry     time.Duration
+	replicaDownUntil atomic.Int64
+
+	timeouts timeouts
 }
 
-func New(cfg config.PostgreConfig) (*Storage, error) {
+type timeouts struct {
+	read      time.Duration
+	write     time.Duration
+	aggregate time.Duration
+	job       time.Duration
+	stream    time.Duration
+}
+
+type opKind int
+
+const (
+	opRead opKind = iota
+	opWrite
+	opAggregate
+	opJob
+	opStream
+)
+
+// Option adjusts the primary and the replica pool configs alike.
+type Option func(*pgxpool.Config)
+
+// WithCredentials makes every new connection to the primary and the replica
+// log in with the user and password creds returns at that moment, instead of
+// the configured ones, so credentials that rotate apply without restarting.
+func WithCredentials(creds func() (user, password string)) Option {
+	return func(poolCfg *pgxpool.Config) {
+		poolCfg.BeforeConnect = func(_ context.Context, cc *pgx.ConnConfig) error {
+			cc.User, cc.Password = creds()
+			return nil
+		}
+	}
+}
+
+func New(cfg config.PostgreConfig, opts ...Option) (*Storage, error) {
 	const op = "storage.postgresql.New"
 
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
-	db, err := sql.Open("postgres", connStr)
+	poolCfg, err := pgxpool.ParseConfig(connString(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	if err := applyPoolSettings(poolCfg, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, opt := range opts {
+		opt(poolCfg)
+	}
+
+	// DSNs handed out by managed platforms rarely name the application, so
+	// keep ours visible in pg_stat_activity unless the DSN sets one.
+	if cfg.DSN != "" && cfg.ApplicationName != "" {
+		if _, ok := poolCfg.ConnConfig.RuntimeParams["application_name"]; !ok {
+			poolCfg.ConnConfig.RuntimeParams["application_name"] = cfg.ApplicationName
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err = db.PingContext(ctx); err != nil {
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	s := &Storage{
+		db:           pool,
+		pool:         pool,
+		sqlDB:        stdlib.OpenDBFromPool(pool),
+		replicaRetry: cfg.ReplicaRetryAfter,
+		timeouts:     timeouts{read: cfg.ReadTimeout, write: cfg.WriteTimeout, aggregate: cfg.AggregateTimeout, job: cfg.JobTimeout, stream: cfg.StreamTimeout},
+	}
+
+	// The replica is not pinged: reads fall back to the primary until it
+	// becomes reachable.
+	if cfg.ReplicaDSN != "" {
+		replicaCfg, err := pgxpool.ParseConfig(cfg.ReplicaDSN)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("%s: replica: %w", op, err)
+		}
+		if err := applyPoolSettings(replicaCfg, cfg); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("%s: replica: %w", op, err)
+		}
+		for _, opt := range opts {
+			opt(replicaCfg)
+		}
+
+		s.replica, err = pgxpool.NewWithConfig(context.Background(), replicaCfg)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("%s: replica: %w", op, err)
+		}
+	}
+
+	return s, nil
 }
 
+// connString builds a keyword/value connection string from cfg, leaving out
+// empty settings so the driver defaults apply.
+func connString(cfg config.PostgreConfig) string {
+	if cfg.DSN != "" {
+		return cfg.DSN
+	}
+
+	var connectTimeout string
+	if cfg.ConnectTimeout > 0 {
+		connectTimeout = strconv.Itoa(max(int(cfg.ConnectTimeout/time.Second), 1))
+	}
+
+	params := [][2]string{
+		{"host", cfg.Host},
+		{"port", strconv.Itoa(cfg.Port)},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.DBName},
+		{"sslmode", cfg.SSLMode},
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+		{"connect_timeout", connectTimeout},
+		{"search_path", cfg.SearchPath},
+		{"application_name", cfg.ApplicationName},
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p[1] == "" {
+			continue
+		}
+		parts = append(parts, p[0]+"="+quoteConnValue(p[1]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func quoteConnValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+func applyPoolSettings(poolCfg *pgxpool.Config, cfg config.PostgreConfig) error {
+	if cfg.MaxOpenConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxOpenConns)
+	}
+	if cfg.MinIdleConns > 0 {
+		poolCfg.MinIdleConns = int32(min(cfg.MinIdleConns, int(poolCfg.MaxConns)))
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	}
+
+	if cfg.QueryExecMode != "" {
+		mode, ok := queryExecModes[cfg.QueryExecMode]
+		if !ok {
+			return fmt.Errorf("unknown query exec mode %q", cfg.QueryExecMode)
+		}
+		poolCfg.ConnConfig.DefaultQueryExecMode = mode
+	}
+	if cfg.StatementCacheCapacity > 0 {
+		poolCfg.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	}
+	if cfg.StatementTimeout > 0 {
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	return nil
+}
+
+// GetDB exposes the pool through database/sql for callers that need it, such
+// as the migrator.
 func (s *Storage) GetDB() *sql.DB {
-	return s.db
+	return s.sqlDB
+}
+
+// WithinTx runs fn against a Storage bound to a single transaction, committed
+// when fn returns nil and rolled back otherwise. Reads inside fn never go to
+// the replica.
+func (s *Storage) WithinTx(ctx context.Context, fn func(tx *Storage) error) error {
+	const op = "storage.postgresql.WithinTx"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err := fn(&Storage{db: tx, pool: s.pool, sqlDB: s.sqlDB, timeouts: s.timeouts}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// withTimeout bounds ctx by the configured timeout for the kind of
+// operation.
+func (s *Storage) withTimeout(ctx context.Context, kind opKind) (context.Context, context.CancelFunc) {
+	var timeout time.Duration
+	switch kind {
+	case opRead:
+		timeout = s.timeouts.read
+	case opWrite:
+		timeout = s.timeouts.write
+	case opAggregate:
+		timeout = s.timeouts.aggregate
+	case opJob:
+		timeout = s.timeouts.job
+	case opStream:
+		timeout = s.timeouts.stream
+	}
+
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ResetConnections closes the idle connections to the primary and the
+// replica and those in use once they are released, so the pools reconnect
+// with fresh credentials.
+func (s *Storage) ResetConnections() {
+	s.pool.Reset()
+	if s.replica != nil {
+		s.replica.Reset()
+	}
 }
 
 func (s *Storage) Close() error {
-	return s.db.Close()
+	err := s.sqlDB.Close()
+	s.pool.Close()
+	if s.replica != nil {
+		s.replica.Close()
+	}
+	return err
 }
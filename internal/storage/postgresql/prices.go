@@ -0,0 +1,166 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+const priceChangeColumns = "id, service_name, price, effective_month, created_at, notified_at, applied_at"
+
+func (s *Storage) SchedulePriceChange(ctx context.Context, input domain.PriceAdjustment) (domain.PriceChange, error) {
+	const op = "storage.postgresql.SchedulePriceChange"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	query := `INSERT INTO price_changes (service_name, price, effective_month)
+VALUES ($1, $2, $3)
+RETURNING ` + priceChangeColumns
+
+	change, err := scanPriceChange(s.db.QueryRow(ctx, query, input.ServiceName, input.Price, input.EffectiveMonth))
+	if err != nil {
+		return domain.PriceChange{}, priceChangeWriteError(op, err)
+	}
+
+	return change, nil
+}
+
+// PendingPriceChanges returns the changes not applied yet that take effect in
+// or before the through month, ordered by effective month.
+func (s *Storage) PendingPriceChanges(ctx context.Context, through domain.MonthKey) ([]domain.PriceChange, error) {
+	const op = "storage.postgresql.PendingPriceChanges"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	query := `SELECT ` + priceChangeColumns + `
+FROM price_changes
+WHERE applied_at IS NULL AND effective_month_key <= $1
+ORDER BY effective_month_key, id`
+
+	rows, err := s.db.Query(ctx, query, through)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []domain.PriceChange
+	for rows.Next() {
+		change, err := scanPriceChange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// PriceIncreaseRecipients returns the subscriptions whose price goes up when
+// change is applied.
+func (s *Storage) PriceIncreaseRecipients(ctx context.Context, change domain.PriceChange) ([]domain.Subscription, error) {
+	const op = "storage.postgresql.PriceIncreaseRecipients"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	query := baseSelect + `
+WHERE service_name = $1 AND (end_month_key IS NULL OR end_month_key >= $2) AND price < $3
+ORDER BY user_id`
+
+	rows, err := s.db.Query(ctx, query, change.ServiceName, domain.MonthKeyOf(change.EffectiveMonth), change.Price)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []domain.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) MarkPriceChangeNotified(ctx context.Context, id int64) error {
+	const op = "storage.postgresql.MarkPriceChangeNotified"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	if _, err := s.db.Exec(ctx, "UPDATE price_changes SET notified_at = now() WHERE id = $1", id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ApplyPriceChange adjusts the subscriptions and marks the change applied in
+// one transaction. A change that was already applied affects nothing.
+func (s *Storage) ApplyPriceChange(ctx context.Context, id int64) ([]domain.Subscription, error) {
+	const op = "storage.postgresql.ApplyPriceChange"
+
+	ctx, cancel := s.withTimeout(ctx, opJob)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := "SELECT " + priceChangeColumns + " FROM price_changes WHERE id = $1 AND applied_at IS NULL FOR UPDATE SKIP LOCKED"
+
+	change, err := scanPriceChange(tx.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, err := adjustPrices(ctx, tx, domain.PriceAdjustment{
+		ServiceName:    change.ServiceName,
+		Price:          change.Price,
+		EffectiveMonth: change.EffectiveMonth,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE price_changes SET applied_at = now() WHERE id = $1", id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func scanPriceChange(row rowScanner) (domain.PriceChange, error) {
+	var change domain.PriceChange
+	err := row.Scan(&change.ID, &change.ServiceName, &change.Price, &change.EffectiveMonth, &change.CreatedAt,
+		&change.NotifiedAt, &change.AppliedAt)
+	return change, err
+}
@@ -0,0 +1,53 @@
+-- name: CreateSubscription :one
+INSERT INTO subscriptions (id, service_name, price, user_id, start_month, end_month)
+VALUES (@id, @service_name, @price, @user_id, @start_month, sqlc.narg('end_month'))
+ON CONFLICT (id) DO NOTHING
+RETURNING id, service_name, price, user_id, start_month, end_month, status, version;
+
+-- name: GetSubscription :one
+SELECT id, service_name, price, user_id, start_month, end_month, status, version
+FROM subscriptions
+WHERE id = @id;
+
+-- name: UpdateSubscription :one
+UPDATE subscriptions
+SET service_name = @service_name,
+    price = @price,
+    start_month = @start_month,
+    end_month = sqlc.narg('end_month'),
+    version = version + 1,
+    updated_at = now(),
+    status = CASE
+        WHEN status = 'expired' AND (sqlc.narg('end_month')::date IS NULL OR sqlc.narg('end_month')::date >= date_trunc('month', now() AT TIME ZONE 'UTC')::date) THEN 'active'
+        ELSE status
+    END
+WHERE id = @id
+RETURNING id, service_name, price, user_id, start_month, end_month, status, version;
+
+-- name: DeleteSubscription :one
+DELETE FROM subscriptions
+WHERE id = @id
+RETURNING user_id;
+
+-- name: SubscriptionExists :one
+SELECT EXISTS (SELECT 1 FROM subscriptions WHERE id = @id);
+
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions (id, service_name, price, user_id, start_month, end_month)
+VALUES (@id, @service_name, @price, @user_id, @start_month, sqlc.narg('end_month'))
+ON CONFLICT (user_id, service_name, start_month) DO UPDATE
+SET price = EXCLUDED.price,
+    end_month = EXCLUDED.end_month,
+    version = subscriptions.version + 1,
+    updated_at = now(),
+    status = CASE
+        WHEN subscriptions.status = 'expired' AND (EXCLUDED.end_month IS NULL OR EXCLUDED.end_month >= date_trunc('month', now() AT TIME ZONE 'UTC')::date) THEN 'active'
+        ELSE subscriptions.status
+    END
+WHERE (subscriptions.price, subscriptions.end_month) IS DISTINCT FROM (EXCLUDED.price, EXCLUDED.end_month)
+RETURNING id, service_name, price, user_id, start_month, end_month, status, version, (xmax = 0)::bool AS inserted;
+
+-- name: GetSubscriptionByKey :one
+SELECT id, service_name, price, user_id, start_month, end_month, status, version
+FROM subscriptions
+WHERE user_id = @user_id AND service_name = @service_name AND start_month = @start_month;
@@ -0,0 +1,93 @@
+package postgresql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+// queryBuilder numbers bind parameters and collects WHERE conditions, so a
+// new filter is one where call instead of $n bookkeeping.
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// bind adds value as the next parameter and returns its placeholder.
+func (b *queryBuilder) bind(value any) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// where adds a condition, replacing each ? in it with the placeholder of the
+// next value.
+func (b *queryBuilder) where(condition string, values ...any) {
+	var sb strings.Builder
+	next := 0
+
+	for _, r := range condition {
+		if r != '?' {
+			sb.WriteRune(r)
+			continue
+		}
+
+		if next == len(values) {
+			panic(fmt.Sprintf("postgresql: too few values for condition %q", condition))
+		}
+		sb.WriteString(b.bind(values[next]))
+		next++
+	}
+
+	if next != len(values) {
+		panic(fmt.Sprintf("postgresql: too many values for condition %q", condition))
+	}
+
+	b.conditions = append(b.conditions, sb.String())
+}
+
+// whereClause joins the conditions with AND, returning an empty string when
+// there are none.
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike makes s match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// subscriberConditions adds the user, service and category filters shared by
+// the subscriptions table and the monthly spend rollup.
+func subscriberConditions(b *queryBuilder, filter domain.ListFilter) {
+	if !filter.UserID.IsNil() {
+		b.where("user_id = ?", filter.UserID)
+	}
+
+	if len(filter.UserIDs) > 0 {
+		ids := make([]string, 0, len(filter.UserIDs))
+		for _, id := range filter.UserIDs {
+			ids = append(ids, id.String())
+		}
+		b.where("user_id = ANY(?::uuid[])", ids)
+	}
+
+	if filter.ServiceName != nil {
+		b.where("service_name = ?", *filter.ServiceName)
+	}
+
+	if len(filter.ServiceNames) > 0 {
+		b.where("service_name = ANY(?)", filter.ServiceNames)
+	}
+
+	if !filter.CategoryID.IsNil() {
+		b.where("service_name IN (SELECT service_name FROM service_categories WHERE category_id = ?)", filter.CategoryID)
+	}
+}
@@ -0,0 +1,100 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql/sqlcdb"
+)
+
+// reader runs read-only queries.
+type reader interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// read returns the reader for queries that tolerate replication lag: the
+// replica when one is configured and reachable, the primary otherwise.
+func (s *Storage) read() reader {
+	if s.replica == nil || time.Now().UnixNano() < s.replicaDownUntil.Load() {
+		return s.db
+	}
+
+	return replicaReader{s: s}
+}
+
+// readQueries returns the generated queries bound to the reader. Read-only
+// queries never call Exec, which the generated DBTX also requires; it goes to
+// the primary.
+func (s *Storage) readQueries() *sqlcdb.Queries {
+	return sqlcdb.New(readDB{reader: s.read(), primary: s.db})
+}
+
+type readDB struct {
+	reader
+	primary dbtx
+}
+
+func (r readDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return r.primary.Exec(ctx, sql, args...)
+}
+
+// replicaReader sends queries to the replica and repeats them on the primary
+// when the replica cannot be reached, which also keeps later reads on the
+// primary for the configured retry period.
+type replicaReader struct {
+	s *Storage
+}
+
+func (r replicaReader) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := r.s.replica.Query(ctx, sql, args...)
+	if err == nil || !isUnreachable(ctx, err) {
+		return rows, err
+	}
+
+	r.s.markReplicaDown()
+	return r.s.db.Query(ctx, sql, args...)
+}
+
+func (r replicaReader) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return fallbackRow{ctx: ctx, s: r.s, sql: sql, args: args, row: r.s.replica.QueryRow(ctx, sql, args...)}
+}
+
+type fallbackRow struct {
+	ctx  context.Context
+	s    *Storage
+	sql  string
+	args []any
+	row  pgx.Row
+}
+
+func (r fallbackRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if err == nil || !isUnreachable(r.ctx, err) {
+		return err
+	}
+
+	r.s.markReplicaDown()
+	return r.s.db.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+}
+
+func (s *Storage) markReplicaDown() {
+	s.replicaDownUntil.Store(time.Now().Add(s.replicaRetry).UnixNano())
+}
+
+// isUnreachable reports whether err means the server could not be reached,
+// as opposed to the query failing or the caller giving up.
+func isUnreachable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) || pgconn.SafeToRetry(err)
+}
@@ -0,0 +1,170 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/jackc/pgx/v5"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/report"
+)
+
+const reportColumns = "id, name, user_id, service_name, period_months, grouping, format, schedule, channel, target, next_run_at, last_run_at, last_error, created_at"
+
+func (s *Storage) CreateReport(ctx context.Context, input domain.CreateInput) (domain.Report, error) {
+	const op = "storage.postgresql.CreateReport"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	query := `INSERT INTO reports (name, user_id, service_name, period_months, grouping, format, schedule, channel, target, next_run_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING ` + reportColumns
+
+	rep, err := scanReport(s.db.QueryRow(ctx, query,
+		input.Name,
+		input.UserID,
+		input.ServiceName,
+		input.PeriodMonths,
+		input.Grouping,
+		input.Format,
+		input.Schedule,
+		input.Channel,
+		input.Target,
+		input.NextRunAt,
+	))
+	if err != nil {
+		return domain.Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rep, nil
+}
+
+func (s *Storage) GetReport(ctx context.Context, id uuid.UUID) (domain.Report, error) {
+	const op = "storage.postgresql.GetReport"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	rep, err := scanReport(s.db.QueryRow(ctx, "SELECT "+reportColumns+" FROM reports WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Report{}, domain.ErrNotFound
+		}
+		return domain.Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rep, nil
+}
+
+func (s *Storage) ListReports(ctx context.Context) ([]domain.Report, error) {
+	const op = "storage.postgresql.ListReports"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, "SELECT "+reportColumns+" FROM reports ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	result, err := scanReports(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) DeleteReport(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.postgresql.DeleteReport"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, "DELETE FROM reports WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// ClaimDueReports returns up to limit reports due at now and pushes their
+// next run to leaseUntil, so other instances skip them while they are being
+// generated. CompleteReportRun sets the real next run afterwards.
+func (s *Storage) ClaimDueReports(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.Report, error) {
+	const op = "storage.postgresql.ClaimDueReports"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	query := `UPDATE reports
+SET next_run_at = $2
+WHERE id IN (
+    SELECT id FROM reports
+    WHERE next_run_at <= $1
+    ORDER BY next_run_at
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + reportColumns
+
+	rows, err := s.db.Query(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	result, err := scanReports(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) CompleteReportRun(ctx context.Context, id uuid.UUID, ranAt, nextRunAt time.Time, runErr *string) error {
+	const op = "storage.postgresql.CompleteReportRun"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	query := `UPDATE reports
+SET last_run_at = $2, next_run_at = $3, last_error = $4
+WHERE id = $1`
+
+	if _, err := s.db.Exec(ctx, query, id, ranAt, nextRunAt, runErr); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanReports(rows pgx.Rows) ([]domain.Report, error) {
+	var result []domain.Report
+	for rows.Next() {
+		rep, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rep)
+	}
+
+	return result, rows.Err()
+}
+
+func scanReport(row rowScanner) (domain.Report, error) {
+	var rep domain.Report
+	err := row.Scan(&rep.ID, &rep.Name, &rep.UserID, &rep.ServiceName, &rep.PeriodMonths, &rep.Grouping, &rep.Format,
+		&rep.Schedule, &rep.Channel, &rep.Target, &rep.NextRunAt, &rep.LastRunAt, &rep.LastError, &rep.CreatedAt)
+	return rep, err
+}
@@ -0,0 +1,81 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+// PurgeEndedSubscriptions deletes up to limit subscriptions that ended before
+// the given month and returns the removed ones. With archive the rows
+// are kept in the history as deleted, so as-of reports still see them;
+// otherwise their history goes too.
+func (s *Storage) PurgeEndedSubscriptions(ctx context.Context, before domain.MonthKey, limit int, archive bool) ([]domain.Subscription, error) {
+	const op = "storage.postgresql.PurgeEndedSubscriptions"
+
+	ctx, cancel := s.withTimeout(ctx, opJob)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var ids []string
+	rows, err := tx.Query(ctx, `SELECT id FROM subscriptions
+WHERE end_month_key IS NOT NULL AND end_month_key < $1
+ORDER BY end_month_key
+LIMIT $2
+FOR UPDATE SKIP LOCKED`, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if archive {
+		if _, err := archiveSubscriptions(ctx, tx, historyDelete, "id = ANY($1::uuid[])", ids); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	} else if _, err := tx.Exec(ctx, `DELETE FROM subscription_history WHERE subscription_id = ANY($1::uuid[])`, ids); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	purged, err := collectSubscriptions(tx.Query(ctx, `DELETE FROM subscriptions WHERE id = ANY($1::uuid[]) RETURNING `+subscriptionColumns, ids))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	userIDs := make([]string, 0, len(purged))
+	for _, sub := range purged {
+		userIDs = append(userIDs, sub.UserID.String())
+	}
+
+	if err := refreshUserSpend(ctx, tx, userIDs...); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return purged, nil
+}
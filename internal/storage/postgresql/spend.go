@@ -0,0 +1,231 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+// spendRollupInsert expands every subscription matching the %s condition into
+// one row per active month up to the stored horizon. Nothing is inserted until
+// the rollup job has recorded a horizon.
+const spendRollupInsert = `INSERT INTO monthly_spend (month_key, user_id, service_name, total, subscriptions)
+SELECT (EXTRACT(YEAR FROM m) * 100 + EXTRACT(MONTH FROM m))::int, s.user_id, s.service_name, SUM(s.price), COUNT(*)
+FROM subscriptions s
+CROSS JOIN (SELECT make_date(horizon_key / 100, horizon_key %% 100, 1) AS horizon FROM monthly_spend_state) AS st
+CROSS JOIN LATERAL generate_series(s.start_month, LEAST(COALESCE(s.end_month, st.horizon), st.horizon), interval '1 month') AS m
+WHERE %s
+GROUP BY 1, 2, 3`
+
+// RebuildMonthlySpend recomputes the whole monthly_spend rollup up to and
+// including the horizon month.
+func (s *Storage) RebuildMonthlySpend(ctx context.Context, horizon domain.MonthKey) error {
+	const op = "storage.postgresql.RebuildMonthlySpend"
+
+	ctx, cancel := s.withTimeout(ctx, opJob)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// Waits for in-flight per-user refreshes and holds new ones back until the
+	// rebuild is committed.
+	if _, err := tx.Exec(ctx, "LOCK TABLE monthly_spend IN EXCLUSIVE MODE"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `INSERT INTO monthly_spend_state (id, horizon_key, refreshed_at)
+VALUES (TRUE, $1, now())
+ON CONFLICT (id) DO UPDATE SET horizon_key = EXCLUDED.horizon_key, refreshed_at = EXCLUDED.refreshed_at`
+
+	if _, err := tx.Exec(ctx, query, horizon); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM monthly_spend"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(spendRollupInsert, "TRUE")); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MonthlySpend reads the per-month totals for the active period of filter from
+// the rollup. It reports false when the rollup does not cover the period yet.
+func (s *Storage) MonthlySpend(ctx context.Context, filter domain.ListFilter) ([]domain.MonthTotal, bool, error) {
+	const op = "storage.postgresql.MonthlySpend"
+
+	ctx, cancel := s.withTimeout(ctx, opAggregate)
+	defer cancel()
+
+	if filter.ActivePeriodFrom == nil || filter.ActivePeriodTo == nil {
+		return nil, false, nil
+	}
+
+	from := domain.MonthKeyOf(*filter.ActivePeriodFrom)
+	to := domain.MonthKeyOf(*filter.ActivePeriodTo)
+
+	var horizon domain.MonthKey
+	if err := s.read().QueryRow(ctx, "SELECT horizon_key FROM monthly_spend_state").Scan(&horizon); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if to > horizon {
+		return nil, false, nil
+	}
+
+	var b queryBuilder
+	b.where("month_key >= ?", from)
+	b.where("month_key <= ?", to)
+	subscriberConditions(&b, filter)
+
+	query := `SELECT month_key, SUM(total)
+FROM monthly_spend` + b.whereClause() + `
+GROUP BY month_key
+ORDER BY month_key`
+
+	rows, err := s.read().Query(ctx, query, b.args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []domain.MonthTotal
+	for rows.Next() {
+		var total domain.MonthTotal
+		if err := rows.Scan(&total.Month, &total.Total); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, total)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, true, nil
+}
+
+// refreshUserSpend recomputes the rollup rows of the given users inside the
+// caller's transaction so the rollup changes together with the subscriptions.
+func refreshUserSpend(ctx context.Context, tx pgx.Tx, userIDs ...string) error {
+	userIDs = slices.Compact(slices.Sorted(slices.Values(userIDs)))
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	for _, id := range userIDs {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM monthly_spend WHERE user_id = ANY($1::uuid[])", userIDs); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, fmt.Sprintf(spendRollupInsert, "s.user_id = ANY($1::uuid[])"), userIDs)
+	return err
+}
+
+// UserSpend groups the subscriptions running in month by user, most
+// expensive first. filter narrows the users and services and pages the
+// result with Limit and Offset.
+func (s *Storage) UserSpend(ctx context.Context, month domain.MonthKey, filter domain.ListFilter) ([]domain.UserSpend, error) {
+	const op = "storage.postgresql.UserSpend"
+
+	ctx, cancel := s.withTimeout(ctx, opAggregate)
+	defer cancel()
+
+	var b queryBuilder
+	subscriberConditions(&b, filter)
+	b.where("start_month_key <= ?", month)
+	b.where("(end_month_key IS NULL OR end_month_key >= ?)", month)
+
+	query := "SELECT user_id, COUNT(*), SUM(price) FROM subscriptions" + b.whereClause() +
+		" GROUP BY user_id ORDER BY SUM(price) DESC, user_id"
+	if filter.Limit > 0 {
+		query += " LIMIT " + b.bind(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + b.bind(filter.Offset)
+	}
+
+	rows, err := s.read().Query(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []domain.UserSpend
+	for rows.Next() {
+		var spend domain.UserSpend
+		if err := rows.Scan(&spend.UserID, &spend.ActiveCount, &spend.MonthlyTotal); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, spend)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// SumSubscriptions computes the cost of the subscriptions matching filter over
+// its active period in the database: each subscription contributes its price
+// times the number of months it overlaps the period.
+func (s *Storage) SumSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error) {
+	const op = "storage.postgresql.SumSubscriptions"
+
+	ctx, cancel := s.withTimeout(ctx, opAggregate)
+	defer cancel()
+
+	if filter.ActivePeriodFrom == nil || filter.ActivePeriodTo == nil {
+		return 0, fmt.Errorf("%s: active period is required", op)
+	}
+
+	filter.WithTotal = false
+
+	var b queryBuilder
+	matched := buildListQuery(&b, filter)
+	from, to := b.bind(*filter.ActivePeriodFrom), b.bind(*filter.ActivePeriodTo)
+
+	query := `SELECT COALESCE(SUM(price * (
+    (EXTRACT(YEAR FROM last_month) - EXTRACT(YEAR FROM first_month)) * 12
+    + EXTRACT(MONTH FROM last_month) - EXTRACT(MONTH FROM first_month) + 1)), 0)::bigint
+FROM (
+    SELECT price,
+        GREATEST(start_month, ` + from + `::date) AS first_month,
+        LEAST(COALESCE(end_month, ` + to + `::date), ` + to + `::date) AS last_month
+    FROM (` + matched + `) AS matched
+) AS overlaps`
+
+	var total int64
+	if err := s.read().QueryRow(ctx, query, b.args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,46 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+)
+
+func (s *Storage) ServiceStats(ctx context.Context, minSubscribers int) ([]domain.ServiceStats, error) {
+	const op = "storage.postgresql.ServiceStats"
+
+	ctx, cancel := s.withTimeout(ctx, opAggregate)
+	defer cancel()
+
+	query := `SELECT service_name,
+       COUNT(DISTINCT user_id)                            AS subscribers,
+       AVG(price)::float8                                 AS average_price,
+       RANK() OVER (ORDER BY COUNT(DISTINCT user_id) DESC) AS rank
+FROM subscriptions
+WHERE status = $1
+GROUP BY service_name
+HAVING COUNT(DISTINCT user_id) >= $2
+ORDER BY rank, service_name`
+
+	rows, err := s.db.Query(ctx, query, domain.StatusActive, minSubscribers)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []domain.ServiceStats
+	for rows.Next() {
+		var st domain.ServiceStats
+		if err := rows.Scan(&st.ServiceName, &st.Subscribers, &st.AveragePrice, &st.Rank); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, st)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
@@ -2,157 +2,551 @@ package postgresql
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/storage/postgresql/sqlcdb"
+	"github.com/jackc/pgx/v5"
 
 	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
 )
 
-const baseSelect = "SELECT id, service_name, price, user_id, start_month, end_month FROM subscriptions"
+const (
+	subscriptionColumns = "id, service_name, price, user_id, start_month, end_month, status, version"
+	baseSelect          = "SELECT " + subscriptionColumns + " FROM subscriptions"
+
+	historyColumns = "subscription_id AS id, service_name, price, user_id, start_month, end_month, status, version"
+
+	// asOfSource replaces the subscriptions table with the versions that were
+	// current just before the cutoff passed as $1.
+	asOfSource = `(SELECT ` + subscriptionColumns + `, start_month_key, end_month_key
+FROM subscriptions
+WHERE updated_at < $1
+UNION ALL
+SELECT ` + historyColumns + `, start_month_key, end_month_key
+FROM subscription_history
+WHERE valid_from < $1 AND valid_to >= $1) AS subscriptions`
+
+	historyUpdate = "update"
+	historyDelete = "delete"
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
 func (s *Storage) CreateSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, error) {
 	const op = "storage.postgresql.CreateSubscription"
 
-	query := `INSERT INTO subscriptions (service_name, price, user_id, start_month, end_month)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, service_name, price, user_id, start_month, end_month`
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
 
-	var sub domain.Subscription
-	err := s.db.QueryRowContext(ctx, query,
-		input.ServiceName,
-		input.Price,
-		input.UserID,
-		input.StartMonth,
-		sqlNullTime(input.EndMonth),
-	).Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartMonth, &sub.EndMonth)
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	row, err := sqlcdb.New(tx).CreateSubscription(ctx, sqlcdb.CreateSubscriptionParams{
+		ID:          subscriptionID(input),
+		ServiceName: input.ServiceName,
+		Price:       input.Price,
+		UserID:      input.UserID,
+		StartMonth:  input.StartMonth,
+		EndMonth:    input.EndMonth,
+	})
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) && input.ID != nil {
+			return s.existingSubscription(ctx, op, input)
+		}
+		return domain.Subscription{}, writeError(op, err)
+	}
+
+	if err := refreshUserSpend(ctx, tx, row.UserID.String()); err != nil {
 		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return sub, nil
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return domain.Subscription(row), nil
+}
+
+// existingSubscription resolves a create whose client-supplied ID is taken:
+// a retry with the same content gets the stored row, anything else conflicts.
+// The row is read from the primary, which the replica may not have caught up with.
+func (s *Storage) existingSubscription(ctx context.Context, op string, input domain.CreateInput) (domain.Subscription, error) {
+	row, err := sqlcdb.New(s.db).GetSubscription(ctx, *input.ID)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sub := domain.Subscription(row)
+	sameEnd := (sub.EndMonth == nil && input.EndMonth == nil) ||
+		(sub.EndMonth != nil && input.EndMonth != nil && domain.MonthKeyOf(*sub.EndMonth) == domain.MonthKeyOf(*input.EndMonth))
+
+	if sub.ServiceName != input.ServiceName ||
+		sub.Price != input.Price ||
+		sub.UserID != input.UserID ||
+		domain.MonthKeyOf(sub.StartMonth) != domain.MonthKeyOf(input.StartMonth) ||
+		!sameEnd {
+		return domain.Subscription{}, domain.ErrConflict
+	}
+
+	return sub, domain.ErrAlreadyExists
+}
+
+// UpsertSubscription creates the subscription or updates the price and end
+// month of the one with the same user, service and start month. A row that
+// already matches is left as is, without a new version or history entry.
+func (s *Storage) UpsertSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, domain.UpsertOutcome, error) {
+	const op = "storage.postgresql.UpsertSubscription"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// Upserts of the same key queue up here, so each one archives the
+	// version the previous one left, even when the row did not exist yet.
+	// The row lock keeps other writers off it until the upsert commits.
+	key := fmt.Sprintf("subscription:%s:%s:%s", input.UserID, input.ServiceName, input.StartMonth.Format(time.DateOnly))
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", key); err != nil {
+		return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.Exec(ctx, "SELECT 1 FROM subscriptions WHERE user_id = $1 AND service_name = $2 AND start_month = $3 FOR UPDATE",
+		input.UserID, input.ServiceName, input.StartMonth)
+	if err != nil {
+		return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = archiveSubscriptions(ctx, tx, historyUpdate,
+		"user_id = $1 AND service_name = $2 AND start_month = $3 AND (price, end_month) IS DISTINCT FROM ($4::int, $5::date)",
+		input.UserID, input.ServiceName, input.StartMonth, input.Price, input.EndMonth)
+	if err != nil {
+		return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	queries := sqlcdb.New(tx)
+	row, err := queries.UpsertSubscription(ctx, sqlcdb.UpsertSubscriptionParams{
+		ID:          subscriptionID(input),
+		ServiceName: input.ServiceName,
+		Price:       input.Price,
+		UserID:      input.UserID,
+		StartMonth:  input.StartMonth,
+		EndMonth:    input.EndMonth,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, err := queries.GetSubscriptionByKey(ctx, sqlcdb.GetSubscriptionByKeyParams{
+			UserID:      input.UserID,
+			ServiceName: input.ServiceName,
+			StartMonth:  input.StartMonth,
+		})
+		if err != nil {
+			return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		return domain.Subscription(current), domain.UpsertUnchanged, nil
+	}
+	if err != nil {
+		return domain.Subscription{}, 0, writeError(op, err)
+	}
+
+	if err := refreshUserSpend(ctx, tx, row.UserID.String()); err != nil {
+		return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Subscription{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	outcome := domain.UpsertUpdated
+	if row.Inserted {
+		outcome = domain.UpsertCreated
+	}
+
+	return domain.Subscription{
+		ID:          row.ID,
+		ServiceName: row.ServiceName,
+		Price:       row.Price,
+		UserID:      row.UserID,
+		StartMonth:  row.StartMonth,
+		EndMonth:    row.EndMonth,
+		Status:      row.Status,
+		Version:     row.Version,
+	}, outcome, nil
 }
 
 func (s *Storage) GetSubscription(ctx context.Context, id uuid.UUID) (domain.Subscription, error) {
 	const op = "storage.postgresql.GetSubscription"
 
-	query := baseSelect + " WHERE id = $1"
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
 
-	var sub domain.Subscription
-	err := s.db.QueryRowContext(ctx, query, id).Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartMonth, &sub.EndMonth)
+	row, err := s.readQueries().GetSubscription(ctx, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return domain.Subscription{}, domain.ErrNotFound
 		}
 		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return sub, nil
+	return domain.Subscription(row), nil
 }
 
 func (s *Storage) UpdateSubscription(ctx context.Context, id uuid.UUID, input domain.UpdateInput) (domain.Subscription, error) {
 	const op = "storage.postgresql.UpdateSubscription"
 
-	query := `UPDATE subscriptions
-SET service_name = $1,
-    price = $2,
-    start_month = $3,
-    end_month = $4
-WHERE id = $5
-RETURNING id, service_name, price, user_id, start_month, end_month`
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
 
-	var sub domain.Subscription
-	err := s.db.QueryRowContext(ctx, query,
-		input.ServiceName,
-		input.Price,
-		input.StartMonth,
-		sqlNullTime(input.EndMonth),
-		id,
-	).Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartMonth, &sub.EndMonth)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return domain.Subscription{}, domain.ErrNotFound
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	archived, err := archiveSubscriptions(ctx, tx, historyUpdate, "id = $1 AND ($2::int IS NULL OR version = $2)", id, input.Version)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(archived) == 0 {
+		if input.Version != nil {
+			return domain.Subscription{}, s.versionMismatch(ctx, op, id)
 		}
+		return domain.Subscription{}, domain.ErrNotFound
+	}
+
+	row, err := sqlcdb.New(tx).UpdateSubscription(ctx, sqlcdb.UpdateSubscriptionParams{
+		ServiceName: input.ServiceName,
+		Price:       input.Price,
+		StartMonth:  input.StartMonth,
+		EndMonth:    input.EndMonth,
+		ID:          id,
+	})
+	if err != nil {
+		return domain.Subscription{}, writeError(op, err)
+	}
+
+	if err := refreshUserSpend(ctx, tx, row.UserID.String()); err != nil {
+		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return domain.Subscription{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return sub, nil
+	return domain.Subscription(row), nil
+}
+
+// versionMismatch tells a stale version apart from a missing subscription
+// after a versioned update matched no rows.
+func (s *Storage) versionMismatch(ctx context.Context, op string, id uuid.UUID) error {
+	exists, err := sqlcdb.New(s.db).SubscriptionExists(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if exists {
+		return domain.ErrConflict
+	}
+
+	return domain.ErrNotFound
 }
 
 func (s *Storage) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
 	const op = "storage.postgresql.DeleteSubscription"
 
-	res, err := s.db.ExecContext(ctx, "DELETE FROM subscriptions WHERE id = $1", id)
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
 
-	affected, err := res.RowsAffected()
+	archived, err := archiveSubscriptions(ctx, tx, historyDelete, "id = $1", id)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if affected == 0 {
+	if len(archived) == 0 {
 		return domain.ErrNotFound
 	}
 
+	userID, err := sqlcdb.New(tx).DeleteSubscription(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := refreshUserSpend(ctx, tx, userID.String()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
-func (s *Storage) ListSubscriptions(ctx context.Context, filter domain.ListFilter) ([]domain.Subscription, error) {
+// ListSubscriptions returns the page selected by filter. With WithTotal the
+// total comes from a COUNT(*) OVER() window on the same query; only a page
+// past the end, which has no rows to carry it, costs a separate count.
+func (s *Storage) ListSubscriptions(ctx context.Context, filter domain.ListFilter) (domain.Page, error) {
 	const op = "storage.postgresql.ListSubscriptions"
 
-	query := baseSelect
-	var conditions []string
-	var args []any
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	query, args := listQuery(filter)
+
+	rows, err := s.read().Query(ctx, query, args...)
+	if err != nil {
+		return domain.Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var page domain.Page
+	var total int64
+	var row rowScanner = rows
+	if filter.WithTotal {
+		row = totalScanner{rowScanner: rows, total: &total}
+	}
+	for rows.Next() {
+		sub, err := scanSubscription(row)
+		if err != nil {
+			return domain.Page{}, fmt.Errorf("%s: %w", op, err)
+		}
+		page.Subscriptions = append(page.Subscriptions, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return domain.Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if filter.WithTotal {
+		if len(page.Subscriptions) == 0 && filter.Offset > 0 {
+			total, err = s.countSubscriptions(ctx, filter)
+			if err != nil {
+				return domain.Page{}, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+func (s *Storage) countSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error) {
+	filter.WithTotal, filter.Limit, filter.Offset = false, 0, 0
+	query, args := listQuery(filter)
+
+	var total int64
+	err := s.read().QueryRow(ctx, "SELECT COUNT(*) FROM ("+query+") AS matched", args...).Scan(&total)
+	return total, err
+}
+
+// EstimateSubscriptions returns the planner's row estimate for the list query
+// built from filter without executing it.
+func (s *Storage) EstimateSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error) {
+	const op = "storage.postgresql.EstimateSubscriptions"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	filter.WithTotal = false
+	query, args := listQuery(filter)
+
+	var raw []byte
+	if err := s.read().QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&raw); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(plan) == 0 {
+		return 0, fmt.Errorf("%s: empty plan", op)
+	}
+
+	return int64(plan[0].Plan.Rows), nil
+}
+
+// ListSubscriptionsFunc calls fn for every subscription matching filter as
+// rows arrive instead of collecting them, so memory stays flat however many
+// rows match. WithTotal is ignored. An error from fn stops the iteration and
+// is returned as is.
+func (s *Storage) ListSubscriptionsFunc(ctx context.Context, filter domain.ListFilter, fn func(domain.Subscription) error) error {
+	const op = "storage.postgresql.ListSubscriptionsFunc"
+
+	ctx, cancel := s.withTimeout(ctx, opStream)
+	defer cancel()
+
+	filter.WithTotal = false
+	query, args := listQuery(filter)
+
+	rows, err := s.read().Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ServiceNames returns the distinct service names of the subscriptions
+// matching the user and service filters, sorted.
+func (s *Storage) ServiceNames(ctx context.Context, filter domain.ListFilter) ([]string, error) {
+	const op = "storage.postgresql.ServiceNames"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	var b queryBuilder
+	subscriberConditions(&b, filter)
+
+	rows, err := s.read().Query(ctx, "SELECT DISTINCT service_name FROM subscriptions"+b.whereClause()+" ORDER BY service_name", b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-	if filter.UserID != nil {
-		args = append(args, *filter.UserID)
-		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if filter.ServiceName != nil {
-		args = append(args, *filter.ServiceName)
-		conditions = append(conditions, fmt.Sprintf("service_name = $%d", len(args)))
+	return names, nil
+}
+
+func listQuery(filter domain.ListFilter) (string, []any) {
+	var b queryBuilder
+	return buildListQuery(&b, filter), b.args
+}
+
+// buildListQuery writes the list query for filter, binding its parameters on
+// b so callers can embed it and bind more after it.
+func buildListQuery(b *queryBuilder, filter domain.ListFilter) string {
+	columns := subscriptionColumns
+	if filter.WithTotal {
+		columns += ", COUNT(*) OVER()"
 	}
 
+	query := "SELECT " + columns + " FROM subscriptions"
+
+	// asOfSource refers to the cutoff as $1, so it is bound first.
+	if filter.AsOf != nil {
+		b.bind(asOfCutoff(*filter.AsOf))
+		query = "SELECT " + columns + " FROM " + asOfSource
+	}
+
+	subscriberConditions(b, filter)
+
 	if filter.StartMonthFrom != nil {
-		args = append(args, *filter.StartMonthFrom)
-		conditions = append(conditions, fmt.Sprintf("start_month >= $%d", len(args)))
+		b.where("start_month_key >= ?", domain.MonthKeyOf(*filter.StartMonthFrom))
 	}
 
 	if filter.StartMonthTo != nil {
-		args = append(args, *filter.StartMonthTo)
-		conditions = append(conditions, fmt.Sprintf("start_month <= $%d", len(args)))
+		b.where("start_month_key <= ?", domain.MonthKeyOf(*filter.StartMonthTo))
 	}
 
 	if filter.ActivePeriodFrom != nil && filter.ActivePeriodTo != nil {
-		args = append(args, *filter.ActivePeriodTo)
-		conditions = append(conditions, fmt.Sprintf("start_month <= $%d", len(args)))
-
-		args = append(args, *filter.ActivePeriodFrom)
-		conditions = append(conditions, fmt.Sprintf("(end_month IS NULL OR end_month >= $%d)", len(args)))
+		b.where("start_month_key <= ?", domain.MonthKeyOf(*filter.ActivePeriodTo))
+		b.where("(end_month_key IS NULL OR end_month_key >= ?)", domain.MonthKeyOf(*filter.ActivePeriodFrom))
 	}
 
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	order := " ORDER BY start_month_key"
+	if filter.Search != nil {
+		b.where("(service_name ILIKE ? OR ? <% service_name)", "%"+escapeLike(*filter.Search)+"%", *filter.Search)
+		order = " ORDER BY word_similarity(" + b.bind(*filter.Search) + ", service_name) DESC, start_month_key"
 	}
 
-	query += " ORDER BY start_month"
+	query += b.whereClause() + order
 
+	// Paging values are bound rather than inlined so every page of a filter
+	// shape shares one prepared statement.
 	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+		query += " LIMIT " + b.bind(filter.Limit)
 	}
 
 	if filter.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", filter.Offset)
+		query += " OFFSET " + b.bind(filter.Offset)
 	}
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	return query
+}
+
+func (s *Storage) ExpireSubscriptions(ctx context.Context, before domain.MonthKey) ([]domain.Subscription, error) {
+	const op = "storage.postgresql.ExpireSubscriptions"
+
+	ctx, cancel := s.withTimeout(ctx, opJob)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	archived, err := archiveSubscriptions(ctx, tx, historyUpdate,
+		"status = $1 AND end_month_key IS NOT NULL AND end_month_key < $2", domain.StatusActive, before)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(archived) == 0 {
+		return nil, nil
+	}
+
+	query := `UPDATE subscriptions
+SET status = $1,
+    version = version + 1,
+    updated_at = now()
+WHERE id = ANY($2::uuid[])
+RETURNING ` + subscriptionColumns
+
+	rows, err := tx.Query(ctx, query, domain.StatusExpired, archived)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -160,8 +554,8 @@ func (s *Storage) ListSubscriptions(ctx context.Context, filter domain.ListFilte
 
 	var result []domain.Subscription
 	for rows.Next() {
-		var sub domain.Subscription
-		if err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartMonth, &sub.EndMonth); err != nil {
+		sub, err := scanSubscription(rows)
+		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 		result = append(result, sub)
@@ -171,13 +565,237 @@ func (s *Storage) ListSubscriptions(ctx context.Context, filter domain.ListFilte
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) AdjustPrices(ctx context.Context, input domain.PriceAdjustment) ([]domain.Subscription, error) {
+	const op = "storage.postgresql.AdjustPrices"
+
+	ctx, cancel := s.withTimeout(ctx, opJob)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	result, err := adjustPrices(ctx, tx, input)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// adjustPrices sets the price of every subscription to the service that is
+// still running in or after the effective month, keeping the old versions in
+// the history. A subscription that started before the effective month ends
+// the month before it and continues as a new subscription with the new price,
+// so the spend of earlier months stays as it was. The ended, continuing and
+// repriced subscriptions are all returned.
+func adjustPrices(ctx context.Context, tx pgx.Tx, input domain.PriceAdjustment) ([]domain.Subscription, error) {
+	effective := domain.MonthKeyOf(input.EffectiveMonth)
+
+	archived, err := archiveSubscriptions(ctx, tx, historyUpdate,
+		"service_name = $1 AND (end_month_key IS NULL OR end_month_key >= $2) AND price <> $3",
+		input.ServiceName, effective, input.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(archived) == 0 {
+		return nil, nil
+	}
+
+	// Every archived subscription gets an ID for its continuation; those
+	// starting in or after the effective month leave theirs unused.
+	ids := make([]string, len(archived))
+	for i := range ids {
+		ids[i] = uuid.NewV7().String()
+	}
+
+	continued := `INSERT INTO subscriptions (id, service_name, price, user_id, start_month, end_month, status)
+SELECT c.new_id, s.service_name, $1, s.user_id, $3, s.end_month, s.status
+FROM unnest($2::uuid[], $5::uuid[]) AS c(old_id, new_id)
+JOIN subscriptions s ON s.id = c.old_id
+WHERE s.start_month_key < $4
+ON CONFLICT (user_id, service_name, start_month) DO NOTHING
+RETURNING ` + subscriptionColumns
+
+	result, err := collectSubscriptions(tx.Query(ctx, continued, input.Price, archived, effective.Time(), effective, ids))
+	if err != nil {
+		return nil, err
+	}
+
+	closed := `UPDATE subscriptions
+SET end_month = $2,
+    version = version + 1,
+    updated_at = now()
+WHERE id = ANY($1::uuid[]) AND start_month_key < $3
+RETURNING ` + subscriptionColumns
+
+	ended, err := collectSubscriptions(tx.Query(ctx, closed, archived, effective.Prev().Time(), effective))
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, ended...)
+
+	repriced := `UPDATE subscriptions
+SET price = $1,
+    version = version + 1,
+    updated_at = now()
+WHERE id = ANY($2::uuid[]) AND start_month_key >= $3
+RETURNING ` + subscriptionColumns
+
+	updated, err := collectSubscriptions(tx.Query(ctx, repriced, input.Price, archived, effective))
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, updated...)
+
+	userIDs := make([]string, 0, len(result))
+	for _, sub := range result {
+		userIDs = append(userIDs, sub.UserID.String())
+	}
+
+	if err := refreshUserSpend(ctx, tx, userIDs...); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// collectSubscriptions scans every row returned by a subscription query.
+func collectSubscriptions(rows pgx.Rows, err error) ([]domain.Subscription, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []domain.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sub)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) SubscriptionHistory(ctx context.Context, id uuid.UUID) ([]domain.HistoryEntry, error) {
+	const op = "storage.postgresql.SubscriptionHistory"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	query := `SELECT ` + historyColumns + `, valid_from, valid_to, operation
+FROM subscription_history
+WHERE subscription_id = $1
+UNION ALL
+SELECT ` + subscriptionColumns + `, updated_at, NULL, NULL
+FROM subscriptions
+WHERE id = $1
+ORDER BY version`
+
+	rows, err := s.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []domain.HistoryEntry
+	for rows.Next() {
+		var entry domain.HistoryEntry
+		var operation *string
+
+		err := rows.Scan(&entry.ID, &entry.ServiceName, &entry.Price, &entry.UserID, &entry.StartMonth, &entry.EndMonth,
+			&entry.Status, &entry.Version, &entry.ValidFrom, &entry.ValidTo, &operation)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if operation != nil {
+			entry.Operation = *operation
+		}
+
+		result = append(result, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return result, nil
 }
 
-func sqlNullTime(t *time.Time) any {
-	if t == nil {
-		return sql.NullTime{}
+// archiveSubscriptions copies the rows matching where into the history table,
+// locking them for the rest of the transaction, and returns their ids. The
+// operation is bound after the caller's arguments.
+func archiveSubscriptions(ctx context.Context, tx pgx.Tx, operation, where string, args ...any) ([]string, error) {
+	query := fmt.Sprintf(`INSERT INTO subscription_history
+    (subscription_id, service_name, price, user_id, start_month, end_month, status, version, valid_from, valid_to, operation)
+SELECT id, service_name, price, user_id, start_month, end_month, status, version, updated_at, now(), $%d
+FROM (SELECT * FROM subscriptions WHERE %s FOR UPDATE) AS locked
+RETURNING subscription_id`, len(args)+1, where)
+
+	rows, err := tx.Query(ctx, query, append(args, operation)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// asOfCutoff returns the instant the state of month is read at: the start of
+// the following month, so changes made during month are included.
+func asOfCutoff(month time.Time) time.Time {
+	return domain.MonthKeyOf(month).Next().Time()
+}
+
+// subscriptionID returns the ID input asks for or, without one, a new one.
+func subscriptionID(input domain.CreateInput) uuid.UUID {
+	if input.ID != nil {
+		return *input.ID
 	}
+	return uuid.NewV7()
+}
+
+func scanSubscription(row rowScanner) (domain.Subscription, error) {
+	var sub domain.Subscription
+	err := row.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartMonth, &sub.EndMonth, &sub.Status, &sub.Version)
+	return sub, err
+}
+
+// totalScanner reads the match count that follows the columns of every row
+// when a list asks for its total.
+type totalScanner struct {
+	rowScanner
+	total *int64
+}
 
-	return sql.NullTime{Time: *t, Valid: true}
+func (s totalScanner) Scan(dest ...any) error {
+	return s.rowScanner.Scan(append(dest, s.total)...)
 }
@@ -0,0 +1,270 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/jackc/pgx/v5"
+
+	subdomain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/suggestion"
+)
+
+const suggestionColumns = "id, user_id, service_name, price, start_month, confidence, source, external_id, raw, status, subscription_id, created_at, reviewed_at, review_reason"
+
+func (s *Storage) CreateSuggestion(ctx context.Context, input domain.CreateInput) (domain.Suggestion, error) {
+	const op = "storage.postgresql.CreateSuggestion"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	query := `INSERT INTO subscription_suggestions (user_id, service_name, price, start_month, confidence, source, external_id, raw)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (source, external_id) WHERE external_id IS NOT NULL DO NOTHING
+RETURNING ` + suggestionColumns
+
+	sug, err := scanSuggestion(s.db.QueryRow(ctx, query,
+		input.UserID,
+		input.ServiceName,
+		input.Price,
+		input.StartMonth,
+		input.Confidence,
+		input.Source,
+		input.ExternalID,
+		nullableJSON(input.Raw),
+	))
+	if err == nil {
+		return sug, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return domain.Suggestion{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The detection was ingested before; it is only returned to the same
+	// user.
+	query = "SELECT " + suggestionColumns + " FROM subscription_suggestions WHERE source = $1 AND external_id = $2 AND user_id = $3"
+	sug, err = scanSuggestion(s.db.QueryRow(ctx, query, input.Source, input.ExternalID, input.UserID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Suggestion{}, domain.ErrConflict
+		}
+		return domain.Suggestion{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sug, nil
+}
+
+func (s *Storage) GetSuggestion(ctx context.Context, id uuid.UUID) (domain.Suggestion, error) {
+	const op = "storage.postgresql.GetSuggestion"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	query := "SELECT " + suggestionColumns + " FROM subscription_suggestions WHERE id = $1"
+
+	sug, err := scanSuggestion(s.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Suggestion{}, domain.ErrNotFound
+		}
+		return domain.Suggestion{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sug, nil
+}
+
+func (s *Storage) ConfirmSuggestion(ctx context.Context, id uuid.UUID, input subdomain.CreateInput) (subdomain.Subscription, error) {
+	const op = "storage.postgresql.ConfirmSuggestion"
+
+	sub, err := s.confirmSuggestion(ctx, id, func(ctx context.Context, tx pgx.Tx) (subdomain.Subscription, error) {
+		query := `INSERT INTO subscriptions (id, service_name, price, user_id, start_month, end_month)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING ` + subscriptionColumns
+
+		sub, err := scanSubscription(tx.QueryRow(ctx, query,
+			uuid.NewV7(),
+			input.ServiceName,
+			input.Price,
+			input.UserID,
+			input.StartMonth,
+			input.EndMonth,
+		))
+		if err != nil {
+			return subdomain.Subscription{}, err
+		}
+
+		return sub, refreshUserSpend(ctx, tx, sub.UserID.String())
+	})
+	if err != nil {
+		return subdomain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sub, nil
+}
+
+// ConfirmSuggestionWith confirms the suggestion with a subscription that
+// create stores elsewhere. The suggestion stays locked while create runs, so
+// it is confirmed at most once.
+func (s *Storage) ConfirmSuggestionWith(ctx context.Context, id uuid.UUID, create func(ctx context.Context) (subdomain.Subscription, error)) (subdomain.Subscription, error) {
+	const op = "storage.postgresql.ConfirmSuggestionWith"
+
+	sub, err := s.confirmSuggestion(ctx, id, func(ctx context.Context, _ pgx.Tx) (subdomain.Subscription, error) {
+		return create(ctx)
+	})
+	if err != nil {
+		return subdomain.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sub, nil
+}
+
+// confirmSuggestion locks the pending suggestion, creates its subscription
+// and marks the suggestion accepted in one transaction.
+func (s *Storage) confirmSuggestion(ctx context.Context, id uuid.UUID, create func(ctx context.Context, tx pgx.Tx) (subdomain.Subscription, error)) (subdomain.Subscription, error) {
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return subdomain.Subscription{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var status domain.Status
+	err = tx.QueryRow(ctx, "SELECT status FROM subscription_suggestions WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return subdomain.Subscription{}, domain.ErrNotFound
+		}
+		return subdomain.Subscription{}, err
+	}
+
+	if status != domain.StatusPending {
+		return subdomain.Subscription{}, domain.ErrAlreadyReviewed
+	}
+
+	sub, err := create(ctx, tx)
+	if err != nil {
+		return subdomain.Subscription{}, err
+	}
+
+	query := `UPDATE subscription_suggestions
+SET status = $1, subscription_id = $2, reviewed_at = NOW()
+WHERE id = $3`
+
+	if _, err := tx.Exec(ctx, query, domain.StatusAccepted, sub.ID, id); err != nil {
+		return subdomain.Subscription{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return subdomain.Subscription{}, err
+	}
+
+	return sub, nil
+}
+
+func (s *Storage) ListSuggestions(ctx context.Context, filter domain.ListFilter) ([]domain.Suggestion, error) {
+	const op = "storage.postgresql.ListSuggestions"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	var b queryBuilder
+	b.where("user_id = ?", filter.UserID)
+
+	if filter.Status != nil {
+		b.where("status = ?", *filter.Status)
+	}
+
+	if len(filter.ServiceNames) > 0 {
+		b.where("service_name = ANY(?)", filter.ServiceNames)
+	}
+
+	query := "SELECT " + suggestionColumns + " FROM subscription_suggestions" + b.whereClause() + " ORDER BY created_at DESC"
+
+	if filter.Limit > 0 {
+		query += " LIMIT " + b.bind(filter.Limit)
+	}
+
+	if filter.Offset > 0 {
+		query += " OFFSET " + b.bind(filter.Offset)
+	}
+
+	rows, err := s.db.Query(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []domain.Suggestion
+	for rows.Next() {
+		sug, err := scanSuggestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, sug)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) DismissSuggestion(ctx context.Context, id uuid.UUID, reason *string) (domain.Suggestion, error) {
+	const op = "storage.postgresql.DismissSuggestion"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	query := `UPDATE subscription_suggestions
+SET status = $1, review_reason = $2, reviewed_at = NOW()
+WHERE id = $3 AND status = $4
+RETURNING ` + suggestionColumns
+
+	sug, err := scanSuggestion(s.db.QueryRow(ctx, query, domain.StatusDismissed, reason, id, domain.StatusPending))
+	if err == nil {
+		return sug, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return domain.Suggestion{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.GetSuggestion(ctx, id); err != nil {
+		return domain.Suggestion{}, err
+	}
+
+	return domain.Suggestion{}, domain.ErrAlreadyReviewed
+}
+
+func scanSuggestion(row rowScanner) (domain.Suggestion, error) {
+	var (
+		sug domain.Suggestion
+		raw []byte
+	)
+
+	err := row.Scan(&sug.ID, &sug.UserID, &sug.ServiceName, &sug.Price, &sug.StartMonth, &sug.Confidence, &sug.Source,
+		&sug.ExternalID, &raw, &sug.Status, &sug.SubscriptionID, &sug.CreatedAt, &sug.ReviewedAt, &sug.ReviewReason)
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+
+	sug.Raw = raw
+
+	return sug, nil
+}
+
+func nullableJSON(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return string(raw)
+}
@@ -0,0 +1,146 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/jackc/pgx/v5"
+
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/notification"
+)
+
+// CreateTelegramLinkToken stores token, dropping expired ones on the way.
+func (s *Storage) CreateTelegramLinkToken(ctx context.Context, token domain.LinkToken) error {
+	const op = "storage.postgresql.CreateTelegramLinkToken"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	if _, err := s.db.Exec(ctx, "DELETE FROM telegram_link_tokens WHERE expires_at <= NOW()"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `INSERT INTO telegram_link_tokens (token_hash, user_id, relink, expires_at)
+VALUES ($1, $2, $3, $4)`
+
+	if _, err := s.db.Exec(ctx, query, domain.HashLinkToken(token.Token), token.UserID, token.Relink, token.ExpiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LinkTelegramChat consumes token and links chatID to its user. A link of
+// that user to another chat is only replaced when the token allows it;
+// otherwise the token is kept and ErrAlreadyLinked returned.
+func (s *Storage) LinkTelegramChat(ctx context.Context, token string, chatID int64) (uuid.UUID, error) {
+	const op = "storage.postgresql.LinkTelegramChat"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var (
+		userID uuid.UUID
+		relink bool
+	)
+	err = tx.QueryRow(ctx, `DELETE FROM telegram_link_tokens
+WHERE token_hash = $1 AND expires_at > NOW()
+RETURNING user_id, relink`, domain.HashLinkToken(token)).Scan(&userID, &relink)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", domain.ErrLinkTokenInvalid
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var current int64
+	err = tx.QueryRow(ctx, "SELECT chat_id FROM telegram_chats WHERE user_id = $1 FOR UPDATE", userID).Scan(&current)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		if _, err := tx.Exec(ctx, "INSERT INTO telegram_chats (user_id, chat_id) VALUES ($1, $2)", userID, chatID); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	case err != nil:
+		return "", fmt.Errorf("%s: %w", op, err)
+	case current == chatID:
+	case !relink:
+		return "", domain.ErrAlreadyLinked
+	default:
+		if _, err := tx.Exec(ctx, "UPDATE telegram_chats SET chat_id = $1, linked_at = NOW() WHERE user_id = $2", chatID, userID); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userID, nil
+}
+
+// UnlinkTelegramChat removes every link of chatID.
+func (s *Storage) UnlinkTelegramChat(ctx context.Context, chatID int64) error {
+	const op = "storage.postgresql.UnlinkTelegramChat"
+
+	ctx, cancel := s.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	tag, err := s.db.Exec(ctx, "DELETE FROM telegram_chats WHERE chat_id = $1", chatID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrChatNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) GetTelegramChatID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	const op = "storage.postgresql.GetTelegramChatID"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	var chatID int64
+	err := s.db.QueryRow(ctx, "SELECT chat_id FROM telegram_chats WHERE user_id = $1", userID).Scan(&chatID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, domain.ErrChatNotFound
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chatID, nil
+}
+
+func (s *Storage) GetTelegramUserID(ctx context.Context, chatID int64) (uuid.UUID, error) {
+	const op = "storage.postgresql.GetTelegramUserID"
+
+	ctx, cancel := s.withTimeout(ctx, opRead)
+	defer cancel()
+
+	query := "SELECT user_id FROM telegram_chats WHERE chat_id = $1 ORDER BY linked_at DESC LIMIT 1"
+
+	var userID uuid.UUID
+	err := s.db.QueryRow(ctx, query, chatID).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", domain.ErrChatNotFound
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userID, nil
+}
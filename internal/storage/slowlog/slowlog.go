@@ -0,0 +1,195 @@
+package slowlog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/domain/category"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+	"github.com/Kulibyka/effective-mobile/internal/logger"
+	service "github.com/Kulibyka/effective-mobile/internal/services/subscriptions"
+)
+
+const logGroup = "slow_queries"
+
+// Repository times every call to the wrapped repository and logs the ones
+// slower than the threshold at WARN.
+type Repository struct {
+	repo      service.Repository
+	threshold time.Duration
+	logger    *slog.Logger
+}
+
+// summingRepository keeps the Summer capability of the wrapped repository
+// visible to the service.
+type summingRepository struct {
+	*Repository
+	summer service.Summer
+}
+
+func (r *summingRepository) SumSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error) {
+	defer r.observe(ctx, "SumSubscriptions", time.Now(), filterAttr(filter))
+	return r.summer.SumSubscriptions(ctx, filter)
+}
+
+func New(repo service.Repository, threshold time.Duration, logger *slog.Logger) service.Repository {
+	r := &Repository{repo: repo, threshold: threshold, logger: logger.WithGroup(logGroup)}
+
+	if summer, ok := repo.(service.Summer); ok {
+		return &summingRepository{Repository: r, summer: summer}
+	}
+
+	return r
+}
+
+func (r *Repository) CreateSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, error) {
+	defer r.observe(ctx, "CreateSubscription", time.Now(), slog.String("user_id", input.UserID.String()))
+	return r.repo.CreateSubscription(ctx, input)
+}
+
+func (r *Repository) UpsertSubscription(ctx context.Context, input domain.CreateInput) (domain.Subscription, domain.UpsertOutcome, error) {
+	defer r.observe(ctx, "UpsertSubscription", time.Now(), slog.String("user_id", input.UserID.String()))
+	return r.repo.UpsertSubscription(ctx, input)
+}
+
+func (r *Repository) ServiceNames(ctx context.Context, filter domain.ListFilter) ([]string, error) {
+	defer r.observe(ctx, "ServiceNames", time.Now(), filterAttr(filter))
+	return r.repo.ServiceNames(ctx, filter)
+}
+
+func (r *Repository) UserSpend(ctx context.Context, month domain.MonthKey, filter domain.ListFilter) ([]domain.UserSpend, error) {
+	defer r.observe(ctx, "UserSpend", time.Now(), slog.Int("month", int(month)), filterAttr(filter))
+	return r.repo.UserSpend(ctx, month, filter)
+}
+
+func (r *Repository) GetSubscription(ctx context.Context, id uuid.UUID) (domain.Subscription, error) {
+	defer r.observe(ctx, "GetSubscription", time.Now(), slog.String("subscription_id", id.String()))
+	return r.repo.GetSubscription(ctx, id)
+}
+
+func (r *Repository) UpdateSubscription(ctx context.Context, id uuid.UUID, input domain.UpdateInput) (domain.Subscription, error) {
+	defer r.observe(ctx, "UpdateSubscription", time.Now(), slog.String("subscription_id", id.String()))
+	return r.repo.UpdateSubscription(ctx, id, input)
+}
+
+func (r *Repository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	defer r.observe(ctx, "DeleteSubscription", time.Now(), slog.String("subscription_id", id.String()))
+	return r.repo.DeleteSubscription(ctx, id)
+}
+
+func (r *Repository) ListSubscriptions(ctx context.Context, filter domain.ListFilter) (domain.Page, error) {
+	defer r.observe(ctx, "ListSubscriptions", time.Now(), filterAttr(filter))
+	return r.repo.ListSubscriptions(ctx, filter)
+}
+
+func (r *Repository) ExpireSubscriptions(ctx context.Context, before domain.MonthKey) ([]domain.Subscription, error) {
+	defer r.observe(ctx, "ExpireSubscriptions", time.Now(), slog.Int("before", int(before)))
+	return r.repo.ExpireSubscriptions(ctx, before)
+}
+
+func (r *Repository) SubscriptionHistory(ctx context.Context, id uuid.UUID) ([]domain.HistoryEntry, error) {
+	defer r.observe(ctx, "SubscriptionHistory", time.Now(), slog.String("subscription_id", id.String()))
+	return r.repo.SubscriptionHistory(ctx, id)
+}
+
+func (r *Repository) AdjustPrices(ctx context.Context, input domain.PriceAdjustment) ([]domain.Subscription, error) {
+	defer r.observe(ctx, "AdjustPrices", time.Now(), slog.String("service", input.ServiceName))
+	return r.repo.AdjustPrices(ctx, input)
+}
+
+func (r *Repository) EstimateSubscriptions(ctx context.Context, filter domain.ListFilter) (int64, error) {
+	defer r.observe(ctx, "EstimateSubscriptions", time.Now(), filterAttr(filter))
+	return r.repo.EstimateSubscriptions(ctx, filter)
+}
+
+func (r *Repository) MonthlySpend(ctx context.Context, filter domain.ListFilter) ([]domain.MonthTotal, bool, error) {
+	defer r.observe(ctx, "MonthlySpend", time.Now(), filterAttr(filter))
+	return r.repo.MonthlySpend(ctx, filter)
+}
+
+func (r *Repository) SchedulePriceChange(ctx context.Context, input domain.PriceAdjustment) (domain.PriceChange, error) {
+	defer r.observe(ctx, "SchedulePriceChange", time.Now(), slog.String("service", input.ServiceName))
+	return r.repo.SchedulePriceChange(ctx, input)
+}
+
+func (r *Repository) PendingPriceChanges(ctx context.Context, through domain.MonthKey) ([]domain.PriceChange, error) {
+	defer r.observe(ctx, "PendingPriceChanges", time.Now(), slog.Int("through", int(through)))
+	return r.repo.PendingPriceChanges(ctx, through)
+}
+
+func (r *Repository) ApplyPriceChange(ctx context.Context, id int64) ([]domain.Subscription, error) {
+	defer r.observe(ctx, "ApplyPriceChange", time.Now(), slog.Int64("price_change_id", id))
+	return r.repo.ApplyPriceChange(ctx, id)
+}
+
+func (r *Repository) PurgeEndedSubscriptions(ctx context.Context, before domain.MonthKey, limit int, archive bool) ([]domain.Subscription, error) {
+	defer r.observe(ctx, "PurgeEndedSubscriptions", time.Now(), slog.Int("before", int(before)), slog.Int("limit", limit))
+	return r.repo.PurgeEndedSubscriptions(ctx, before, limit, archive)
+}
+
+func (r *Repository) ServiceCategories(ctx context.Context) (map[string]category.Category, error) {
+	defer r.observe(ctx, "ServiceCategories", time.Now())
+	return r.repo.ServiceCategories(ctx)
+}
+
+// WithinTx reports the whole transaction as well as each call inside it.
+func (r *Repository) WithinTx(ctx context.Context, fn func(repo service.Repository) error) error {
+	defer r.observe(ctx, "WithinTx", time.Now())
+	return r.repo.WithinTx(ctx, func(repo service.Repository) error {
+		return fn(New(repo, r.threshold, r.logger))
+	})
+}
+
+func (r *Repository) observe(ctx context.Context, operation string, start time.Time, attrs ...slog.Attr) {
+	elapsed := time.Since(start)
+	if elapsed < r.threshold {
+		return
+	}
+
+	attrs = append(attrs, slog.String("operation", operation), slog.Duration("duration", elapsed))
+	logger.FromContext(ctx, r.logger, logGroup).LogAttrs(ctx, slog.LevelWarn, "slow storage call", attrs...)
+}
+
+// filterAttr summarises the filter by the fields that are set.
+func filterAttr(filter domain.ListFilter) slog.Attr {
+	var attrs []any
+
+	if !filter.UserID.IsNil() {
+		attrs = append(attrs, slog.String("user_id", filter.UserID.String()))
+	}
+	if len(filter.UserIDs) > 0 {
+		attrs = append(attrs, slog.Int("user_ids", len(filter.UserIDs)))
+	}
+	if filter.ServiceName != nil {
+		attrs = append(attrs, slog.String("service", *filter.ServiceName))
+	}
+	if len(filter.ServiceNames) > 0 {
+		attrs = append(attrs, slog.Int("services", len(filter.ServiceNames)))
+	}
+	if !filter.CategoryID.IsNil() {
+		attrs = append(attrs, slog.String("category_id", filter.CategoryID.String()))
+	}
+	if filter.StartMonthFrom != nil || filter.StartMonthTo != nil {
+		attrs = append(attrs, slog.Bool("start_month_range", true))
+	}
+	if filter.ActivePeriodFrom != nil && filter.ActivePeriodTo != nil {
+		attrs = append(attrs, slog.String("active_from", filter.ActivePeriodFrom.Format(domain.MonthLayout)),
+			slog.String("active_to", filter.ActivePeriodTo.Format(domain.MonthLayout)))
+	}
+	if filter.AsOf != nil {
+		attrs = append(attrs, slog.String("as_of", filter.AsOf.Format(domain.MonthLayout)))
+	}
+	if filter.Limit > 0 {
+		attrs = append(attrs, slog.Int("limit", filter.Limit))
+	}
+	if filter.Offset > 0 {
+		attrs = append(attrs, slog.Int("offset", filter.Offset))
+	}
+	if filter.WithTotal {
+		attrs = append(attrs, slog.Bool("with_total", true))
+	}
+
+	return slog.Group("filter", attrs...)
+}
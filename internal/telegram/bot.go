@@ -0,0 +1,199 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/domain/notification"
+	domain "github.com/Kulibyka/effective-mobile/internal/domain/subscription"
+	"github.com/Kulibyka/effective-mobile/internal/lib/uuid"
+)
+
+const retryDelay = 5 * time.Second
+
+type SubscriptionService interface {
+	List(ctx context.Context, filter domain.ListFilter) (domain.Page, error)
+	Sum(ctx context.Context, input domain.SummaryFilter) (int, error)
+}
+
+type ChatStore interface {
+	LinkTelegramChat(ctx context.Context, token string, chatID int64) (uuid.UUID, error)
+	UnlinkTelegramChat(ctx context.Context, chatID int64) error
+	GetTelegramUserID(ctx context.Context, chatID int64) (uuid.UUID, error)
+}
+
+type Bot struct {
+	client  *Client
+	service SubscriptionService
+	chats   ChatStore
+	logger  *slog.Logger
+}
+
+func NewBot(client *Client, service SubscriptionService, chats ChatStore, logger *slog.Logger) *Bot {
+	return &Bot{client: client, service: service, chats: chats, logger: logger.WithGroup("telegram_bot")}
+}
+
+func (b *Bot) Run(ctx context.Context) {
+	b.logger.Info("starting telegram bot")
+
+	var offset int64
+	for {
+		updates, err := b.client.GetUpdates(ctx, offset)
+		if err != nil {
+			if ctx.Err() != nil {
+				b.logger.Info("stopping telegram bot")
+				return
+			}
+
+			b.logger.Error("failed to get updates", slog.Any("error", err))
+			select {
+			case <-ctx.Done():
+				b.logger.Info("stopping telegram bot")
+				return
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			if update.Message == nil {
+				continue
+			}
+
+			b.handleMessage(ctx, update.Message)
+		}
+	}
+}
+
+func (b *Bot) handleMessage(ctx context.Context, msg *IncomingMessage) {
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 {
+		return
+	}
+
+	command := strings.SplitN(fields[0], "@", 2)[0]
+	b.logger.Debug("handling command", slog.String("command", command), slog.Int64("chat_id", msg.Chat.ID))
+
+	var reply string
+	switch command {
+	case "/start":
+		reply = b.handleStart(ctx, msg.Chat.ID, fields[1:])
+	case "/list":
+		reply = b.handleList(ctx, msg.Chat.ID)
+	case "/total":
+		reply = b.handleTotal(ctx, msg.Chat.ID)
+	case "/stop":
+		reply = b.handleStop(ctx, msg.Chat.ID)
+	default:
+		reply = "Unknown command. Available commands: /start <link token>, /list, /total, /stop"
+	}
+
+	if err := b.client.SendMessage(ctx, msg.Chat.ID, reply); err != nil {
+		b.logger.Error("failed to send reply", slog.Int64("chat_id", msg.Chat.ID), slog.Any("error", err))
+	}
+}
+
+// handleStart links the chat with the one-time token issued through the API
+// for the user, which proves the chat belongs to them.
+func (b *Bot) handleStart(ctx context.Context, chatID int64, args []string) string {
+	if len(args) != 1 {
+		return "Send /start <link token> with the token from your account settings to link this chat."
+	}
+
+	userID, err := b.chats.LinkTelegramChat(ctx, args[0], chatID)
+	if err != nil {
+		switch {
+		case errors.Is(err, notification.ErrLinkTokenInvalid):
+			b.logger.Warn("invalid telegram link token", slog.Int64("chat_id", chatID))
+			return "This link token is invalid or expired. Request a new one."
+		case errors.Is(err, notification.ErrAlreadyLinked):
+			b.logger.Warn("telegram user already linked to another chat", slog.Int64("chat_id", chatID))
+			return "Your account is already linked to another chat. Send /stop there, or request a token that replaces the link."
+		default:
+			b.logger.Error("failed to link telegram chat", slog.Int64("chat_id", chatID), slog.Any("error", err))
+			return "Failed to link chat, please try again later."
+		}
+	}
+
+	b.logger.Info("telegram chat linked", slog.Int64("chat_id", chatID), slog.String("user_id", userID.String()))
+	return "Chat linked. You will receive subscription notifications here."
+}
+
+func (b *Bot) handleStop(ctx context.Context, chatID int64) string {
+	if err := b.chats.UnlinkTelegramChat(ctx, chatID); err != nil {
+		if errors.Is(err, notification.ErrChatNotFound) {
+			return "This chat is not linked."
+		}
+
+		b.logger.Error("failed to unlink telegram chat", slog.Int64("chat_id", chatID), slog.Any("error", err))
+		return "Failed to unlink chat, please try again later."
+	}
+
+	b.logger.Info("telegram chat unlinked", slog.Int64("chat_id", chatID))
+	return "Chat unlinked. You will no longer receive notifications here."
+}
+
+func (b *Bot) handleList(ctx context.Context, chatID int64) string {
+	userID, reply, ok := b.resolveUser(ctx, chatID)
+	if !ok {
+		return reply
+	}
+
+	page, err := b.service.List(ctx, domain.ListFilter{UserID: userID})
+	if err != nil {
+		return "Failed to load subscriptions, please try again later."
+	}
+
+	subs := page.Subscriptions
+	if len(subs) == 0 {
+		return "You have no subscriptions."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your subscriptions:\n")
+	for _, sub := range subs {
+		fmt.Fprintf(&sb, "- %s: %d (since %s", sub.ServiceName, sub.Price, sub.StartMonth.Format(domain.MonthLayout))
+		if sub.EndMonth != nil {
+			fmt.Fprintf(&sb, " until %s", sub.EndMonth.Format(domain.MonthLayout))
+		}
+		sb.WriteString(")\n")
+	}
+
+	return sb.String()
+}
+
+func (b *Bot) handleTotal(ctx context.Context, chatID int64) string {
+	userID, reply, ok := b.resolveUser(ctx, chatID)
+	if !ok {
+		return reply
+	}
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	total, err := b.service.Sum(ctx, domain.SummaryFilter{UserID: userID, PeriodStart: month, PeriodEnd: month})
+	if err != nil {
+		return "Failed to calculate total, please try again later."
+	}
+
+	return fmt.Sprintf("Total for %s: %d", month.Format(domain.MonthLayout), total)
+}
+
+func (b *Bot) resolveUser(ctx context.Context, chatID int64) (uuid.UUID, string, bool) {
+	userID, err := b.chats.GetTelegramUserID(ctx, chatID)
+	if err != nil {
+		if errors.Is(err, notification.ErrChatNotFound) {
+			return "", "This chat is not linked yet. Send /start <link token> first.", false
+		}
+
+		b.logger.Error("failed to resolve telegram chat", slog.Int64("chat_id", chatID), slog.Any("error", err))
+		return "", "Something went wrong, please try again later.", false
+	}
+
+	return userID, "", true
+}
@@ -0,0 +1,127 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+type APIError struct {
+	Code        int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram api error %d: %s", e.Code, e.Description)
+}
+
+type Update struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *IncomingMessage `json:"message"`
+}
+
+type IncomingMessage struct {
+	Chat Chat   `json:"chat"`
+	Text string `json:"text"`
+}
+
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+type Client struct {
+	httpClient  *http.Client
+	baseURL     string
+	pollTimeout time.Duration
+}
+
+func NewClient(cfg config.TelegramConfig) *Client {
+	return &Client{
+		httpClient:  &http.Client{Timeout: cfg.PollTimeout + cfg.RequestTimeout},
+		baseURL:     strings.TrimRight(cfg.APIURL, "/") + "/bot" + cfg.Token,
+		pollTimeout: cfg.PollTimeout,
+	}
+}
+
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) error {
+	const op = "telegram.Client.SendMessage"
+
+	req := struct {
+		ChatID int64  `json:"chat_id"`
+		Text   string `json:"text"`
+	}{ChatID: chatID, Text: text}
+
+	if err := c.call(ctx, "sendMessage", req, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Client) GetUpdates(ctx context.Context, offset int64) ([]Update, error) {
+	const op = "telegram.Client.GetUpdates"
+
+	req := struct {
+		Offset         int64    `json:"offset"`
+		Timeout        int      `json:"timeout"`
+		AllowedUpdates []string `json:"allowed_updates"`
+	}{Offset: offset, Timeout: int(c.pollTimeout.Seconds()), AllowedUpdates: []string{"message"}}
+
+	var updates []Update
+	if err := c.call(ctx, "getUpdates", req, &updates); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return updates, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, payload any, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	if !envelope.OK {
+		code := envelope.ErrorCode
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		return &APIError{Code: code, Description: envelope.Description}
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(envelope.Result, result); err != nil {
+			return fmt.Errorf("decode result: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+// Package vault reads PostgreSQL credentials from the HashiCorp Vault
+// database secrets engine over its HTTP API.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kulibyka/effective-mobile/internal/config"
+)
+
+// Credentials is a database user and password. LeaseID is empty for static
+// roles, which are not leased; TTL is then the time until Vault rotates the
+// password.
+type Credentials struct {
+	Username  string
+	Password  string
+	LeaseID   string
+	TTL       time.Duration
+	Renewable bool
+}
+
+type Client struct {
+	cfg  config.VaultConfig
+	http *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+func New(cfg config.VaultConfig) *Client {
+	return &Client{
+		cfg:   cfg,
+		http:  &http.Client{Timeout: cfg.Timeout},
+		token: cfg.Token,
+	}
+}
+
+// Login obtains a Vault token with the kubernetes auth method. With the token
+// method the configured token is used as is and Login does nothing.
+func (c *Client) Login(ctx context.Context) error {
+	const op = "vault.Login"
+
+	if c.cfg.AuthMethod != "kubernetes" {
+		return nil
+	}
+
+	jwt, err := os.ReadFile(c.cfg.KubernetesTokenPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	body := map[string]string{"role": c.cfg.AuthRole, "jwt": strings.TrimSpace(string(jwt))}
+	if err := c.do(ctx, http.MethodPost, "auth/"+c.cfg.AuthMount+"/login", body, &resp); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return fmt.Errorf("%s: no token in response", op)
+	}
+
+	c.mu.Lock()
+	c.token = resp.Auth.ClientToken
+	c.mu.Unlock()
+
+	return nil
+}
+
+// DatabaseCredentials reads credentials for the configured role: a new
+// leased user for a dynamic role, the current password of a static one.
+func (c *Client) DatabaseCredentials(ctx context.Context) (Credentials, error) {
+	const op = "vault.DatabaseCredentials"
+
+	endpoint := "creds"
+	if c.cfg.Static {
+		endpoint = "static-creds"
+	}
+
+	var resp struct {
+		LeaseID       string `json:"lease_id"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+		Data          struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			TTL      int64  `json:"ttl"`
+		} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.cfg.DatabaseMount+"/"+endpoint+"/"+c.cfg.Role, nil, &resp); err != nil {
+		return Credentials{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if resp.Data.Username == "" || resp.Data.Password == "" {
+		return Credentials{}, fmt.Errorf("%s: no credentials in response", op)
+	}
+
+	creds := Credentials{
+		Username:  resp.Data.Username,
+		Password:  resp.Data.Password,
+		LeaseID:   resp.LeaseID,
+		TTL:       time.Duration(resp.LeaseDuration) * time.Second,
+		Renewable: resp.Renewable,
+	}
+	if c.cfg.Static {
+		creds.TTL = time.Duration(resp.Data.TTL) * time.Second
+	}
+
+	return creds, nil
+}
+
+// RenewLease asks to extend the lease by increment and returns the TTL Vault
+// granted, which is shorter once the lease nears its maximum.
+func (c *Client) RenewLease(ctx context.Context, leaseID string, increment time.Duration) (time.Duration, error) {
+	const op = "vault.RenewLease"
+
+	var resp struct {
+		LeaseDuration int64 `json:"lease_duration"`
+	}
+	body := map[string]any{"lease_id": leaseID, "increment": int64(increment / time.Second)}
+	if err := c.do(ctx, http.MethodPut, "sys/leases/renew", body, &resp); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return time.Duration(resp.LeaseDuration) * time.Second, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.cfg.Address, "/")+"/v1/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.cfg.Namespace)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		if len(apiErr.Errors) == 0 {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.Join(apiErr.Errors, "; "))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
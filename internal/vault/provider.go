@@ -0,0 +1,138 @@
+package vault
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Provider holds the current database credentials and keeps them valid:
+// leases are renewed at two thirds of their TTL and, once Vault stops
+// extending them, replaced by new credentials. Static credentials are
+// reread after Vault rotates them.
+type Provider struct {
+	client  *Client
+	backoff time.Duration
+	logger  *slog.Logger
+
+	mu       sync.RWMutex
+	creds    Credentials
+	onRotate func()
+}
+
+// NewProvider logs in and reads the first credentials.
+func NewProvider(ctx context.Context, client *Client, backoff time.Duration, logger *slog.Logger) (*Provider, error) {
+	p := &Provider{client: client, backoff: backoff, logger: logger.WithGroup("vault")}
+
+	creds, err := p.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.creds = creds
+
+	p.logger.Info("database credentials obtained",
+		slog.String("username", creds.Username),
+		slog.Duration("ttl", creds.TTL),
+		slog.Bool("renewable", creds.Renewable),
+	)
+
+	return p, nil
+}
+
+// Credentials returns the user and password new connections should use.
+func (p *Provider) Credentials() (string, string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.creds.Username, p.creds.Password
+}
+
+// OnRotate registers fn to run after the credentials were replaced, e.g. to
+// close connections opened with the old ones before Vault revokes them.
+func (p *Provider) OnRotate(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.onRotate = fn
+}
+
+// Run keeps the credentials valid until ctx is cancelled.
+func (p *Provider) Run(ctx context.Context) {
+	for {
+		p.mu.RLock()
+		creds := p.creds
+		p.mu.RUnlock()
+
+		// A zero TTL means the credentials do not expire.
+		if creds.TTL <= 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(creds.TTL * 2 / 3):
+		}
+
+		if creds.LeaseID != "" && creds.Renewable {
+			ttl, err := p.client.RenewLease(ctx, creds.LeaseID, creds.TTL)
+			switch {
+			case err != nil:
+				p.logger.Warn("failed to renew database credentials, replacing them", slog.Any("error", err))
+			case ttl >= creds.TTL:
+				p.setTTL(ttl)
+				continue
+			default:
+				p.logger.Info("database credentials reached their maximum TTL, replacing them", slog.Duration("ttl", ttl))
+			}
+		}
+
+		p.rotate(ctx)
+	}
+}
+
+// rotate replaces the credentials, retrying until it succeeds or ctx is
+// cancelled.
+func (p *Provider) rotate(ctx context.Context) {
+	for {
+		creds, err := p.fetch(ctx)
+		if err == nil {
+			p.mu.Lock()
+			p.creds = creds
+			onRotate := p.onRotate
+			p.mu.Unlock()
+
+			p.logger.Info("database credentials replaced", slog.String("username", creds.Username), slog.Duration("ttl", creds.TTL))
+			if onRotate != nil {
+				onRotate()
+			}
+			return
+		}
+
+		p.logger.Error("failed to replace database credentials", slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.backoff):
+		}
+	}
+}
+
+// fetch logs in again first, since a kubernetes auth token may have expired
+// since the last time.
+func (p *Provider) fetch(ctx context.Context) (Credentials, error) {
+	if err := p.client.Login(ctx); err != nil {
+		return Credentials{}, err
+	}
+
+	return p.client.DatabaseCredentials(ctx)
+}
+
+func (p *Provider) setTTL(ttl time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.creds.TTL = ttl
+}
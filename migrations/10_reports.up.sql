@@ -0,0 +1,19 @@
+CREATE TABLE reports
+(
+    id            UUID PRIMARY KEY     DEFAULT uuid_generate_v4(),
+    name          TEXT        NOT NULL,
+    user_id       UUID,
+    service_name  TEXT,
+    period_months INT         NOT NULL CHECK (period_months > 0),
+    grouping      TEXT        NOT NULL CHECK (grouping IN ('none', 'service', 'user', 'month')),
+    format        TEXT        NOT NULL CHECK (format IN ('csv', 'json', 'xlsx')),
+    schedule      TEXT        NOT NULL,
+    channel       TEXT        NOT NULL CHECK (channel IN ('email', 'webhook', 'file')),
+    target        TEXT        NOT NULL,
+    next_run_at   TIMESTAMPTZ NOT NULL,
+    last_run_at   TIMESTAMPTZ,
+    last_error    TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_reports_next_run ON reports (next_run_at);
@@ -0,0 +1,14 @@
+CREATE TABLE price_changes
+(
+    id                  BIGSERIAL PRIMARY KEY,
+    service_name        TEXT        NOT NULL,
+    price               INT         NOT NULL CHECK (price >= 0),
+    effective_month     DATE        NOT NULL,
+    effective_month_key INT GENERATED ALWAYS AS
+        ((EXTRACT(YEAR FROM effective_month) * 100 + EXTRACT(MONTH FROM effective_month))::int) STORED,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    notified_at         TIMESTAMPTZ,
+    applied_at          TIMESTAMPTZ
+);
+
+CREATE INDEX idx_price_changes_pending ON price_changes (effective_month_key) WHERE applied_at IS NULL;
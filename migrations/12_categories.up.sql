@@ -0,0 +1,23 @@
+CREATE TABLE categories
+(
+    id         UUID PRIMARY KEY     DEFAULT uuid_generate_v4(),
+    name       TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE service_categories
+(
+    service_name TEXT PRIMARY KEY,
+    category_id  UUID NOT NULL REFERENCES categories (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_service_categories_category ON service_categories (category_id);
+
+INSERT INTO categories (name)
+VALUES ('streaming'),
+       ('music'),
+       ('cloud'),
+       ('fitness'),
+       ('software'),
+       ('news'),
+       ('gaming');
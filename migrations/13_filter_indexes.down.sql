@@ -0,0 +1,7 @@
+-- no-transaction
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_subscriptions_user ON subscriptions (user_id);
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_subscriptions_open_ended;
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_subscriptions_user_start;
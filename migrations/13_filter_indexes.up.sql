@@ -0,0 +1,22 @@
+-- no-transaction
+-- timeout: 30m
+
+-- List filters compare the generated month keys, not start_month itself.
+--
+-- idx_subscriptions_user_start serves user_id = $1 with optional
+-- start_month_key bounds and ORDER BY start_month_key as a single index scan
+-- without a sort. It also covers plain user_id lookups, so it replaces
+-- idx_subscriptions_user.
+--
+-- service_name lookups (service_name = $1 and = ANY($1), price changes,
+-- category filters) already use idx_subscriptions_service from 1_init.
+--
+-- idx_subscriptions_open_ended serves the end_month_key IS NULL branch of the
+-- active period condition; the planner combines it with
+-- idx_subscriptions_month_keys in a BitmapOr instead of scanning every
+-- subscription that has not ended.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_subscriptions_user_start ON subscriptions (user_id, start_month_key);
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_subscriptions_user;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_subscriptions_open_ended ON subscriptions (start_month_key) WHERE end_month_key IS NULL;
@@ -0,0 +1,43 @@
+-- no-transaction
+-- timeout: 30m
+
+-- Upserts identify a subscription by user, service and start month.
+--
+-- Duplicates of that key would fail the index build. The most recently
+-- updated subscription of each key is kept; the others are archived as
+-- deleted and the spend rollup of their owners is rebuilt.
+DO $$
+DECLARE
+    affected UUID[];
+BEGIN
+    WITH ranked AS (
+        SELECT id, row_number() OVER (PARTITION BY user_id, service_name, start_month ORDER BY updated_at DESC, version DESC, id) AS n
+        FROM subscriptions
+    ), removed AS (
+        DELETE FROM subscriptions s
+        USING ranked r
+        WHERE s.id = r.id AND r.n > 1
+        RETURNING s.*
+    ), archived AS (
+        INSERT INTO subscription_history
+            (subscription_id, service_name, price, user_id, start_month, end_month, status, version, valid_from, valid_to, operation)
+        SELECT id, service_name, price, user_id, start_month, end_month, status, version, updated_at, now(), 'delete'
+        FROM removed
+        RETURNING user_id
+    )
+    SELECT array_agg(DISTINCT user_id) INTO affected FROM archived;
+
+    IF affected IS NOT NULL THEN
+        DELETE FROM monthly_spend WHERE user_id = ANY (affected);
+
+        INSERT INTO monthly_spend (month_key, user_id, service_name, total, subscriptions)
+        SELECT (EXTRACT(YEAR FROM m) * 100 + EXTRACT(MONTH FROM m))::int, s.user_id, s.service_name, SUM(s.price), COUNT(*)
+        FROM subscriptions s
+        CROSS JOIN (SELECT make_date(horizon_key / 100, horizon_key % 100, 1) AS horizon FROM monthly_spend_state) AS st
+        CROSS JOIN LATERAL generate_series(s.start_month, LEAST(COALESCE(s.end_month, st.horizon), st.horizon), interval '1 month') AS m
+        WHERE s.user_id = ANY (affected)
+        GROUP BY 1, 2, 3;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_subscriptions_natural_key ON subscriptions (user_id, service_name, start_month);
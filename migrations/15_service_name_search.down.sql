@@ -0,0 +1,3 @@
+-- no-transaction
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_subscriptions_service_trgm;
@@ -0,0 +1,8 @@
+-- no-transaction
+-- timeout: 30m
+
+-- Serves the fuzzy service name search on list: ILIKE substring matches and
+-- the <% word similarity operator both use the trigram index.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_subscriptions_service_trgm ON subscriptions USING GIN (service_name gin_trgm_ops);
@@ -0,0 +1,12 @@
+-- One-time tokens proving that the chat sending /start <token> belongs to
+-- the user the token was issued for. Only a hash of each token is kept.
+CREATE TABLE telegram_link_tokens
+(
+    token_hash TEXT PRIMARY KEY,
+    user_id    UUID        NOT NULL,
+    relink     BOOLEAN     NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_telegram_link_tokens_expires ON telegram_link_tokens (expires_at);
@@ -0,0 +1,15 @@
+-- Monthly spending limits users set per category. alerted_at is when the
+-- user was last warned about going over the limit, so the budgets job warns
+-- once a month.
+CREATE TABLE category_budgets
+(
+    user_id       UUID        NOT NULL,
+    category_id   UUID        NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    monthly_limit INT         NOT NULL CHECK (monthly_limit >= 0),
+    alerted_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, category_id)
+);
+
+CREATE INDEX idx_category_budgets_category ON category_budgets (category_id);
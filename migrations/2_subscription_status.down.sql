@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscriptions_status_end;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS status;
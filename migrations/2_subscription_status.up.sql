@@ -0,0 +1,10 @@
+ALTER TABLE subscriptions
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'expired', 'cancelled'));
+
+UPDATE subscriptions
+SET status = 'expired'
+WHERE end_month IS NOT NULL
+  AND end_month < date_trunc('month', CURRENT_DATE);
+
+CREATE INDEX idx_subscriptions_status_end ON subscriptions (status, end_month);
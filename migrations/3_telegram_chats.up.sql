@@ -0,0 +1,8 @@
+CREATE TABLE telegram_chats
+(
+    user_id   UUID PRIMARY KEY,
+    chat_id   BIGINT      NOT NULL,
+    linked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_telegram_chats_chat ON telegram_chats (chat_id);
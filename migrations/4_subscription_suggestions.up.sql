@@ -0,0 +1,21 @@
+CREATE TABLE subscription_suggestions
+(
+    id              UUID PRIMARY KEY          DEFAULT uuid_generate_v4(),
+    user_id         UUID             NOT NULL,
+    service_name    TEXT             NOT NULL,
+    price           INT CHECK (price >= 0),
+    start_month     DATE,
+    confidence      DOUBLE PRECISION NOT NULL CHECK (confidence >= 0 AND confidence <= 1),
+    source          TEXT             NOT NULL,
+    external_id     TEXT,
+    raw             JSONB,
+    status          TEXT             NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'accepted', 'dismissed')),
+    subscription_id UUID REFERENCES subscriptions (id) ON DELETE SET NULL,
+    created_at      TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+    reviewed_at     TIMESTAMPTZ
+);
+
+CREATE INDEX idx_suggestions_user_status ON subscription_suggestions (user_id, status);
+CREATE UNIQUE INDEX idx_suggestions_source_external ON subscription_suggestions (source, external_id)
+    WHERE external_id IS NOT NULL;
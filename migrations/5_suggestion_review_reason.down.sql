@@ -0,0 +1,2 @@
+ALTER TABLE subscription_suggestions
+    DROP COLUMN IF EXISTS review_reason;
@@ -0,0 +1,2 @@
+ALTER TABLE subscription_suggestions
+    ADD COLUMN review_reason TEXT;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_subscriptions_status_end_key;
+DROP INDEX IF EXISTS idx_subscriptions_month_keys;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS end_month_key,
+    DROP COLUMN IF EXISTS start_month_key;
@@ -0,0 +1,8 @@
+ALTER TABLE subscriptions
+    ADD COLUMN start_month_key INT GENERATED ALWAYS AS
+        ((EXTRACT(YEAR FROM start_month) * 100 + EXTRACT(MONTH FROM start_month))::int) STORED,
+    ADD COLUMN end_month_key   INT GENERATED ALWAYS AS
+        ((EXTRACT(YEAR FROM end_month) * 100 + EXTRACT(MONTH FROM end_month))::int) STORED;
+
+CREATE INDEX idx_subscriptions_month_keys ON subscriptions (start_month_key, end_month_key);
+CREATE INDEX idx_subscriptions_status_end_key ON subscriptions (status, end_month_key);
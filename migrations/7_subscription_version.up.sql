@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS subscription_history;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,28 @@
+ALTER TABLE subscriptions
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+UPDATE subscriptions
+SET updated_at = start_month;
+
+CREATE TABLE subscription_history
+(
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id UUID        NOT NULL,
+    service_name    TEXT        NOT NULL,
+    price           INT         NOT NULL,
+    user_id         UUID        NOT NULL,
+    start_month     DATE        NOT NULL,
+    end_month       DATE,
+    status          TEXT        NOT NULL,
+    version         INT         NOT NULL,
+    valid_from      TIMESTAMPTZ NOT NULL,
+    valid_to        TIMESTAMPTZ NOT NULL,
+    operation       TEXT        NOT NULL CHECK (operation IN ('update', 'delete')),
+    start_month_key INT GENERATED ALWAYS AS
+        ((EXTRACT(YEAR FROM start_month) * 100 + EXTRACT(MONTH FROM start_month))::int) STORED,
+    end_month_key   INT GENERATED ALWAYS AS
+        ((EXTRACT(YEAR FROM end_month) * 100 + EXTRACT(MONTH FROM end_month))::int) STORED
+);
+
+CREATE INDEX idx_subscription_history_subscription ON subscription_history (subscription_id, version);
+CREATE INDEX idx_subscription_history_validity ON subscription_history (valid_from, valid_to);
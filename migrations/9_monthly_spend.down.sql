@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS monthly_spend_state;
+DROP TABLE IF EXISTS monthly_spend;
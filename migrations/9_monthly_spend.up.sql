@@ -0,0 +1,18 @@
+CREATE TABLE monthly_spend
+(
+    month_key     INT    NOT NULL,
+    user_id       UUID   NOT NULL,
+    service_name  TEXT   NOT NULL,
+    total         BIGINT NOT NULL,
+    subscriptions INT    NOT NULL,
+    PRIMARY KEY (user_id, month_key, service_name)
+);
+
+CREATE INDEX idx_monthly_spend_month ON monthly_spend (month_key);
+
+CREATE TABLE monthly_spend_state
+(
+    id           BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    horizon_key  INT         NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL
+);
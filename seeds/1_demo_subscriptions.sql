@@ -0,0 +1,28 @@
+-- Demo data for local development. Every statement is idempotent, so the
+-- seeds can be loaded again after a partial run.
+
+INSERT INTO service_categories (service_name, category_id)
+SELECT v.service_name, c.id
+FROM (VALUES ('Yandex Plus', 'streaming'),
+             ('Netflix', 'streaming'),
+             ('Spotify', 'music'),
+             ('iCloud', 'cloud'),
+             ('JetBrains', 'software')) AS v (service_name, category)
+         JOIN categories c ON c.name = v.category
+ON CONFLICT (service_name) DO NOTHING;
+
+INSERT INTO subscriptions (id, service_name, price, user_id, start_month, end_month)
+VALUES ('0190a1b0-0000-7000-8000-000000000001', 'Yandex Plus', 400, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '2025-07-01', NULL),
+       ('0190a1b0-0000-7000-8000-000000000002', 'Spotify', 299, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '2025-01-01', '2025-12-01'),
+       ('0190a1b0-0000-7000-8000-000000000003', 'iCloud', 149, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '2024-03-01', NULL),
+       ('0190a1b0-0000-7000-8000-000000000004', 'Netflix', 999, '2b7f4c1e-8d3a-4e5b-9f60-1a2b3c4d5e6f', '2025-02-01', NULL),
+       ('0190a1b0-0000-7000-8000-000000000005', 'JetBrains', 2490, '2b7f4c1e-8d3a-4e5b-9f60-1a2b3c4d5e6f', '2024-09-01', '2025-08-01'),
+       ('0190a1b0-0000-7000-8000-000000000006', 'Yandex Plus', 400, '2b7f4c1e-8d3a-4e5b-9f60-1a2b3c4d5e6f', '2025-05-01', NULL)
+ON CONFLICT DO NOTHING;
+
+-- Expired rows get the status the expiration job would have given them.
+UPDATE subscriptions
+SET status = 'expired'
+WHERE id IN ('0190a1b0-0000-7000-8000-000000000002', '0190a1b0-0000-7000-8000-000000000005')
+  AND status = 'active'
+  AND end_month < date_trunc('month', CURRENT_DATE);